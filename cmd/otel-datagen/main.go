@@ -1,13 +1,19 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"log"
 	"os"
 
 	"github.com/antithesishq/otel-datagen/internal/aggro"
 	"github.com/antithesishq/otel-datagen/internal/config"
+	"github.com/antithesishq/otel-datagen/internal/exporters"
 	"github.com/antithesishq/otel-datagen/internal/generators"
+	"github.com/antithesishq/otel-datagen/internal/latency"
+	"github.com/antithesishq/otel-datagen/internal/scenario"
+	"github.com/antithesishq/otel-datagen/internal/selftelemetry"
+	"github.com/antithesishq/otel-datagen/internal/severity"
 	"github.com/antithesishq/otel-datagen/internal/timestamps"
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
@@ -26,11 +32,67 @@ func parseTimestampConfig(cmd *cobra.Command) (*timestamps.TimestampConfig, erro
 	return timestamps.ParseTimestampConfig(timestampStart, timestampSpacing)
 }
 
+// parseLatency reads the span latency distribution for a component (empty keeps the default 10-100ms)
+func parseLatency(component string, cmd *cobra.Command) (latency.Distribution, error) {
+	spec := viper.GetString("generate." + component + ".latency")
+	if spec == "" {
+		spec, _ = cmd.Flags().GetString("latency")
+	}
+	if spec == "" {
+		return latency.Default(), nil
+	}
+	return latency.Parse(spec)
+}
+
+// reportExportOutcome prints the export summary and exits with status 1 when the share of items that
+// were not delivered exceeds the allowed failure ratio
+func reportExportOutcome() {
+	summaryConfig, err := exporters.ParseSummaryConfig()
+	if err != nil {
+		log.Fatalf("Invalid summary configuration: %v", err)
+	}
+	summary := exporters.Outcomes()
+	if summaryConfig.Format != "none" {
+		if err := summary.Write(os.Stderr, summaryConfig.Format); err != nil {
+			log.Printf("Error writing export summary: %v", err)
+		}
+	}
+	if summaryConfig.Exceeded(summary) {
+		log.Printf("Failure ratio %.4f exceeds the maximum of %.4f", summary.FailureRatio, summaryConfig.MaxFailureRatio)
+		os.Exit(1)
+	}
+}
+
 var generateCmd = &cobra.Command{
 	Use:   "generate",
 	Short: "Generate OpenTelemetry signals",
+	PersistentPreRun: func(cmd *cobra.Command, args []string) {
+		// Reject an invalid summary configuration before generating anything
+		if _, err := exporters.ParseSummaryConfig(); err != nil {
+			log.Fatalf("Invalid summary configuration: %v", err)
+		}
+
+		// Report generation and export metrics of the process itself while it runs
+		telemetryConfig, err := selftelemetry.ParseConfig()
+		if err != nil {
+			log.Fatalf("Invalid self-telemetry configuration: %v", err)
+		}
+		selfTelemetry, err = selftelemetry.Start(context.Background(), telemetryConfig)
+		if err != nil {
+			log.Fatalf("Failed to start self-telemetry: %v", err)
+		}
+	},
+	PersistentPostRun: func(cmd *cobra.Command, args []string) {
+		if err := selfTelemetry.Shutdown(context.Background()); err != nil {
+			log.Printf("Error shutting down self-telemetry: %v", err)
+		}
+		reportExportOutcome()
+	},
 }
 
+// selfTelemetry reports metrics about the running generate command, when enabled
+var selfTelemetry *selftelemetry.Telemetry
+
 var tracesCmd = &cobra.Command{
 	Use:   "traces",
 	Short: "Generate trace data",
@@ -56,6 +118,27 @@ var tracesCmd = &cobra.Command{
 			overrideAttrs, _ = cmd.Flags().GetStringSlice("override-attr")
 		}
 
+		logsPerSpan := viper.GetInt("generate.traces.logs_per_span")
+		if logsPerSpan == 0 {
+			logsPerSpan, _ = cmd.Flags().GetInt("logs-per-span")
+		}
+
+		logMismatchRatio := viper.GetFloat64("generate.traces.log_mismatch_ratio")
+		if logMismatchRatio == 0 {
+			logMismatchRatio, _ = cmd.Flags().GetFloat64("log-mismatch-ratio")
+		}
+
+		spanLogSeverity, err := severity.ParseSeverityConfig("traces")
+		if err != nil {
+			log.Fatalf("Invalid severity configuration: %v", err)
+		}
+
+		spanLogConfig := &generators.SpanLogConfig{
+			LogsPerSpan:   logsPerSpan,
+			MismatchRatio: logMismatchRatio,
+			Severity:      spanLogSeverity,
+		}
+
 		// Parse new aggro configuration
 		_ = aggro.ParseAggroConfig("traces")
 
@@ -92,7 +175,12 @@ var tracesCmd = &cobra.Command{
 			log.Fatalf("Error parsing timestamp configuration: %v", err)
 		}
 
-		generators.GenerateTraces(numTraces, numSpans, numAttributes, overrideAttrs, resourceAttrs, otlpEndpoint, stdoutEnabled, timestampConfig)
+		latencyDist, err := parseLatency("traces", cmd)
+		if err != nil {
+			log.Fatalf("Error parsing latency: %v", err)
+		}
+
+		generators.GenerateTraces(numTraces, numSpans, numAttributes, overrideAttrs, resourceAttrs, otlpEndpoint, stdoutEnabled, spanLogConfig, latencyDist, timestampConfig)
 	},
 }
 
@@ -228,7 +316,125 @@ var metricsCmd = &cobra.Command{
 			log.Fatalf("Error parsing timestamp configuration: %v", err)
 		}
 
-		generators.GenerateMetrics(numMetrics, metricType, metricName, counterMin, counterMax, resourceAttrs, otlpEndpoint, otlpProtocol, stdoutEnabled, timestampConfig)
+		temporality := viper.GetString("generate.metrics.temporality")
+		if temporality == "" {
+			temporality, _ = cmd.Flags().GetString("temporality")
+		}
+
+		generators.GenerateMetrics(numMetrics, metricType, metricName, counterMin, counterMax, resourceAttrs, otlpEndpoint, otlpProtocol, stdoutEnabled, temporality, timestampConfig)
+	},
+}
+
+var allCmd = &cobra.Command{
+	Use:   "all",
+	Short: "Generate correlated traces, logs and metrics from one shared run",
+	Run: func(cmd *cobra.Command, args []string) {
+		// Get values from viper (includes config file with CLI flag precedence)
+		numTraces := viper.GetInt("generate.all.num_traces")
+		if numTraces == 0 {
+			numTraces, _ = cmd.Flags().GetInt("num-traces")
+		}
+
+		numSpans := viper.GetInt("generate.all.num_spans")
+		if numSpans == 0 {
+			numSpans, _ = cmd.Flags().GetInt("num-spans")
+		}
+
+		numAttributes := viper.GetInt("generate.all.num_attributes")
+		if numAttributes == 0 {
+			numAttributes, _ = cmd.Flags().GetInt("num-attributes")
+		}
+
+		overrideAttrs := viper.GetStringSlice("generate.all.override_attr")
+		if len(overrideAttrs) == 0 {
+			overrideAttrs, _ = cmd.Flags().GetStringSlice("override-attr")
+		}
+
+		logsPerSpan := viper.GetInt("generate.all.logs_per_span")
+		if logsPerSpan == 0 {
+			logsPerSpan, _ = cmd.Flags().GetInt("logs-per-span")
+		}
+
+		logMismatchRatio := viper.GetFloat64("generate.all.log_mismatch_ratio")
+		if logMismatchRatio == 0 {
+			logMismatchRatio, _ = cmd.Flags().GetFloat64("log-mismatch-ratio")
+		}
+
+		errorRate := viper.GetFloat64("generate.all.error_rate")
+		if errorRate == 0 {
+			errorRate, _ = cmd.Flags().GetFloat64("error-rate")
+		}
+
+		spanLogSeverity, err := severity.ParseSeverityConfig("all")
+		if err != nil {
+			log.Fatalf("Invalid severity configuration: %v", err)
+		}
+
+		spanLogConfig := &generators.SpanLogConfig{
+			LogsPerSpan:   logsPerSpan,
+			MismatchRatio: logMismatchRatio,
+			Severity:      spanLogSeverity,
+		}
+
+		// Parse new aggro configuration
+		_ = aggro.ParseAggroConfig("all")
+
+		// Get global flags - resource attributes and OTLP endpoint
+		resourceAttrs, _ := cmd.Root().PersistentFlags().GetStringSlice("resource-attr")
+
+		// If no CLI resource attributes, get from config file format
+		if len(resourceAttrs) == 0 {
+			if resourceMap := viper.GetStringMapString("resource"); len(resourceMap) > 0 {
+				for key, value := range resourceMap {
+					resourceAttrs = append(resourceAttrs, key+"="+value)
+				}
+			}
+		}
+
+		otlpEndpoint := viper.GetString("otlp-endpoint")
+		if otlpEndpoint == "" {
+			otlpEndpoint, _ = cmd.Root().PersistentFlags().GetString("otlp-endpoint")
+		}
+
+		stdoutEnabled := viper.GetBool("stdout")
+		if !stdoutEnabled {
+			stdoutEnabled, _ = cmd.Root().PersistentFlags().GetBool("stdout")
+		}
+
+		// Automatically enable stdout when no OTLP endpoint is specified
+		if otlpEndpoint == "" {
+			stdoutEnabled = true
+		}
+
+		// Default to gRPC protocol for now
+		otlpProtocol := "grpc"
+
+		// Parse timestamp configuration
+		timestampConfig, err := parseTimestampConfig(cmd.Parent())
+		if err != nil {
+			log.Fatalf("Error parsing timestamp configuration: %v", err)
+		}
+
+		// A scenario file replaces the generic trace shape with simulated services
+		scenarioFile := viper.GetString("generate.all.scenario")
+		if scenarioFile == "" {
+			scenarioFile, _ = cmd.Flags().GetString("scenario")
+		}
+		if scenarioFile != "" {
+			sc, err := scenario.Load(scenarioFile)
+			if err != nil {
+				log.Fatalf("Error loading scenario: %v", err)
+			}
+			generators.GenerateScenario(sc, numTraces, resourceAttrs, otlpEndpoint, otlpProtocol, stdoutEnabled, timestampConfig)
+			return
+		}
+
+		latencyDist, err := parseLatency("all", cmd)
+		if err != nil {
+			log.Fatalf("Error parsing latency: %v", err)
+		}
+
+		generators.GenerateAll(numTraces, numSpans, numAttributes, overrideAttrs, resourceAttrs, otlpEndpoint, otlpProtocol, stdoutEnabled, spanLogConfig, errorRate, latencyDist, timestampConfig)
 	},
 }
 
@@ -237,9 +443,10 @@ func init() {
 	generateCmd.AddCommand(tracesCmd)
 	generateCmd.AddCommand(logsCmd)
 	generateCmd.AddCommand(metricsCmd)
+	generateCmd.AddCommand(allCmd)
 
 	// Set up flags using config package
-	config.SetupFlags(rootCmd, generateCmd, tracesCmd, logsCmd, metricsCmd)
+	config.SetupFlags(rootCmd, generateCmd, tracesCmd, logsCmd, metricsCmd, allCmd)
 
 	// Set up viper configuration
 	config.Initialize(rootCmd)
@@ -2,33 +2,79 @@ package main
 
 import (
 	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/binary"
 	"encoding/json"
+	"encoding/pem"
 	"fmt"
+	"io"
+	"maps"
+	"math"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
 	"os"
 	"path/filepath"
+	"regexp"
+	"slices"
+	"strconv"
 	"strings"
+	"sync"
 	"testing"
+	"time"
 
 	"github.com/antithesishq/otel-datagen/internal/aggro"
+	"github.com/antithesishq/otel-datagen/internal/exporters"
+	"github.com/antithesishq/otel-datagen/internal/fleet"
 	"github.com/antithesishq/otel-datagen/internal/generators"
+	"github.com/antithesishq/otel-datagen/internal/latency"
 	"github.com/antithesishq/otel-datagen/internal/metrics"
+	"github.com/antithesishq/otel-datagen/internal/prometheus"
 	"github.com/antithesishq/otel-datagen/internal/randomness"
+	"github.com/antithesishq/otel-datagen/internal/scenario"
+	"github.com/antithesishq/otel-datagen/internal/selftelemetry"
+	"github.com/antithesishq/otel-datagen/internal/severity"
+	"github.com/antithesishq/otel-datagen/internal/shapes"
 	"github.com/antithesishq/otel-datagen/internal/timestamps"
 	"github.com/go-faker/faker/v4"
+	"github.com/golang/snappy"
+	"github.com/spf13/pflag"
+	"github.com/spf13/viper"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 	"go.opentelemetry.io/otel"
 	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
 	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracegrpc"
 	"go.opentelemetry.io/otel/exporters/stdout/stdoutlog"
 	"go.opentelemetry.io/otel/exporters/stdout/stdoutmetric"
 	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
 	otellog "go.opentelemetry.io/otel/log"
+	"go.opentelemetry.io/otel/metric"
 	sdklog "go.opentelemetry.io/otel/sdk/log"
 	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
 	"go.opentelemetry.io/otel/sdk/resource"
 	"go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
 	semconv "go.opentelemetry.io/otel/semconv/v1.21.0"
+	oteltrace "go.opentelemetry.io/otel/trace"
+	collogspb "go.opentelemetry.io/proto/otlp/collector/logs/v1"
+	coltracepb "go.opentelemetry.io/proto/otlp/collector/trace/v1"
+	resourcepb "go.opentelemetry.io/proto/otlp/resource/v1"
+	"google.golang.org/grpc"
+	grpccodes "google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/stats"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/encoding/protowire"
 	"gopkg.in/yaml.v3"
 )
 
@@ -1433,3 +1479,3504 @@ func TestAggroFlagSyntaxBehavior(t *testing.T) {
 	// Without proper flag detection should NOT have aggro metadata
 	assert.NotContains(t, outputWithoutEquals, "aggro.string", "When aggro is not active, should not apply string chaos engineering")
 }
+
+func TestMetricAggroValuesOnlyForValueAggro(t *testing.T) {
+	ctx := context.Background()
+
+	// seriesOf records a shaped counter twice and returns the attributes of every series
+	seriesOf := func(aggroConfig *aggro.AggroConfig) []attribute.Set {
+		reader := sdkmetric.NewManualReader()
+		mp := sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader))
+		defer mp.Shutdown(ctx)
+		shape, err := shapes.Parse("sawtooth:period=4", 0, 30)
+		require.NoError(t, err)
+		require.NoError(t, metrics.GenerateWithShape(ctx, mp, 4, "counter", "aggro.series", shape, nil, nil, 1, aggroConfig, "grpc"))
+		require.NoError(t, metrics.GenerateWithShape(ctx, mp, 4, "counter", "aggro.series", shape, nil, nil, 1, aggroConfig, "grpc"))
+
+		rm := &metricdata.ResourceMetrics{}
+		require.NoError(t, reader.Collect(ctx, rm))
+		var sets []attribute.Set
+		for _, dp := range rm.ScopeMetrics[0].Metrics[0].Data.(metricdata.Sum[int64]).DataPoints {
+			sets = append(sets, dp.Attributes)
+		}
+		return sets
+	}
+
+	// Value aggro adds an aggro value to every data point, splitting the counter into series
+	sets := seriesOf(&aggro.AggroConfig{StringActive: true})
+	assert.Greater(t, len(sets), 1)
+	for _, set := range sets {
+		_, ok := set.Value("aggro.value")
+		assert.True(t, ok)
+	}
+
+	// Other aggro kinds keep the shaped counter a single series that evolves over time;
+	// with temporality aggro the same series is then exported by both resources
+	for name, aggroConfig := range map[string]*aggro.AggroConfig{
+		"labels":        {LabelsActive: true},
+		"temporality":   {TemporalityActive: true},
+		"remote-write":  {RemoteWriteActive: true},
+		"trace-format":  {TraceFormatActive: true},
+		"metric-format": {MetricFormatActive: true},
+	} {
+		sets = seriesOf(aggroConfig)
+		require.Len(t, sets, 1, name)
+		_, ok := sets[0].Value("aggro.value")
+		assert.False(t, ok, name)
+	}
+}
+
+// ===== LOG SEVERITY TESTS =====
+
+// generateLogsWithProviderToFile is a test helper that runs the real log generator against a file exporter
+func generateLogsWithProviderToFile(numLogs int, aggroConfig *aggro.AggroConfig, severityConfig *severity.SeverityConfig, outputFile string) error {
+	ctx := context.Background()
+
+	// Create file for output
+	file, err := os.Create(outputFile)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	// Create file exporter for logs
+	exporter, err := stdoutlog.New(
+		stdoutlog.WithPrettyPrint(),
+		stdoutlog.WithWriter(file),
+	)
+	if err != nil {
+		return err
+	}
+
+	// Create logger provider
+	lp := sdklog.NewLoggerProvider(
+		sdklog.WithProcessor(sdklog.NewBatchProcessor(exporter)),
+	)
+	defer lp.Shutdown(ctx)
+
+	timestampConfig := &timestamps.TimestampConfig{}
+	if err := generators.GenerateLogsWithProvider(ctx, lp, numLogs, 2, []string{}, aggroConfig, severityConfig, timestampConfig); err != nil {
+		return err
+	}
+
+	// Force flush to ensure output
+	return lp.ForceFlush(ctx)
+}
+
+func TestSeverityWeightParsing(t *testing.T) {
+	weights, err := severity.ParseWeights("info=70, warn=20,error=10,21")
+	require.NoError(t, err)
+	require.Len(t, weights, 4)
+	assert.Equal(t, otellog.SeverityInfo, weights[0].Severity)
+	assert.Equal(t, 70, weights[0].Weight)
+	assert.Equal(t, otellog.SeverityWarn, weights[1].Severity)
+	assert.Equal(t, otellog.SeverityFatal, weights[3].Severity)
+	assert.Equal(t, 1, weights[3].Weight)
+
+	_, err = severity.ParseWeights("info=0")
+	assert.Error(t, err)
+	_, err = severity.ParseWeights("loud=5")
+	assert.Error(t, err)
+}
+
+func TestGenerateLogsSeverityMixAndEventName(t *testing.T) {
+	tmpDir := t.TempDir()
+	outputFile := filepath.Join(tmpDir, "logs.json")
+
+	severityConfig := &severity.SeverityConfig{
+		Weights:    []severity.Weight{{Severity: otellog.SeverityError, Weight: 1}},
+		TextMode:   "standard",
+		EventNames: []string{"user.login"},
+		EventRatio: 1.0,
+	}
+
+	err := generateLogsWithProviderToFile(3, &aggro.AggroConfig{}, severityConfig, outputFile)
+	require.NoError(t, err)
+
+	content, err := os.ReadFile(outputFile)
+	require.NoError(t, err)
+
+	output := string(content)
+	assert.Contains(t, output, `"Severity": 17`)
+	assert.Contains(t, output, `"SeverityText": "ERROR"`)
+	assert.Contains(t, output, `"EventName": "user.login"`)
+	assert.NotContains(t, output, `"SeverityText": "INFO"`)
+	assert.NotContains(t, output, "aggro.severity")
+}
+
+func TestGenerateLogsSeverityTextMismatch(t *testing.T) {
+	severityConfig := &severity.SeverityConfig{
+		Weights:  []severity.Weight{{Severity: otellog.SeverityWarn, Weight: 1}},
+		TextMode: "mismatch",
+	}
+
+	for i := 0; i < 20; i++ {
+		choice := severityConfig.Pick()
+		assert.Equal(t, otellog.SeverityWarn, choice.Severity)
+		assert.Equal(t, "warn", choice.Level)
+		assert.NotEqual(t, "WARN", choice.Text)
+		assert.Empty(t, choice.EventName)
+	}
+}
+
+func TestAggroSeverityNumber(t *testing.T) {
+	tmpDir := t.TempDir()
+	outputFile := filepath.Join(tmpDir, "logs.json")
+
+	aggroConfig := &aggro.AggroConfig{
+		SeverityActive: true,
+		SeverityTarget: "number",
+	}
+
+	err := generateLogsWithProviderToFile(5, aggroConfig, nil, outputFile)
+	require.NoError(t, err)
+
+	content, err := os.ReadFile(outputFile)
+	require.NoError(t, err)
+
+	output := string(content)
+	assert.Contains(t, output, "aggro.severity")
+	// Every record should have had its severity number replaced with an out-of-range value
+	assert.NotContains(t, output, `"Severity": 9,`)
+	// Other aggro types stay inactive
+	assert.NotContains(t, output, "aggro.string")
+}
+
+// ===== LOG-TRACE CORRELATION TESTS =====
+
+// generateCorrelatedTracesToFiles is a test helper that writes spans and their span logs to separate files
+func generateCorrelatedTracesToFiles(numTraces int, numSpans int, spanLogConfig *generators.SpanLogConfig, traceFile string, logFile string) error {
+	ctx := context.Background()
+
+	tf, err := os.Create(traceFile)
+	if err != nil {
+		return err
+	}
+	defer tf.Close()
+
+	lf, err := os.Create(logFile)
+	if err != nil {
+		return err
+	}
+	defer lf.Close()
+
+	traceExporter, err := stdouttrace.New(stdouttrace.WithWriter(tf))
+	if err != nil {
+		return err
+	}
+	logExporter, err := stdoutlog.New(stdoutlog.WithWriter(lf))
+	if err != nil {
+		return err
+	}
+
+	tp := trace.NewTracerProvider(trace.WithSyncer(traceExporter))
+	defer tp.Shutdown(ctx)
+	lp := sdklog.NewLoggerProvider(sdklog.WithProcessor(sdklog.NewSimpleProcessor(logExporter)))
+	defer lp.Shutdown(ctx)
+
+	timestampConfig := &timestamps.TimestampConfig{StartTime: time.Now()}
+	return generators.GenerateTracesWithLogs(ctx, tp, lp, numTraces, numSpans, 1, []string{}, &aggro.AggroConfig{}, spanLogConfig, nil, timestampConfig)
+}
+
+// decodeJSONStream decodes a stream of concatenated JSON objects from a file
+func decodeJSONStream(t *testing.T, path string) []map[string]interface{} {
+	f, err := os.Open(path)
+	require.NoError(t, err)
+	defer f.Close()
+
+	var objects []map[string]interface{}
+	decoder := json.NewDecoder(f)
+	for decoder.More() {
+		var obj map[string]interface{}
+		require.NoError(t, decoder.Decode(&obj))
+		objects = append(objects, obj)
+	}
+	return objects
+}
+
+func TestSpanLogsCarrySpanContext(t *testing.T) {
+	tmpDir := t.TempDir()
+	traceFile := filepath.Join(tmpDir, "traces.json")
+	logFile := filepath.Join(tmpDir, "logs.json")
+
+	err := generateCorrelatedTracesToFiles(2, 3, &generators.SpanLogConfig{LogsPerSpan: 2}, traceFile, logFile)
+	require.NoError(t, err)
+
+	// Index spans by span ID
+	type window struct{ traceID, start, end string }
+	spans := map[string]window{}
+	for _, span := range decodeJSONStream(t, traceFile) {
+		sc := span["SpanContext"].(map[string]interface{})
+		spans[sc["SpanID"].(string)] = window{sc["TraceID"].(string), span["StartTime"].(string), span["EndTime"].(string)}
+	}
+	require.Len(t, spans, 6)
+
+	logs := decodeJSONStream(t, logFile)
+	require.Len(t, logs, 12)
+	for _, record := range logs {
+		span, ok := spans[record["SpanID"].(string)]
+		require.True(t, ok, "log record should reference a generated span")
+		assert.Equal(t, span.traceID, record["TraceID"])
+		assert.Equal(t, "01", record["TraceFlags"])
+
+		// Log timestamps fall inside the span window
+		logTime, err := time.Parse(time.RFC3339Nano, record["Timestamp"].(string))
+		require.NoError(t, err)
+		start, err := time.Parse(time.RFC3339Nano, span.start)
+		require.NoError(t, err)
+		end, err := time.Parse(time.RFC3339Nano, span.end)
+		require.NoError(t, err)
+		assert.True(t, logTime.After(start))
+		assert.True(t, logTime.Before(end))
+	}
+}
+
+func TestSpanLogsFollowSeverityConfig(t *testing.T) {
+	tmpDir := t.TempDir()
+	traceFile := filepath.Join(tmpDir, "traces.json")
+	logFile := filepath.Join(tmpDir, "logs.json")
+
+	spanLogConfig := &generators.SpanLogConfig{
+		LogsPerSpan: 2,
+		Severity: &severity.SeverityConfig{
+			Weights:    []severity.Weight{{Severity: otellog.SeverityError, Weight: 1}},
+			TextMode:   "lower",
+			EventNames: []string{"checkout.failed"},
+			EventRatio: 1.0,
+		},
+	}
+
+	err := generateCorrelatedTracesToFiles(1, 2, spanLogConfig, traceFile, logFile)
+	require.NoError(t, err)
+
+	logs := decodeJSONStream(t, logFile)
+	require.Len(t, logs, 4)
+	for _, record := range logs {
+		assert.Equal(t, float64(otellog.SeverityError), record["Severity"])
+		assert.Equal(t, "error", record["SeverityText"])
+		assert.Equal(t, "checkout.failed", record["EventName"])
+	}
+}
+
+func TestSpanLogsMismatchedTraceIDs(t *testing.T) {
+	tmpDir := t.TempDir()
+	traceFile := filepath.Join(tmpDir, "traces.json")
+	logFile := filepath.Join(tmpDir, "logs.json")
+
+	err := generateCorrelatedTracesToFiles(2, 2, &generators.SpanLogConfig{LogsPerSpan: 1, MismatchRatio: 1.0}, traceFile, logFile)
+	require.NoError(t, err)
+
+	// Index span contexts by span name
+	type ids struct{ traceID, spanID string }
+	spansByName := map[string]ids{}
+	for _, span := range decodeJSONStream(t, traceFile) {
+		sc := span["SpanContext"].(map[string]interface{})
+		spansByName[span["Name"].(string)] = ids{sc["TraceID"].(string), sc["SpanID"].(string)}
+	}
+
+	content, err := os.ReadFile(logFile)
+	require.NoError(t, err)
+	assert.Contains(t, string(content), "aggro.correlation")
+
+	// Every log must point at a different trace than the span it was emitted in
+	logs := decodeJSONStream(t, logFile)
+	require.Len(t, logs, 4)
+	for _, record := range logs {
+		body := record["Body"].(map[string]interface{})["Value"].(string)
+		spanName := strings.TrimSuffix(body, "-log-1")
+		enclosing, ok := spansByName[spanName]
+		require.True(t, ok, "unexpected log body %q", body)
+		assert.NotEqual(t, enclosing.traceID, record["TraceID"])
+		assert.NotEqual(t, enclosing.spanID, record["SpanID"])
+	}
+}
+
+// ===== UNIFIED GENERATION TESTS =====
+
+func TestGenerateAllCorrelatesSignals(t *testing.T) {
+	ctx := context.Background()
+	tmpDir := t.TempDir()
+	logFile := filepath.Join(tmpDir, "logs.json")
+
+	// Shared resource across all three providers
+	res, err := resource.New(ctx, resource.WithAttributes(semconv.ServiceName("checkout")))
+	require.NoError(t, err)
+
+	spanExporter := tracetest.NewInMemoryExporter()
+	tp := trace.NewTracerProvider(trace.WithSyncer(spanExporter), trace.WithResource(res))
+	defer tp.Shutdown(ctx)
+
+	lf, err := os.Create(logFile)
+	require.NoError(t, err)
+	defer lf.Close()
+	logExporter, err := stdoutlog.New(stdoutlog.WithWriter(lf))
+	require.NoError(t, err)
+	lp := sdklog.NewLoggerProvider(sdklog.WithProcessor(sdklog.NewSimpleProcessor(logExporter)), sdklog.WithResource(res))
+	defer lp.Shutdown(ctx)
+
+	reader := sdkmetric.NewManualReader()
+	mp := sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader), sdkmetric.WithResource(res))
+	defer mp.Shutdown(ctx)
+
+	timestampConfig := &timestamps.TimestampConfig{StartTime: time.Now()}
+	spanLogConfig := &generators.SpanLogConfig{LogsPerSpan: 1}
+	err = generators.GenerateAllWithProviders(ctx, tp, lp, mp, 3, 2, 1, []string{}, &aggro.AggroConfig{}, spanLogConfig, 1.0, nil, timestampConfig, nil)
+	require.NoError(t, err)
+
+	// Every span was generated and marked as failed
+	spans := spanExporter.GetSpans()
+	require.Len(t, spans, 6)
+	traceIDs := map[string]bool{}
+	for _, span := range spans {
+		traceIDs[span.SpanContext.TraceID().String()] = true
+		assert.Equal(t, "Error", span.Status.Code.String())
+	}
+
+	// Every log belongs to a generated trace
+	logs := decodeJSONStream(t, logFile)
+	require.Len(t, logs, 6)
+	for _, record := range logs {
+		assert.True(t, traceIDs[record["TraceID"].(string)])
+	}
+
+	// RED metrics carry exemplars that link back to generated traces
+	rm := &metricdata.ResourceMetrics{}
+	require.NoError(t, reader.Collect(ctx, rm))
+	assert.Equal(t, res, rm.Resource)
+
+	found := map[string]bool{}
+	for _, sm := range rm.ScopeMetrics {
+		for _, m := range sm.Metrics {
+			found[m.Name] = true
+			switch data := m.Data.(type) {
+			case metricdata.Sum[int64]:
+				var total int64
+				for _, dp := range data.DataPoints {
+					total += dp.Value
+					for _, ex := range dp.Exemplars {
+						assert.True(t, traceIDs[oteltrace.TraceID(ex.TraceID).String()])
+					}
+				}
+				assert.Equal(t, int64(6), total)
+			case metricdata.Histogram[float64]:
+				exemplars := 0
+				for _, dp := range data.DataPoints {
+					for _, ex := range dp.Exemplars {
+						exemplars++
+						assert.True(t, traceIDs[oteltrace.TraceID(ex.TraceID).String()])
+					}
+				}
+				assert.Greater(t, exemplars, 0)
+			}
+		}
+	}
+	assert.True(t, found["requests"])
+	assert.True(t, found["errors"])
+	assert.True(t, found["duration"])
+}
+
+// ===== SCENARIO TESTS =====
+
+const testScenario = `
+name: shop
+services:
+  - name: frontend
+    resource:
+      service.version: 1.4.2
+    operations:
+      - name: GET /checkout
+        latency: 20ms
+        attributes:
+          http.route: /checkout
+        logs:
+          - body: checkout started
+          - body: checkout failed
+            severity: error
+            when: error
+        calls:
+          - service: checkout
+            operation: PlaceOrder
+    metrics:
+      - name: http.server.active_requests
+        type: gauge
+        min: 0
+        max: 50
+  - name: checkout
+    operations:
+      - name: PlaceOrder
+        latency: 30ms
+        error_rate: 1.0
+`
+
+func TestScenarioValidation(t *testing.T) {
+	sc, err := scenario.Parse([]byte(testScenario))
+	require.NoError(t, err)
+
+	// Entrypoints default to operations nobody calls
+	require.Len(t, sc.Entrypoints, 1)
+	assert.Equal(t, "frontend", sc.Entrypoints[0].Service)
+	assert.Equal(t, "GET /checkout", sc.Entrypoints[0].Operation)
+	assert.Equal(t, []string{"service.name=frontend", "service.version=1.4.2"}, sc.Service("frontend").ResourceAttributes())
+
+	invalid := map[string]string{
+		"unknown call": `
+services:
+  - name: a
+    operations:
+      - name: op
+        calls:
+          - service: b
+            operation: op
+`,
+		"cycle": `
+services:
+  - name: a
+    operations:
+      - name: op
+        calls:
+          - service: b
+            operation: op
+  - name: b
+    operations:
+      - name: op
+        calls:
+          - service: a
+            operation: op
+`,
+		"bad latency": `
+services:
+  - name: a
+    operations:
+      - name: op
+        latency: fast
+`,
+	}
+	for name, spec := range invalid {
+		_, err := scenario.Parse([]byte(spec))
+		assert.Error(t, err, name)
+	}
+}
+
+func TestGenerateScenarioSpansAcrossServices(t *testing.T) {
+	ctx := context.Background()
+	tmpDir := t.TempDir()
+
+	sc, err := scenario.Parse([]byte(testScenario))
+	require.NoError(t, err)
+
+	// One set of providers per service, each with its own resource
+	spanExporters := map[string]*tracetest.InMemoryExporter{}
+	logFiles := map[string]string{}
+	providers := map[string]*generators.ServiceProviders{}
+	for _, svc := range sc.Services {
+		res, err := resource.New(ctx, resource.WithAttributes(semconv.ServiceName(svc.Name)))
+		require.NoError(t, err)
+
+		spanExporters[svc.Name] = tracetest.NewInMemoryExporter()
+		tp := trace.NewTracerProvider(trace.WithSyncer(spanExporters[svc.Name]), trace.WithResource(res))
+		defer tp.Shutdown(ctx)
+
+		logFiles[svc.Name] = filepath.Join(tmpDir, svc.Name+".json")
+		lf, err := os.Create(logFiles[svc.Name])
+		require.NoError(t, err)
+		defer lf.Close()
+		logExporter, err := stdoutlog.New(stdoutlog.WithWriter(lf))
+		require.NoError(t, err)
+		lp := sdklog.NewLoggerProvider(sdklog.WithProcessor(sdklog.NewSimpleProcessor(logExporter)), sdklog.WithResource(res))
+		defer lp.Shutdown(ctx)
+
+		mp := sdkmetric.NewMeterProvider(sdkmetric.WithReader(sdkmetric.NewManualReader()), sdkmetric.WithResource(res))
+		defer mp.Shutdown(ctx)
+
+		providers[svc.Name] = &generators.ServiceProviders{TracerProvider: tp, LoggerProvider: lp, MeterProvider: mp}
+	}
+
+	timestampConfig := &timestamps.TimestampConfig{StartTime: time.Now()}
+	err = generators.GenerateScenarioWithProviders(ctx, sc, providers, 2, &aggro.AggroConfig{}, timestampConfig, nil)
+	require.NoError(t, err)
+
+	// frontend: server span plus client span for the call; checkout: server span
+	frontendSpans := spanExporters["frontend"].GetSpans()
+	checkoutSpans := spanExporters["checkout"].GetSpans()
+	require.Len(t, frontendSpans, 4)
+	require.Len(t, checkoutSpans, 2)
+
+	clients := map[oteltrace.SpanID]tracetest.SpanStub{}
+	for _, span := range frontendSpans {
+		if span.SpanKind == oteltrace.SpanKindClient {
+			clients[span.SpanContext.SpanID()] = span
+		}
+	}
+	require.Len(t, clients, 2)
+
+	for _, server := range checkoutSpans {
+		assert.Equal(t, "PlaceOrder", server.Name)
+		assert.Equal(t, oteltrace.SpanKindServer, server.SpanKind)
+		assert.Equal(t, "Error", server.Status.Code.String())
+
+		// Server span is a child of frontend's client span and lies within it
+		client, ok := clients[server.Parent.SpanID()]
+		require.True(t, ok)
+		assert.False(t, server.StartTime.Before(client.StartTime))
+		assert.False(t, server.EndTime.After(client.EndTime))
+	}
+
+	// Failure propagates to the entrypoint, which logs both lines
+	logs := decodeJSONStream(t, logFiles["frontend"])
+	require.Len(t, logs, 4)
+	bodies := map[string]int{}
+	for _, record := range logs {
+		body := record["Body"].(map[string]interface{})["Value"].(string)
+		bodies[body]++
+	}
+	assert.Equal(t, 2, bodies["checkout started"])
+	assert.Equal(t, 2, bodies["checkout failed"])
+}
+
+// ===== LATENCY DISTRIBUTION TESTS =====
+
+func TestLatencyDistributions(t *testing.T) {
+	histogramFile := filepath.Join(t.TempDir(), "latencies.txt")
+	require.NoError(t, os.WriteFile(histogramFile, []byte("# bound count\n10ms 5\n50ms,0\n200ms 1\n"), 0644))
+
+	bounds := map[string][2]time.Duration{
+		"fixed:value=42ms":                         {42 * time.Millisecond, 42 * time.Millisecond},
+		"40ms":                                     {20 * time.Millisecond, 60 * time.Millisecond},
+		"uniform:min=5ms,max=10ms":                 {5 * time.Millisecond, 10 * time.Millisecond},
+		"normal:mean=50ms,stddev=10ms":             {0, time.Second},
+		"lognormal:median=50ms,sigma=0.5":          {0, time.Hour},
+		"pareto:min=10ms,alpha=1.2,max=2s":         {10 * time.Millisecond, 2 * time.Second},
+		"bimodal:fast=5ms,slow=500ms,slow_ratio=1": {0, time.Hour},
+		"empirical:file=" + histogramFile:          {0, 200 * time.Millisecond},
+	}
+	for specStr, bound := range bounds {
+		dist, err := latency.Parse(specStr)
+		require.NoError(t, err, specStr)
+		for i := 0; i < 100; i++ {
+			d := dist.Sample()
+			assert.GreaterOrEqual(t, d, bound[0], specStr)
+			assert.LessOrEqual(t, d, bound[1], specStr)
+		}
+	}
+
+	// Empty 10-50ms bucket is never sampled
+	dist, err := latency.Parse("empirical:file=" + histogramFile)
+	require.NoError(t, err)
+	for i := 0; i < 100; i++ {
+		d := dist.Sample()
+		assert.False(t, d > 10*time.Millisecond && d <= 50*time.Millisecond)
+	}
+
+	for _, invalid := range []string{"gamma:shape=2", "lognormal:median=fast", "normal:mean=10ms,stdev=1ms", "pareto:alpha=2", "empirical:file=/nonexistent",
+		"lognormal:median=50ms,sigma=NaN", "pareto:min=10ms,alpha=nan", "pareto:min=10ms,alpha=+Inf", "bimodal:fast=5ms,slow=100ms,slow_ratio=NaN", "bimodal:fast=5ms,slow=100ms,sigma=-Inf"} {
+		_, err := latency.Parse(invalid)
+		assert.Error(t, err, invalid)
+	}
+}
+
+func TestNormFloat64IsFiniteStandardNormal(t *testing.T) {
+	const n = 20000
+	var sum, sumSquares float64
+	for i := 0; i < n; i++ {
+		v := randomness.NormFloat64()
+		require.False(t, math.IsInf(v, 0) || math.IsNaN(v))
+		sum += v
+		sumSquares += v * v
+	}
+	mean := sum / n
+	assert.InDelta(t, 0, mean, 0.05)
+	assert.InDelta(t, 1, sumSquares/n-mean*mean, 0.05)
+}
+
+func TestRootSpanCoversChildren(t *testing.T) {
+	ctx := context.Background()
+
+	spanExporter := tracetest.NewInMemoryExporter()
+	tp := trace.NewTracerProvider(trace.WithSyncer(spanExporter))
+	defer tp.Shutdown(ctx)
+
+	// Children start a second apart, well past the root's own 10ms
+	dist, err := latency.Parse("fixed:value=10ms")
+	require.NoError(t, err)
+	timestampConfig := &timestamps.TimestampConfig{StartTime: time.Now().Add(-time.Hour), Spacing: time.Second}
+	err = generators.GenerateTracesWithLogs(ctx, tp, nil, 2, 4, 0, []string{}, &aggro.AggroConfig{}, nil, dist, timestampConfig)
+	require.NoError(t, err)
+
+	spans := spanExporter.GetSpans()
+	require.Len(t, spans, 8)
+
+	roots := map[oteltrace.SpanID]tracetest.SpanStub{}
+	for _, span := range spans {
+		if !span.Parent.IsValid() {
+			roots[span.SpanContext.SpanID()] = span
+		}
+	}
+	require.Len(t, roots, 2)
+
+	for _, span := range spans {
+		if !span.Parent.IsValid() {
+			continue
+		}
+		assert.Equal(t, 10*time.Millisecond, span.EndTime.Sub(span.StartTime))
+		root := roots[span.Parent.SpanID()]
+		assert.False(t, span.EndTime.After(root.EndTime), "child ends after root")
+	}
+	for _, root := range roots {
+		assert.Equal(t, 3*time.Second+10*time.Millisecond, root.EndTime.Sub(root.StartTime))
+	}
+}
+
+// ===== HISTOGRAM AGGREGATION TESTS =====
+
+// collectHistogram generates histogram data points with the given views and returns the single collected metric
+func collectHistogram(t *testing.T, histogramConfig *metrics.HistogramConfig, metricType string, metricName string, aggroConfig *aggro.AggroConfig) metricdata.Metrics {
+	ctx := context.Background()
+	reader := sdkmetric.NewManualReader()
+	mp := sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader), sdkmetric.WithView(histogramConfig.Views()...))
+	defer mp.Shutdown(ctx)
+
+	require.NoError(t, metrics.Generate(ctx, mp, 10, metricType, metricName, 1, 100, aggroConfig, "grpc"))
+
+	rm := &metricdata.ResourceMetrics{}
+	require.NoError(t, reader.Collect(ctx, rm))
+	require.Len(t, rm.ScopeMetrics, 1)
+	require.Len(t, rm.ScopeMetrics[0].Metrics, 1)
+	return rm.ScopeMetrics[0].Metrics[0]
+}
+
+func TestHistogramViews(t *testing.T) {
+	// Exponential aggregation with limited size and no min/max
+	config := metrics.DefaultHistogramConfig()
+	config.Aggregation = "exponential"
+	config.MaxSize = 8
+	config.NoMinMax = true
+	m := collectHistogram(t, config, "histogram", "latency", nil)
+	expo, ok := m.Data.(metricdata.ExponentialHistogram[float64])
+	require.True(t, ok, "expected exponential histogram, got %T", m.Data)
+	for _, dp := range expo.DataPoints {
+		assert.LessOrEqual(t, len(dp.PositiveBucket.Counts), 8)
+		_, defined := dp.Min.Value()
+		assert.False(t, defined)
+	}
+
+	// Per-metric boundaries override the global aggregation
+	config.MetricBoundaries = map[string][]float64{"latency": {10, 50, 90}}
+	m = collectHistogram(t, config, "int64-histogram", "latency", nil)
+	explicit, ok := m.Data.(metricdata.Histogram[int64])
+	require.True(t, ok, "expected explicit histogram, got %T", m.Data)
+	for _, dp := range explicit.DataPoints {
+		assert.Equal(t, []float64{10, 50, 90}, dp.Bounds)
+	}
+
+	_, err := metrics.ParseBoundaries("10,5")
+	assert.Error(t, err)
+	config = metrics.DefaultHistogramConfig()
+	config.MaxScale = 21
+	assert.Error(t, config.Validate())
+}
+
+func TestAggroHistogramOverflow(t *testing.T) {
+	config := metrics.DefaultHistogramConfig()
+	config.Boundaries = []float64{1, 10, 100}
+	aggroConfig := &aggro.AggroConfig{HistogramActive: true, HistogramTarget: "overflow"}
+
+	m := collectHistogram(t, config, "histogram", "sizes", aggroConfig)
+	hist, ok := m.Data.(metricdata.Histogram[float64])
+	require.True(t, ok)
+	require.NotEmpty(t, hist.DataPoints)
+
+	for _, dp := range hist.DataPoints {
+		// Only the overflow bucket holds samples
+		last := len(dp.BucketCounts) - 1
+		assert.Equal(t, dp.Count, dp.BucketCounts[last])
+		for _, c := range dp.BucketCounts[:last] {
+			assert.Equal(t, uint64(0), c)
+		}
+		value, ok := dp.Attributes.Value("aggro.histogram")
+		assert.True(t, ok)
+		assert.Equal(t, "overflow", value.AsString())
+	}
+}
+
+func TestAggroHistogramBuildsUpInOneSeries(t *testing.T) {
+	ctx := context.Background()
+	reader := sdkmetric.NewManualReader()
+	mp := sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader))
+	defer mp.Shutdown(ctx)
+
+	// A shaped histogram keeps one series, so the empty buckets between the two values show up in it
+	shape, err := shapes.Parse("step:every=2", 5, 5)
+	require.NoError(t, err)
+	aggroConfig := &aggro.AggroConfig{HistogramActive: true, HistogramTarget: "zero-buckets"}
+	require.NoError(t, metrics.GenerateWithShape(ctx, mp, 6, "histogram", "sizes", shape, nil, nil, 1, aggroConfig, "grpc"))
+
+	rm := &metricdata.ResourceMetrics{}
+	require.NoError(t, reader.Collect(ctx, rm))
+	hist, ok := rm.ScopeMetrics[0].Metrics[0].Data.(metricdata.Histogram[float64])
+	require.True(t, ok)
+	require.Len(t, hist.DataPoints, 1)
+	dp := hist.DataPoints[0]
+	assert.Equal(t, uint64(6), dp.Count)
+	assert.Equal(t, 3*1.0+3*1e6, dp.Sum)
+	_, hasValue := dp.Attributes.Value("aggro.value")
+	assert.False(t, hasValue)
+}
+
+// ===== TEMPORALITY TESTS =====
+
+func TestTemporalitySelector(t *testing.T) {
+	cases := map[string]map[sdkmetric.InstrumentKind]metricdata.Temporality{
+		"cumulative": {
+			sdkmetric.InstrumentKindCounter:       metricdata.CumulativeTemporality,
+			sdkmetric.InstrumentKindHistogram:     metricdata.CumulativeTemporality,
+			sdkmetric.InstrumentKindUpDownCounter: metricdata.CumulativeTemporality,
+		},
+		"delta": {
+			sdkmetric.InstrumentKindCounter:           metricdata.DeltaTemporality,
+			sdkmetric.InstrumentKindObservableCounter: metricdata.DeltaTemporality,
+			sdkmetric.InstrumentKindHistogram:         metricdata.DeltaTemporality,
+			sdkmetric.InstrumentKindUpDownCounter:     metricdata.CumulativeTemporality,
+		},
+		"lowmemory": {
+			sdkmetric.InstrumentKindCounter:           metricdata.DeltaTemporality,
+			sdkmetric.InstrumentKindObservableCounter: metricdata.CumulativeTemporality,
+			sdkmetric.InstrumentKindHistogram:         metricdata.DeltaTemporality,
+		},
+	}
+	for preference, expected := range cases {
+		selector, err := exporters.TemporalitySelector(preference)
+		require.NoError(t, err)
+		for kind, temporality := range expected {
+			assert.Equal(t, temporality, selector(kind), "%s: %v", preference, kind)
+		}
+	}
+
+	_, err := exporters.TemporalitySelector("sideways")
+	assert.Error(t, err)
+
+	// Temporality aggro exports the other temporality unless a target is given
+	aggroConfig := &aggro.AggroConfig{TemporalityActive: true}
+	assert.Equal(t, "delta", aggroConfig.AlternateTemporality("cumulative"))
+	assert.Equal(t, "cumulative", aggroConfig.AlternateTemporality("lowmemory"))
+	aggroConfig.TemporalityTarget = "lowmemory"
+	assert.Equal(t, "lowmemory", aggroConfig.AlternateTemporality("cumulative"))
+}
+
+func TestDeltaCounterResetsBetweenCollections(t *testing.T) {
+	ctx := context.Background()
+	selector, err := exporters.TemporalitySelector("delta")
+	require.NoError(t, err)
+	reader := sdkmetric.NewManualReader(sdkmetric.WithTemporalitySelector(selector))
+	mp := sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader))
+	defer mp.Shutdown(ctx)
+
+	counter, err := mp.Meter("test").Int64Counter("requests")
+	require.NoError(t, err)
+
+	for _, n := range []int64{5, 3} {
+		counter.Add(ctx, n)
+		rm := &metricdata.ResourceMetrics{}
+		require.NoError(t, reader.Collect(ctx, rm))
+		sum, ok := rm.ScopeMetrics[0].Metrics[0].Data.(metricdata.Sum[int64])
+		require.True(t, ok)
+		assert.Equal(t, metricdata.DeltaTemporality, sum.Temporality)
+		assert.Equal(t, n, sum.DataPoints[0].Value)
+	}
+}
+
+// ===== VALUE SHAPE TESTS =====
+
+func TestValueShapes(t *testing.T) {
+	// Sawtooth ramps from min to max and starts over
+	shape, err := shapes.Parse("sawtooth:period=5", 0, 100)
+	require.NoError(t, err)
+	var values []float64
+	for i := 0; i < 6; i++ {
+		values = append(values, shape.Next())
+	}
+	assert.Equal(t, []float64{0, 25, 50, 75, 100, 0}, values)
+
+	// Sine stays within offset +/- amplitude
+	shape, err = shapes.Parse("sine:period=10,amplitude=5,offset=50", 0, 100)
+	require.NoError(t, err)
+	for i := 0; i < 20; i++ {
+		v := shape.Next()
+		assert.InDelta(t, 50, v, 5.0001)
+	}
+
+	// Random walk never leaves its range and moves by small steps
+	shape, err = shapes.Parse("random-walk:step=1,drift=0.5", 10, 20)
+	require.NoError(t, err)
+	previous := shape.Next()
+	for i := 0; i < 200; i++ {
+		v := shape.Next()
+		assert.GreaterOrEqual(t, v, 10.0)
+		assert.LessOrEqual(t, v, 20.0)
+		assert.Less(t, math.Abs(v-previous), 10.0)
+		previous = v
+	}
+
+	// Step holds each level for the configured number of points
+	shape, err = shapes.Parse("step:every=4", 0, 100)
+	require.NoError(t, err)
+	first := shape.Next()
+	for i := 0; i < 3; i++ {
+		assert.Equal(t, first, shape.Next())
+	}
+
+	// Monotonic is a running total; sums record the increments
+	shape, err = shapes.Parse("monotonic:start=100,rate=10", 0, 0)
+	require.NoError(t, err)
+	next := shapes.Increments(shape)
+	assert.Equal(t, 110.0, next())
+	assert.Equal(t, 10.0, next())
+
+	// Dropouts zero the value
+	shape, err = shapes.Parse("uniform:min=5,max=10,dropout=1", 0, 0)
+	require.NoError(t, err)
+	assert.Equal(t, 0.0, shape.Next())
+
+	for _, bad := range []string{"triangle", "sine:period=0", "sine:wavelength=3", "monotonic:spike=0.1", "uniform:min=5,max=1"} {
+		_, err := shapes.Parse(bad, 0, 100)
+		assert.Error(t, err, bad)
+	}
+}
+
+func TestTimestampedMetricsFollowShape(t *testing.T) {
+	ctx := context.Background()
+	reader := sdkmetric.NewManualReader()
+	mp := sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader))
+	defer mp.Shutdown(ctx)
+
+	// Capture every exported data point in order
+	exporter := &capturingMetricExporter{}
+	shape, err := shapes.Parse("sawtooth:period=4", 0, 30)
+	require.NoError(t, err)
+	timestampConfig := &timestamps.TimestampConfig{StartTime: time.Now().Add(-time.Hour), Spacing: time.Minute}
+	require.NoError(t, generators.GenerateMetricsWithTimestamps(ctx, mp, reader, []sdkmetric.Exporter{exporter}, 5, "gauge", "shaped", func() shapes.Shape { return shape }, nil, nil, nil, nil, timestampConfig))
+
+	var values []int64
+	for _, rm := range exporter.exported {
+		for _, sm := range rm.ScopeMetrics {
+			for _, m := range sm.Metrics {
+				gauge, ok := m.Data.(metricdata.Gauge[int64])
+				require.True(t, ok)
+				for _, dp := range gauge.DataPoints {
+					values = append(values, dp.Value)
+				}
+			}
+		}
+	}
+	assert.Equal(t, []int64{0, 10, 20, 30, 0}, values)
+}
+
+func TestPeriodicMetricsFollowShape(t *testing.T) {
+	ctx := context.Background()
+	reader := sdkmetric.NewManualReader()
+	mp := sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader))
+	defer mp.Shutdown(ctx)
+
+	shape, err := shapes.Parse("sawtooth:period=4", 0, 30)
+	require.NoError(t, err)
+	require.NoError(t, metrics.GenerateWithShape(ctx, mp, 5, "histogram", "shaped", shape, nil, nil, 1, nil, "grpc"))
+
+	rm := &metricdata.ResourceMetrics{}
+	require.NoError(t, reader.Collect(ctx, rm))
+	histogram, ok := rm.ScopeMetrics[0].Metrics[0].Data.(metricdata.Histogram[float64])
+	require.True(t, ok)
+
+	// All five shaped values land in one series: 0, 10, 20, 30, 0
+	require.Len(t, histogram.DataPoints, 1)
+	dp := histogram.DataPoints[0]
+	assert.Equal(t, uint64(5), dp.Count)
+	assert.Equal(t, 60.0, dp.Sum)
+	minimum, _ := dp.Min.Value()
+	maximum, _ := dp.Max.Value()
+	assert.Equal(t, 0.0, minimum)
+	assert.Equal(t, 30.0, maximum)
+}
+
+func TestShapedCountersNeverDecrease(t *testing.T) {
+	ctx := context.Background()
+	reader := sdkmetric.NewManualReader()
+	mp := sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader))
+	defer mp.Shutdown(ctx)
+
+	// The sine goes 0, 10, 0, -10, ...: the negative half adds nothing instead of being dropped by the SDK
+	shape, err := shapes.Parse("sine:period=4,amplitude=10,offset=0", 0, 100)
+	require.NoError(t, err)
+	require.NoError(t, metrics.GenerateWithShape(ctx, mp, 8, "counter", "requests", shape, nil, nil, 1, nil, "grpc"))
+
+	rm := &metricdata.ResourceMetrics{}
+	require.NoError(t, reader.Collect(ctx, rm))
+	sum, ok := rm.ScopeMetrics[0].Metrics[0].Data.(metricdata.Sum[int64])
+	require.True(t, ok)
+	require.Len(t, sum.DataPoints, 1)
+	assert.Equal(t, int64(20), sum.DataPoints[0].Value)
+
+	// Up/down counters keep the negative amounts
+	shape, err = shapes.Parse("sine:period=4,amplitude=10,offset=0", 0, 100)
+	require.NoError(t, err)
+	next := shapes.Increments(shape)
+	var total float64
+	for i := 0; i < 4; i++ {
+		total += next()
+	}
+	assert.InDelta(t, 0, total, 1e-9)
+}
+
+// capturingMetricExporter keeps exported metrics in memory
+type capturingMetricExporter struct {
+	exported []metricdata.ResourceMetrics
+}
+
+func (e *capturingMetricExporter) Temporality(kind sdkmetric.InstrumentKind) metricdata.Temporality {
+	return sdkmetric.DefaultTemporalitySelector(kind)
+}
+
+func (e *capturingMetricExporter) Aggregation(kind sdkmetric.InstrumentKind) sdkmetric.Aggregation {
+	return sdkmetric.DefaultAggregationSelector(kind)
+}
+
+func (e *capturingMetricExporter) Export(_ context.Context, rm *metricdata.ResourceMetrics) error {
+	e.exported = append(e.exported, *rm)
+	return nil
+}
+
+func (e *capturingMetricExporter) ForceFlush(context.Context) error { return nil }
+
+func (e *capturingMetricExporter) Shutdown(context.Context) error { return nil }
+
+// ===== METRIC CATALOG TESTS =====
+
+func TestMetricPacks(t *testing.T) {
+	defer viper.Reset()
+	viper.Set("generate.metrics.packs", metrics.PackNames())
+	catalog, err := metrics.ParseCatalog("metrics")
+	require.NoError(t, err)
+
+	ctx := context.Background()
+	reader := sdkmetric.NewManualReader()
+	mp := sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader))
+	defer mp.Shutdown(ctx)
+	require.NoError(t, metrics.GenerateCatalog(ctx, mp, catalog, 3, nil, nil, 1, nil, "grpc"))
+
+	rm := &metricdata.ResourceMetrics{}
+	require.NoError(t, reader.Collect(ctx, rm))
+	require.Len(t, rm.ScopeMetrics, 1)
+
+	// Every catalog entry becomes one instrument with its unit and one series per dimension combination
+	collected := make(map[string]metricdata.Metrics)
+	for _, m := range rm.ScopeMetrics[0].Metrics {
+		collected[m.Name] = m
+	}
+	require.Len(t, collected, len(catalog))
+	for _, entry := range catalog {
+		assert.Equal(t, entry.Unit, collected[entry.Name].Unit, entry.Name)
+	}
+	duration, ok := collected["http.server.request.duration"].Data.(metricdata.Histogram[float64])
+	require.True(t, ok)
+	assert.Len(t, duration.DataPoints, 2*3*3)
+	for _, dp := range duration.DataPoints {
+		assert.Equal(t, uint64(3), dp.Count)
+	}
+
+	viper.Set("generate.metrics.packs", []string{"mainframe"})
+	_, err = metrics.ParseCatalog("metrics")
+	assert.Error(t, err)
+}
+
+func TestCatalogWithTimestampsAccumulates(t *testing.T) {
+	catalog := []metrics.CatalogEntry{
+		{Name: "jobs.completed", Type: "counter", Shape: "monotonic:rate=10"},
+		{Name: "queue.depth", Shape: "sawtooth:period=3", Min: 0, Max: 20, Attributes: map[string][]string{"queue": {"a", "b"}}},
+	}
+	require.NoError(t, metrics.ValidateCatalog(catalog))
+
+	ctx := context.Background()
+	reader := sdkmetric.NewManualReader()
+	mp := sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader))
+	defer mp.Shutdown(ctx)
+
+	exporter := &capturingMetricExporter{}
+	start := time.Now().Add(-time.Hour).Truncate(time.Second)
+	timestampConfig := &timestamps.TimestampConfig{StartTime: start, Spacing: time.Minute}
+	require.NoError(t, generators.GenerateCatalogWithTimestamps(ctx, mp, reader, []sdkmetric.Exporter{exporter}, catalog, 3, nil, nil, nil, nil, timestampConfig))
+	require.Len(t, exporter.exported, 3)
+
+	for i, rm := range exporter.exported {
+		for _, m := range rm.ScopeMetrics[0].Metrics {
+			switch data := m.Data.(type) {
+			case metricdata.Sum[int64]:
+				// One cumulative series that keeps growing from the same start
+				require.Len(t, data.DataPoints, 1)
+				assert.Equal(t, int64(10*(i+1)), data.DataPoints[0].Value)
+				assert.Equal(t, start, data.DataPoints[0].StartTime)
+				assert.Equal(t, timestampConfig.CalculateTimestamp(i), data.DataPoints[0].Time)
+			case metricdata.Gauge[int64]:
+				require.Len(t, data.DataPoints, 2)
+				for _, dp := range data.DataPoints {
+					assert.Equal(t, int64(10*i), dp.Value)
+				}
+			default:
+				t.Fatalf("unexpected data %T for %s", m.Data, m.Name)
+			}
+		}
+	}
+}
+
+// ===== CARDINALITY TESTS =====
+
+func TestSeriesPool(t *testing.T) {
+	config := &metrics.CardinalityConfig{
+		Dimensions: []metrics.Dimension{{Key: "region", Values: []string{"eu", "us"}}, {Key: "host", Values: []string{"a", "b", "c"}}},
+	}
+	require.NoError(t, config.Validate())
+	require.True(t, config.Enabled())
+
+	distinct := func(series [][]attribute.KeyValue) int {
+		seen := make(map[attribute.Distinct]bool)
+		for _, attrs := range series {
+			set := attribute.NewSet(attrs...)
+			seen[set.Equivalent()] = true
+		}
+		return len(seen)
+	}
+
+	// One series per dimension combination, stable over time
+	pool := config.NewSeriesPool()
+	first := pool.Active()
+	pool.Advance()
+	assert.Equal(t, first, pool.Active())
+	assert.Len(t, first, 6)
+	assert.Equal(t, 6, distinct(first))
+
+	// A target beyond the combinations adds a series.id to keep series distinct
+	config.Series = 10
+	assert.Equal(t, 10, distinct(config.NewSeriesPool().Active()))
+
+	// Full churn replaces every series, keeping the active count
+	config.Churn = 1
+	pool = config.NewSeriesPool()
+	before := pool.Active()
+	pool.Advance()
+	after := pool.Active()
+	assert.Len(t, after, 10)
+	assert.Equal(t, 20, distinct(append(before, after...)))
+
+	// Explosion grows without bound
+	config.Churn = 0
+	config.Explosion = 5
+	pool = config.NewSeriesPool()
+	for i := 0; i < 3; i++ {
+		pool.Advance()
+	}
+	assert.Equal(t, 25, distinct(pool.Active()))
+
+	assert.Error(t, (&metrics.CardinalityConfig{Churn: 2}).Validate())
+	assert.Error(t, (&metrics.CardinalityConfig{Dimensions: []metrics.Dimension{{Key: "empty"}}}).Validate())
+}
+
+func TestCardinalityControlsPeriodicSeries(t *testing.T) {
+	ctx := context.Background()
+	reader := sdkmetric.NewManualReader()
+	mp := sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader))
+	defer mp.Shutdown(ctx)
+
+	config := &metrics.CardinalityConfig{Series: 4}
+	require.NoError(t, metrics.GenerateWithShape(ctx, mp, 5, "counter", "requests", shapes.Uniform(1, 1), config, nil, 1, nil, "grpc"))
+
+	rm := &metricdata.ResourceMetrics{}
+	require.NoError(t, reader.Collect(ctx, rm))
+	sum, ok := rm.ScopeMetrics[0].Metrics[0].Data.(metricdata.Sum[int64])
+	require.True(t, ok)
+
+	// Four series, each with one increment per data point
+	require.Len(t, sum.DataPoints, 4)
+	for _, dp := range sum.DataPoints {
+		assert.Equal(t, int64(5), dp.Value)
+		_, hasID := dp.Attributes.Value("series.id")
+		assert.True(t, hasID)
+	}
+}
+
+// ===== SERIES LIFECYCLE TESTS =====
+
+// lifecycleCollection builds one collection with a cumulative counter and a gauge, both at value
+func lifecycleCollection(start time.Time, value int64) *metricdata.ResourceMetrics {
+	attrs := attribute.NewSet(attribute.String("host", "a"))
+	return &metricdata.ResourceMetrics{ScopeMetrics: []metricdata.ScopeMetrics{{Metrics: []metricdata.Metrics{
+		{Name: "requests", Data: metricdata.Sum[int64]{
+			Temporality: metricdata.CumulativeTemporality, IsMonotonic: true,
+			DataPoints: []metricdata.DataPoint[int64]{{Attributes: attrs, StartTime: start, Value: value}},
+		}},
+		{Name: "temperature", Data: metricdata.Gauge[int64]{
+			DataPoints: []metricdata.DataPoint[int64]{{Attributes: attrs, Value: value}},
+		}},
+	}}}}
+}
+
+func TestLifecycleRestartResetsCumulativeSeries(t *testing.T) {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	config := &metrics.LifecycleConfig{RestartRate: 1, GapLength: 3}
+	require.NoError(t, config.Validate())
+	lifecycle := config.NewLifecycle()
+
+	var sums []metricdata.DataPoint[int64]
+	var gauges []int64
+	for i := 1; i <= 3; i++ {
+		rm := lifecycleCollection(start, int64(10*i))
+		lifecycle.Apply(rm, start.Add(time.Duration(i)*time.Minute))
+		sums = append(sums, rm.ScopeMetrics[0].Metrics[0].Data.(metricdata.Sum[int64]).DataPoints[0])
+		gauges = append(gauges, rm.ScopeMetrics[0].Metrics[1].Data.(metricdata.Gauge[int64]).DataPoints[0].Value)
+	}
+
+	// The first collection has nothing to restart from; after that every restart drops the earlier count
+	assert.Equal(t, int64(10), sums[0].Value)
+	assert.Equal(t, start, sums[0].StartTime)
+	for i, dp := range sums[1:] {
+		assert.Equal(t, int64(10), dp.Value)
+		assert.Equal(t, start.Add(time.Duration(i+1)*time.Minute+30*time.Second), dp.StartTime)
+	}
+	// Gauges carry on unaffected
+	assert.Equal(t, []int64{10, 20, 30}, gauges)
+
+	// A restart that keeps its StartTime looks like a counter that went backwards
+	config.KeepStartRate = 1
+	lifecycle = config.NewLifecycle()
+	lifecycle.Apply(lifecycleCollection(start, 10), start.Add(time.Minute))
+	rm := lifecycleCollection(start, 25)
+	lifecycle.Apply(rm, start.Add(2*time.Minute))
+	dp := rm.ScopeMetrics[0].Metrics[0].Data.(metricdata.Sum[int64]).DataPoints[0]
+	assert.Equal(t, int64(15), dp.Value)
+	assert.Equal(t, start, dp.StartTime)
+
+	assert.Error(t, (&metrics.LifecycleConfig{RestartRate: 1.5, GapLength: 3}).Validate())
+	assert.Error(t, (&metrics.LifecycleConfig{GapLength: 0}).Validate())
+}
+
+func TestLifecycleGapsAndStaleness(t *testing.T) {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	// Gaps last at least gap length intervals, then the series returns
+	lifecycle := (&metrics.LifecycleConfig{GapRate: 0.3, GapLength: 3}).NewLifecycle()
+	var present []bool
+	for i := 0; i < 200; i++ {
+		rm := lifecycleCollection(start, int64(i))
+		lifecycle.Apply(rm, start.Add(time.Duration(i)*time.Minute))
+		present = append(present, len(rm.ScopeMetrics[0].Metrics[0].Data.(metricdata.Sum[int64]).DataPoints) == 1)
+	}
+	assert.Contains(t, present, true)
+	assert.Contains(t, present, false)
+	missing := 0
+	for i, ok := range present {
+		if !ok {
+			missing++
+			continue
+		}
+		if missing > 0 {
+			assert.Zero(t, missing%3, "gap ending at interval %d is %d intervals long", i, missing)
+		}
+		missing = 0
+	}
+
+	// Ended series never come back
+	lifecycle = (&metrics.LifecycleConfig{EndRate: 1, GapLength: 3}).NewLifecycle()
+	for i := 0; i < 3; i++ {
+		rm := lifecycleCollection(start, int64(i))
+		lifecycle.Apply(rm, start.Add(time.Duration(i)*time.Minute))
+		assert.Empty(t, rm.ScopeMetrics[0].Metrics[0].Data.(metricdata.Sum[int64]).DataPoints)
+		assert.Empty(t, rm.ScopeMetrics[0].Metrics[1].Data.(metricdata.Gauge[int64]).DataPoints)
+	}
+}
+
+func TestLifecycleRestartResetsExponentialHistograms(t *testing.T) {
+	ctx := context.Background()
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	// A cumulative reader rewritten by the lifecycle and a delta reader over the same recordings
+	// Few buckets make the histogram lower its scale as wider values come in
+	view := sdkmetric.NewView(sdkmetric.Instrument{Name: "*"}, sdkmetric.Stream{
+		Aggregation: sdkmetric.AggregationBase2ExponentialHistogram{MaxSize: 4, MaxScale: 20},
+	})
+	cumulative := sdkmetric.NewManualReader()
+	delta := sdkmetric.NewManualReader(sdkmetric.WithTemporalitySelector(func(sdkmetric.InstrumentKind) metricdata.Temporality {
+		return metricdata.DeltaTemporality
+	}))
+	mp := sdkmetric.NewMeterProvider(sdkmetric.WithReader(cumulative), sdkmetric.WithReader(delta), sdkmetric.WithView(view))
+	defer mp.Shutdown(ctx)
+	histogram, err := mp.Meter("test").Float64Histogram("latency")
+	require.NoError(t, err)
+
+	// histogramOf returns the single exponential histogram point of a collection
+	histogramOf := func(rm *metricdata.ResourceMetrics) metricdata.ExponentialHistogramDataPoint[float64] {
+		data, ok := rm.ScopeMetrics[0].Metrics[0].Data.(metricdata.ExponentialHistogram[float64])
+		require.True(t, ok, "expected exponential histogram, got %T", rm.ScopeMetrics[0].Metrics[0].Data)
+		require.Len(t, data.DataPoints, 1)
+		return data.DataPoints[0]
+	}
+	total := func(counts []uint64) (n uint64) {
+		for _, c := range counts {
+			n += c
+		}
+		return n
+	}
+
+	// Every collection after the first follows a restart, so the rebased histogram holds only the last interval
+	lifecycle := (&metrics.LifecycleConfig{RestartRate: 1, GapLength: 3}).NewLifecycle()
+	for i, values := range [][]float64{{1, 2, 0}, {5, 1000, -3, 0}, {0.5, 7, -40}} {
+		for _, v := range values {
+			histogram.Record(ctx, v)
+		}
+		rm, want := &metricdata.ResourceMetrics{}, &metricdata.ResourceMetrics{}
+		require.NoError(t, cumulative.Collect(ctx, rm))
+		require.NoError(t, delta.Collect(ctx, want))
+		lifecycle.Apply(rm, start.Add(time.Duration(i+1)*time.Minute))
+
+		got, expected := histogramOf(rm), histogramOf(want)
+		assert.Equal(t, expected.Count, got.Count, "collection %d", i)
+		assert.Equal(t, expected.ZeroCount, got.ZeroCount, "collection %d", i)
+		assert.InDelta(t, expected.Sum, got.Sum, 1e-9, "collection %d", i)
+		assert.Equal(t, total(expected.PositiveBucket.Counts), total(got.PositiveBucket.Counts), "collection %d", i)
+		assert.Equal(t, total(expected.NegativeBucket.Counts), total(got.NegativeBucket.Counts), "collection %d", i)
+
+		// Min and max are dropped on every collection since a restart, not only the first
+		_, defined := got.Min.Value()
+		assert.Equal(t, i == 0, defined, "collection %d", i)
+		_, defined = got.Max.Value()
+		assert.Equal(t, i == 0, defined, "collection %d", i)
+	}
+
+	// Ended exponential histograms go stale like every other series
+	lifecycle = (&metrics.LifecycleConfig{EndRate: 1, GapLength: 3}).NewLifecycle()
+	histogram.Record(ctx, 3)
+	rm := &metricdata.ResourceMetrics{}
+	require.NoError(t, cumulative.Collect(ctx, rm))
+	lifecycle.Apply(rm, start.Add(time.Hour))
+	assert.Empty(t, rm.ScopeMetrics[0].Metrics[0].Data.(metricdata.ExponentialHistogram[float64]).DataPoints)
+}
+
+// ===== EXEMPLAR TESTS =====
+
+func TestExemplarsLinkToGeneratedSpans(t *testing.T) {
+	ctx := context.Background()
+	spanExporter := tracetest.NewInMemoryExporter()
+	tp := trace.NewTracerProvider(trace.WithSyncer(spanExporter))
+	defer tp.Shutdown(ctx)
+
+	config := &metrics.ExemplarConfig{Filter: "trace-based", Reservoir: "fixed", ReservoirSize: 4, SpanRatio: 1}
+	require.NoError(t, config.Validate())
+	reader := sdkmetric.NewManualReader()
+	mp := sdkmetric.NewMeterProvider(append([]sdkmetric.Option{sdkmetric.WithReader(reader)}, config.Options()...)...)
+	defer mp.Shutdown(ctx)
+
+	spans := config.NewExemplarSpans(tp.Tracer("test"))
+	require.NoError(t, metrics.GenerateWithShape(ctx, mp, 10, "histogram", "latency", shapes.Uniform(1, 100), &metrics.CardinalityConfig{Series: 1}, spans, 1, nil, "grpc"))
+
+	rm := &metricdata.ResourceMetrics{}
+	require.NoError(t, reader.Collect(ctx, rm))
+	histogram, ok := rm.ScopeMetrics[0].Metrics[0].Data.(metricdata.Histogram[float64])
+	require.True(t, ok)
+	require.Len(t, histogram.DataPoints, 1)
+
+	// Every measurement had its own exported span; the fixed reservoir keeps four of them
+	exported := make(map[oteltrace.SpanID]oteltrace.TraceID)
+	for _, span := range spanExporter.GetSpans() {
+		exported[span.SpanContext.SpanID()] = span.SpanContext.TraceID()
+	}
+	assert.Len(t, exported, 10)
+	exemplars := histogram.DataPoints[0].Exemplars
+	require.Len(t, exemplars, 4)
+	for _, e := range exemplars {
+		var spanID oteltrace.SpanID
+		var traceID oteltrace.TraceID
+		copy(spanID[:], e.SpanID)
+		copy(traceID[:], e.TraceID)
+		assert.Equal(t, exported[spanID], traceID)
+	}
+}
+
+func TestExemplarFilters(t *testing.T) {
+	ctx := context.Background()
+	tp := trace.NewTracerProvider()
+	defer tp.Shutdown(ctx)
+
+	collect := func(config *metrics.ExemplarConfig) []metricdata.Exemplar[int64] {
+		reader := sdkmetric.NewManualReader()
+		mp := sdkmetric.NewMeterProvider(append([]sdkmetric.Option{sdkmetric.WithReader(reader)}, config.Options()...)...)
+		defer mp.Shutdown(ctx)
+		require.NoError(t, metrics.GenerateWithShape(ctx, mp, 5, "counter", "requests", shapes.Uniform(1, 1), &metrics.CardinalityConfig{Series: 1}, config.NewExemplarSpans(tp.Tracer("test")), 1, nil, "grpc"))
+
+		rm := &metricdata.ResourceMetrics{}
+		require.NoError(t, reader.Collect(ctx, rm))
+		return rm.ScopeMetrics[0].Metrics[0].Data.(metricdata.Sum[int64]).DataPoints[0].Exemplars
+	}
+
+	// Always-on keeps exemplars without any trace context
+	exemplars := collect(&metrics.ExemplarConfig{Filter: "always-on", Reservoir: "fixed", ReservoirSize: 2})
+	require.Len(t, exemplars, 2)
+	assert.Empty(t, exemplars[0].TraceID)
+
+	// Trace-based drops measurements outside sampled spans
+	assert.Empty(t, collect(&metrics.ExemplarConfig{Filter: "trace-based", SpanRatio: 1, UnsampledRatio: 1}))
+	assert.NotEmpty(t, collect(&metrics.ExemplarConfig{Filter: "trace-based", SpanRatio: 1}))
+
+	// Off never keeps exemplars, even inside sampled spans
+	assert.Empty(t, collect(&metrics.ExemplarConfig{Filter: "off", SpanRatio: 1}))
+
+	assert.Error(t, (&metrics.ExemplarConfig{Filter: "sometimes"}).Validate())
+	assert.Error(t, (&metrics.ExemplarConfig{Reservoir: "fixed", ReservoirSize: 0}).Validate())
+}
+
+// ===== TIMESTAMPED INSTRUMENT TESTS =====
+
+func TestTimestampedMetricsSupportAllInstrumentTypes(t *testing.T) {
+	ctx := context.Background()
+	types := []string{"counter", "float64-counter", "histogram", "int64-histogram", "updowncounter", "float64-updowncounter", "gauge", "float64-gauge",
+		"observable-counter", "float64-observable-counter", "observable-updowncounter", "float64-observable-updowncounter", "observable-gauge", "float64-observable-gauge"}
+	newShape := func() shapes.Shape {
+		shape, err := shapes.Parse("monotonic:rate=10", 0, 100)
+		require.NoError(t, err)
+		return shape
+	}
+	start := time.Now().Add(-time.Hour).Truncate(time.Second)
+	timestampConfig := &timestamps.TimestampConfig{StartTime: start, Spacing: time.Minute}
+
+	for _, metricType := range types {
+		reader := sdkmetric.NewManualReader()
+		mp := sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader))
+		exporter := &capturingMetricExporter{}
+		require.NoError(t, generators.GenerateMetricsWithTimestamps(ctx, mp, reader, []sdkmetric.Exporter{exporter}, 3, metricType, "timestamped", newShape, nil, nil, nil, nil, timestampConfig), metricType)
+		mp.Shutdown(ctx)
+
+		// One collection per timestamp, each with the one series at its intended time
+		require.Len(t, exporter.exported, 3, metricType)
+		var values []float64
+		for i, rm := range exporter.exported {
+			require.Len(t, rm.ScopeMetrics, 1, metricType)
+			require.Len(t, rm.ScopeMetrics[0].Metrics, 1, metricType)
+			expected := timestampConfig.CalculateTimestamp(i)
+			switch data := rm.ScopeMetrics[0].Metrics[0].Data.(type) {
+			case metricdata.Sum[int64]:
+				require.Len(t, data.DataPoints, 1, metricType)
+				assert.Equal(t, expected, data.DataPoints[0].Time, metricType)
+				values = append(values, float64(data.DataPoints[0].Value))
+			case metricdata.Sum[float64]:
+				require.Len(t, data.DataPoints, 1, metricType)
+				assert.Equal(t, expected, data.DataPoints[0].Time, metricType)
+				values = append(values, data.DataPoints[0].Value)
+			case metricdata.Gauge[int64]:
+				require.Len(t, data.DataPoints, 1, metricType)
+				assert.Equal(t, expected, data.DataPoints[0].Time, metricType)
+				values = append(values, float64(data.DataPoints[0].Value))
+			case metricdata.Gauge[float64]:
+				require.Len(t, data.DataPoints, 1, metricType)
+				assert.Equal(t, expected, data.DataPoints[0].Time, metricType)
+				values = append(values, data.DataPoints[0].Value)
+			case metricdata.Histogram[int64]:
+				require.Len(t, data.DataPoints, 1, metricType)
+				assert.Equal(t, expected, data.DataPoints[0].Time, metricType)
+				assert.Equal(t, uint64(i+1), data.DataPoints[0].Count, metricType)
+			case metricdata.Histogram[float64]:
+				require.Len(t, data.DataPoints, 1, metricType)
+				assert.Equal(t, expected, data.DataPoints[0].Time, metricType)
+				assert.Equal(t, uint64(i+1), data.DataPoints[0].Count, metricType)
+			default:
+				t.Fatalf("%s: unexpected data %T", metricType, data)
+			}
+		}
+
+		// Sums and gauges report the running total of the shape, whichever way they are recorded
+		if !strings.Contains(metricType, "histogram") {
+			reference := newShape()
+			assert.Equal(t, []float64{reference.Next(), reference.Next(), reference.Next()}, values, metricType)
+		}
+	}
+}
+
+func TestTimestampedMetricsApplyAggro(t *testing.T) {
+	ctx := context.Background()
+	reader := sdkmetric.NewManualReader()
+	mp := sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader))
+	defer mp.Shutdown(ctx)
+
+	exporter := &capturingMetricExporter{}
+	aggroConfig := &aggro.AggroConfig{HistogramActive: true, HistogramTarget: "overflow"}
+	timestampConfig := &timestamps.TimestampConfig{StartTime: time.Now().Add(-time.Hour), Spacing: time.Minute}
+	require.NoError(t, generators.GenerateMetricsWithTimestamps(ctx, mp, reader, []sdkmetric.Exporter{exporter}, 3, "histogram", "latency", func() shapes.Shape { return shapes.Uniform(1, 100) }, nil, nil, aggroConfig, nil, timestampConfig))
+
+	// Every point overflows the buckets without an aggro value of its own; each interval only reports its own point
+	require.Len(t, exporter.exported, 3)
+	for _, rm := range exporter.exported {
+		histogram, ok := rm.ScopeMetrics[0].Metrics[0].Data.(metricdata.Histogram[float64])
+		require.True(t, ok)
+		require.Len(t, histogram.DataPoints, 1)
+		dp := histogram.DataPoints[0]
+		_, hasValue := dp.Attributes.Value("aggro.value")
+		assert.False(t, hasValue)
+		target, _ := dp.Attributes.Value("aggro.histogram")
+		assert.Equal(t, "overflow", target.AsString())
+		assert.GreaterOrEqual(t, dp.Sum, 1e12)
+	}
+}
+
+// ===== BACKFILL TESTS =====
+
+// sequenceShape returns fixed values in order
+type sequenceShape struct {
+	values []float64
+	next   int
+}
+
+func (s *sequenceShape) Next() float64 {
+	value := s.values[s.next%len(s.values)]
+	s.next++
+	return value
+}
+
+func TestBackfillExportsEveryStepInBoundedBatches(t *testing.T) {
+	ctx := context.Background()
+	series := [][]attribute.KeyValue{
+		{attribute.String("instance", "a")},
+		{attribute.String("instance", "b")},
+		{attribute.String("instance", "c")},
+	}
+	backfill, err := metrics.NewBackfill([]metrics.BackfillMetric{{
+		Name:     "requests",
+		Type:     "counter",
+		NewShape: func() shapes.Shape { return &sequenceShape{values: []float64{5}} },
+		Series:   func() [][]attribute.KeyValue { return series },
+	}}, sdkmetric.DefaultTemporalitySelector, nil, nil, nil, "grpc")
+	require.NoError(t, err)
+
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	exporter := &capturingMetricExporter{}
+	points, batches, err := backfill.Run(ctx, resource.Empty(), start, start.Add(9*time.Minute), time.Minute, 7, func(ctx context.Context, rm *metricdata.ResourceMetrics) error {
+		return exporter.Export(ctx, rm)
+	})
+	require.NoError(t, err)
+
+	// 10 steps of 3 series, at most 7 points per export
+	assert.Equal(t, 30, points)
+	assert.Equal(t, 5, batches)
+	require.Len(t, exporter.exported, 5)
+
+	totals := make(map[string][]int64)
+	for _, rm := range exporter.exported {
+		require.Len(t, rm.ScopeMetrics, 1)
+		require.Len(t, rm.ScopeMetrics[0].Metrics, 1)
+		sum, ok := rm.ScopeMetrics[0].Metrics[0].Data.(metricdata.Sum[int64])
+		require.True(t, ok)
+		assert.LessOrEqual(t, len(sum.DataPoints), 7)
+		assert.True(t, sum.IsMonotonic)
+		assert.Equal(t, metricdata.CumulativeTemporality, sum.Temporality)
+		for _, dp := range sum.DataPoints {
+			instance, _ := dp.Attributes.Value("instance")
+			step := len(totals[instance.AsString()])
+			assert.Equal(t, start, dp.StartTime)
+			assert.Equal(t, start.Add(time.Duration(step)*time.Minute), dp.Time)
+			totals[instance.AsString()] = append(totals[instance.AsString()], dp.Value)
+		}
+	}
+
+	// Each series accumulates from the backfill start
+	require.Len(t, totals, 3)
+	for _, values := range totals {
+		assert.Equal(t, []int64{5, 10, 15, 20, 25, 30, 35, 40, 45, 50}, values)
+	}
+}
+
+func TestBackfillHistogramsMatchSDKAggregation(t *testing.T) {
+	ctx := context.Background()
+	values := []float64{0.5, 3, 7, 12, 0, 250, 1000, 4.2, 1e6, 75}
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	for _, histogramConfig := range []*metrics.HistogramConfig{
+		{Aggregation: "explicit", Boundaries: []float64{1, 10, 100}, MaxSize: 160, MaxScale: 20},
+		{Aggregation: "exponential", MaxSize: 4, MaxScale: 20},
+	} {
+		// Reference: the same values recorded through the SDK
+		reader := sdkmetric.NewManualReader()
+		mp := sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader), sdkmetric.WithView(histogramConfig.Views()...))
+		histogram, err := mp.Meter("test").Float64Histogram("latency")
+		require.NoError(t, err)
+		for _, value := range values {
+			histogram.Record(ctx, value, metric.WithAttributes(attribute.String("instance", "primary")))
+		}
+		var reference metricdata.ResourceMetrics
+		require.NoError(t, reader.Collect(ctx, &reference))
+		mp.Shutdown(ctx)
+
+		backfill, err := metrics.NewBackfill([]metrics.BackfillMetric{{
+			Name:     "latency",
+			Type:     "histogram",
+			NewShape: func() shapes.Shape { return &sequenceShape{values: values} },
+			Series: func() [][]attribute.KeyValue {
+				return [][]attribute.KeyValue{{attribute.String("instance", "primary")}}
+			},
+		}}, sdkmetric.DefaultTemporalitySelector, histogramConfig, nil, nil, "grpc")
+		require.NoError(t, err)
+		exporter := &capturingMetricExporter{}
+		_, _, err = backfill.Run(ctx, resource.Empty(), start, start.Add(time.Duration(len(values)-1)*time.Minute), time.Minute, 1000, func(ctx context.Context, rm *metricdata.ResourceMetrics) error {
+			return exporter.Export(ctx, rm)
+		})
+		require.NoError(t, err)
+		require.Len(t, exporter.exported, 1)
+
+		// The last cumulative point holds every value, bucketed like the SDK does
+		expected := reference.ScopeMetrics[0].Metrics[0].Data
+		actual := exporter.exported[0].ScopeMetrics[0].Metrics[0].Data
+		switch want := expected.(type) {
+		case metricdata.Histogram[float64]:
+			got, ok := actual.(metricdata.Histogram[float64])
+			require.True(t, ok)
+			require.Len(t, got.DataPoints, len(values))
+			last := got.DataPoints[len(values)-1]
+			assert.Equal(t, want.DataPoints[0].Count, last.Count)
+			assert.Equal(t, want.DataPoints[0].Bounds, last.Bounds)
+			assert.Equal(t, want.DataPoints[0].BucketCounts, last.BucketCounts)
+			assert.InDelta(t, want.DataPoints[0].Sum, last.Sum, 1e-6)
+			assert.Equal(t, want.DataPoints[0].Max, last.Max)
+		case metricdata.ExponentialHistogram[float64]:
+			got, ok := actual.(metricdata.ExponentialHistogram[float64])
+			require.True(t, ok)
+			require.Len(t, got.DataPoints, len(values))
+			last := got.DataPoints[len(values)-1]
+			assert.Equal(t, want.DataPoints[0].Count, last.Count)
+			assert.Equal(t, want.DataPoints[0].Scale, last.Scale)
+			assert.Equal(t, want.DataPoints[0].ZeroCount, last.ZeroCount)
+			assert.Equal(t, want.DataPoints[0].PositiveBucket, last.PositiveBucket)
+			assert.InDelta(t, want.DataPoints[0].Sum, last.Sum, 1e-6)
+		default:
+			t.Fatalf("unexpected reference data %T", expected)
+		}
+	}
+}
+
+// ===== PROMETHEUS TESTS =====
+
+// scrapeCollection records a counter and a histogram with two series and collects them
+func scrapeCollection(t *testing.T) *metricdata.ResourceMetrics {
+	ctx := context.Background()
+	reader := sdkmetric.NewManualReader()
+	res := resource.NewSchemaless(attribute.String("service.name", "checkout"))
+	mp := sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader), sdkmetric.WithResource(res))
+	defer mp.Shutdown(ctx)
+
+	meter := mp.Meter("otel-datagen")
+	counter, err := meter.Int64Counter("http.requests")
+	require.NoError(t, err)
+	histogram, err := meter.Float64Histogram("http.duration", metric.WithUnit("s"), metric.WithExplicitBucketBoundaries(0.1, 1))
+	require.NoError(t, err)
+	for _, route := range []string{"/a", "/b"} {
+		attrs := metric.WithAttributes(attribute.String("http.route", route))
+		counter.Add(ctx, 3, attrs)
+		histogram.Record(ctx, 0.5, attrs)
+	}
+
+	rm := &metricdata.ResourceMetrics{}
+	require.NoError(t, reader.Collect(ctx, rm))
+	return rm
+}
+
+// scrape fetches the server's metrics with the given Accept header
+func scrape(t *testing.T, server *prometheus.Server, accept string) (*http.Response, []byte, error) {
+	ts := httptest.NewServer(server)
+	defer ts.Close()
+	req, err := http.NewRequest(http.MethodGet, ts.URL+"/metrics", nil)
+	require.NoError(t, err)
+	if accept != "" {
+		req.Header.Set("Accept", accept)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	return resp, body, err
+}
+
+func TestPrometheusEndpointExposesGeneratedInstruments(t *testing.T) {
+	server := prometheus.NewServer(&prometheus.ServeConfig{Interval: time.Second}, nil)
+	server.Update(scrapeCollection(t))
+
+	// Classic text format by default
+	resp, body, err := scrape(t, server, "")
+	require.NoError(t, err)
+	assert.Equal(t, prometheus.ContentTypePrometheus, resp.Header.Get("Content-Type"))
+	text := string(body)
+	assert.Contains(t, text, `target_info{service_name="checkout"} 1`)
+	assert.Contains(t, text, "# TYPE http_requests_total counter\n")
+	assert.Contains(t, text, `http_requests_total{http_route="/a",otel_scope_name="otel-datagen"} 3`)
+	assert.Contains(t, text, "# TYPE http_duration_seconds histogram\n")
+	assert.Contains(t, text, `http_duration_seconds_bucket{http_route="/b",otel_scope_name="otel-datagen",le="0.1"} 0`)
+	assert.Contains(t, text, `http_duration_seconds_bucket{http_route="/b",otel_scope_name="otel-datagen",le="1"} 1`)
+	assert.Contains(t, text, `http_duration_seconds_bucket{http_route="/b",otel_scope_name="otel-datagen",le="+Inf"} 1`)
+	assert.Contains(t, text, `http_duration_seconds_count{http_route="/b",otel_scope_name="otel-datagen"} 1`)
+	assert.NotContains(t, text, "# EOF")
+
+	// OpenMetrics when the scraper asks for it
+	resp, body, err = scrape(t, server, "application/openmetrics-text;version=1.0.0,text/plain;q=0.5")
+	require.NoError(t, err)
+	assert.Equal(t, prometheus.ContentTypeOpenMetrics, resp.Header.Get("Content-Type"))
+	text = string(body)
+	assert.Contains(t, text, "# TYPE http_requests counter\n")
+	assert.Contains(t, text, "# UNIT http_duration_seconds seconds\n")
+	assert.Contains(t, text, "# TYPE target info\n")
+	assert.True(t, strings.HasSuffix(text, "# EOF\n"))
+}
+
+func TestPrometheusScrapeFaults(t *testing.T) {
+	rm := scrapeCollection(t)
+
+	// Truncated scrapes announce the full body and drop the connection part way
+	truncating := prometheus.NewServer(&prometheus.ServeConfig{Interval: time.Second, TruncateRate: 1}, nil)
+	truncating.Update(rm)
+	_, _, err := scrape(t, truncating, "")
+	assert.Error(t, err)
+
+	// Duplicating scrapes repeat one sample line
+	duplicating := prometheus.NewServer(&prometheus.ServeConfig{Interval: time.Second, DuplicateRate: 1}, nil)
+	duplicating.Update(rm)
+	_, body, err := scrape(t, duplicating, "")
+	require.NoError(t, err)
+	seen := make(map[string]bool)
+	duplicates := 0
+	for _, line := range strings.Split(string(body), "\n") {
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		if seen[line] {
+			duplicates++
+		}
+		seen[line] = true
+	}
+	assert.Equal(t, 1, duplicates)
+
+	// Label aggro adds an invalid label name to each series, the same on every scrape
+	aggroConfig := &aggro.AggroConfig{LabelsActive: true, LabelsTarget: "name"}
+	labelled := prometheus.NewServer(&prometheus.ServeConfig{Interval: time.Second}, aggroConfig)
+	labelled.Update(rm)
+	_, first, err := scrape(t, labelled, "")
+	require.NoError(t, err)
+	_, second, err := scrape(t, labelled, "")
+	require.NoError(t, err)
+	assert.Equal(t, string(first), string(second))
+	found := false
+	for _, name := range aggro.GetAggroLabelNames() {
+		if strings.Contains(string(first), ","+name+`="aggro"`) {
+			found = true
+		}
+	}
+	assert.True(t, found)
+}
+
+// ===== REMOTE WRITE TESTS =====
+
+// protoField is one decoded protobuf field; fixed64 and varint values are both in number
+type protoField struct {
+	num    protowire.Number
+	bytes  []byte
+	number uint64
+}
+
+// decodeProto splits a protobuf message into its fields
+func decodeProto(t *testing.T, b []byte) []protoField {
+	var fields []protoField
+	for len(b) > 0 {
+		num, typ, n := protowire.ConsumeTag(b)
+		require.GreaterOrEqual(t, n, 0)
+		b = b[n:]
+		field := protoField{num: num}
+		switch typ {
+		case protowire.BytesType:
+			field.bytes, n = protowire.ConsumeBytes(b)
+		case protowire.VarintType:
+			field.number, n = protowire.ConsumeVarint(b)
+		case protowire.Fixed64Type:
+			field.number, n = protowire.ConsumeFixed64(b)
+		default:
+			t.Fatalf("unexpected wire type %d", typ)
+		}
+		require.GreaterOrEqual(t, n, 0)
+		b = b[n:]
+		fields = append(fields, field)
+	}
+	return fields
+}
+
+// remoteWriteSample is a decoded sample
+type remoteWriteSample struct {
+	value     float64
+	timestamp int64
+}
+
+// decodedSeries is a decoded time series, with labels in their sent order
+type decodedSeries struct {
+	labels  [][2]string
+	samples []remoteWriteSample
+	created int64
+}
+
+// label returns the value of the first label with the given name
+func (s decodedSeries) label(name string) string {
+	for _, l := range s.labels {
+		if l[0] == name {
+			return l[1]
+		}
+	}
+	return ""
+}
+
+// decodeSample decodes a Sample message
+func decodeSample(t *testing.T, b []byte) remoteWriteSample {
+	var sample remoteWriteSample
+	for _, f := range decodeProto(t, b) {
+		switch f.num {
+		case 1:
+			sample.value = math.Float64frombits(f.number)
+		case 2:
+			sample.timestamp = int64(f.number)
+		}
+	}
+	return sample
+}
+
+// receiveRemoteWrite starts a receiver keeping the headers and decompressed body of every request
+func receiveRemoteWrite(t *testing.T) (*httptest.Server, *[]http.Header, *[][]byte) {
+	var headers []http.Header
+	var bodies [][]byte
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		compressed, err := io.ReadAll(r.Body)
+		require.NoError(t, err)
+		body, err := snappy.Decode(nil, compressed)
+		require.NoError(t, err)
+		headers = append(headers, r.Header)
+		bodies = append(bodies, body)
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	t.Cleanup(ts.Close)
+	return ts, &headers, &bodies
+}
+
+func TestRemoteWriteV1SendsSnappyProtobuf(t *testing.T) {
+	ts, headers, bodies := receiveRemoteWrite(t)
+	exporter := prometheus.NewRemoteWriteExporter(&prometheus.RemoteWriteConfig{URL: ts.URL, Version: "1"}, nil)
+	rm := scrapeCollection(t)
+	require.NoError(t, exporter.Export(context.Background(), rm))
+
+	require.Len(t, *bodies, 1)
+	assert.Equal(t, "snappy", (*headers)[0].Get("Content-Encoding"))
+	assert.Equal(t, "application/x-protobuf", (*headers)[0].Get("Content-Type"))
+	assert.Equal(t, "0.1.0", (*headers)[0].Get("X-Prometheus-Remote-Write-Version"))
+
+	var series []decodedSeries
+	families := make(map[string]uint64)
+	for _, f := range decodeProto(t, (*bodies)[0]) {
+		switch f.num {
+		case 1:
+			var s decodedSeries
+			for _, tf := range decodeProto(t, f.bytes) {
+				switch tf.num {
+				case 1:
+					l := decodeProto(t, tf.bytes)
+					s.labels = append(s.labels, [2]string{string(l[0].bytes), string(l[1].bytes)})
+				case 2:
+					s.samples = append(s.samples, decodeSample(t, tf.bytes))
+				}
+			}
+			series = append(series, s)
+		case 3:
+			md := decodeProto(t, f.bytes)
+			families[string(md[1].bytes)] = md[0].number
+		}
+	}
+
+	// Two routes: one counter series and 3 buckets, sum and count each; plus target_info
+	require.Len(t, series, 2+2*5+1)
+	timestamp := rm.ScopeMetrics[0].Metrics[0].Data.(metricdata.Sum[int64]).DataPoints[0].Time.UnixMilli()
+	found := false
+	for _, s := range series {
+		// Labels are sorted by name, with __name__ among them
+		for i := 1; i < len(s.labels); i++ {
+			assert.Less(t, s.labels[i-1][0], s.labels[i][0])
+		}
+		assert.Equal(t, "checkout", s.label("job"))
+		if s.label("__name__") == "http_requests_total" && s.label("http_route") == "/a" {
+			found = true
+			require.Len(t, s.samples, 1)
+			assert.Equal(t, remoteWriteSample{value: 3, timestamp: timestamp}, s.samples[0])
+		}
+	}
+	assert.True(t, found)
+	assert.Equal(t, map[string]uint64{"http_requests": 1, "http_duration_seconds": 3, "target_info": 6}, families)
+}
+
+func TestRemoteWriteV2AndAggro(t *testing.T) {
+	ts, headers, bodies := receiveRemoteWrite(t)
+	ctx := context.Background()
+	rm := scrapeCollection(t)
+
+	// Version 2 interns every string in symbols and sends the created timestamp of cumulative series
+	exporter := prometheus.NewRemoteWriteExporter(&prometheus.RemoteWriteConfig{URL: ts.URL, Version: "2"}, nil)
+	require.NoError(t, exporter.Export(ctx, rm))
+	assert.Equal(t, "application/x-protobuf;proto=io.prometheus.write.v2.Request", (*headers)[0].Get("Content-Type"))
+	assert.Equal(t, "2.0.0", (*headers)[0].Get("X-Prometheus-Remote-Write-Version"))
+
+	decodeV2 := func(body []byte) []decodedSeries {
+		var symbols []string
+		var series []decodedSeries
+		for _, f := range decodeProto(t, body) {
+			switch f.num {
+			case 4:
+				symbols = append(symbols, string(f.bytes))
+			case 5:
+				var s decodedSeries
+				for _, tf := range decodeProto(t, f.bytes) {
+					switch tf.num {
+					case 1:
+						var refs []uint64
+						for b := tf.bytes; len(b) > 0; {
+							v, n := protowire.ConsumeVarint(b)
+							refs = append(refs, v)
+							b = b[n:]
+						}
+						for i := 0; i+1 < len(refs); i += 2 {
+							s.labels = append(s.labels, [2]string{symbols[refs[i]], symbols[refs[i+1]]})
+						}
+					case 2:
+						s.samples = append(s.samples, decodeSample(t, tf.bytes))
+					case 6:
+						s.created = int64(tf.number)
+					}
+				}
+				series = append(series, s)
+			}
+		}
+		require.Equal(t, "", symbols[0])
+		return series
+	}
+
+	start := rm.ScopeMetrics[0].Metrics[0].Data.(metricdata.Sum[int64]).DataPoints[0].StartTime.UnixMilli()
+	counters := 0
+	for _, s := range decodeV2((*bodies)[0]) {
+		if s.label("__name__") == "http_requests_total" {
+			counters++
+			assert.Equal(t, start, s.created)
+		}
+	}
+	assert.Equal(t, 2, counters)
+
+	// Aggro puts one fault in each request, tagged on the affected series
+	for _, target := range []string{"duplicate-labels", "stale", "out-of-order"} {
+		aggroConfig := &aggro.AggroConfig{RemoteWriteActive: true, RemoteWriteTarget: target}
+		exporter := prometheus.NewRemoteWriteExporter(&prometheus.RemoteWriteConfig{URL: ts.URL, Version: "2"}, aggroConfig)
+		require.NoError(t, exporter.Export(ctx, rm))
+
+		var affected []decodedSeries
+		for _, s := range decodeV2((*bodies)[len(*bodies)-1]) {
+			if s.label("aggro_remote_write") == target {
+				affected = append(affected, s)
+			}
+		}
+		require.Len(t, affected, 1, target)
+		s := affected[0]
+		switch target {
+		case "duplicate-labels":
+			names := make(map[string]int)
+			for _, l := range s.labels {
+				names[l[0]]++
+			}
+			assert.Contains(t, slices.Collect(maps.Values(names)), 2)
+		case "stale":
+			require.Len(t, s.samples, 2)
+			assert.Equal(t, uint64(0x7ff0000000000002), math.Float64bits(s.samples[1].value))
+		case "out-of-order":
+			require.Len(t, s.samples, 2)
+			assert.Less(t, s.samples[1].timestamp, s.samples[0].timestamp)
+		}
+	}
+}
+
+// ===== ZIPKIN AND JAEGER TESTS =====
+
+// legacySpans returns a server span and its failing client child, with an event, from service checkout
+func legacySpans(t *testing.T) []trace.ReadOnlySpan {
+	spanExporter := tracetest.NewInMemoryExporter()
+	res := resource.NewSchemaless(semconv.ServiceName("checkout"), attribute.String("deployment.environment", "test"))
+	tp := trace.NewTracerProvider(trace.WithSyncer(spanExporter), trace.WithResource(res))
+	tracer := tp.Tracer("otel-datagen")
+
+	ctx, root := tracer.Start(context.Background(), "GET /cart", oteltrace.WithSpanKind(oteltrace.SpanKindServer),
+		oteltrace.WithAttributes(attribute.Int("http.status_code", 500)))
+	_, child := tracer.Start(ctx, "SELECT carts", oteltrace.WithSpanKind(oteltrace.SpanKindClient),
+		oteltrace.WithAttributes(attribute.String("peer.service", "postgres")))
+	child.AddEvent("cache miss")
+	child.SetStatus(codes.Error, "connection reset")
+	child.End()
+	root.End()
+	// Shutting down would reset the in-memory exporter, and the syncer has already exported both spans
+	return spanExporter.GetSpans().Snapshots()
+}
+
+// zipkinTestSpan is the part of a Zipkin v2 span the tests check
+type zipkinTestSpan struct {
+	TraceID       string `json:"traceId"`
+	ID            string `json:"id"`
+	ParentID      string `json:"parentId"`
+	Name          string `json:"name"`
+	Kind          string `json:"kind"`
+	Duration      int64  `json:"duration"`
+	LocalEndpoint *struct {
+		ServiceName string `json:"serviceName"`
+	} `json:"localEndpoint"`
+	RemoteEndpoint *struct {
+		ServiceName string `json:"serviceName"`
+	} `json:"remoteEndpoint"`
+	Annotations []struct {
+		Value string `json:"value"`
+	} `json:"annotations"`
+	Tags map[string]string `json:"tags"`
+}
+
+// receiveSpans starts a receiver keeping the content type and body of every request
+func receiveSpans(t *testing.T) (*httptest.Server, *[]string, *[][]byte) {
+	var contentTypes []string
+	var bodies [][]byte
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, err := io.ReadAll(r.Body)
+		require.NoError(t, err)
+		contentTypes = append(contentTypes, r.Header.Get("Content-Type"))
+		bodies = append(bodies, body)
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	t.Cleanup(ts.Close)
+	return ts, &contentTypes, &bodies
+}
+
+func TestZipkinExporterSendsV2JSON(t *testing.T) {
+	spans := legacySpans(t)
+	ts, contentTypes, bodies := receiveSpans(t)
+	exporter := exporters.NewZipkinExporter(ts.URL+"/api/v2/spans", nil)
+	require.NoError(t, exporter.ExportSpans(context.Background(), spans))
+
+	require.Len(t, *bodies, 1)
+	assert.Equal(t, "application/json", (*contentTypes)[0])
+	var sent []zipkinTestSpan
+	require.NoError(t, json.Unmarshal((*bodies)[0], &sent))
+	require.Len(t, sent, 2)
+	child, root := sent[0], sent[1]
+
+	assert.Len(t, root.TraceID, 32)
+	assert.Equal(t, root.TraceID, child.TraceID)
+	assert.Equal(t, root.ID, child.ParentID)
+	assert.Empty(t, root.ParentID)
+	assert.Equal(t, "GET /cart", root.Name)
+	assert.Equal(t, "SERVER", root.Kind)
+	assert.Equal(t, "CLIENT", child.Kind)
+	assert.Equal(t, "500", root.Tags["http.status_code"])
+	require.NotNil(t, root.LocalEndpoint)
+	assert.Equal(t, "checkout", root.LocalEndpoint.ServiceName)
+	require.NotNil(t, child.RemoteEndpoint)
+	assert.Equal(t, "postgres", child.RemoteEndpoint.ServiceName)
+	assert.Equal(t, "ERROR", child.Tags["otel.status_code"])
+	assert.Equal(t, "connection reset", child.Tags["error"])
+	require.Len(t, child.Annotations, 1)
+	assert.Equal(t, "cache miss", child.Annotations[0].Value)
+
+	// Each format fault goes into exactly one span of the export
+	for _, target := range aggro.GetAggroTraceFormatCases() {
+		*bodies = nil
+		aggroConfig := &aggro.AggroConfig{TraceFormatActive: true, TraceFormatTarget: target}
+		exporter := exporters.NewZipkinExporter(ts.URL, aggroConfig)
+		require.NoError(t, exporter.ExportSpans(context.Background(), spans))
+		require.Len(t, *bodies, 1)
+		var sent []zipkinTestSpan
+		require.NoError(t, json.Unmarshal((*bodies)[0], &sent))
+
+		var faulty []zipkinTestSpan
+		for _, span := range sent {
+			tagged := span.Tags["aggro.trace_format"] == target
+			for _, annotation := range span.Annotations {
+				tagged = tagged || annotation.Value == "aggro.trace_format="+target
+			}
+			if tagged {
+				faulty = append(faulty, span)
+			}
+		}
+		require.Len(t, faulty, 1, target)
+		switch target {
+		case "64-bit-ids":
+			assert.Len(t, faulty[0].TraceID, 16)
+		case "missing-local-endpoint":
+			assert.Nil(t, faulty[0].LocalEndpoint)
+		case "annotation-only":
+			assert.Empty(t, faulty[0].Name)
+			assert.Empty(t, faulty[0].Kind)
+			assert.Zero(t, faulty[0].Duration)
+			assert.Empty(t, faulty[0].Tags)
+			assert.NotEmpty(t, faulty[0].Annotations)
+		}
+	}
+}
+
+// decodeThrift decodes a Thrift binary struct into its fields by id, returning the remaining bytes
+// Structs decode to maps, lists to slices, integers to int64 and strings to string
+func decodeThrift(t *testing.T, b []byte) (map[int16]any, []byte) {
+	fields := make(map[int16]any)
+	for {
+		require.NotEmpty(t, b)
+		kind := b[0]
+		if kind == 0 {
+			return fields, b[1:]
+		}
+		id := int16(binary.BigEndian.Uint16(b[1:3]))
+		fields[id], b = decodeThriftValue(t, kind, b[3:])
+	}
+}
+
+// decodeThriftValue decodes one value of the given Thrift type
+func decodeThriftValue(t *testing.T, kind byte, b []byte) (any, []byte) {
+	switch kind {
+	case 2:
+		return b[0] == 1, b[1:]
+	case 4:
+		return math.Float64frombits(binary.BigEndian.Uint64(b)), b[8:]
+	case 8:
+		return int64(int32(binary.BigEndian.Uint32(b))), b[4:]
+	case 10:
+		return int64(binary.BigEndian.Uint64(b)), b[8:]
+	case 11:
+		n := binary.BigEndian.Uint32(b)
+		return string(b[4 : 4+n]), b[4+n:]
+	case 12:
+		return decodeThrift(t, b)
+	case 15:
+		elem, n := b[0], binary.BigEndian.Uint32(b[1:5])
+		b = b[5:]
+		list := []any{}
+		for range n {
+			var value any
+			value, b = decodeThriftValue(t, elem, b)
+			list = append(list, value)
+		}
+		return list, b
+	}
+	t.Fatalf("unexpected thrift type %d", kind)
+	return nil, nil
+}
+
+// testRawCodec passes raw protobuf bytes through a test gRPC server
+type testRawCodec struct{}
+
+func (testRawCodec) Marshal(v any) ([]byte, error) { return *v.(*[]byte), nil }
+func (testRawCodec) Unmarshal(data []byte, v any) error {
+	*v.(*[]byte) = append([]byte{}, data...)
+	return nil
+}
+func (testRawCodec) Name() string { return "proto" }
+
+// receiveJaegerGRPC starts a gRPC server keeping the method and request of every call
+func receiveJaegerGRPC(t *testing.T) (string, *[]string, *[][]byte) {
+	var methods []string
+	var requests [][]byte
+	server := grpc.NewServer(grpc.ForceServerCodec(testRawCodec{}), grpc.UnknownServiceHandler(func(_ any, stream grpc.ServerStream) error {
+		method, _ := grpc.MethodFromServerStream(stream)
+		var request []byte
+		if err := stream.RecvMsg(&request); err != nil {
+			return err
+		}
+		methods = append(methods, method)
+		requests = append(requests, request)
+		return stream.SendMsg(&[]byte{})
+	}))
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	go server.Serve(listener)
+	t.Cleanup(server.Stop)
+	return listener.Addr().String(), &methods, &requests
+}
+
+func TestJaegerExportersSendThriftAndGRPC(t *testing.T) {
+	spans := legacySpans(t)
+	root := spans[1].SpanContext()
+	traceID := root.TraceID()
+	high := int64(binary.BigEndian.Uint64(traceID[:8]))
+	low := int64(binary.BigEndian.Uint64(traceID[8:]))
+	rootID := root.SpanID()
+	rootSpanID := int64(binary.BigEndian.Uint64(rootID[:]))
+
+	// Thrift over HTTP
+	ts, contentTypes, bodies := receiveSpans(t)
+	thriftExporter := exporters.NewJaegerThriftExporter(ts.URL+"/api/traces", nil)
+	require.NoError(t, thriftExporter.ExportSpans(context.Background(), spans))
+	require.Len(t, *bodies, 1)
+	assert.Equal(t, "application/x-thrift", (*contentTypes)[0])
+
+	batch, rest := decodeThrift(t, (*bodies)[0])
+	assert.Empty(t, rest)
+	process := batch[1].(map[int16]any)
+	assert.Equal(t, "checkout", process[1])
+	sent := batch[2].([]any)
+	require.Len(t, sent, 2)
+	child, parent := sent[0].(map[int16]any), sent[1].(map[int16]any)
+	assert.Equal(t, low, parent[1])
+	assert.Equal(t, high, parent[2])
+	assert.Equal(t, rootSpanID, parent[3])
+	assert.Equal(t, "GET /cart", parent[5])
+	assert.Equal(t, rootSpanID, child[4])
+	ref := child[6].([]any)[0].(map[int16]any)
+	assert.Equal(t, int64(0), ref[1]) // CHILD_OF
+	assert.Equal(t, rootSpanID, ref[4])
+
+	tags := make(map[string]map[int16]any)
+	for _, tag := range child[10].([]any) {
+		tags[tag.(map[int16]any)[1].(string)] = tag.(map[int16]any)
+	}
+	assert.Equal(t, "client", tags["span.kind"][3])
+	assert.Equal(t, true, tags["error"][5])
+	logs := child[11].([]any)
+	require.Len(t, logs, 1)
+	assert.Equal(t, "cache miss", logs[0].(map[int16]any)[2].([]any)[0].(map[int16]any)[3])
+
+	// The missing local endpoint of Jaeger is a process without a service name
+	*bodies = nil
+	aggroConfig := &aggro.AggroConfig{TraceFormatActive: true, TraceFormatTarget: "missing-local-endpoint"}
+	require.NoError(t, exporters.NewJaegerThriftExporter(ts.URL, aggroConfig).ExportSpans(context.Background(), spans))
+	batch, _ = decodeThrift(t, (*bodies)[0])
+	assert.Equal(t, "", batch[1].(map[int16]any)[1])
+
+	// gRPC, with 64-bit trace IDs on one span
+	endpoint, methods, requests := receiveJaegerGRPC(t)
+	aggroConfig = &aggro.AggroConfig{TraceFormatActive: true, TraceFormatTarget: "64-bit-ids"}
+	grpcExporter, err := exporters.NewJaegerGRPCExporter(endpoint, aggroConfig)
+	require.NoError(t, err)
+	defer grpcExporter.Shutdown(context.Background())
+	require.NoError(t, grpcExporter.ExportSpans(context.Background(), spans))
+	require.Len(t, *requests, 1)
+	assert.Equal(t, "/jaeger.api_v2.CollectorService/PostSpans", (*methods)[0])
+
+	request := decodeProto(t, (*requests)[0])
+	require.Len(t, request, 1)
+	var traceIDs [][]byte
+	var serviceName string
+	for _, f := range decodeProto(t, request[0].bytes) {
+		switch f.num {
+		case 1:
+			for _, sf := range decodeProto(t, f.bytes) {
+				if sf.num == 1 {
+					traceIDs = append(traceIDs, sf.bytes)
+				}
+			}
+		case 2:
+			serviceName = string(decodeProto(t, f.bytes)[0].bytes)
+		}
+	}
+	assert.Equal(t, "checkout", serviceName)
+	require.Len(t, traceIDs, 2)
+	full, truncated := 0, 0
+	for _, id := range traceIDs {
+		require.Len(t, id, 16)
+		assert.Equal(t, traceID[:][8:], id[8:])
+		if slices.Equal(id[:8], traceID[:][:8]) {
+			full++
+		} else if slices.Equal(id[:8], make([]byte, 8)) {
+			truncated++
+		}
+	}
+	assert.Equal(t, 1, full)
+	assert.Equal(t, 1, truncated)
+}
+
+func TestJaegerExportersSendOneBatchPerProcess(t *testing.T) {
+	// One export mixing the spans of two instances, as batch processors shared by a fleet send them
+	var spans []trace.ReadOnlySpan
+	for _, instance := range []string{"web-1", "web-2", "web-1"} {
+		spanExporter := tracetest.NewInMemoryExporter()
+		res := resource.NewSchemaless(semconv.ServiceName("checkout"), semconv.ServiceInstanceID(instance))
+		tp := trace.NewTracerProvider(trace.WithSyncer(spanExporter), trace.WithResource(res))
+		_, span := tp.Tracer("otel-datagen").Start(context.Background(), "GET /"+instance)
+		span.End()
+		spans = append(spans, spanExporter.GetSpans().Snapshots()...)
+	}
+
+	// Thrift over HTTP posts one batch per process, each with its own instance tag
+	ts, _, bodies := receiveSpans(t)
+	require.NoError(t, exporters.NewJaegerThriftExporter(ts.URL, nil).ExportSpans(context.Background(), spans))
+	require.Len(t, *bodies, 2)
+	for i, instance := range []string{"web-1", "web-2"} {
+		batch, _ := decodeThrift(t, (*bodies)[i])
+		process := batch[1].(map[int16]any)
+		assert.Equal(t, "checkout", process[1])
+		tags := make(map[string]any)
+		for _, tag := range process[2].([]any) {
+			tags[tag.(map[int16]any)[1].(string)] = tag.(map[int16]any)[3]
+		}
+		assert.Equal(t, instance, tags["service.instance.id"])
+		for _, span := range batch[2].([]any) {
+			assert.Equal(t, "GET /"+instance, span.(map[int16]any)[5])
+		}
+	}
+	first, _ := decodeThrift(t, (*bodies)[0])
+	assert.Len(t, first[2].([]any), 2)
+
+	// gRPC makes one PostSpans call per process
+	endpoint, _, requests := receiveJaegerGRPC(t)
+	grpcExporter, err := exporters.NewJaegerGRPCExporter(endpoint, nil)
+	require.NoError(t, err)
+	defer grpcExporter.Shutdown(context.Background())
+	require.NoError(t, grpcExporter.ExportSpans(context.Background(), spans))
+	assert.Len(t, *requests, 2)
+}
+
+// ===== LOG OUTPUT TESTS =====
+
+// emitLogs sends three generated records of service checkout on host web-1 through the exporters
+func emitLogs(t *testing.T, logExporters ...sdklog.Exporter) {
+	res := resource.NewSchemaless(semconv.ServiceName("checkout"), semconv.HostName("web-1"))
+	options := []sdklog.LoggerProviderOption{sdklog.WithResource(res)}
+	for _, exporter := range logExporters {
+		options = append(options, sdklog.WithProcessor(sdklog.NewSimpleProcessor(exporter)))
+	}
+	lp := sdklog.NewLoggerProvider(options...)
+	overrides := []string{`note=say "hi" ]`}
+	require.NoError(t, generators.GenerateLogsWithProvider(context.Background(), lp, 3, 0, overrides, nil, nil, &timestamps.TimestampConfig{}))
+	require.NoError(t, lp.Shutdown(context.Background()))
+}
+
+func TestLokiAndElasticsearchExporters(t *testing.T) {
+	ts, contentTypes, bodies := receiveSpans(t)
+	emitLogs(t,
+		exporters.NewLokiExporter(ts.URL+"/loki", "protobuf"),
+		exporters.NewLokiExporter(ts.URL+"/loki", "json"),
+		exporters.NewElasticsearchExporter(ts.URL+"/", "logs-test"))
+	// Simple processors export each record, in the order the exporters were given
+	require.Len(t, *bodies, 9)
+
+	// Loki protobuf: snappy-compressed PushRequest with one stream per label set
+	assert.Equal(t, "application/x-protobuf", (*contentTypes)[0])
+	body, err := snappy.Decode(nil, (*bodies)[0])
+	require.NoError(t, err)
+	streams := decodeProto(t, body)
+	require.Len(t, streams, 1)
+	stream := decodeProto(t, streams[0].bytes)
+	assert.Equal(t, `{level="info", service_name="checkout"}`, string(stream[0].bytes))
+	entry := decodeProto(t, stream[1].bytes)
+	assert.Equal(t, "example-log-1", string(entry[1].bytes))
+	metadata := make(map[string]string)
+	for _, f := range entry[2:] {
+		pair := decodeProto(t, f.bytes)
+		metadata[string(pair[0].bytes)] = string(pair[1].bytes)
+	}
+	assert.Equal(t, `say "hi" ]`, metadata["note"])
+
+	// Loki JSON
+	assert.Equal(t, "application/json", (*contentTypes)[1])
+	var push struct {
+		Streams []struct {
+			Stream map[string]string `json:"stream"`
+			Values [][]any           `json:"values"`
+		} `json:"streams"`
+	}
+	require.NoError(t, json.Unmarshal((*bodies)[1], &push))
+	require.Len(t, push.Streams, 1)
+	assert.Equal(t, map[string]string{"level": "info", "service_name": "checkout"}, push.Streams[0].Stream)
+	require.Len(t, push.Streams[0].Values, 1)
+	assert.Equal(t, "example-log-1", push.Streams[0].Values[0][1])
+	assert.Equal(t, `say "hi" ]`, push.Streams[0].Values[0][2].(map[string]any)["note"])
+
+	// Elasticsearch: a create action and a document per record
+	assert.Equal(t, "application/x-ndjson", (*contentTypes)[2])
+	lines := strings.Split(strings.TrimSuffix(string((*bodies)[2]), "\n"), "\n")
+	require.Len(t, lines, 2)
+	assert.JSONEq(t, `{"create":{"_index":"logs-test"}}`, lines[0])
+	var document map[string]any
+	require.NoError(t, json.Unmarshal([]byte(lines[1]), &document))
+	assert.Equal(t, "example-log-1", document["message"])
+	assert.Equal(t, "INFO", document["severity_text"])
+	assert.Equal(t, `say "hi" ]`, document["attributes"].(map[string]any)["note"])
+	assert.Equal(t, "checkout", document["resource"].(map[string]any)["attributes"].(map[string]any)["service.name"])
+
+	// Documents rejected inside a 200 bulk answer are reported
+	rejecting := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, `{"errors":true,"items":[{"create":{"status":400,"error":{"type":"mapper_parsing_exception","reason":"failed to parse"}}}]}`)
+	}))
+	defer rejecting.Close()
+	var record sdklog.Record
+	record.SetBody(otellog.StringValue("rejected"))
+	err = exporters.NewElasticsearchExporter(rejecting.URL, "logs-test").Export(context.Background(), []sdklog.Record{record})
+	assert.ErrorContains(t, err, "rejected 1 of 1 documents: mapper_parsing_exception: failed to parse")
+}
+
+// acceptTCP starts a TCP listener returning everything sent on its first connection once it is closed
+func acceptTCP(t *testing.T) (string, <-chan []byte) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() { listener.Close() })
+	received := make(chan []byte, 1)
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			received <- nil
+			return
+		}
+		defer conn.Close()
+		data, _ := io.ReadAll(conn)
+		received <- data
+	}()
+	return listener.Addr().String(), received
+}
+
+// decodeMsgpack decodes one MessagePack value of the types the Fluent Forward exporter writes,
+// returning the remaining bytes; EventTime extensions decode to time.Time
+func decodeMsgpack(t *testing.T, b []byte) (any, []byte) {
+	c := b[0]
+	b = b[1:]
+	switch {
+	case c&0xf0 == 0x90, c == 0xdc:
+		n := int(c & 0x0f)
+		if c == 0xdc {
+			n, b = int(binary.BigEndian.Uint16(b)), b[2:]
+		}
+		list := make([]any, n)
+		for i := range list {
+			list[i], b = decodeMsgpack(t, b)
+		}
+		return list, b
+	case c&0xf0 == 0x80, c == 0xde:
+		n := int(c & 0x0f)
+		if c == 0xde {
+			n, b = int(binary.BigEndian.Uint16(b)), b[2:]
+		}
+		values := make(map[string]any, n)
+		for range n {
+			var key, value any
+			key, b = decodeMsgpack(t, b)
+			value, b = decodeMsgpack(t, b)
+			values[key.(string)] = value
+		}
+		return values, b
+	case c&0xe0 == 0xa0, c == 0xd9, c == 0xda:
+		n := int(c & 0x1f)
+		switch c {
+		case 0xd9:
+			n, b = int(b[0]), b[1:]
+		case 0xda:
+			n, b = int(binary.BigEndian.Uint16(b)), b[2:]
+		}
+		return string(b[:n]), b[n:]
+	case c == 0xd3:
+		return int64(binary.BigEndian.Uint64(b)), b[8:]
+	case c == 0xd7:
+		require.Equal(t, byte(0), b[0], "EventTime extension type")
+		return time.Unix(int64(binary.BigEndian.Uint32(b[1:])), int64(binary.BigEndian.Uint32(b[5:]))), b[9:]
+	case c == 0xc2, c == 0xc3:
+		return c == 0xc3, b
+	}
+	t.Fatalf("unexpected msgpack type 0x%x", c)
+	return nil, nil
+}
+
+func TestSyslogAndFluentForwardExporters(t *testing.T) {
+	// RFC5424 over TCP with octet counting
+	address, received := acceptTCP(t)
+	emitLogs(t, exporters.NewSyslogExporter("tcp://"+address, "rfc5424"))
+	data := string(<-received)
+	var messages []string
+	for len(data) > 0 {
+		length, rest, ok := strings.Cut(data, " ")
+		require.True(t, ok)
+		n, err := strconv.Atoi(length)
+		require.NoError(t, err)
+		messages = append(messages, rest[:n])
+		data = rest[n:]
+	}
+	require.Len(t, messages, 3)
+	assert.Regexp(t, `^<14>1 \d{4}-\d\d-\d\dT\d\d:\d\d:\d\d\.\d{6}Z web-1 checkout - - \[otel@32473 .*note="say \\"hi\\" \\]"\] example-log-1$`, messages[0])
+
+	// RFC3164 over UDP, one datagram per message
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer conn.Close()
+	emitLogs(t, exporters.NewSyslogExporter("udp://"+conn.LocalAddr().String(), "rfc3164"))
+	buf := make([]byte, 2048)
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	n, _, err := conn.ReadFrom(buf)
+	require.NoError(t, err)
+	assert.Regexp(t, `^<14>[A-Z][a-z]{2} [ \d]\d \d\d:\d\d:\d\d web-1 checkout: example-log-1$`, string(buf[:n]))
+
+	// Fluent Forward: one Forward mode message per export
+	address, received = acceptTCP(t)
+	emitLogs(t, exporters.NewFluentForwardExporter(address, "app.logs"))
+	data = string(<-received)
+	var events []any
+	b := []byte(data)
+	for len(b) > 0 {
+		var message any
+		message, b = decodeMsgpack(t, b)
+		forward := message.([]any)
+		require.Len(t, forward, 3)
+		assert.Equal(t, "app.logs", forward[0])
+		assert.Equal(t, map[string]any{"size": int64(1)}, forward[2])
+		events = append(events, forward[1].([]any)...)
+	}
+	require.Len(t, events, 3)
+	event := events[0].([]any)
+	assert.IsType(t, time.Time{}, event[0])
+	record := event[1].(map[string]any)
+	assert.Equal(t, "example-log-1", record["message"])
+	assert.Equal(t, `say "hi" ]`, record["note"])
+	assert.Equal(t, int64(9), record["severity_number"])
+	assert.Equal(t, "checkout", record["service.name"])
+}
+
+// ===== METRIC OUTPUT TESTS =====
+
+// receiveUDP listens on a local UDP port and returns its address and a function reading the next datagram
+func receiveUDP(t *testing.T) (string, func() string) {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() { conn.Close() })
+	return conn.LocalAddr().String(), func() string {
+		buf := make([]byte, 65536)
+		conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+		n, _, err := conn.ReadFrom(buf)
+		require.NoError(t, err)
+		return string(buf[:n])
+	}
+}
+
+func TestStatsDInfluxAndGraphiteExporters(t *testing.T) {
+	ctx := context.Background()
+	rm := scrapeCollection(t)
+	ts := rm.ScopeMetrics[0].Metrics[0].Data.(metricdata.Sum[int64]).DataPoints[0].Time
+	histogramTS := rm.ScopeMetrics[0].Metrics[1].Data.(metricdata.Histogram[float64]).DataPoints[0].Time
+
+	// DogStatsD over UDP; cumulative counters are sent as increments, so the second export adds nothing
+	address, read := receiveUDP(t)
+	exporter := exporters.NewStatsDExporter("udp://"+address, "dogstatsd", nil)
+	require.NoError(t, exporter.Export(ctx, rm))
+	lines := strings.Split(strings.TrimSpace(read()), "\n")
+	assert.Contains(t, lines, "http.requests:3|c|#http.route:/a,service.name:checkout")
+	assert.Contains(t, lines, "http.duration:1|h|#http.route:/b,service.name:checkout")
+	require.NoError(t, exporter.Export(ctx, rm))
+	assert.Contains(t, strings.Split(read(), "\n"), "http.requests:0|c|#http.route:/a,service.name:checkout")
+	assert.Equal(t, metricdata.DeltaTemporality, exporter.Temporality(sdkmetric.InstrumentKindHistogram))
+
+	// Plain StatsD over TCP has no tags and sends histograms as timers
+	address, received := acceptTCP(t)
+	exporter = exporters.NewStatsDExporter("tcp://"+address, "statsd", nil)
+	require.NoError(t, exporter.Export(ctx, rm))
+	require.NoError(t, exporter.Shutdown(ctx))
+	assert.Equal(t, "http.requests:3|c\nhttp.requests:3|c\nhttp.duration:1|ms\nhttp.duration:1|ms\n", string(<-received))
+
+	// InfluxDB line protocol over HTTP, in the Telegraf prometheus schema
+	server, contentTypes, bodies := receiveSpans(t)
+	exporter = exporters.NewInfluxExporter(server.URL+"/write", nil)
+	require.NoError(t, exporter.Export(ctx, rm))
+	require.Len(t, *bodies, 1)
+	assert.Equal(t, "text/plain; charset=utf-8", (*contentTypes)[0])
+	lines = strings.Split(strings.TrimSpace(string((*bodies)[0])), "\n")
+	assert.Contains(t, lines, "http.requests,http.route=/a,service.name=checkout counter=3i "+strconv.FormatInt(ts.UnixNano(), 10))
+	assert.Contains(t, lines, "http.duration,http.route=/b,service.name=checkout 0.1=0,1=1,+Inf=1,count=1,sum=0.5 "+strconv.FormatInt(histogramTS.UnixNano(), 10))
+
+	// Graphite over TCP with tags; histograms become count, sum and bucket series
+	address, received = acceptTCP(t)
+	exporter = exporters.NewGraphiteExporter("tcp://"+address, nil)
+	require.NoError(t, exporter.Export(ctx, rm))
+	require.NoError(t, exporter.Shutdown(ctx))
+	lines = strings.Split(strings.TrimSpace(string(<-received)), "\n")
+	seconds := " " + strconv.FormatInt(histogramTS.Unix(), 10)
+	assert.Contains(t, lines, "http.requests;http.route=/a;service.name=checkout 3 "+strconv.FormatInt(ts.Unix(), 10))
+	assert.Contains(t, lines, "http.duration.count;http.route=/a;service.name=checkout 1"+seconds)
+	assert.Contains(t, lines, "http.duration.sum;http.route=/a;service.name=checkout 0.5"+seconds)
+	assert.Contains(t, lines, "http.duration.bucket;http.route=/a;service.name=checkout;le=0.1 0"+seconds)
+	assert.Contains(t, lines, "http.duration.bucket;http.route=/a;service.name=checkout;le=+Inf 1"+seconds)
+	assert.Len(t, lines, 12)
+
+	_, err := exporters.ParseMetricOutputConfig("metrics")
+	require.NoError(t, err)
+	config := &exporters.MetricOutputConfig{StatsDFlavor: "dogstatsd", GraphiteAddress: "localhost:2003"}
+	assert.ErrorContains(t, config.Validate(), "invalid graphite address")
+	config = &exporters.MetricOutputConfig{StatsDFlavor: "etsy"}
+	assert.ErrorContains(t, config.Validate(), "unsupported statsd flavor")
+}
+
+func TestMetricFormatAggroKeepsLinesParseable(t *testing.T) {
+	ctx := context.Background()
+	for _, target := range aggro.GetAggroMetricFormatCases() {
+		aggroConfig := &aggro.AggroConfig{MetricFormatActive: true, MetricFormatTarget: target}
+
+		address, read := receiveUDP(t)
+		require.NoError(t, exporters.NewStatsDExporter("udp://"+address, "dogstatsd", aggroConfig).Export(ctx, scrapeCollection(t)))
+		var faulty []string
+		for _, line := range strings.Split(strings.TrimSpace(read()), "\n") {
+			if strings.Contains(line, "aggro.metric_format:"+target) {
+				faulty = append(faulty, line)
+			}
+			name, rest, ok := strings.Cut(line, ":")
+			require.True(t, ok, line)
+			assert.NotContains(t, name, "|", line)
+			assert.NotContains(t, rest, "\n", line)
+		}
+		require.NotEmpty(t, faulty, target)
+		if target == "sample-rate" {
+			rates := regexp.MustCompile(`\|@([^|]*)`).FindStringSubmatch(faulty[0])
+			require.Len(t, rates, 2)
+			assert.Contains(t, aggro.GetAggroSampleRates(), rates[1])
+		}
+
+		// Every Influx line still has exactly a measurement and tags, fields and a timestamp
+		server, _, bodies := receiveSpans(t)
+		require.NoError(t, exporters.NewInfluxExporter(server.URL, aggroConfig).Export(ctx, scrapeCollection(t)))
+		body := string((*bodies)[0])
+		assert.Contains(t, body, "aggro.metric_format="+target)
+		for _, line := range strings.Split(strings.TrimSpace(body), "\n") {
+			unescaped := regexp.MustCompile(`(^|[^\\]) `).FindAllStringIndex(line, -1)
+			assert.Len(t, unescaped, 2, line)
+		}
+
+		// And every Graphite line has a path, a value and a timestamp
+		address, received := acceptTCP(t)
+		exporter := exporters.NewGraphiteExporter("tcp://"+address, aggroConfig)
+		require.NoError(t, exporter.Export(ctx, scrapeCollection(t)))
+		require.NoError(t, exporter.Shutdown(ctx))
+		data := string(<-received)
+		assert.Contains(t, data, "aggro.metric_format="+target)
+		for _, line := range strings.Split(strings.TrimSpace(data), "\n") {
+			assert.Len(t, strings.Fields(line), 3, line)
+		}
+	}
+}
+
+// ===== OTLP SECURITY TESTS =====
+
+// writeTestPKI writes a CA, and a client certificate and key it signed, to a temporary directory and
+// returns the directory and a server TLS configuration for collector.test requiring such client certificates
+func writeTestPKI(t *testing.T) (string, *tls.Config) {
+	dir := t.TempDir()
+	newKey := func() *ecdsa.PrivateKey {
+		key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+		require.NoError(t, err)
+		return key
+	}
+	writePEM := func(name, kind string, der []byte) {
+		require.NoError(t, os.WriteFile(filepath.Join(dir, name), pem.EncodeToMemory(&pem.Block{Type: kind, Bytes: der}), 0o600))
+	}
+
+	caKey := newKey()
+	caTemplate := &x509.Certificate{SerialNumber: big.NewInt(1), Subject: pkix.Name{CommonName: "test CA"}, NotBefore: time.Now().Add(-time.Hour),
+		NotAfter: time.Now().Add(time.Hour), IsCA: true, BasicConstraintsValid: true, KeyUsage: x509.KeyUsageCertSign}
+	caDER, err := x509.CreateCertificate(rand.Reader, caTemplate, caTemplate, &caKey.PublicKey, caKey)
+	require.NoError(t, err)
+	ca, err := x509.ParseCertificate(caDER)
+	require.NoError(t, err)
+	writePEM("ca.pem", "CERTIFICATE", caDER)
+
+	issue := func(serial int64, usage x509.ExtKeyUsage, dnsNames []string) (*ecdsa.PrivateKey, []byte) {
+		key := newKey()
+		template := &x509.Certificate{SerialNumber: big.NewInt(serial), Subject: pkix.Name{CommonName: "test"}, NotBefore: time.Now().Add(-time.Hour),
+			NotAfter: time.Now().Add(time.Hour), ExtKeyUsage: []x509.ExtKeyUsage{usage}, DNSNames: dnsNames}
+		der, err := x509.CreateCertificate(rand.Reader, template, ca, &key.PublicKey, caKey)
+		require.NoError(t, err)
+		return key, der
+	}
+	clientKey, clientDER := issue(2, x509.ExtKeyUsageClientAuth, nil)
+	writePEM("client.pem", "CERTIFICATE", clientDER)
+	clientKeyDER, err := x509.MarshalECPrivateKey(clientKey)
+	require.NoError(t, err)
+	writePEM("client-key.pem", "EC PRIVATE KEY", clientKeyDER)
+
+	serverKey, serverDER := issue(3, x509.ExtKeyUsageServerAuth, []string{"collector.test"})
+	pool := x509.NewCertPool()
+	pool.AddCert(ca)
+	return dir, &tls.Config{
+		Certificates: []tls.Certificate{{Certificate: [][]byte{serverDER}, PrivateKey: serverKey}},
+		ClientAuth:   tls.RequireAndVerifyClientCert,
+		ClientCAs:    pool,
+	}
+}
+
+func TestOTLPSecurityConfigFromFlagsAndEnvironment(t *testing.T) {
+	defer viper.Reset()
+	dir, _ := writeTestPKI(t)
+	tokenFile := filepath.Join(dir, "token")
+	require.NoError(t, os.WriteFile(tokenFile, []byte("s3cret\n"), 0o600))
+
+	// Headers from the environment are URL-decoded; flags win over them, and the token becomes Authorization
+	t.Setenv("OTEL_EXPORTER_OTLP_HEADERS", "X-Scope-OrgID=tenant%201,api-key=from-env")
+	viper.Set("otlp.headers", []string{"api-key=from-flag"})
+	viper.Set("otlp.bearer_token_file", tokenFile)
+	config := exporters.ExporterConfig{OTLPEndpoint: "http://collector:4317"}
+	require.NoError(t, exporters.ConfigureOTLPSecurity(&config))
+	assert.Equal(t, "collector:4317", config.OTLPEndpoint)
+	assert.True(t, config.Insecure)
+	assert.Nil(t, config.TLSConfig)
+	assert.Equal(t, map[string]string{"X-Scope-OrgID": "tenant 1", "api-key": "from-flag", "Authorization": "Bearer s3cret"}, config.Headers)
+
+	// Any TLS setting turns TLS on, and https:// does too
+	viper.Set("otlp.ca_file", filepath.Join(dir, "ca.pem"))
+	viper.Set("otlp.server_name", "collector.test")
+	config = exporters.ExporterConfig{OTLPEndpoint: "localhost:4317"}
+	require.NoError(t, exporters.ConfigureOTLPSecurity(&config))
+	assert.False(t, config.Insecure)
+	require.NotNil(t, config.TLSConfig)
+	assert.Equal(t, "collector.test", config.TLSConfig.ServerName)
+	assert.NotNil(t, config.TLSConfig.RootCAs)
+
+	viper.Reset()
+	config = exporters.ExporterConfig{OTLPEndpoint: "localhost:4317"}
+	require.NoError(t, exporters.ConfigureOTLPSecurity(&config))
+	assert.True(t, config.Insecure, "plaintext stays the default")
+	config = exporters.ExporterConfig{OTLPEndpoint: "https://otlp.example.com"}
+	require.NoError(t, exporters.ConfigureOTLPSecurity(&config))
+	assert.False(t, config.Insecure)
+	assert.Equal(t, "otlp.example.com", config.OTLPEndpoint)
+	t.Setenv("OTEL_EXPORTER_OTLP_INSECURE", "false")
+	config = exporters.ExporterConfig{OTLPEndpoint: "localhost:4317"}
+	require.NoError(t, exporters.ConfigureOTLPSecurity(&config))
+	assert.False(t, config.Insecure)
+
+	viper.Set("otlp.cert_file", filepath.Join(dir, "client.pem"))
+	assert.ErrorContains(t, exporters.ConfigureOTLPSecurity(&config), "must be given together")
+}
+
+func TestOTLPSignalEnvironmentOverridesGeneric(t *testing.T) {
+	defer viper.Reset()
+	dir, _ := writeTestPKI(t)
+
+	// A collector receiving logs over HTTP on a custom path
+	var paths []string
+	var mu sync.Mutex
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		paths = append(paths, r.URL.Path)
+	}))
+	defer server.Close()
+
+	viper.BindEnv("otlp.logs.endpoint", "OTEL_EXPORTER_OTLP_LOGS_ENDPOINT")
+	viper.BindEnv("otlp.traces.ca_file", "OTEL_EXPORTER_OTLP_TRACES_CERTIFICATE")
+	t.Setenv("OTEL_EXPORTER_OTLP_HEADERS", "api-key=generic")
+	t.Setenv("OTEL_EXPORTER_OTLP_LOGS_ENDPOINT", server.URL+"/ingest/logs")
+	t.Setenv("OTEL_EXPORTER_OTLP_LOGS_HEADERS", "api-key=logs")
+	t.Setenv("OTEL_EXPORTER_OTLP_TRACES_CERTIFICATE", filepath.Join(dir, "ca.pem"))
+	t.Setenv("OTEL_EXPORTER_OTLP_METRICS_INSECURE", "false")
+
+	exporterConfig := exporters.ExporterConfig{OTLPEndpoint: "collector:4317", Protocol: "http"}
+	require.NoError(t, exporters.ConfigureOTLPSecurity(&exporterConfig))
+
+	// Generic settings stay for the exporters of signals without their own
+	assert.True(t, exporterConfig.Insecure)
+	assert.Equal(t, map[string]string{"api-key": "generic"}, exporterConfig.Headers)
+
+	// Each signal's variables replace the generic ones for that signal only
+	traces := exporterConfig.ForSignal("traces")
+	assert.Equal(t, "collector:4317", traces.OTLPEndpoint)
+	assert.False(t, traces.Insecure)
+	require.NotNil(t, traces.TLSConfig)
+	assert.NotNil(t, traces.TLSConfig.RootCAs)
+	assert.Equal(t, map[string]string{"api-key": "generic"}, traces.Headers)
+	metricsConfig := exporterConfig.ForSignal("metrics")
+	assert.False(t, metricsConfig.Insecure)
+	logs := exporterConfig.ForSignal("logs")
+	assert.Equal(t, strings.TrimPrefix(server.URL, "http://"), logs.OTLPEndpoint)
+	assert.True(t, logs.Insecure)
+	assert.Equal(t, map[string]string{"api-key": "logs"}, logs.Headers)
+
+	// The signal's endpoint URL is used with its path
+	logExporters, err := exporters.CreateDualLogExporters(context.Background(), exporterConfig, nil)
+	require.NoError(t, err)
+	require.Len(t, logExporters, 1)
+	lp := sdklog.NewLoggerProvider(sdklog.WithProcessor(sdklog.NewSimpleProcessor(logExporters[0])))
+	var record otellog.Record
+	record.SetBody(otellog.StringValue("routed"))
+	lp.Logger("test").Emit(context.Background(), record)
+	require.NoError(t, lp.Shutdown(context.Background()))
+	mu.Lock()
+	assert.Equal(t, []string{"/ingest/logs"}, paths)
+	mu.Unlock()
+
+	// Flags are bound to the signal's keys too, so they still win over its variables
+	flags := pflag.NewFlagSet("test", pflag.ContinueOnError)
+	flags.String("otlp-endpoint", "", "")
+	viper.BindPFlag("otlp.logs.endpoint", flags.Lookup("otlp-endpoint"))
+	require.NoError(t, flags.Set("otlp-endpoint", "flag:4317"))
+	exporterConfig = exporters.ExporterConfig{OTLPEndpoint: "flag:4317"}
+	require.NoError(t, exporters.ConfigureOTLPSecurity(&exporterConfig))
+	assert.Equal(t, "flag:4317", exporterConfig.ForSignal("logs").OTLPEndpoint)
+
+	t.Setenv("OTEL_EXPORTER_OTLP_TRACES_HEADERS", "no-equals-sign")
+	assert.ErrorContains(t, exporters.ConfigureOTLPSecurity(&exporterConfig), "OTEL_EXPORTER_OTLP_TRACES_HEADERS")
+}
+
+func TestOTLPExportOverMTLSWithHeaders(t *testing.T) {
+	defer viper.Reset()
+	dir, serverTLS := writeTestPKI(t)
+
+	// A collector requiring client certificates, keeping the metadata of every call
+	var methods []string
+	var headers []metadata.MD
+	server := grpc.NewServer(grpc.Creds(credentials.NewTLS(serverTLS)), grpc.ForceServerCodec(testRawCodec{}),
+		grpc.UnknownServiceHandler(func(_ any, stream grpc.ServerStream) error {
+			method, _ := grpc.MethodFromServerStream(stream)
+			md, _ := metadata.FromIncomingContext(stream.Context())
+			var request []byte
+			if err := stream.RecvMsg(&request); err != nil {
+				return err
+			}
+			methods = append(methods, method)
+			headers = append(headers, md)
+			return stream.SendMsg(&[]byte{})
+		}))
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	go server.Serve(listener)
+	defer server.Stop()
+
+	export := func() error {
+		config := exporters.ExporterConfig{OTLPEndpoint: listener.Addr().String()}
+		require.NoError(t, exporters.ConfigureOTLPSecurity(&config))
+		traceExporters, err := exporters.CreateDualTraceExporters(context.Background(), config, nil)
+		require.NoError(t, err)
+		require.Len(t, traceExporters, 1)
+		ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+		defer cancel()
+		defer traceExporters[0].Shutdown(ctx)
+		return traceExporters[0].ExportSpans(ctx, tracetest.SpanStubs{{Name: "checkout"}}.Snapshots())
+	}
+
+	viper.Set("otlp.ca_file", filepath.Join(dir, "ca.pem"))
+	viper.Set("otlp.server_name", "collector.test")
+	viper.Set("otlp.headers", []string{"X-Scope-OrgID=staging"})
+	assert.Error(t, export(), "the collector refuses clients without a certificate")
+	assert.Empty(t, methods)
+
+	viper.Set("otlp.cert_file", filepath.Join(dir, "client.pem"))
+	viper.Set("otlp.key_file", filepath.Join(dir, "client-key.pem"))
+	require.NoError(t, export())
+	require.Len(t, methods, 1)
+	assert.Equal(t, "/opentelemetry.proto.collector.trace.v1.TraceService/Export", methods[0])
+	assert.Equal(t, []string{"staging"}, headers[0].Get("x-scope-orgid"))
+}
+
+// ===== EXPORTER TUNING TESTS =====
+
+func TestTuningConfigBatchingAndSyncMode(t *testing.T) {
+	defer viper.Reset()
+	ctx := context.Background()
+
+	// Defaults keep the SDK behavior, with retries on
+	config, err := exporters.ParseTuningConfig()
+	require.NoError(t, err)
+	assert.True(t, config.Retry)
+	assert.Zero(t, config.BatchSize)
+
+	// A full batch is exported at once; a partial one waits for the schedule delay
+	viper.Set("batch.size", 2)
+	viper.Set("batch.queue_size", 10)
+	viper.Set("batch.schedule_delay", "1h")
+	config, err = exporters.ParseTuningConfig()
+	require.NoError(t, err)
+	exporter := tracetest.NewInMemoryExporter()
+	tp := trace.NewTracerProvider(trace.WithSpanProcessor(config.SpanProcessor(exporter)))
+	_, span := tp.Tracer("test").Start(ctx, "first")
+	span.End()
+	time.Sleep(50 * time.Millisecond)
+	assert.Empty(t, exporter.GetSpans())
+	_, span = tp.Tracer("test").Start(ctx, "second")
+	span.End()
+	require.Eventually(t, func() bool { return len(exporter.GetSpans()) == 2 }, 5*time.Second, 10*time.Millisecond)
+
+	// Sync mode exports every span and log record before End and Emit return
+	viper.Reset()
+	viper.Set("batch.sync", true)
+	config, err = exporters.ParseTuningConfig()
+	require.NoError(t, err)
+	exporter = tracetest.NewInMemoryExporter()
+	tp = trace.NewTracerProvider(trace.WithSpanProcessor(config.SpanProcessor(exporter)))
+	_, span = tp.Tracer("test").Start(ctx, "sync")
+	span.End()
+	assert.Len(t, exporter.GetSpans(), 1)
+
+	var buf strings.Builder
+	logExporter, err := stdoutlog.New(stdoutlog.WithWriter(&buf))
+	require.NoError(t, err)
+	lp := sdklog.NewLoggerProvider(sdklog.WithProcessor(config.LogProcessor(logExporter)))
+	var record otellog.Record
+	record.SetBody(otellog.StringValue("sync-record"))
+	lp.Logger("test").Emit(ctx, record)
+	assert.Contains(t, buf.String(), "sync-record")
+
+	viper.Set("batch.size", 100)
+	_, err = exporters.ParseTuningConfig()
+	assert.ErrorContains(t, err, "cannot be combined")
+	viper.Reset()
+	viper.Set("otlp.compression", "brotli")
+	_, err = exporters.ParseTuningConfig()
+	assert.ErrorContains(t, err, "unsupported compression")
+	viper.Reset()
+	viper.Set("batch.size", 100)
+	viper.Set("batch.queue_size", 10)
+	_, err = exporters.ParseTuningConfig()
+	assert.ErrorContains(t, err, "must not exceed queue size")
+}
+
+// compressionRecorder is a gRPC stats handler keeping the compression of the latest incoming call
+type compressionRecorder struct {
+	mu          sync.Mutex
+	compression string
+}
+
+func (r *compressionRecorder) TagRPC(ctx context.Context, _ *stats.RPCTagInfo) context.Context {
+	return ctx
+}
+func (r *compressionRecorder) TagConn(ctx context.Context, _ *stats.ConnTagInfo) context.Context {
+	return ctx
+}
+func (r *compressionRecorder) HandleConn(context.Context, stats.ConnStats) {}
+func (r *compressionRecorder) HandleRPC(_ context.Context, s stats.RPCStats) {
+	if header, ok := s.(*stats.InHeader); ok {
+		r.mu.Lock()
+		r.compression = header.Compression
+		r.mu.Unlock()
+	}
+}
+func (r *compressionRecorder) last() string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.compression
+}
+
+func TestOTLPExportCompressionTimeoutAndRetry(t *testing.T) {
+	// A collector failing the first calls with Unavailable, then hanging or answering as told
+	var mu sync.Mutex
+	var encodings []string
+	failures, delay := 0, time.Duration(0)
+	handler := &compressionRecorder{}
+	server := grpc.NewServer(grpc.StatsHandler(handler), grpc.ForceServerCodec(testRawCodec{}), grpc.UnknownServiceHandler(func(_ any, stream grpc.ServerStream) error {
+		var request []byte
+		if err := stream.RecvMsg(&request); err != nil {
+			return err
+		}
+		mu.Lock()
+		encodings = append(encodings, handler.last())
+		fail := failures > 0
+		failures--
+		mu.Unlock()
+		if fail {
+			return status.Error(grpccodes.Unavailable, "try again")
+		}
+		time.Sleep(delay)
+		return stream.SendMsg(&[]byte{})
+	}))
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	go server.Serve(listener)
+	defer server.Stop()
+
+	export := func(tuning *exporters.TuningConfig) (time.Duration, error) {
+		config := exporters.ExporterConfig{OTLPEndpoint: listener.Addr().String(), Insecure: true, Tuning: tuning}
+		traceExporters, err := exporters.CreateDualTraceExporters(context.Background(), config, nil)
+		require.NoError(t, err)
+		defer traceExporters[0].Shutdown(context.Background())
+		start := time.Now()
+		err = traceExporters[0].ExportSpans(context.Background(), tracetest.SpanStubs{{Name: "checkout"}}.Snapshots())
+		return time.Since(start), err
+	}
+
+	// gzip, and two failures retried after short backoffs
+	failures = 2
+	_, err = export(&exporters.TuningConfig{Compression: "gzip", Retry: true, RetryInitial: 10 * time.Millisecond, RetryMax: 20 * time.Millisecond})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"gzip", "gzip", "gzip"}, encodings)
+
+	// Without retries the first failure is returned
+	failures, encodings = 1, nil
+	_, err = export(&exporters.TuningConfig{Retry: false})
+	assert.ErrorContains(t, err, "try again")
+	assert.Equal(t, []string{""}, encodings)
+
+	// A slow collector hits the export timeout
+	delay = 2 * time.Second
+	elapsed, err := export(&exporters.TuningConfig{Timeout: 100 * time.Millisecond, Retry: false})
+	assert.Error(t, err)
+	assert.Less(t, elapsed, time.Second)
+}
+
+// ===== EXPORT OUTCOME TESTS =====
+
+// blockingSpanExporter holds every export until release is closed
+type blockingSpanExporter struct {
+	release chan struct{}
+}
+
+func (e *blockingSpanExporter) ExportSpans(context.Context, []trace.ReadOnlySpan) error {
+	<-e.release
+	return nil
+}
+func (e *blockingSpanExporter) Shutdown(context.Context) error { return nil }
+
+// failingMetricExporter fails every export
+type failingMetricExporter struct {
+	capturingMetricExporter
+}
+
+func (e *failingMetricExporter) Export(context.Context, *metricdata.ResourceMetrics) error {
+	return fmt.Errorf("collector unavailable")
+}
+
+func TestExportOutcomeCountsRetriesRejectionsAndDrops(t *testing.T) {
+	exporters.ResetOutcomes()
+	defer exporters.ResetOutcomes()
+	ctx := context.Background()
+
+	// A collector failing the first call with Unavailable, then rejecting one span in a partial success
+	partialSuccess := protowire.AppendVarint(protowire.AppendTag(nil, 1, protowire.VarintType), 1)
+	response := protowire.AppendBytes(protowire.AppendTag(nil, 1, protowire.BytesType), partialSuccess)
+	var mu sync.Mutex
+	calls := 0
+	server := grpc.NewServer(grpc.ForceServerCodec(testRawCodec{}), grpc.UnknownServiceHandler(func(_ any, stream grpc.ServerStream) error {
+		var request []byte
+		if err := stream.RecvMsg(&request); err != nil {
+			return err
+		}
+		mu.Lock()
+		calls++
+		first := calls == 1
+		mu.Unlock()
+		if first {
+			return status.Error(grpccodes.Unavailable, "try again")
+		}
+		return stream.SendMsg(&response)
+	}))
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	go server.Serve(listener)
+	defer server.Stop()
+
+	tuning := &exporters.TuningConfig{Retry: true, RetryInitial: 10 * time.Millisecond, RetryMax: 20 * time.Millisecond, BatchSize: 3}
+	config := exporters.ExporterConfig{OTLPEndpoint: listener.Addr().String(), Insecure: true, Tuning: tuning}
+	traceExporters, err := exporters.CreateDualTraceExporters(ctx, config, nil)
+	require.NoError(t, err)
+	tp := trace.NewTracerProvider(trace.WithSpanProcessor(tuning.SpanProcessor(traceExporters[0])))
+	for _, name := range []string{"a", "b", "c"} {
+		_, span := tp.Tracer("test").Start(ctx, name)
+		span.End()
+	}
+	require.NoError(t, tp.Shutdown(ctx))
+
+	counts := exporters.Outcomes().Signals["traces"]
+	assert.Equal(t, exporters.OutcomeCounts{Exported: 2, Retried: 3, Rejected: 1}, counts)
+
+	// A full queue drops the spans the blocked exporter cannot take
+	exporters.ResetOutcomes()
+	blocking := &blockingSpanExporter{release: make(chan struct{})}
+	tuning = &exporters.TuningConfig{BatchSize: 1, QueueSize: 1}
+	tp = trace.NewTracerProvider(trace.WithSpanProcessor(tuning.SpanProcessor(blocking)))
+	for i := 0; i < 20; i++ {
+		_, span := tp.Tracer("test").Start(ctx, "queued")
+		span.End()
+	}
+	close(blocking.release)
+	require.NoError(t, tp.Shutdown(ctx))
+
+	counts = exporters.Outcomes().Signals["traces"]
+	assert.Positive(t, counts.Dropped)
+	assert.Equal(t, int64(20), counts.Exported+counts.Dropped)
+	assert.Zero(t, counts.Failed)
+}
+
+func TestExportSummaryFormatsAndFailureRatio(t *testing.T) {
+	defer viper.Reset()
+	exporters.ResetOutcomes()
+	defer exporters.ResetOutcomes()
+	ctx := context.Background()
+
+	// Nothing exported: an empty text summary and a ratio of zero
+	config, err := exporters.ParseSummaryConfig()
+	require.NoError(t, err)
+	assert.Equal(t, "text", config.Format)
+	var buf strings.Builder
+	require.NoError(t, exporters.Outcomes().Write(&buf, "text"))
+	assert.Empty(t, buf.String())
+	assert.False(t, config.Exceeded(exporters.Outcomes()))
+
+	// One exporter delivers three data points, another fails them
+	rm := &metricdata.ResourceMetrics{ScopeMetrics: []metricdata.ScopeMetrics{{Metrics: []metricdata.Metrics{
+		{Name: "requests", Data: metricdata.Sum[int64]{DataPoints: []metricdata.DataPoint[int64]{{Value: 1}, {Value: 2}}}},
+		{Name: "latency", Data: metricdata.Histogram[float64]{DataPoints: []metricdata.HistogramDataPoint[float64]{{Count: 1}}}},
+	}}}}
+	counted := exporters.CountMetricExporters([]sdkmetric.Exporter{&capturingMetricExporter{}, &failingMetricExporter{}})
+	require.NoError(t, counted[0].Export(ctx, rm))
+	assert.ErrorContains(t, counted[1].Export(ctx, rm), "collector unavailable")
+
+	summary := exporters.Outcomes()
+	assert.Equal(t, exporters.OutcomeCounts{Exported: 3, Failed: 3}, summary.Signals["metrics"])
+	assert.NotContains(t, summary.Signals, "traces")
+	assert.InDelta(t, 0.5, summary.FailureRatio, 1e-9)
+
+	buf.Reset()
+	require.NoError(t, summary.Write(&buf, "text"))
+	assert.Contains(t, buf.String(), "metrics: 3 exported, 3 failed, 0 retried, 0 dropped, 0 rejected")
+	assert.Contains(t, buf.String(), "failure ratio: 0.5000")
+
+	buf.Reset()
+	require.NoError(t, summary.Write(&buf, "json"))
+	var decoded exporters.OutcomeSummary
+	require.NoError(t, json.Unmarshal([]byte(buf.String()), &decoded))
+	assert.Equal(t, summary.Signals, decoded.Signals)
+
+	// The threshold decides the exit status
+	assert.True(t, config.Exceeded(summary))
+	viper.Set("summary.max_failure_ratio", 0.5)
+	config, err = exporters.ParseSummaryConfig()
+	require.NoError(t, err)
+	assert.False(t, config.Exceeded(summary))
+
+	viper.Set("summary.format", "xml")
+	_, err = exporters.ParseSummaryConfig()
+	assert.ErrorContains(t, err, "unsupported summary format")
+	viper.Set("summary.format", "json")
+	viper.Set("summary.max_failure_ratio", 1.5)
+	_, err = exporters.ParseSummaryConfig()
+	assert.ErrorContains(t, err, "between 0 and 1")
+}
+
+// ===== SELF-TELEMETRY TESTS =====
+
+// failingSpanExporter fails every export the way an unreachable collector does
+type failingSpanExporter struct{}
+
+func (failingSpanExporter) ExportSpans(context.Context, []trace.ReadOnlySpan) error {
+	return status.Error(grpccodes.Unavailable, "connection refused")
+}
+func (failingSpanExporter) Shutdown(context.Context) error { return nil }
+
+// freeAddress returns a local address nothing listens on
+func freeAddress(t *testing.T) string {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	address := listener.Addr().String()
+	require.NoError(t, listener.Close())
+	return address
+}
+
+func TestSelfTelemetryServesGenerationAndExportMetrics(t *testing.T) {
+	exporters.ResetOutcomes()
+	defer exporters.ResetOutcomes()
+	ctx := context.Background()
+
+	address := freeAddress(t)
+	telemetry, err := selftelemetry.Start(ctx, &selftelemetry.Config{Address: address, Interval: time.Second})
+	require.NoError(t, err)
+	defer telemetry.Shutdown(ctx)
+
+	// Three spans, delivered by one exporter and failed by the other, and one severity injection
+	tuning := &exporters.TuningConfig{Sync: true}
+	tp := trace.NewTracerProvider(
+		trace.WithSpanProcessor(exporters.GeneratedSpanCounter()),
+		trace.WithSpanProcessor(tuning.SpanProcessor(tracetest.NewInMemoryExporter())),
+		trace.WithSpanProcessor(tuning.SpanProcessor(failingSpanExporter{})),
+	)
+	for i := 0; i < 3; i++ {
+		_, span := tp.Tracer("test").Start(ctx, "checkout")
+		span.End()
+	}
+	require.NoError(t, tp.Shutdown(ctx))
+	before := aggro.Injections()["severity"]
+	(&aggro.AggroConfig{SeverityActive: true, SeverityTarget: "number"}).ApplyAggroToLogSeverity(otellog.SeverityInfo, "INFO")
+
+	resp, err := http.Get("http://" + address + "/metrics")
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	assert.Equal(t, prometheus.ContentTypePrometheus, resp.Header.Get("Content-Type"))
+	body, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	scrape := string(body)
+
+	value := func(series string) string {
+		match := regexp.MustCompile(`(?m)^` + regexp.QuoteMeta(series) + `[^ ]* (\S+)$`).FindStringSubmatch(scrape)
+		require.NotNil(t, match, "no series %s in\n%s", series, scrape)
+		return match[1]
+	}
+	assert.Equal(t, "3", value(`otel_datagen_items_generated_total{signal="traces"`))
+	assert.Equal(t, "3", value(`otel_datagen_export_items_total{outcome="exported",signal="traces"`))
+	assert.Equal(t, "3", value(`otel_datagen_export_items_total{outcome="failed",signal="traces"`))
+	assert.Equal(t, "3", value(`otel_datagen_export_errors_total{error_type="Unavailable",signal="traces"`))
+	assert.Equal(t, "3", value(`otel_datagen_export_duration_seconds_count{signal="traces",`))
+	assert.Equal(t, "3", value(`otel_datagen_export_duration_seconds_count{error_type="Unavailable",signal="traces"`))
+	assert.Equal(t, "0", value(`otel_datagen_queue_depth{signal="traces"`))
+	assert.Equal(t, strconv.FormatInt(before+1, 10), value(`otel_datagen_aggro_injections_total{category="severity"`))
+	assert.Contains(t, scrape, `target_info{service_name="otel-datagen"} 1`)
+}
+
+func TestSelfTelemetryConfigAndErrorTypes(t *testing.T) {
+	defer viper.Reset()
+
+	// Disabled by default; a nil telemetry shuts down quietly
+	config, err := selftelemetry.ParseConfig()
+	require.NoError(t, err)
+	assert.False(t, config.Enabled())
+	assert.Equal(t, 10*time.Second, config.Interval)
+	telemetry, err := selftelemetry.Start(context.Background(), config)
+	require.NoError(t, err)
+	assert.Nil(t, telemetry)
+	assert.NoError(t, telemetry.Shutdown(context.Background()))
+
+	viper.Set("self_telemetry.endpoint", "localhost:4317")
+	viper.Set("self_telemetry.interval", "1s")
+	config, err = selftelemetry.ParseConfig()
+	require.NoError(t, err)
+	assert.True(t, config.Enabled())
+	assert.Equal(t, time.Second, config.Interval)
+
+	viper.Set("self_telemetry.interval", "-1s")
+	_, err = selftelemetry.ParseConfig()
+	assert.ErrorContains(t, err, "interval must be positive")
+
+	// A busy scrape address fails the start
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer listener.Close()
+	_, err = selftelemetry.Start(context.Background(), &selftelemetry.Config{Address: listener.Addr().String(), Interval: time.Second})
+	assert.Error(t, err)
+
+	// Errors are classified by timeout, gRPC code and network failure
+	assert.Equal(t, "timeout", selftelemetry.ErrorType(fmt.Errorf("traces export: %w", context.DeadlineExceeded)))
+	assert.Equal(t, "timeout", selftelemetry.ErrorType(status.Error(grpccodes.DeadlineExceeded, "slow")))
+	assert.Equal(t, "Unavailable", selftelemetry.ErrorType(fmt.Errorf("traces export: %w", status.Error(grpccodes.Unavailable, "down"))))
+	_, dialErr := net.Dial("tcp", listener.Addr().String()+"0")
+	assert.Equal(t, "network", selftelemetry.ErrorType(dialErr))
+	assert.Equal(t, "other", selftelemetry.ErrorType(fmt.Errorf("status 500")))
+}
+
+func TestMetricAggroInjectionsAreCounted(t *testing.T) {
+	ctx := context.Background()
+	before := aggro.Injections()
+
+	// Every metric data point with an aggro value counts, whichever recorder adds it
+	mp := sdkmetric.NewMeterProvider(sdkmetric.WithReader(sdkmetric.NewManualReader()))
+	defer mp.Shutdown(ctx)
+	aggroConfig := &aggro.AggroConfig{NumericActive: true}
+	require.NoError(t, metrics.Generate(ctx, mp, 4, "gauge", "aggro.counted", 1, 10, aggroConfig, "grpc"))
+	catalog := []metrics.CatalogEntry{{Name: "queue.depth", Type: "gauge", Max: 40, Attributes: map[string][]string{"queue": {"a", "b"}}}}
+	require.NoError(t, metrics.GenerateCatalog(ctx, mp, catalog, 3, nil, nil, 1, aggroConfig, "grpc"))
+	assert.Equal(t, before["value"]+4+2*3, aggro.Injections()["value"])
+
+	// Mixing temporalities counts once per alternate resource
+	(&aggro.AggroConfig{TemporalityActive: true}).AlternateTemporality("cumulative")
+	assert.Equal(t, before["temporality"]+1, aggro.Injections()["temporality"])
+}
+
+// ===== WORKER AND CONNECTION TESTS =====
+
+// connectionCollector records which client connection delivered each span and log record
+type connectionCollector struct {
+	coltracepb.UnimplementedTraceServiceServer
+	collogspb.UnimplementedLogsServiceServer
+	mu        sync.Mutex
+	nextID    int
+	spans     map[string]int // Span name -> connection
+	traces    map[string]map[int]bool
+	records   map[string]time.Time
+	resources map[string]map[string]string // Span or record name -> resource attributes
+}
+
+type connectionKey struct{}
+
+func (c *connectionCollector) TagConn(ctx context.Context, _ *stats.ConnTagInfo) context.Context {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.nextID++
+	return context.WithValue(ctx, connectionKey{}, c.nextID)
+}
+func (c *connectionCollector) TagRPC(ctx context.Context, _ *stats.RPCTagInfo) context.Context {
+	return ctx
+}
+func (c *connectionCollector) HandleConn(context.Context, stats.ConnStats) {}
+func (c *connectionCollector) HandleRPC(context.Context, stats.RPCStats)   {}
+
+func (c *connectionCollector) Export(ctx context.Context, request *coltracepb.ExportTraceServiceRequest) (*coltracepb.ExportTraceServiceResponse, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	connection := ctx.Value(connectionKey{}).(int)
+	for _, resourceSpans := range request.ResourceSpans {
+		for _, scopeSpans := range resourceSpans.ScopeSpans {
+			for _, span := range scopeSpans.Spans {
+				c.resources[span.Name] = resourceAttributes(resourceSpans.Resource)
+				if _, ok := c.spans[span.Name]; ok {
+					c.spans[span.Name+" (duplicate)"] = connection
+				}
+				c.spans[span.Name] = connection
+				traceID := fmt.Sprintf("%x", span.TraceId)
+				if c.traces[traceID] == nil {
+					c.traces[traceID] = map[int]bool{}
+				}
+				c.traces[traceID][connection] = true
+			}
+		}
+	}
+	return &coltracepb.ExportTraceServiceResponse{}, nil
+}
+
+// logsService adapts the collector to the logs service, whose Export method has another signature
+type logsService struct{ *connectionCollector }
+
+func (s logsService) Export(ctx context.Context, request *collogspb.ExportLogsServiceRequest) (*collogspb.ExportLogsServiceResponse, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, resourceLogs := range request.ResourceLogs {
+		for _, scopeLogs := range resourceLogs.ScopeLogs {
+			for _, record := range scopeLogs.LogRecords {
+				name := record.Body.GetStringValue()
+				s.resources[name] = resourceAttributes(resourceLogs.Resource)
+				if _, ok := s.records[name]; ok {
+					s.records[name+" (duplicate)"] = time.Time{}
+				}
+				s.records[name] = time.Unix(0, int64(record.TimeUnixNano))
+			}
+		}
+	}
+	return &collogspb.ExportLogsServiceResponse{}, nil
+}
+
+// resourceAttributes returns the string attributes of an exported resource
+func resourceAttributes(res *resourcepb.Resource) map[string]string {
+	attrs := make(map[string]string)
+	for _, kv := range res.GetAttributes() {
+		attrs[kv.Key] = kv.Value.GetStringValue()
+	}
+	return attrs
+}
+
+// startConnectionCollector serves the trace and logs services and returns the collector's address
+func startConnectionCollector(t *testing.T) (string, *connectionCollector) {
+	collector := &connectionCollector{spans: map[string]int{}, traces: map[string]map[int]bool{}, records: map[string]time.Time{}, resources: map[string]map[string]string{}}
+	server := grpc.NewServer(grpc.StatsHandler(collector))
+	coltracepb.RegisterTraceServiceServer(server, collector)
+	collogspb.RegisterLogsServiceServer(server, logsService{collector})
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	go server.Serve(listener)
+	t.Cleanup(server.Stop)
+	return listener.Addr().String(), collector
+}
+
+func TestWorkersAndConnectionsSpreadTraces(t *testing.T) {
+	defer viper.Reset()
+	viper.Set("concurrency.workers", 4)
+	viper.Set("concurrency.connections", 3)
+	address, collector := startConnectionCollector(t)
+
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	generators.GenerateTraces(60, 3, 1, []string{}, []string{}, address, false, nil, nil, &timestamps.TimestampConfig{StartTime: start, Spacing: time.Second})
+
+	collector.mu.Lock()
+	defer collector.mu.Unlock()
+
+	// Every span of every trace arrives exactly once, whichever worker generated it
+	assert.Len(t, collector.spans, 180)
+	for traceIdx := 1; traceIdx <= 60; traceIdx++ {
+		assert.Contains(t, collector.spans, fmt.Sprintf("trace-%d-root", traceIdx))
+		assert.Contains(t, collector.spans, fmt.Sprintf("trace-%d-span-3", traceIdx))
+	}
+
+	// Traces are spread over the three connections, each trace staying on one
+	assert.Len(t, collector.traces, 60)
+	used := map[int]bool{}
+	for traceID, connections := range collector.traces {
+		assert.Len(t, connections, 1, "trace %s", traceID)
+		for connection := range connections {
+			used[connection] = true
+		}
+	}
+	assert.Len(t, used, 3)
+}
+
+func TestWorkersShardLogsAndValidateConcurrency(t *testing.T) {
+	defer viper.Reset()
+	viper.Set("concurrency.workers", 3)
+	viper.Set("concurrency.connections", 2)
+	address, collector := startConnectionCollector(t)
+
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	generators.GenerateLogs(50, 1, []string{}, []string{}, address, "grpc", false, &timestamps.TimestampConfig{StartTime: start, Spacing: time.Second})
+
+	// Sharded records keep the names and timestamps of a single worker
+	collector.mu.Lock()
+	assert.Len(t, collector.records, 50)
+	for i := 1; i <= 50; i++ {
+		assert.Equal(t, start.Add(time.Duration(i-1)*time.Second), collector.records[fmt.Sprintf("example-log-%d", i)].UTC())
+	}
+	collector.mu.Unlock()
+
+	workers, err := generators.ParseWorkers()
+	require.NoError(t, err)
+	assert.Equal(t, 3, workers)
+	viper.Set("concurrency.workers", 0)
+	workers, err = generators.ParseWorkers()
+	require.NoError(t, err)
+	assert.Equal(t, 1, workers)
+	viper.Set("concurrency.workers", -2)
+	_, err = generators.ParseWorkers()
+	assert.ErrorContains(t, err, "workers must not be negative")
+
+	viper.Set("concurrency.connections", -1)
+	_, err = exporters.ParseTuningConfig()
+	assert.ErrorContains(t, err, "connections must not be negative")
+}
+
+func TestWorkersRecordMetricsUnchanged(t *testing.T) {
+	ctx := context.Background()
+
+	// collect generates with the given number of workers and returns every series' value by its attributes
+	collect := func(workers int, generate func(mp *sdkmetric.MeterProvider, workers int) error) map[string]float64 {
+		reader := sdkmetric.NewManualReader()
+		mp := sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader))
+		defer mp.Shutdown(ctx)
+		require.NoError(t, generate(mp, workers))
+
+		rm := &metricdata.ResourceMetrics{}
+		require.NoError(t, reader.Collect(ctx, rm))
+		values := make(map[string]float64)
+		for _, sm := range rm.ScopeMetrics {
+			for _, m := range sm.Metrics {
+				switch data := m.Data.(type) {
+				case metricdata.Gauge[int64]:
+					for _, dp := range data.DataPoints {
+						values[m.Name+" "+dp.Attributes.Encoded(attribute.DefaultEncoder())] = float64(dp.Value)
+					}
+				case metricdata.Sum[int64]:
+					for _, dp := range data.DataPoints {
+						values[m.Name+" "+dp.Attributes.Encoded(attribute.DefaultEncoder())] = float64(dp.Value)
+					}
+				case metricdata.Histogram[float64]:
+					for _, dp := range data.DataPoints {
+						values[m.Name+" "+dp.Attributes.Encoded(attribute.DefaultEncoder())] = dp.Sum + float64(dp.Count)
+					}
+				}
+			}
+		}
+		return values
+	}
+
+	// Series are dealt to workers whole, so a gauge's last value and a counter's total are those of one worker
+	for _, metricType := range []string{"gauge", "counter"} {
+		generate := func(mp *sdkmetric.MeterProvider, workers int) error {
+			shape, err := shapes.Parse("sawtooth:period=7", 0, 60)
+			require.NoError(t, err)
+			return metrics.GenerateWithShape(ctx, mp, 25, metricType, "sharded", shape, &metrics.CardinalityConfig{Series: 6}, nil, workers, nil, "grpc")
+		}
+		single := collect(1, generate)
+		assert.Len(t, single, 6, metricType)
+		assert.Equal(t, single, collect(4, generate), metricType)
+	}
+
+	// Catalog series are spread over the workers with all their data points
+	catalog := []metrics.CatalogEntry{
+		{Name: "queue.depth", Type: "gauge", Shape: "sawtooth:period=5", Max: 40, Attributes: map[string][]string{"queue": {"a", "b", "c"}}},
+		{Name: "request.duration", Type: "histogram", Shape: "step:every=3", Min: 5, Max: 5, Attributes: map[string][]string{"route": {"/a", "/b"}}},
+	}
+	generate := func(mp *sdkmetric.MeterProvider, workers int) error {
+		return metrics.GenerateCatalog(ctx, mp, catalog, 12, nil, nil, workers, nil, "grpc")
+	}
+	single := collect(1, generate)
+	assert.Len(t, single, 5)
+	assert.Equal(t, single, collect(4, generate))
+}
+
+// ===== FLEET TESTS =====
+
+func TestFleetTemplatesChurnAndValidation(t *testing.T) {
+	defer viper.Reset()
+	viper.Set("generate.traces.fleet_size", 4)
+	viper.Set("generate.traces.fleet_attr", []string{"host.name=node-{slot%2}", "k8s.pod.name=api-{instance}-{slot}"})
+	viper.Set("generate.traces.fleet_churn", 0.5)
+	config, err := fleet.ParseConfig("traces")
+	require.NoError(t, err)
+	require.True(t, config.Enabled())
+
+	// Templates render per instance, and every instance gets a random service.instance.id
+	f := config.NewFleet()
+	uuidPattern := regexp.MustCompile(`^service\.instance\.id=[0-9a-f]{8}-[0-9a-f]{4}-4[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`)
+	for slot, instance := range f.Active() {
+		assert.Equal(t, slot, instance.ID)
+		require.Len(t, instance.Attributes, 3)
+		assert.Equal(t, fmt.Sprintf("host.name=node-%d", slot%2), instance.Attributes[0])
+		assert.Equal(t, fmt.Sprintf("k8s.pod.name=api-%d-%d", slot, slot), instance.Attributes[1])
+		assert.Regexp(t, uuidPattern, instance.Attributes[2])
+	}
+
+	// Half the fleet is replaced after a round; replacements keep the slot but get new instance numbers
+	before := slices.Clone(f.Active())
+	replaced := f.Advance()
+	assert.Len(t, replaced, 2)
+	for slot, instance := range f.Active() {
+		if slices.Contains(replaced, slot) {
+			assert.GreaterOrEqual(t, instance.ID, 4)
+			assert.Equal(t, fmt.Sprintf("k8s.pod.name=api-%d-%d", instance.ID, slot), instance.Attributes[1])
+			assert.NotEqual(t, before[slot].Attributes[2], instance.Attributes[2])
+		} else {
+			assert.Equal(t, before[slot], instance)
+		}
+		assert.Equal(t, fmt.Sprintf("host.name=node-%d", slot%2), instance.Attributes[0])
+	}
+
+	// A templated service.instance.id replaces the random one
+	templated := (&fleet.Config{Size: 1, Templates: []fleet.Template{{Key: "service.instance.id", Value: "i-{instance}"}}}).NewFleet()
+	assert.Equal(t, []string{"service.instance.id=i-0"}, templated.Active()[0].Attributes)
+
+	for _, invalid := range []*fleet.Config{
+		{Size: -1},
+		{Size: 2, Churn: 1.5},
+		{Churn: 0.1},
+		{Size: 2, Templates: []fleet.Template{{Key: "host.name", Value: "node-{host}"}}},
+		{Size: 2, Templates: []fleet.Template{{Key: "id", Value: "{uuid%4}"}}},
+		{Size: 2, Templates: []fleet.Template{{Key: "host.name", Value: "node-{slot%0}"}}},
+	} {
+		assert.Error(t, invalid.Validate(), "%+v", invalid)
+	}
+}
+
+func TestFleetExportsEveryInstanceUnderItsOwnResource(t *testing.T) {
+	defer viper.Reset()
+	viper.Set("generate.traces.fleet_size", 3)
+	viper.Set("generate.traces.fleet_attr", []string{"host.name=node-{slot}"})
+	viper.Set("generate.traces.fleet_churn", 1.0/3)
+	viper.Set("concurrency.workers", 2)
+	address, collector := startConnectionCollector(t)
+
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	generators.GenerateTraces(12, 1, 0, []string{}, []string{"deployment.environment=load-test"}, address, false, nil, nil, &timestamps.TimestampConfig{StartTime: start})
+
+	// Log records spread over the instances the same way
+	viper.Set("generate.logs.fleet_size", 2)
+	generators.GenerateLogs(4, 0, []string{}, []string{}, address, "grpc", false, &timestamps.TimestampConfig{StartTime: start})
+
+	collector.mu.Lock()
+	defer collector.mu.Unlock()
+	require.Len(t, collector.spans, 12)
+
+	// Trace i comes from the instance in slot i%3 of its round; one instance is replaced after every round
+	instances := map[string]bool{}
+	for round := 0; round < 4; round++ {
+		inRound := map[string]bool{}
+		for slot := 0; slot < 3; slot++ {
+			attrs := collector.resources[fmt.Sprintf("trace-%d-root", round*3+slot+1)]
+			assert.Equal(t, fmt.Sprintf("node-%d", slot), attrs["host.name"])
+			assert.Equal(t, "load-test", attrs["deployment.environment"])
+			assert.Equal(t, "otel-datagen", attrs["service.name"])
+			inRound[attrs["service.instance.id"]] = true
+			instances[attrs["service.instance.id"]] = true
+		}
+		assert.Len(t, inRound, 3)
+	}
+	assert.Len(t, instances, 6)
+
+	require.Len(t, collector.records, 4)
+	for i := 1; i <= 4; i++ {
+		first := collector.resources[fmt.Sprintf("example-log-%d", (i-1)%2+1)]["service.instance.id"]
+		assert.Equal(t, first, collector.resources[fmt.Sprintf("example-log-%d", i)]["service.instance.id"])
+	}
+	assert.NotEqual(t, collector.resources["example-log-1"]["service.instance.id"], collector.resources["example-log-2"]["service.instance.id"])
+}
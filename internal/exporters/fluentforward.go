@@ -0,0 +1,197 @@
+package exporters
+
+import (
+	"context"
+	"encoding/binary"
+	"math"
+	"net"
+	"sort"
+	"sync"
+	"time"
+
+	otellog "go.opentelemetry.io/otel/log"
+	sdklog "go.opentelemetry.io/otel/sdk/log"
+)
+
+// FluentForwardExporter sends log records to a Fluentd or Fluent Bit forward input
+// Each export is one Forward mode message, [tag, [[time, record], ...], {"size": n}], with EventTime timestamps
+type FluentForwardExporter struct {
+	address string
+	tag     string
+
+	mu   sync.Mutex
+	conn net.Conn // Dialed on first export and again after a write error
+}
+
+// NewFluentForwardExporter creates an exporter for a forward input at address, tagging events with tag
+func NewFluentForwardExporter(address, tag string) *FluentForwardExporter {
+	return &FluentForwardExporter{address: address, tag: tag}
+}
+
+// Export writes the records as one Forward mode message
+func (e *FluentForwardExporter) Export(ctx context.Context, records []sdklog.Record) error {
+	if len(records) == 0 {
+		return nil
+	}
+	w := &msgpackWriter{}
+	w.arrayHeader(3)
+	w.value(e.tag)
+	w.arrayHeader(len(records))
+	for i := range records {
+		w.arrayHeader(2)
+		w.eventTime(recordTime(&records[i]))
+		w.value(fluentRecord(&records[i]))
+	}
+	w.value(map[string]any{"size": int64(len(records))})
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.conn == nil {
+		var dialer net.Dialer
+		conn, err := dialer.DialContext(ctx, "tcp", e.address)
+		if err != nil {
+			return err
+		}
+		e.conn = conn
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		e.conn.SetWriteDeadline(deadline)
+	} else {
+		e.conn.SetWriteDeadline(time.Now().Add(30 * time.Second))
+	}
+	if _, err := e.conn.Write(w.buf); err != nil {
+		e.conn.Close()
+		e.conn = nil
+		return err
+	}
+	return nil
+}
+
+// ForceFlush does nothing: every export is written immediately
+func (e *FluentForwardExporter) ForceFlush(context.Context) error {
+	return nil
+}
+
+// Shutdown closes the connection
+func (e *FluentForwardExporter) Shutdown(context.Context) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.conn == nil {
+		return nil
+	}
+	err := e.conn.Close()
+	e.conn = nil
+	return err
+}
+
+// fluentRecord converts a record to a flat Fluent record: the body as message, severity and trace
+// context next to it, and the attributes as top-level keys, as Fluent Bit inputs produce them
+func fluentRecord(r *sdklog.Record) map[string]any {
+	record := make(map[string]any)
+	r.WalkAttributes(func(kv otellog.KeyValue) bool {
+		record[kv.Key] = logValue(kv.Value)
+		return true
+	})
+	record["message"] = logValue(r.Body())
+	record["severity_number"] = int64(r.Severity())
+	if r.SeverityText() != "" {
+		record["severity_text"] = r.SeverityText()
+	}
+	if r.TraceID().IsValid() {
+		record["trace_id"] = r.TraceID().String()
+	}
+	if r.SpanID().IsValid() {
+		record["span_id"] = r.SpanID().String()
+	}
+	if name := serviceName(r.Resource()); name != "" {
+		record["service.name"] = name
+	}
+	return record
+}
+
+// msgpackWriter writes MessagePack values
+type msgpackWriter struct {
+	buf []byte
+}
+
+// eventTime writes a Fluent EventTime, extension type 0 with seconds and nanoseconds
+func (w *msgpackWriter) eventTime(t time.Time) {
+	w.buf = append(w.buf, 0xd7, 0x00)
+	w.buf = binary.BigEndian.AppendUint32(w.buf, uint32(t.Unix()))
+	w.buf = binary.BigEndian.AppendUint32(w.buf, uint32(t.Nanosecond()))
+}
+
+func (w *msgpackWriter) arrayHeader(n int) {
+	switch {
+	case n < 16:
+		w.buf = append(w.buf, 0x90|byte(n))
+	case n <= math.MaxUint16:
+		w.buf = binary.BigEndian.AppendUint16(append(w.buf, 0xdc), uint16(n))
+	default:
+		w.buf = binary.BigEndian.AppendUint32(append(w.buf, 0xdd), uint32(n))
+	}
+}
+
+func (w *msgpackWriter) mapHeader(n int) {
+	switch {
+	case n < 16:
+		w.buf = append(w.buf, 0x80|byte(n))
+	case n <= math.MaxUint16:
+		w.buf = binary.BigEndian.AppendUint16(append(w.buf, 0xde), uint16(n))
+	default:
+		w.buf = binary.BigEndian.AppendUint32(append(w.buf, 0xdf), uint32(n))
+	}
+}
+
+func (w *msgpackWriter) string(s string) {
+	switch {
+	case len(s) < 32:
+		w.buf = append(w.buf, 0xa0|byte(len(s)))
+	case len(s) <= math.MaxUint8:
+		w.buf = append(w.buf, 0xd9, byte(len(s)))
+	case len(s) <= math.MaxUint16:
+		w.buf = binary.BigEndian.AppendUint16(append(w.buf, 0xda), uint16(len(s)))
+	default:
+		w.buf = binary.BigEndian.AppendUint32(append(w.buf, 0xdb), uint32(len(s)))
+	}
+	w.buf = append(w.buf, s...)
+}
+
+// value writes a value produced by logValue; map keys are sorted so messages are deterministic
+func (w *msgpackWriter) value(v any) {
+	switch v := v.(type) {
+	case nil:
+		w.buf = append(w.buf, 0xc0)
+	case bool:
+		if v {
+			w.buf = append(w.buf, 0xc3)
+		} else {
+			w.buf = append(w.buf, 0xc2)
+		}
+	case int64:
+		w.buf = binary.BigEndian.AppendUint64(append(w.buf, 0xd3), uint64(v))
+	case float64:
+		w.buf = binary.BigEndian.AppendUint64(append(w.buf, 0xcb), math.Float64bits(v))
+	case string:
+		w.string(v)
+	case []byte:
+		w.buf = binary.BigEndian.AppendUint32(append(w.buf, 0xc6), uint32(len(v)))
+		w.buf = append(w.buf, v...)
+	case []any:
+		w.arrayHeader(len(v))
+		for _, item := range v {
+			w.value(item)
+		}
+	case map[string]any:
+		keys := make([]string, 0, len(v))
+		for key := range v {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		w.mapHeader(len(keys))
+		for _, key := range keys {
+			w.string(key)
+			w.value(v[key])
+		}
+	}
+}
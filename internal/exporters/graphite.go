@@ -0,0 +1,57 @@
+package exporters
+
+import (
+	"strconv"
+	"strings"
+)
+
+// graphiteLines formats points in the Graphite plaintext protocol with tags
+//
+//	name;tag=value;... value timestamp
+//
+// Histograms become name.count, name.sum and one name.bucket series per bound, tagged le, with
+// cumulative counts
+func graphiteLines(points []linePoint) []string {
+	var lines []string
+	for _, point := range points {
+		name := graphitePath(point.name)
+		tags := graphiteTags(point.tags)
+		timestamp := " " + strconv.FormatInt(point.timestamp.Unix(), 10)
+
+		if point.kind != "histogram" {
+			lines = append(lines, name+tags+" "+lineValue(point.value)+timestamp)
+			continue
+		}
+		lines = append(lines, name+".count"+tags+" "+strconv.FormatUint(point.count, 10)+timestamp)
+		lines = append(lines, name+".sum"+tags+" "+lineValue(point.sum)+timestamp)
+		for i, bound := range point.bounds {
+			lines = append(lines, name+".bucket"+tags+";le="+lineValue(bound)+" "+strconv.FormatUint(point.counts[i], 10)+timestamp)
+		}
+		lines = append(lines, name+".bucket"+tags+";le=+Inf "+strconv.FormatUint(point.count, 10)+timestamp)
+	}
+	return lines
+}
+
+// graphitePath replaces the characters that end a metric path: spaces, semicolons and newlines
+func graphitePath(name string) string {
+	name = strings.NewReplacer(" ", "_", "\t", "_", ";", "_", "\n", "_").Replace(name)
+	if name == "" {
+		return "_"
+	}
+	return name
+}
+
+// graphiteTags formats tags as ;key=value pairs; Graphite forbids ;!^= in tag names and ;~ in values,
+// and neither may be empty, so empty tags and the reserved name tag are dropped and forbidden characters replaced
+func graphiteTags(tags [][2]string) string {
+	var b strings.Builder
+	for _, tag := range tags {
+		key := strings.NewReplacer(";", "_", "!", "_", "^", "_", "=", "_", " ", "_", "\n", "_").Replace(tag[0])
+		value := strings.NewReplacer(";", "_", "~", "_", " ", "_", "\n", "_").Replace(tag[1])
+		if key == "" || value == "" || key == "name" {
+			continue
+		}
+		b.WriteString(";" + key + "=" + value)
+	}
+	return b.String()
+}
@@ -0,0 +1,57 @@
+package exporters
+
+import (
+	"strconv"
+	"strings"
+)
+
+// influxLines formats points as InfluxDB line protocol in the Telegraf prometheus (v1) schema, which the
+// collector's influxdb receiver maps back to OTLP metrics
+//
+//	name,tag=value counter=1i 1700000000000000000
+//	name,tag=value gauge=0.5 1700000000000000000
+//	name,tag=value 0.1=2,1=5,+Inf=6,count=6,sum=3.2 1700000000000000000
+//
+// Histogram bucket fields hold cumulative counts, named by their upper bound
+func influxLines(points []linePoint) []string {
+	var lines []string
+	for _, point := range points {
+		var b strings.Builder
+		b.WriteString(influxMeasurement(point.name))
+		for _, tag := range point.tags {
+			if tag[0] == "" || tag[1] == "" {
+				continue // Line protocol has no empty tag keys or values
+			}
+			b.WriteString("," + influxKey(tag[0]) + "=" + influxKey(tag[1]))
+		}
+		b.WriteByte(' ')
+
+		switch point.kind {
+		case "histogram":
+			for i, bound := range point.bounds {
+				b.WriteString(influxKey(lineValue(bound)) + "=" + strconv.FormatUint(point.counts[i], 10) + ",")
+			}
+			b.WriteString("+Inf=" + strconv.FormatUint(point.count, 10))
+			b.WriteString(",count=" + strconv.FormatUint(point.count, 10))
+			b.WriteString(",sum=" + lineValue(point.sum))
+		default:
+			b.WriteString(point.kind + "=" + lineValue(point.value))
+			if point.integer {
+				b.WriteByte('i')
+			}
+		}
+		b.WriteString(" " + strconv.FormatInt(point.timestamp.UnixNano(), 10))
+		lines = append(lines, b.String())
+	}
+	return lines
+}
+
+// influxMeasurement escapes commas and spaces in a measurement; newlines end a line, so they become spaces
+func influxMeasurement(name string) string {
+	return strings.NewReplacer(",", `\,`, " ", `\ `, "\n", `\ `).Replace(name)
+}
+
+// influxKey escapes commas, equals signs and spaces in tag keys, tag values and field keys
+func influxKey(key string) string {
+	return strings.NewReplacer(",", `\,`, "=", `\=`, " ", `\ `, "\n", `\ `).Replace(key)
+}
@@ -0,0 +1,288 @@
+package exporters
+
+import (
+	"context"
+	"encoding/binary"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/antithesishq/otel-datagen/internal/aggro"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/sdk/resource"
+	"go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.21.0"
+	oteltrace "go.opentelemetry.io/otel/trace"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+)
+
+// jaegerPostSpans is the method of the Jaeger collector gRPC service
+const jaegerPostSpans = "/jaeger.api_v2.CollectorService/PostSpans"
+
+// Jaeger tag value types, numbered as in the Thrift model; the protobuf model numbers them differently
+const (
+	jaegerString = iota
+	jaegerDouble
+	jaegerBool
+	jaegerLong
+)
+
+// Jaeger span reference types, the same in both models
+const (
+	jaegerChildOf     = 0
+	jaegerFollowsFrom = 1
+)
+
+// jaegerBatch is the spans of one process, the unit both Jaeger protocols send
+type jaegerBatch struct {
+	serviceName string
+	tags        []jaegerTag
+	spans       []jaegerSpan
+}
+
+// jaegerSpan is a span of the Jaeger model
+type jaegerSpan struct {
+	traceIDHigh, traceIDLow uint64
+	spanID, parentSpanID    uint64
+	operationName           string
+	references              []jaegerReference
+	flags                   int32
+	start                   time.Time
+	duration                time.Duration
+	tags                    []jaegerTag
+	logs                    []jaegerLog
+}
+
+// jaegerReference is a parent or link of a span
+type jaegerReference struct {
+	refType                 int
+	traceIDHigh, traceIDLow uint64
+	spanID                  uint64
+}
+
+// jaegerTag is a typed key-value pair
+type jaegerTag struct {
+	key     string
+	vType   int
+	vString string
+	vDouble float64
+	vBool   bool
+	vLong   int64
+}
+
+// jaegerLog is a timestamped event of a span
+type jaegerLog struct {
+	timestamp time.Time
+	fields    []jaegerTag
+}
+
+// jaegerModel converts the spans of one export into one batch per process, grouping spans by resource as the
+// collector's Jaeger translator does; batches come in the order their resources first appear
+// The target format fault, when set, goes into span faulty
+func jaegerModel(spans []trace.ReadOnlySpan, target string, faulty int) []jaegerBatch {
+	var batches []jaegerBatch
+	byResource := make(map[attribute.Distinct]int)
+	for i, span := range spans {
+		key := span.Resource().Equivalent()
+		b, ok := byResource[key]
+		if !ok {
+			b = len(batches)
+			byResource[key] = b
+			batches = append(batches, jaegerProcess(span.Resource()))
+		}
+		batch := &batches[b]
+
+		model := jaegerSpanOf(span)
+		if i == faulty {
+			switch target {
+			case "64-bit-ids":
+				// Only the low 64 bits of the trace ID, as older Jaeger clients send
+				model.traceIDHigh = 0
+				model.tags = append(model.tags, jaegerTagOf(aggroTraceFormatKey.String(target)))
+			case "missing-local-endpoint":
+				// Jaeger carries the local service in the process, shared by the whole batch
+				batch.serviceName = ""
+				batch.tags = append(batch.tags, jaegerTagOf(aggroTraceFormatKey.String(target)))
+			case "annotation-only":
+				// No operation, duration or tags, only logs
+				model.operationName = ""
+				model.duration = 0
+				model.tags = nil
+				model.logs = append(model.logs, jaegerLog{
+					timestamp: span.EndTime(),
+					fields:    []jaegerTag{jaegerTagOf(aggroTraceFormatKey.String(target))},
+				})
+			}
+		}
+		batch.spans = append(batch.spans, model)
+	}
+	return batches
+}
+
+// jaegerProcess returns an empty batch for the process of a resource, with its service name and its other attributes as tags
+func jaegerProcess(res *resource.Resource) jaegerBatch {
+	batch := jaegerBatch{serviceName: serviceName(res)}
+	for _, kv := range res.Attributes() {
+		if kv.Key != semconv.ServiceNameKey {
+			batch.tags = append(batch.tags, jaegerTagOf(kv))
+		}
+	}
+	return batch
+}
+
+// jaegerSpanOf converts one span the way the collector's Jaeger translator does
+func jaegerSpanOf(span trace.ReadOnlySpan) jaegerSpan {
+	sc := span.SpanContext()
+	high, low := jaegerTraceID(sc.TraceID())
+	model := jaegerSpan{
+		traceIDHigh:   high,
+		traceIDLow:    low,
+		spanID:        jaegerSpanID(sc.SpanID()),
+		operationName: span.Name(),
+		start:         span.StartTime(),
+		duration:      span.EndTime().Sub(span.StartTime()),
+	}
+	if sc.IsSampled() {
+		model.flags = 1
+	}
+	if span.Parent().IsValid() {
+		model.parentSpanID = jaegerSpanID(span.Parent().SpanID())
+		model.references = append(model.references, jaegerReference{jaegerChildOf, high, low, model.parentSpanID})
+	}
+	for _, link := range span.Links() {
+		linkHigh, linkLow := jaegerTraceID(link.SpanContext.TraceID())
+		model.references = append(model.references, jaegerReference{jaegerFollowsFrom, linkHigh, linkLow, jaegerSpanID(link.SpanContext.SpanID())})
+	}
+
+	for _, kv := range span.Attributes() {
+		model.tags = append(model.tags, jaegerTagOf(kv))
+	}
+	if span.SpanKind() != oteltrace.SpanKindInternal && span.SpanKind() != oteltrace.SpanKindUnspecified {
+		model.tags = append(model.tags, jaegerTagOf(attribute.String("span.kind", strings.ToLower(span.SpanKind().String()))))
+	}
+	switch span.Status().Code {
+	case codes.Error:
+		model.tags = append(model.tags, jaegerTagOf(attribute.String("otel.status_code", "ERROR")), jaegerTagOf(attribute.Bool("error", true)))
+		if span.Status().Description != "" {
+			model.tags = append(model.tags, jaegerTagOf(attribute.String("otel.status_description", span.Status().Description)))
+		}
+	case codes.Ok:
+		model.tags = append(model.tags, jaegerTagOf(attribute.String("otel.status_code", "OK")))
+	}
+	if scope := span.InstrumentationScope(); scope.Name != "" {
+		model.tags = append(model.tags, jaegerTagOf(attribute.String("otel.scope.name", scope.Name)))
+		if scope.Version != "" {
+			model.tags = append(model.tags, jaegerTagOf(attribute.String("otel.scope.version", scope.Version)))
+		}
+	}
+
+	for _, event := range span.Events() {
+		fields := []jaegerTag{jaegerTagOf(attribute.String("event", event.Name))}
+		for _, kv := range event.Attributes {
+			fields = append(fields, jaegerTagOf(kv))
+		}
+		model.logs = append(model.logs, jaegerLog{timestamp: event.Time, fields: fields})
+	}
+	return model
+}
+
+// jaegerTraceID splits a trace ID into its high and low 64 bits
+func jaegerTraceID(id oteltrace.TraceID) (uint64, uint64) {
+	return binary.BigEndian.Uint64(id[:8]), binary.BigEndian.Uint64(id[8:])
+}
+
+// jaegerSpanID converts a span ID to its 64-bit value
+func jaegerSpanID(id oteltrace.SpanID) uint64 {
+	return binary.BigEndian.Uint64(id[:])
+}
+
+// jaegerTagOf converts an attribute to a typed tag; slices are emitted as strings
+func jaegerTagOf(kv attribute.KeyValue) jaegerTag {
+	tag := jaegerTag{key: string(kv.Key)}
+	switch kv.Value.Type() {
+	case attribute.BOOL:
+		tag.vType = jaegerBool
+		tag.vBool = kv.Value.AsBool()
+	case attribute.INT64:
+		tag.vType = jaegerLong
+		tag.vLong = kv.Value.AsInt64()
+	case attribute.FLOAT64:
+		tag.vType = jaegerDouble
+		tag.vDouble = kv.Value.AsFloat64()
+	default:
+		tag.vType = jaegerString
+		tag.vString = kv.Value.Emit()
+	}
+	return tag
+}
+
+// JaegerThriftExporter sends spans as Thrift-encoded Jaeger batches over HTTP, as the collector's
+// thrift_http protocol expects
+type JaegerThriftExporter struct {
+	url         string
+	aggroConfig *aggro.AggroConfig
+	client      *http.Client
+}
+
+// NewJaegerThriftExporter creates an exporter posting to a Jaeger /api/traces endpoint
+func NewJaegerThriftExporter(url string, aggroConfig *aggro.AggroConfig) *JaegerThriftExporter {
+	return &JaegerThriftExporter{url: url, aggroConfig: aggroConfig, client: &http.Client{Timeout: 30 * time.Second}}
+}
+
+// ExportSpans posts the spans as one batch per process
+func (e *JaegerThriftExporter) ExportSpans(ctx context.Context, spans []trace.ReadOnlySpan) error {
+	if len(spans) == 0 {
+		return nil
+	}
+	target, faulty := formatAggroCase(e.aggroConfig, spans)
+	for _, batch := range jaegerModel(spans, target, faulty) {
+		if _, err := postBatch(ctx, e.client, e.url, "application/x-thrift", encodeJaegerThrift(batch)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Shutdown closes idle connections
+func (e *JaegerThriftExporter) Shutdown(context.Context) error {
+	e.client.CloseIdleConnections()
+	return nil
+}
+
+// JaegerGRPCExporter sends spans to the Jaeger collector gRPC service
+type JaegerGRPCExporter struct {
+	aggroConfig *aggro.AggroConfig
+	conn        *grpc.ClientConn
+}
+
+// NewJaegerGRPCExporter creates an exporter for a Jaeger collector gRPC endpoint; the connection is
+// established lazily on the first export
+func NewJaegerGRPCExporter(endpoint string, aggroConfig *aggro.AggroConfig) (*JaegerGRPCExporter, error) {
+	conn, err := grpc.NewClient(endpoint, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		return nil, err
+	}
+	return &JaegerGRPCExporter{aggroConfig: aggroConfig, conn: conn}, nil
+}
+
+// ExportSpans sends the spans in one PostSpans call per process
+func (e *JaegerGRPCExporter) ExportSpans(ctx context.Context, spans []trace.ReadOnlySpan) error {
+	if len(spans) == 0 {
+		return nil
+	}
+	target, faulty := formatAggroCase(e.aggroConfig, spans)
+	for _, batch := range jaegerModel(spans, target, faulty) {
+		var response []byte
+		if err := e.conn.Invoke(ctx, jaegerPostSpans, encodeJaegerPostSpans(batch), &response, grpc.ForceCodec(rawCodec{})); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Shutdown closes the connection
+func (e *JaegerGRPCExporter) Shutdown(context.Context) error {
+	return e.conn.Close()
+}
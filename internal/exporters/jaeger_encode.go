@@ -0,0 +1,255 @@
+package exporters
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math"
+
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+// Thrift binary protocol field types
+const (
+	thriftBool   = 2
+	thriftDouble = 4
+	thriftI32    = 8
+	thriftI64    = 10
+	thriftString = 11
+	thriftStruct = 12
+	thriftList   = 15
+)
+
+// thriftWriter writes structs in the Thrift binary protocol
+type thriftWriter struct {
+	buf []byte
+}
+
+func (w *thriftWriter) field(kind byte, id int16) {
+	w.buf = append(w.buf, kind)
+	w.buf = binary.BigEndian.AppendUint16(w.buf, uint16(id))
+}
+
+func (w *thriftWriter) i32(id int16, v int32) {
+	w.field(thriftI32, id)
+	w.buf = binary.BigEndian.AppendUint32(w.buf, uint32(v))
+}
+
+func (w *thriftWriter) i64(id int16, v int64) {
+	w.field(thriftI64, id)
+	w.buf = binary.BigEndian.AppendUint64(w.buf, uint64(v))
+}
+
+func (w *thriftWriter) string(id int16, v string) {
+	w.field(thriftString, id)
+	w.buf = binary.BigEndian.AppendUint32(w.buf, uint32(len(v)))
+	w.buf = append(w.buf, v...)
+}
+
+// list starts a list of n structs
+func (w *thriftWriter) list(id int16, n int) {
+	w.field(thriftList, id)
+	w.buf = append(w.buf, thriftStruct)
+	w.buf = binary.BigEndian.AppendUint32(w.buf, uint32(n))
+}
+
+// stop ends a struct
+func (w *thriftWriter) stop() {
+	w.buf = append(w.buf, 0)
+}
+
+// encodeJaegerThrift encodes a jaeger.thrift Batch
+//
+//	Batch   { 1: Process process; 2: list<Span> spans }
+//	Process { 1: string serviceName; 2: list<Tag> tags }
+//	Span    { 1: i64 traceIdLow; 2: i64 traceIdHigh; 3: i64 spanId; 4: i64 parentSpanId; 5: string operationName;
+//	          6: list<SpanRef> references; 7: i32 flags; 8: i64 startTime; 9: i64 duration; 10: list<Tag> tags; 11: list<Log> logs }
+//	SpanRef { 1: SpanRefType refType; 2: i64 traceIdLow; 3: i64 traceIdHigh; 4: i64 spanId }
+//	Tag     { 1: string key; 2: TagType vType; 3: string vStr; 4: double vDouble; 5: bool vBool; 6: i64 vLong }
+//	Log     { 1: i64 timestamp; 2: list<Tag> fields }
+//
+// Times and durations are in microseconds
+func encodeJaegerThrift(batch jaegerBatch) []byte {
+	w := &thriftWriter{}
+	w.field(thriftStruct, 1)
+	w.string(1, batch.serviceName)
+	w.list(2, len(batch.tags))
+	for _, tag := range batch.tags {
+		w.tag(tag)
+	}
+	w.stop()
+
+	w.list(2, len(batch.spans))
+	for _, span := range batch.spans {
+		w.i64(1, int64(span.traceIDLow))
+		w.i64(2, int64(span.traceIDHigh))
+		w.i64(3, int64(span.spanID))
+		w.i64(4, int64(span.parentSpanID))
+		w.string(5, span.operationName)
+		w.list(6, len(span.references))
+		for _, ref := range span.references {
+			w.i32(1, int32(ref.refType))
+			w.i64(2, int64(ref.traceIDLow))
+			w.i64(3, int64(ref.traceIDHigh))
+			w.i64(4, int64(ref.spanID))
+			w.stop()
+		}
+		w.i32(7, span.flags)
+		w.i64(8, span.start.UnixMicro())
+		w.i64(9, span.duration.Microseconds())
+		w.list(10, len(span.tags))
+		for _, tag := range span.tags {
+			w.tag(tag)
+		}
+		w.list(11, len(span.logs))
+		for _, log := range span.logs {
+			w.i64(1, log.timestamp.UnixMicro())
+			w.list(2, len(log.fields))
+			for _, tag := range log.fields {
+				w.tag(tag)
+			}
+			w.stop()
+		}
+		w.stop()
+	}
+	w.stop()
+	return w.buf
+}
+
+// tag writes one Tag struct, with only the value field of its type
+func (w *thriftWriter) tag(tag jaegerTag) {
+	w.string(1, tag.key)
+	w.i32(2, int32(tag.vType))
+	switch tag.vType {
+	case jaegerString:
+		w.string(3, tag.vString)
+	case jaegerDouble:
+		w.field(thriftDouble, 4)
+		w.buf = binary.BigEndian.AppendUint64(w.buf, math.Float64bits(tag.vDouble))
+	case jaegerBool:
+		w.field(thriftBool, 5)
+		if tag.vBool {
+			w.buf = append(w.buf, 1)
+		} else {
+			w.buf = append(w.buf, 0)
+		}
+	case jaegerLong:
+		w.i64(6, tag.vLong)
+	}
+	w.stop()
+}
+
+// protoValueTypes maps tag types to the ValueType enum of the protobuf model
+var protoValueTypes = map[int]uint64{jaegerString: 0, jaegerBool: 1, jaegerLong: 2, jaegerDouble: 3}
+
+// encodeJaegerPostSpans encodes a jaeger.api_v2.PostSpansRequest
+//
+//	PostSpansRequest { Batch batch = 1; }
+//	Batch            { repeated Span spans = 1; Process process = 2; }
+//	Process          { string service_name = 1; repeated KeyValue tags = 2; }
+//	Span             { bytes trace_id = 1; bytes span_id = 2; string operation_name = 3; repeated SpanRef references = 4;
+//	                   uint32 flags = 5; Timestamp start_time = 6; Duration duration = 7; repeated KeyValue tags = 8; repeated Log logs = 9; }
+//	SpanRef          { bytes trace_id = 1; bytes span_id = 2; SpanRefType ref_type = 3; }
+//	KeyValue         { string key = 1; ValueType v_type = 2; string v_str = 3; bool v_bool = 4; int64 v_int64 = 5; double v_float64 = 6; }
+//	Log              { Timestamp timestamp = 1; repeated KeyValue fields = 2; }
+func encodeJaegerPostSpans(batch jaegerBatch) []byte {
+	var b []byte
+	for _, span := range batch.spans {
+		var s []byte
+		s = protoBytes(s, 1, jaegerTraceIDBytes(span.traceIDHigh, span.traceIDLow))
+		s = protoBytes(s, 2, binary.BigEndian.AppendUint64(nil, span.spanID))
+		s = protoBytes(s, 3, []byte(span.operationName))
+		for _, ref := range span.references {
+			var r []byte
+			r = protoBytes(r, 1, jaegerTraceIDBytes(ref.traceIDHigh, ref.traceIDLow))
+			r = protoBytes(r, 2, binary.BigEndian.AppendUint64(nil, ref.spanID))
+			r = protoVarint(r, 3, uint64(ref.refType))
+			s = protoBytes(s, 4, r)
+		}
+		s = protoVarint(s, 5, uint64(uint32(span.flags)))
+		s = protoBytes(s, 6, protoTimestamp(span.start.Unix(), int64(span.start.Nanosecond())))
+		s = protoBytes(s, 7, protoTimestamp(int64(span.duration/1e9), int64(span.duration%1e9)))
+		for _, tag := range span.tags {
+			s = protoBytes(s, 8, protoKeyValue(tag))
+		}
+		for _, log := range span.logs {
+			l := protoBytes(nil, 1, protoTimestamp(log.timestamp.Unix(), int64(log.timestamp.Nanosecond())))
+			for _, tag := range log.fields {
+				l = protoBytes(l, 2, protoKeyValue(tag))
+			}
+			s = protoBytes(s, 9, l)
+		}
+		b = protoBytes(b, 1, s)
+	}
+
+	process := protoBytes(nil, 1, []byte(batch.serviceName))
+	for _, tag := range batch.tags {
+		process = protoBytes(process, 2, protoKeyValue(tag))
+	}
+	b = protoBytes(b, 2, process)
+	return protoBytes(nil, 1, b)
+}
+
+// jaegerTraceIDBytes returns the 16 byte trace ID of the protobuf model
+func jaegerTraceIDBytes(high, low uint64) []byte {
+	return binary.BigEndian.AppendUint64(binary.BigEndian.AppendUint64(nil, high), low)
+}
+
+// protoKeyValue encodes a KeyValue message
+func protoKeyValue(tag jaegerTag) []byte {
+	b := protoBytes(nil, 1, []byte(tag.key))
+	b = protoVarint(b, 2, protoValueTypes[tag.vType])
+	switch tag.vType {
+	case jaegerString:
+		b = protoBytes(b, 3, []byte(tag.vString))
+	case jaegerBool:
+		b = protoVarint(b, 4, protowire.EncodeBool(tag.vBool))
+	case jaegerLong:
+		b = protoVarint(b, 5, uint64(tag.vLong))
+	case jaegerDouble:
+		b = protowire.AppendTag(b, 6, protowire.Fixed64Type)
+		b = protowire.AppendFixed64(b, math.Float64bits(tag.vDouble))
+	}
+	return b
+}
+
+// protoTimestamp encodes a google.protobuf.Timestamp or Duration, which share their layout
+func protoTimestamp(seconds, nanos int64) []byte {
+	b := protoVarint(nil, 1, uint64(seconds))
+	return protoVarint(b, 2, uint64(nanos))
+}
+
+// protoVarint appends a varint field
+func protoVarint(b []byte, field protowire.Number, value uint64) []byte {
+	b = protowire.AppendTag(b, field, protowire.VarintType)
+	return protowire.AppendVarint(b, value)
+}
+
+// protoBytes appends a length-delimited field
+func protoBytes(b []byte, field protowire.Number, value []byte) []byte {
+	b = protowire.AppendTag(b, field, protowire.BytesType)
+	return protowire.AppendBytes(b, value)
+}
+
+// rawCodec passes already encoded protobuf messages through gRPC
+type rawCodec struct{}
+
+func (rawCodec) Marshal(v any) ([]byte, error) {
+	b, ok := v.([]byte)
+	if !ok {
+		return nil, fmt.Errorf("raw codec cannot marshal %T", v)
+	}
+	return b, nil
+}
+
+func (rawCodec) Unmarshal(data []byte, v any) error {
+	b, ok := v.(*[]byte)
+	if !ok {
+		return fmt.Errorf("raw codec cannot unmarshal into %T", v)
+	}
+	*b = append((*b)[:0], data...)
+	return nil
+}
+
+func (rawCodec) Name() string {
+	return "proto"
+}
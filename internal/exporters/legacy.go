@@ -0,0 +1,99 @@
+package exporters
+
+import (
+	"fmt"
+	"net/url"
+
+	"github.com/antithesishq/otel-datagen/internal/aggro"
+	"github.com/antithesishq/otel-datagen/internal/randomness"
+	"github.com/spf13/viper"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/sdk/resource"
+	"go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.21.0"
+)
+
+// LegacyTraceConfig selects the legacy trace protocols spans are sent over, next to or instead of OTLP
+type LegacyTraceConfig struct {
+	ZipkinURL          string // Zipkin v2 JSON endpoint, e.g. "http://localhost:9411/api/v2/spans"
+	JaegerURL          string // Jaeger Thrift over HTTP endpoint, e.g. "http://localhost:14268/api/traces"
+	JaegerGRPCEndpoint string // Jaeger gRPC collector, e.g. "localhost:14250"
+}
+
+// ParseLegacyTraceConfig reads the legacy trace protocol settings for the given component from viper
+func ParseLegacyTraceConfig(component string) (*LegacyTraceConfig, error) {
+	prefix := "generate." + component + "."
+	config := &LegacyTraceConfig{
+		ZipkinURL:          viper.GetString(prefix + "zipkin_url"),
+		JaegerURL:          viper.GetString(prefix + "jaeger_url"),
+		JaegerGRPCEndpoint: viper.GetString(prefix + "jaeger_grpc_endpoint"),
+	}
+	if err := config.Validate(); err != nil {
+		return nil, err
+	}
+	return config, nil
+}
+
+// Validate checks that the HTTP endpoints are absolute URLs
+func (config *LegacyTraceConfig) Validate() error {
+	for name, value := range map[string]string{"zipkin url": config.ZipkinURL, "jaeger url": config.JaegerURL} {
+		if value == "" {
+			continue
+		}
+		u, err := url.Parse(value)
+		if err != nil || u.Scheme == "" || u.Host == "" {
+			return fmt.Errorf("invalid %s '%s': expected an absolute URL like http://host:port/path", name, value)
+		}
+	}
+	return nil
+}
+
+// Enabled reports whether spans are sent over any legacy protocol
+func (config *LegacyTraceConfig) Enabled() bool {
+	return config != nil && (config.ZipkinURL != "" || config.JaegerURL != "" || config.JaegerGRPCEndpoint != "")
+}
+
+// CreateLegacyTraceExporters creates one exporter per configured legacy protocol
+// aggroConfig, when its trace format aggro is active, puts one format fault in every export
+func CreateLegacyTraceExporters(config *LegacyTraceConfig, aggroConfig *aggro.AggroConfig) ([]trace.SpanExporter, error) {
+	var exporters []trace.SpanExporter
+	if config.ZipkinURL != "" {
+		exporters = append(exporters, NewZipkinExporter(config.ZipkinURL, aggroConfig))
+	}
+	if config.JaegerURL != "" {
+		exporters = append(exporters, NewJaegerThriftExporter(config.JaegerURL, aggroConfig))
+	}
+	if config.JaegerGRPCEndpoint != "" {
+		exporter, err := NewJaegerGRPCExporter(config.JaegerGRPCEndpoint, aggroConfig)
+		if err != nil {
+			return nil, err
+		}
+		exporters = append(exporters, exporter)
+	}
+	return exporters, nil
+}
+
+// serviceName returns the service.name of a resource, as legacy formats carry it outside the resource
+func serviceName(res *resource.Resource) string {
+	if res != nil {
+		if name, ok := res.Set().Value(semconv.ServiceNameKey); ok {
+			return name.Emit()
+		}
+	}
+	return ""
+}
+
+// formatAggroCase picks the format fault of an export and the span it applies to, or -1 for none
+func formatAggroCase(aggroConfig *aggro.AggroConfig, spans []trace.ReadOnlySpan) (string, int) {
+	if aggroConfig == nil || len(spans) == 0 {
+		return "", -1
+	}
+	target, ok := aggroConfig.AggroTraceFormatCase()
+	if !ok {
+		return "", -1
+	}
+	return target, randomness.Intn(len(spans))
+}
+
+// aggroTraceFormatKey tags the span or process carrying a format fault
+const aggroTraceFormatKey = attribute.Key("aggro.trace_format")
@@ -0,0 +1,194 @@
+package exporters
+
+import (
+	"fmt"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/spf13/viper"
+	otellog "go.opentelemetry.io/otel/log"
+	sdklog "go.opentelemetry.io/otel/sdk/log"
+)
+
+// LogOutputConfig selects the log ingest protocols records are sent over, next to or instead of OTLP
+type LogOutputConfig struct {
+	LokiURL              string // Loki push endpoint, e.g. "http://localhost:3100/loki/api/v1/push"
+	LokiFormat           string // protobuf (snappy-compressed logproto.PushRequest) or json
+	ElasticsearchURL     string // Elasticsearch base URL, e.g. "http://localhost:9200"
+	ElasticsearchIndex   string // Index or data stream the bulk requests write to
+	SyslogAddress        string // tcp://host:port or udp://host:port
+	SyslogFormat         string // rfc5424 or rfc3164
+	FluentForwardAddress string // Fluent Forward TCP address, e.g. "localhost:24224"
+	FluentTag            string // Tag of forwarded events
+}
+
+// Supported Loki push and syslog formats
+var (
+	lokiFormats   = []string{"protobuf", "json"}
+	syslogFormats = []string{"rfc5424", "rfc3164"}
+)
+
+// ParseLogOutputConfig reads the log ingest protocol settings for the given component from viper
+func ParseLogOutputConfig(component string) (*LogOutputConfig, error) {
+	prefix := "generate." + component + "."
+	config := &LogOutputConfig{
+		LokiURL:              viper.GetString(prefix + "loki_url"),
+		LokiFormat:           viper.GetString(prefix + "loki_format"),
+		ElasticsearchURL:     viper.GetString(prefix + "elasticsearch_url"),
+		ElasticsearchIndex:   viper.GetString(prefix + "elasticsearch_index"),
+		SyslogAddress:        viper.GetString(prefix + "syslog_address"),
+		SyslogFormat:         viper.GetString(prefix + "syslog_format"),
+		FluentForwardAddress: viper.GetString(prefix + "fluent_forward_address"),
+		FluentTag:            viper.GetString(prefix + "fluent_tag"),
+	}
+	if config.LokiFormat == "" {
+		config.LokiFormat = "protobuf"
+	}
+	if config.ElasticsearchIndex == "" {
+		config.ElasticsearchIndex = "otel-datagen-logs"
+	}
+	if config.SyslogFormat == "" {
+		config.SyslogFormat = "rfc5424"
+	}
+	if config.FluentTag == "" {
+		config.FluentTag = "otel-datagen"
+	}
+	if err := config.Validate(); err != nil {
+		return nil, err
+	}
+	return config, nil
+}
+
+// Validate checks the formats and addresses
+func (config *LogOutputConfig) Validate() error {
+	if !slices.Contains(lokiFormats, config.LokiFormat) {
+		return fmt.Errorf("unsupported loki format '%s' (supported: %s)", config.LokiFormat, strings.Join(lokiFormats, ", "))
+	}
+	if !slices.Contains(syslogFormats, config.SyslogFormat) {
+		return fmt.Errorf("unsupported syslog format '%s' (supported: %s)", config.SyslogFormat, strings.Join(syslogFormats, ", "))
+	}
+	for name, value := range map[string]string{"loki url": config.LokiURL, "elasticsearch url": config.ElasticsearchURL} {
+		if value == "" {
+			continue
+		}
+		u, err := url.Parse(value)
+		if err != nil || u.Scheme == "" || u.Host == "" {
+			return fmt.Errorf("invalid %s '%s': expected an absolute URL like http://host:port/path", name, value)
+		}
+	}
+	if config.SyslogAddress != "" {
+		if _, _, err := syslogNetwork(config.SyslogAddress); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Enabled reports whether records are sent over any log ingest protocol
+func (config *LogOutputConfig) Enabled() bool {
+	return config != nil && (config.LokiURL != "" || config.ElasticsearchURL != "" || config.SyslogAddress != "" || config.FluentForwardAddress != "")
+}
+
+// CreateLogOutputExporters creates one exporter per configured log ingest protocol
+func CreateLogOutputExporters(config *LogOutputConfig) []sdklog.Exporter {
+	var exporters []sdklog.Exporter
+	if config.LokiURL != "" {
+		exporters = append(exporters, NewLokiExporter(config.LokiURL, config.LokiFormat))
+	}
+	if config.ElasticsearchURL != "" {
+		exporters = append(exporters, NewElasticsearchExporter(config.ElasticsearchURL, config.ElasticsearchIndex))
+	}
+	if config.SyslogAddress != "" {
+		exporters = append(exporters, NewSyslogExporter(config.SyslogAddress, config.SyslogFormat))
+	}
+	if config.FluentForwardAddress != "" {
+		exporters = append(exporters, NewFluentForwardExporter(config.FluentForwardAddress, config.FluentTag))
+	}
+	return exporters
+}
+
+// syslogNetwork splits a syslog address into its network and host:port
+func syslogNetwork(address string) (string, string, error) {
+	network, hostPort, ok := strings.Cut(address, "://")
+	if !ok || (network != "tcp" && network != "udp") || hostPort == "" {
+		return "", "", fmt.Errorf("invalid syslog address '%s': expected tcp://host:port or udp://host:port", address)
+	}
+	return network, hostPort, nil
+}
+
+// recordTime returns the timestamp of a record, falling back to its observed timestamp as collectors do
+func recordTime(r *sdklog.Record) time.Time {
+	if !r.Timestamp().IsZero() {
+		return r.Timestamp()
+	}
+	return r.ObservedTimestamp()
+}
+
+// recordLevel returns the lower-case severity of a record: its severity text, or the name of its severity number
+func recordLevel(r *sdklog.Record) string {
+	if r.SeverityText() != "" {
+		return strings.ToLower(r.SeverityText())
+	}
+	switch {
+	case r.Severity() >= otellog.SeverityFatal1:
+		return "fatal"
+	case r.Severity() >= otellog.SeverityError1:
+		return "error"
+	case r.Severity() >= otellog.SeverityWarn1:
+		return "warn"
+	case r.Severity() >= otellog.SeverityInfo1:
+		return "info"
+	case r.Severity() >= otellog.SeverityDebug1:
+		return "debug"
+	case r.Severity() >= otellog.SeverityTrace1:
+		return "trace"
+	}
+	return "unknown"
+}
+
+// recordAttributes returns the attributes of a record as strings, with the trace context as trace_id and span_id
+func recordAttributes(r *sdklog.Record) [][2]string {
+	var attrs [][2]string
+	r.WalkAttributes(func(kv otellog.KeyValue) bool {
+		attrs = append(attrs, [2]string{kv.Key, kv.Value.String()})
+		return true
+	})
+	if r.TraceID().IsValid() {
+		attrs = append(attrs, [2]string{"trace_id", r.TraceID().String()})
+	}
+	if r.SpanID().IsValid() {
+		attrs = append(attrs, [2]string{"span_id", r.SpanID().String()})
+	}
+	return attrs
+}
+
+// logValue converts a log value to the Go value JSON and msgpack encoders write
+func logValue(v otellog.Value) any {
+	switch v.Kind() {
+	case otellog.KindBool:
+		return v.AsBool()
+	case otellog.KindInt64:
+		return v.AsInt64()
+	case otellog.KindFloat64:
+		return v.AsFloat64()
+	case otellog.KindString:
+		return v.AsString()
+	case otellog.KindBytes:
+		return v.AsBytes()
+	case otellog.KindSlice:
+		var values []any
+		for _, item := range v.AsSlice() {
+			values = append(values, logValue(item))
+		}
+		return values
+	case otellog.KindMap:
+		values := make(map[string]any)
+		for _, kv := range v.AsMap() {
+			values[kv.Key] = logValue(kv.Value)
+		}
+		return values
+	}
+	return nil
+}
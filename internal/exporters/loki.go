@@ -0,0 +1,166 @@
+package exporters
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/golang/snappy"
+	sdklog "go.opentelemetry.io/otel/sdk/log"
+)
+
+// LokiExporter pushes log records to the Loki push API
+type LokiExporter struct {
+	url    string
+	format string
+	client *http.Client
+}
+
+// NewLokiExporter creates an exporter for a Loki push endpoint; format is protobuf or json
+func NewLokiExporter(url, format string) *LokiExporter {
+	return &LokiExporter{url: url, format: format, client: &http.Client{Timeout: 30 * time.Second}}
+}
+
+// lokiStream is the entries of one label set
+type lokiStream struct {
+	labels  [][2]string // Sorted by name
+	entries []lokiEntry
+}
+
+// lokiEntry is one log line with its structured metadata
+type lokiEntry struct {
+	timestamp time.Time
+	line      string
+	metadata  [][2]string
+}
+
+// Export pushes the records in one request, grouped into streams by service_name and level
+// The body becomes the line and the attributes and trace context become structured metadata
+func (e *LokiExporter) Export(ctx context.Context, records []sdklog.Record) error {
+	if len(records) == 0 {
+		return nil
+	}
+	streams := lokiStreams(records)
+
+	var body []byte
+	var contentType string
+	if e.format == "json" {
+		body = encodeLokiJSON(streams)
+		contentType = "application/json"
+	} else {
+		body = snappy.Encode(nil, encodeLokiProto(streams))
+		contentType = "application/x-protobuf"
+	}
+	_, err := postBatch(ctx, e.client, e.url, contentType, body)
+	return err
+}
+
+// ForceFlush does nothing: every export is sent immediately
+func (e *LokiExporter) ForceFlush(context.Context) error {
+	return nil
+}
+
+// Shutdown closes idle connections
+func (e *LokiExporter) Shutdown(context.Context) error {
+	e.client.CloseIdleConnections()
+	return nil
+}
+
+// lokiStreams groups records into streams, in order of first appearance
+func lokiStreams(records []sdklog.Record) []*lokiStream {
+	var streams []*lokiStream
+	index := make(map[string]*lokiStream)
+	for i := range records {
+		r := &records[i]
+		labels := [][2]string{{"level", recordLevel(r)}}
+		if name := serviceName(r.Resource()); name != "" {
+			labels = append(labels, [2]string{"service_name", name})
+		}
+		key := lokiLabels(labels)
+		stream, ok := index[key]
+		if !ok {
+			stream = &lokiStream{labels: labels}
+			index[key] = stream
+			streams = append(streams, stream)
+		}
+		stream.entries = append(stream.entries, lokiEntry{timestamp: recordTime(r), line: r.Body().String(), metadata: recordAttributes(r)})
+	}
+	return streams
+}
+
+// lokiLabels formats a label set as a LogQL stream selector, the form protobuf pushes carry
+func lokiLabels(labels [][2]string) string {
+	sort.Slice(labels, func(i, j int) bool { return labels[i][0] < labels[j][0] })
+	var b strings.Builder
+	b.WriteByte('{')
+	for i, l := range labels {
+		if i > 0 {
+			b.WriteString(", ")
+		}
+		b.WriteString(l[0] + "=" + strconv.Quote(l[1]))
+	}
+	b.WriteByte('}')
+	return b.String()
+}
+
+// encodeLokiJSON encodes a push request in the JSON format
+//
+//	{"streams": [{"stream": {"label": "value"}, "values": [["<unix ns>", "<line>", {"metadata": "value"}]]}]}
+func encodeLokiJSON(streams []*lokiStream) []byte {
+	type jsonStream struct {
+		Stream map[string]string `json:"stream"`
+		Values [][]any           `json:"values"`
+	}
+	request := struct {
+		Streams []jsonStream `json:"streams"`
+	}{}
+	for _, stream := range streams {
+		s := jsonStream{Stream: make(map[string]string)}
+		for _, l := range stream.labels {
+			s.Stream[l[0]] = l[1]
+		}
+		for _, entry := range stream.entries {
+			value := []any{strconv.FormatInt(entry.timestamp.UnixNano(), 10), entry.line}
+			if len(entry.metadata) > 0 {
+				metadata := make(map[string]string)
+				for _, m := range entry.metadata {
+					metadata[m[0]] = m[1]
+				}
+				value = append(value, metadata)
+			}
+			s.Values = append(s.Values, value)
+		}
+		request.Streams = append(request.Streams, s)
+	}
+	body, _ := json.Marshal(request)
+	return body
+}
+
+// encodeLokiProto encodes a logproto.PushRequest
+//
+//	PushRequest      { repeated StreamAdapter streams = 1; }
+//	StreamAdapter    { string labels = 1; repeated EntryAdapter entries = 2; }
+//	EntryAdapter     { Timestamp timestamp = 1; string line = 2; repeated LabelPairAdapter structuredMetadata = 3; }
+//	LabelPairAdapter { string name = 1; string value = 2; }
+func encodeLokiProto(streams []*lokiStream) []byte {
+	var b []byte
+	for _, stream := range streams {
+		s := protoBytes(nil, 1, []byte(lokiLabels(stream.labels)))
+		for _, entry := range stream.entries {
+			e := protoBytes(nil, 1, protoTimestamp(entry.timestamp.Unix(), int64(entry.timestamp.Nanosecond())))
+			e = protoBytes(e, 2, []byte(entry.line))
+			for _, m := range entry.metadata {
+				pair := protoBytes(nil, 1, []byte(m[0]))
+				pair = protoBytes(pair, 2, []byte(m[1]))
+				e = protoBytes(e, 3, pair)
+			}
+			s = protoBytes(s, 2, e)
+		}
+		b = protoBytes(b, 1, s)
+	}
+	return b
+}
@@ -0,0 +1,360 @@
+package exporters
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/antithesishq/otel-datagen/internal/aggro"
+	"github.com/antithesishq/otel-datagen/internal/prometheus"
+	"github.com/antithesishq/otel-datagen/internal/randomness"
+	"github.com/spf13/viper"
+	"go.opentelemetry.io/otel/attribute"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+)
+
+// MetricOutputConfig selects the plaintext metric protocols metrics are sent over, next to or instead of OTLP
+type MetricOutputConfig struct {
+	StatsDAddress   string // udp://host:port or tcp://host:port
+	StatsDFlavor    string // statsd or dogstatsd (with |#tags)
+	InfluxAddress   string // udp://, tcp:// or an http(s):// write endpoint
+	GraphiteAddress string // udp://host:port or tcp://host:port
+}
+
+// Supported StatsD flavors
+var statsdFlavors = []string{"statsd", "dogstatsd"}
+
+// ParseMetricOutputConfig reads the plaintext metric protocol settings for the given component from viper
+func ParseMetricOutputConfig(component string) (*MetricOutputConfig, error) {
+	prefix := "generate." + component + "."
+	config := &MetricOutputConfig{
+		StatsDAddress:   viper.GetString(prefix + "statsd_address"),
+		StatsDFlavor:    viper.GetString(prefix + "statsd_flavor"),
+		InfluxAddress:   viper.GetString(prefix + "influx_address"),
+		GraphiteAddress: viper.GetString(prefix + "graphite_address"),
+	}
+	if config.StatsDFlavor == "" {
+		config.StatsDFlavor = "dogstatsd"
+	}
+	if err := config.Validate(); err != nil {
+		return nil, err
+	}
+	return config, nil
+}
+
+// Validate checks the flavor and addresses
+func (config *MetricOutputConfig) Validate() error {
+	if !slices.Contains(statsdFlavors, config.StatsDFlavor) {
+		return fmt.Errorf("unsupported statsd flavor '%s' (supported: %s)", config.StatsDFlavor, strings.Join(statsdFlavors, ", "))
+	}
+	for name, address := range map[string]string{"statsd": config.StatsDAddress, "influx": config.InfluxAddress, "graphite": config.GraphiteAddress} {
+		if address == "" {
+			continue
+		}
+		network, _, ok := strings.Cut(address, "://")
+		if !ok || !(network == "udp" || network == "tcp" || (name == "influx" && (network == "http" || network == "https"))) {
+			if name == "influx" {
+				return fmt.Errorf("invalid influx address '%s': expected udp://host:port, tcp://host:port or an http(s):// write URL", address)
+			}
+			return fmt.Errorf("invalid %s address '%s': expected udp://host:port or tcp://host:port", name, address)
+		}
+	}
+	return nil
+}
+
+// Enabled reports whether metrics are sent over any plaintext protocol
+func (config *MetricOutputConfig) Enabled() bool {
+	return config != nil && (config.StatsDAddress != "" || config.InfluxAddress != "" || config.GraphiteAddress != "")
+}
+
+// CreateMetricOutputExporters creates one exporter per configured plaintext protocol
+// aggroConfig, when its metric format aggro is active, puts one format fault in every export
+func CreateMetricOutputExporters(config *MetricOutputConfig, aggroConfig *aggro.AggroConfig) []sdkmetric.Exporter {
+	var exporters []sdkmetric.Exporter
+	if config.StatsDAddress != "" {
+		exporters = append(exporters, NewStatsDExporter(config.StatsDAddress, config.StatsDFlavor, aggroConfig))
+	}
+	if config.InfluxAddress != "" {
+		exporters = append(exporters, NewInfluxExporter(config.InfluxAddress, aggroConfig))
+	}
+	if config.GraphiteAddress != "" {
+		exporters = append(exporters, NewGraphiteExporter(config.GraphiteAddress, aggroConfig))
+	}
+	return exporters
+}
+
+// LineExporter sends metrics in a line-based plaintext protocol: StatsD, InfluxDB line protocol or Graphite
+type LineExporter struct {
+	format      string // statsd, dogstatsd, influx or graphite
+	sender      *lineSender
+	aggroConfig *aggro.AggroConfig
+
+	mu       sync.Mutex
+	previous map[string]float64 // StatsD: last cumulative value of each counter and bucket, to send increments
+}
+
+// NewStatsDExporter creates an exporter for a StatsD server; flavor dogstatsd adds |#key:value tags
+func NewStatsDExporter(address, flavor string, aggroConfig *aggro.AggroConfig) *LineExporter {
+	return &LineExporter{format: flavor, sender: newLineSender(address), aggroConfig: aggroConfig, previous: make(map[string]float64)}
+}
+
+// NewInfluxExporter creates an exporter writing InfluxDB line protocol
+func NewInfluxExporter(address string, aggroConfig *aggro.AggroConfig) *LineExporter {
+	return &LineExporter{format: "influx", sender: newLineSender(address), aggroConfig: aggroConfig}
+}
+
+// NewGraphiteExporter creates an exporter writing the Graphite plaintext protocol with tags
+func NewGraphiteExporter(address string, aggroConfig *aggro.AggroConfig) *LineExporter {
+	return &LineExporter{format: "graphite", sender: newLineSender(address), aggroConfig: aggroConfig}
+}
+
+// Temporality returns delta temporality for counters and histograms sent to StatsD, which aggregates
+// increments itself, and cumulative temporality otherwise
+func (e *LineExporter) Temporality(kind sdkmetric.InstrumentKind) metricdata.Temporality {
+	if e.format == "statsd" || e.format == "dogstatsd" {
+		return deltaTemporality(kind)
+	}
+	return metricdata.CumulativeTemporality
+}
+
+// Aggregation returns the SDK default aggregation
+func (e *LineExporter) Aggregation(kind sdkmetric.InstrumentKind) sdkmetric.Aggregation {
+	return sdkmetric.DefaultAggregationSelector(kind)
+}
+
+// Export sends the metrics as lines
+func (e *LineExporter) Export(ctx context.Context, rm *metricdata.ResourceMetrics) error {
+	points := linePoints(rm)
+	if len(points) == 0 {
+		return nil
+	}
+	if e.aggroConfig != nil {
+		if target, ok := e.aggroConfig.AggroMetricFormatCase(); ok {
+			applyLineAggro(&points[randomness.Intn(len(points))], target)
+		}
+	}
+
+	var lines []string
+	switch e.format {
+	case "influx":
+		lines = influxLines(points)
+	case "graphite":
+		lines = graphiteLines(points)
+	default:
+		e.mu.Lock()
+		lines = statsdLines(points, e.format == "dogstatsd", e.previous)
+		e.mu.Unlock()
+	}
+	return e.sender.send(ctx, lines)
+}
+
+// ForceFlush does nothing: every export is sent immediately
+func (e *LineExporter) ForceFlush(context.Context) error {
+	return nil
+}
+
+// Shutdown closes the connection
+func (e *LineExporter) Shutdown(context.Context) error {
+	return e.sender.close()
+}
+
+// linePoint is one data point, flattened for the line protocols
+type linePoint struct {
+	name       string
+	kind       string      // counter, gauge or histogram
+	tags       [][2]string // Resource and point attributes, sorted by key
+	value      float64
+	integer    bool
+	delta      bool      // The value or the histogram counts are increments since the last export
+	bounds     []float64 // Histogram upper bounds
+	counts     []uint64  // Cumulative count of each bound; count is the +Inf bucket
+	count      uint64
+	sum        float64
+	timestamp  time.Time
+	sampleRate string // StatsD sample rate override, set by aggro
+}
+
+// linePoints flattens the resource metrics; monotonic sums are counters, other sums and gauges are gauges,
+// and exponential histograms are converted to classic buckets at their boundaries
+func linePoints(rm *metricdata.ResourceMetrics) []linePoint {
+	var resourceTags [][2]string
+	if rm.Resource != nil {
+		for _, kv := range rm.Resource.Attributes() {
+			resourceTags = append(resourceTags, [2]string{string(kv.Key), kv.Value.Emit()})
+		}
+	}
+	tagsOf := func(attrs attribute.Set) [][2]string {
+		tags := slices.Clone(resourceTags)
+		for _, kv := range attrs.ToSlice() {
+			tags = append(tags, [2]string{string(kv.Key), kv.Value.Emit()})
+		}
+		sort.SliceStable(tags, func(i, j int) bool { return tags[i][0] < tags[j][0] })
+		return tags
+	}
+
+	var points []linePoint
+	for _, sm := range rm.ScopeMetrics {
+		for _, m := range sm.Metrics {
+			switch data := m.Data.(type) {
+			case metricdata.Sum[int64]:
+				points = appendSumPoints(points, m.Name, data, tagsOf, true)
+			case metricdata.Sum[float64]:
+				points = appendSumPoints(points, m.Name, data, tagsOf, false)
+			case metricdata.Gauge[int64]:
+				for _, dp := range data.DataPoints {
+					points = append(points, linePoint{name: m.Name, kind: "gauge", tags: tagsOf(dp.Attributes), value: float64(dp.Value), integer: true, timestamp: dp.Time})
+				}
+			case metricdata.Gauge[float64]:
+				for _, dp := range data.DataPoints {
+					points = append(points, linePoint{name: m.Name, kind: "gauge", tags: tagsOf(dp.Attributes), value: dp.Value, timestamp: dp.Time})
+				}
+			case metricdata.Histogram[int64]:
+				points = appendHistogramPoints(points, m.Name, data, tagsOf)
+			case metricdata.Histogram[float64]:
+				points = appendHistogramPoints(points, m.Name, data, tagsOf)
+			case metricdata.ExponentialHistogram[int64]:
+				points = appendExponentialHistogramPoints(points, m.Name, data, tagsOf)
+			case metricdata.ExponentialHistogram[float64]:
+				points = appendExponentialHistogramPoints(points, m.Name, data, tagsOf)
+			}
+		}
+	}
+	return points
+}
+
+func appendSumPoints[N int64 | float64](points []linePoint, name string, data metricdata.Sum[N], tagsOf func(attribute.Set) [][2]string, integer bool) []linePoint {
+	kind := "gauge"
+	if data.IsMonotonic {
+		kind = "counter"
+	}
+	for _, dp := range data.DataPoints {
+		points = append(points, linePoint{name: name, kind: kind, tags: tagsOf(dp.Attributes), value: float64(dp.Value), integer: integer,
+			delta: data.Temporality == metricdata.DeltaTemporality, timestamp: dp.Time})
+	}
+	return points
+}
+
+func appendHistogramPoints[N int64 | float64](points []linePoint, name string, data metricdata.Histogram[N], tagsOf func(attribute.Set) [][2]string) []linePoint {
+	for _, dp := range data.DataPoints {
+		points = append(points, linePoint{name: name, kind: "histogram", tags: tagsOf(dp.Attributes), bounds: dp.Bounds, counts: prometheus.CumulativeCounts(dp.BucketCounts),
+			count: dp.Count, sum: float64(dp.Sum), delta: data.Temporality == metricdata.DeltaTemporality, timestamp: dp.Time})
+	}
+	return points
+}
+
+func appendExponentialHistogramPoints[N int64 | float64](points []linePoint, name string, data metricdata.ExponentialHistogram[N], tagsOf func(attribute.Set) [][2]string) []linePoint {
+	for _, dp := range data.DataPoints {
+		bounds, counts := prometheus.ExponentialBuckets(dp)
+		points = append(points, linePoint{name: name, kind: "histogram", tags: tagsOf(dp.Attributes), bounds: bounds, counts: counts,
+			count: dp.Count, sum: float64(dp.Sum), delta: data.Temporality == metricdata.DeltaTemporality, timestamp: dp.Time})
+	}
+	return points
+}
+
+// applyLineAggro puts one format fault in a point, tagged with aggro.metric_format
+func applyLineAggro(point *linePoint, target string) {
+	switch target {
+	case "tag-escaping":
+		tags := aggro.GetAggroTagStrings()
+		point.tags = append(point.tags, [2]string{"aggro " + tags[randomness.Intn(len(tags))], tags[randomness.Intn(len(tags))]})
+	case "sample-rate":
+		point.sampleRate = randomness.Choice(aggro.GetAggroSampleRates())
+	case "name-sanitization":
+		point.name = randomness.Choice(aggro.GetAggroMetricNames())
+	}
+	point.tags = append(point.tags, [2]string{"aggro.metric_format", target})
+}
+
+// lineSender writes lines to a UDP or TCP address, or posts them to an HTTP endpoint
+type lineSender struct {
+	network string // udp, tcp or http
+	address string // host:port, or the URL for http
+	client  *http.Client
+
+	mu   sync.Mutex
+	conn net.Conn // Dialed on first send and again after a write error
+}
+
+// maxDatagram keeps UDP packets within a typical Ethernet MTU, as StatsD clients do
+const maxDatagram = 1432
+
+func newLineSender(address string) *lineSender {
+	network, hostPort, _ := strings.Cut(address, "://")
+	if network == "http" || network == "https" {
+		return &lineSender{network: "http", address: address, client: &http.Client{Timeout: 30 * time.Second}}
+	}
+	return &lineSender{network: network, address: hostPort}
+}
+
+// send writes newline-terminated lines; over UDP lines are packed into datagrams of at most maxDatagram
+// bytes, a longer line going alone into its own datagram
+func (s *lineSender) send(ctx context.Context, lines []string) error {
+	if len(lines) == 0 {
+		return nil
+	}
+	if s.network == "http" {
+		_, err := postBatch(ctx, s.client, s.address, "text/plain; charset=utf-8", []byte(strings.Join(lines, "\n")+"\n"))
+		return err
+	}
+
+	var packets [][]byte
+	if s.network == "udp" {
+		var packet []byte
+		for _, line := range lines {
+			if len(packet) > 0 && len(packet)+len(line)+1 > maxDatagram {
+				packets = append(packets, packet)
+				packet = nil
+			}
+			packet = append(append(packet, line...), '\n')
+		}
+		packets = append(packets, packet)
+	} else {
+		packets = [][]byte{[]byte(strings.Join(lines, "\n") + "\n")}
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.conn == nil {
+		var dialer net.Dialer
+		conn, err := dialer.DialContext(ctx, s.network, s.address)
+		if err != nil {
+			return err
+		}
+		s.conn = conn
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		s.conn.SetWriteDeadline(deadline)
+	} else {
+		s.conn.SetWriteDeadline(time.Now().Add(30 * time.Second))
+	}
+	for _, packet := range packets {
+		if _, err := s.conn.Write(packet); err != nil {
+			s.conn.Close()
+			s.conn = nil
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *lineSender) close() error {
+	if s.client != nil {
+		s.client.CloseIdleConnections()
+		return nil
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.conn == nil {
+		return nil
+	}
+	err := s.conn.Close()
+	s.conn = nil
+	return err
+}
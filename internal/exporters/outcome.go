@@ -0,0 +1,445 @@
+package exporters
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/spf13/viper"
+	sdklog "go.opentelemetry.io/otel/sdk/log"
+	"go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+	"go.opentelemetry.io/otel/sdk/trace"
+	collogspb "go.opentelemetry.io/proto/otlp/collector/logs/v1"
+	colmetricspb "go.opentelemetry.io/proto/otlp/collector/metrics/v1"
+	coltracepb "go.opentelemetry.io/proto/otlp/collector/trace/v1"
+	"google.golang.org/grpc"
+)
+
+// OutcomeCounts are the export outcomes of one signal, in spans, log records or metric data points,
+// summed over all exporters of the signal
+type OutcomeCounts struct {
+	Exported int64 `json:"exported"` // Accepted by the receiving end
+	Failed   int64 `json:"failed"`   // In exports that returned an error, after all retries
+	Retried  int64 `json:"retried"`  // Sent again after a failed attempt, once per retry
+	Dropped  int64 `json:"dropped"`  // Handed to a batch processor but never exported, e.g. on a full queue
+	Rejected int64 `json:"rejected"` // Rejected by a collector in an OTLP partial success response
+}
+
+// Undelivered returns the items that did not reach the receiving end
+func (counts OutcomeCounts) Undelivered() int64 {
+	return counts.Failed + counts.Dropped + counts.Rejected
+}
+
+// Total returns all items, delivered or not
+func (counts OutcomeCounts) Total() int64 {
+	return counts.Exported + counts.Undelivered()
+}
+
+// OutcomeSummary holds the export outcomes of all signals that exported anything
+type OutcomeSummary struct {
+	Signals      map[string]OutcomeCounts `json:"signals"`
+	FailureRatio float64                  `json:"failure_ratio"` // Undelivered items over all items
+}
+
+// Signals in summary order
+var outcomeSignals = []string{"traces", "logs", "metrics"}
+
+// outcomeCounters accumulate the outcomes of one signal; submitted counts the items handed to batch
+// processors, of which those neither exported nor failed were dropped, and generated counts every item
+// once, whatever the number of exporters
+type outcomeCounters struct {
+	generated, submitted, exported, failed, retried, rejected atomic.Int64
+}
+
+var outcomes = map[string]*outcomeCounters{"traces": {}, "logs": {}, "metrics": {}}
+
+// ResetOutcomes clears the export outcomes of all signals
+func ResetOutcomes() {
+	for _, signal := range outcomeSignals {
+		outcomes[signal].generated.Store(0)
+		outcomes[signal].submitted.Store(0)
+		outcomes[signal].exported.Store(0)
+		outcomes[signal].failed.Store(0)
+		outcomes[signal].retried.Store(0)
+		outcomes[signal].rejected.Store(0)
+	}
+}
+
+// Outcomes returns the export outcomes so far; it is complete once the providers are shut down
+func Outcomes() *OutcomeSummary {
+	summary := &OutcomeSummary{Signals: make(map[string]OutcomeCounts)}
+	var undelivered, total int64
+	for _, signal := range outcomeSignals {
+		counters := outcomes[signal]
+		counts := OutcomeCounts{
+			Exported: counters.exported.Load(),
+			Failed:   counters.failed.Load(),
+			Retried:  counters.retried.Load(),
+			Rejected: counters.rejected.Load(),
+		}
+		if submitted := counters.submitted.Load(); submitted > 0 {
+			counts.Dropped = max(0, submitted-counts.Exported-counts.Rejected-counts.Failed)
+		}
+		if counts.Total() == 0 && counts.Retried == 0 {
+			continue
+		}
+		summary.Signals[signal] = counts
+		undelivered += counts.Undelivered()
+		total += counts.Total()
+	}
+	if total > 0 {
+		summary.FailureRatio = float64(undelivered) / float64(total)
+	}
+	return summary
+}
+
+// Generated returns the spans, log records and metric data points generated so far, per signal
+func Generated() map[string]int64 {
+	generated := make(map[string]int64)
+	for _, signal := range outcomeSignals {
+		generated[signal] = outcomes[signal].generated.Load()
+	}
+	return generated
+}
+
+// Pending returns the spans and log records handed to batch processors and not yet exported, per signal
+// Dropped items never leave it, so a count that keeps growing past the queue size shows a dropping queue
+func Pending() map[string]int64 {
+	pending := make(map[string]int64)
+	for _, signal := range []string{"traces", "logs"} {
+		counters := outcomes[signal]
+		pending[signal] = max(0, counters.submitted.Load()-counters.exported.Load()-counters.failed.Load()-counters.rejected.Load())
+	}
+	return pending
+}
+
+// ExportObserver is told the signal, size, duration and error of every export
+type ExportObserver func(signal string, items int64, duration time.Duration, err error)
+
+var (
+	observersMu sync.Mutex
+	observers   = make(map[int]ExportObserver)
+	nextID      int
+)
+
+// ObserveExports registers an observer of every export and returns a function unregistering it
+func ObserveExports(observer ExportObserver) func() {
+	observersMu.Lock()
+	defer observersMu.Unlock()
+	id := nextID
+	nextID++
+	observers[id] = observer
+	return func() {
+		observersMu.Lock()
+		defer observersMu.Unlock()
+		delete(observers, id)
+	}
+}
+
+// notifyObservers passes one export to every observer
+func notifyObservers(signal string, items int64, duration time.Duration, err error) {
+	observersMu.Lock()
+	defer observersMu.Unlock()
+	for _, observer := range observers {
+		observer(signal, items, duration, err)
+	}
+}
+
+// Write prints the summary as text or JSON
+func (summary *OutcomeSummary) Write(w io.Writer, format string) error {
+	if format == "json" {
+		return json.NewEncoder(w).Encode(summary)
+	}
+	if len(summary.Signals) == 0 {
+		return nil
+	}
+	fmt.Fprintln(w, "Export summary:")
+	for _, signal := range outcomeSignals {
+		counts, ok := summary.Signals[signal]
+		if !ok {
+			continue
+		}
+		fmt.Fprintf(w, "  %-8s %d exported, %d failed, %d retried, %d dropped, %d rejected\n",
+			signal+":", counts.Exported, counts.Failed, counts.Retried, counts.Dropped, counts.Rejected)
+	}
+	_, err := fmt.Fprintf(w, "  failure ratio: %.4f\n", summary.FailureRatio)
+	return err
+}
+
+// SummaryConfig holds the final summary and exit code settings
+type SummaryConfig struct {
+	Format          string  // text, json or none
+	MaxFailureRatio float64 // Highest failure ratio that still exits with status 0
+}
+
+// Supported summary formats
+var summaryFormats = []string{"text", "json", "none"}
+
+// ParseSummaryConfig reads the summary settings from viper
+func ParseSummaryConfig() (*SummaryConfig, error) {
+	config := &SummaryConfig{
+		Format:          viper.GetString("summary.format"),
+		MaxFailureRatio: viper.GetFloat64("summary.max_failure_ratio"),
+	}
+	if config.Format == "" {
+		config.Format = "text"
+	}
+	if err := config.Validate(); err != nil {
+		return nil, err
+	}
+	return config, nil
+}
+
+// Validate checks the format and ratio
+func (config *SummaryConfig) Validate() error {
+	if !slices.Contains(summaryFormats, config.Format) {
+		return fmt.Errorf("unsupported summary format '%s' (supported: text, json, none)", config.Format)
+	}
+	if config.MaxFailureRatio < 0 || config.MaxFailureRatio > 1 {
+		return fmt.Errorf("max failure ratio must be between 0 and 1, got %g", config.MaxFailureRatio)
+	}
+	return nil
+}
+
+// Exceeded reports whether a summary fails the run
+func (config *SummaryConfig) Exceeded(summary *OutcomeSummary) bool {
+	return summary.FailureRatio > config.MaxFailureRatio
+}
+
+// outcomeProbe watches the calls of one OTLP gRPC exporter, counting attempts and the items the
+// collector rejects in partial success responses
+type outcomeProbe struct {
+	attempts atomic.Int64
+	rejected atomic.Int64
+}
+
+// intercept is a unary client interceptor recording every export attempt
+func (probe *outcomeProbe) intercept(ctx context.Context, method string, req, reply any, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+	probe.attempts.Add(1)
+	err := invoker(ctx, method, req, reply, cc, opts...)
+	if err != nil {
+		return err
+	}
+	switch response := reply.(type) {
+	case *coltracepb.ExportTraceServiceResponse:
+		probe.rejected.Add(response.GetPartialSuccess().GetRejectedSpans())
+	case *collogspb.ExportLogsServiceResponse:
+		probe.rejected.Add(response.GetPartialSuccess().GetRejectedLogRecords())
+	case *colmetricspb.ExportMetricsServiceResponse:
+		probe.rejected.Add(response.GetPartialSuccess().GetRejectedDataPoints())
+	}
+	return nil
+}
+
+// newOutcomeProbe returns a probe and the dial option installing it
+func newOutcomeProbe() (*outcomeProbe, grpc.DialOption) {
+	probe := &outcomeProbe{}
+	return probe, grpc.WithChainUnaryInterceptor(probe.intercept)
+}
+
+// probed is implemented by the OTLP exporters the factory attaches a probe to
+type probed interface {
+	probe() *outcomeProbe
+}
+
+type probedSpanExporter struct {
+	trace.SpanExporter
+	attached *outcomeProbe
+}
+
+func (e probedSpanExporter) probe() *outcomeProbe { return e.attached }
+
+type probedLogExporter struct {
+	sdklog.Exporter
+	attached *outcomeProbe
+}
+
+func (e probedLogExporter) probe() *outcomeProbe { return e.attached }
+
+type probedMetricExporter struct {
+	metric.Exporter
+	attached *outcomeProbe
+}
+
+func (e probedMetricExporter) probe() *outcomeProbe { return e.attached }
+
+// record adds the outcome of one export of items to a signal; the probe, when the exporter has one,
+// tells how often the export was retried and how many items were rejected
+func record(signal string, exporter any, items int64, export func() error) error {
+	counters := outcomes[signal]
+	var probe *outcomeProbe
+	var attempts, rejected int64
+	if p, ok := exporter.(probed); ok {
+		probe = p.probe()
+		attempts, rejected = probe.attempts.Load(), probe.rejected.Load()
+		defer func() {
+			if retries := probe.attempts.Load() - attempts - 1; retries > 0 {
+				counters.retried.Add(retries * items)
+			}
+		}()
+	}
+
+	start := time.Now()
+	err := export()
+	notifyObservers(signal, items, time.Since(start), err)
+	if err != nil {
+		counters.failed.Add(items)
+		return err
+	}
+	if probe != nil {
+		rejected = min(items, probe.rejected.Load()-rejected)
+		counters.rejected.Add(rejected)
+	} else {
+		rejected = 0
+	}
+	counters.exported.Add(items - rejected)
+	return nil
+}
+
+// countingSpanExporter records the outcome of every span export
+type countingSpanExporter struct {
+	trace.SpanExporter
+}
+
+func (e countingSpanExporter) ExportSpans(ctx context.Context, spans []trace.ReadOnlySpan) error {
+	return record("traces", e.SpanExporter, int64(len(spans)), func() error {
+		return e.SpanExporter.ExportSpans(ctx, spans)
+	})
+}
+
+// GeneratedSpanCounter returns a processor counting every generated span; providers register it once,
+// next to the processors of their exporters
+func GeneratedSpanCounter() trace.SpanProcessor {
+	return generatedSpanCounter{}
+}
+
+type generatedSpanCounter struct{}
+
+func (generatedSpanCounter) OnStart(context.Context, trace.ReadWriteSpan) {}
+func (generatedSpanCounter) OnEnd(s trace.ReadOnlySpan) {
+	if s.SpanContext().IsSampled() {
+		outcomes["traces"].generated.Add(1)
+	}
+}
+func (generatedSpanCounter) Shutdown(context.Context) error   { return nil }
+func (generatedSpanCounter) ForceFlush(context.Context) error { return nil }
+
+// countingSpanProcessor counts the spans handed to a processor
+type countingSpanProcessor struct {
+	trace.SpanProcessor
+}
+
+func (p countingSpanProcessor) OnEnd(s trace.ReadOnlySpan) {
+	if s.SpanContext().IsSampled() {
+		outcomes["traces"].submitted.Add(1)
+	}
+	p.SpanProcessor.OnEnd(s)
+}
+
+// countingLogExporter records the outcome of every log export
+type countingLogExporter struct {
+	sdklog.Exporter
+}
+
+func (e countingLogExporter) Export(ctx context.Context, records []sdklog.Record) error {
+	return record("logs", e.Exporter, int64(len(records)), func() error {
+		return e.Exporter.Export(ctx, records)
+	})
+}
+
+// GeneratedLogCounter returns a processor counting every generated log record; providers register it
+// once, next to the processors of their exporters
+func GeneratedLogCounter() sdklog.Processor {
+	return generatedLogCounter{}
+}
+
+type generatedLogCounter struct{}
+
+func (generatedLogCounter) OnEmit(context.Context, *sdklog.Record) error {
+	outcomes["logs"].generated.Add(1)
+	return nil
+}
+func (generatedLogCounter) Shutdown(context.Context) error   { return nil }
+func (generatedLogCounter) ForceFlush(context.Context) error { return nil }
+
+// countingLogProcessor counts the log records handed to a processor
+type countingLogProcessor struct {
+	sdklog.Processor
+}
+
+func (p countingLogProcessor) OnEmit(ctx context.Context, record *sdklog.Record) error {
+	outcomes["logs"].submitted.Add(1)
+	return p.Processor.OnEmit(ctx, record)
+}
+
+// countingMetricExporter records the outcome of every metric export; the first exporter of a provider
+// also counts the data points as generated, since every reader collects the same ones
+type countingMetricExporter struct {
+	metric.Exporter
+	first bool
+}
+
+func (e countingMetricExporter) Export(ctx context.Context, rm *metricdata.ResourceMetrics) error {
+	items := int64(dataPointCount(rm))
+	if e.first {
+		outcomes["metrics"].generated.Add(items)
+	}
+	return record("metrics", e.Exporter, items, func() error {
+		return e.Exporter.Export(ctx, rm)
+	})
+}
+
+// CountMetricExporters wraps metric exporters so their exports are included in the outcomes
+func CountMetricExporters(exporters []metric.Exporter) []metric.Exporter {
+	counted := make([]metric.Exporter, len(exporters))
+	for i, exporter := range exporters {
+		pool, ok := exporter.(*metricExporterPool)
+		if !ok {
+			counted[i] = countingMetricExporter{exporter, i == 0}
+			continue
+		}
+		// Members are counted one by one so each keeps its probe
+		members := make([]metric.Exporter, len(pool.members))
+		for j, member := range pool.members {
+			members[j] = countingMetricExporter{member, i == 0}
+		}
+		counted[i] = &metricExporterPool{members: members}
+	}
+	return counted
+}
+
+// dataPointCount returns the number of data points in resource metrics
+func dataPointCount(rm *metricdata.ResourceMetrics) int {
+	count := 0
+	for _, sm := range rm.ScopeMetrics {
+		for _, m := range sm.Metrics {
+			switch data := m.Data.(type) {
+			case metricdata.Gauge[int64]:
+				count += len(data.DataPoints)
+			case metricdata.Gauge[float64]:
+				count += len(data.DataPoints)
+			case metricdata.Sum[int64]:
+				count += len(data.DataPoints)
+			case metricdata.Sum[float64]:
+				count += len(data.DataPoints)
+			case metricdata.Histogram[int64]:
+				count += len(data.DataPoints)
+			case metricdata.Histogram[float64]:
+				count += len(data.DataPoints)
+			case metricdata.ExponentialHistogram[int64]:
+				count += len(data.DataPoints)
+			case metricdata.ExponentialHistogram[float64]:
+				count += len(data.DataPoints)
+			case metricdata.Summary:
+				count += len(data.DataPoints)
+			}
+		}
+	}
+	return count
+}
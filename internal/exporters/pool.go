@@ -0,0 +1,185 @@
+package exporters
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"sync/atomic"
+
+	sdklog "go.opentelemetry.io/otel/sdk/log"
+	"go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+	"go.opentelemetry.io/otel/sdk/trace"
+	oteltrace "go.opentelemetry.io/otel/trace"
+)
+
+// spanExporterPool holds several instances of one exporter, each with its own connection
+// Used directly it sends each export over the next instance; batch processors instead get one
+// processor per instance so the connections export concurrently
+type spanExporterPool struct {
+	members []trace.SpanExporter
+	next    atomic.Uint64
+}
+
+// newSpanExporterPool returns the only exporter, or a pool of several
+func newSpanExporterPool(members []trace.SpanExporter) trace.SpanExporter {
+	if len(members) == 1 {
+		return members[0]
+	}
+	return &spanExporterPool{members: members}
+}
+
+func (p *spanExporterPool) ExportSpans(ctx context.Context, spans []trace.ReadOnlySpan) error {
+	return p.members[(p.next.Add(1)-1)%uint64(len(p.members))].ExportSpans(ctx, spans)
+}
+
+func (p *spanExporterPool) Shutdown(ctx context.Context) error {
+	var errs []error
+	for _, member := range p.members {
+		errs = append(errs, member.Shutdown(ctx))
+	}
+	return errors.Join(errs...)
+}
+
+// logExporterPool is the log counterpart of spanExporterPool
+type logExporterPool struct {
+	members []sdklog.Exporter
+	next    atomic.Uint64
+}
+
+// newLogExporterPool returns the only exporter, or a pool of several
+func newLogExporterPool(members []sdklog.Exporter) sdklog.Exporter {
+	if len(members) == 1 {
+		return members[0]
+	}
+	return &logExporterPool{members: members}
+}
+
+func (p *logExporterPool) Export(ctx context.Context, records []sdklog.Record) error {
+	return p.members[(p.next.Add(1)-1)%uint64(len(p.members))].Export(ctx, records)
+}
+
+func (p *logExporterPool) Shutdown(ctx context.Context) error {
+	var errs []error
+	for _, member := range p.members {
+		errs = append(errs, member.Shutdown(ctx))
+	}
+	return errors.Join(errs...)
+}
+
+func (p *logExporterPool) ForceFlush(ctx context.Context) error {
+	var errs []error
+	for _, member := range p.members {
+		errs = append(errs, member.ForceFlush(ctx))
+	}
+	return errors.Join(errs...)
+}
+
+// metricExporterPool sends each metric export over the next of several exporter instances
+// The instances share their temporality and aggregation, so the first one answers for all
+type metricExporterPool struct {
+	members []metric.Exporter
+	next    atomic.Uint64
+}
+
+// newMetricExporterPool returns the only exporter, or a pool of several
+func newMetricExporterPool(members []metric.Exporter) metric.Exporter {
+	if len(members) == 1 {
+		return members[0]
+	}
+	return &metricExporterPool{members: members}
+}
+
+func (p *metricExporterPool) Temporality(kind metric.InstrumentKind) metricdata.Temporality {
+	return p.members[0].Temporality(kind)
+}
+
+func (p *metricExporterPool) Aggregation(kind metric.InstrumentKind) metric.Aggregation {
+	return p.members[0].Aggregation(kind)
+}
+
+func (p *metricExporterPool) Export(ctx context.Context, rm *metricdata.ResourceMetrics) error {
+	return p.members[(p.next.Add(1)-1)%uint64(len(p.members))].Export(ctx, rm)
+}
+
+func (p *metricExporterPool) ForceFlush(ctx context.Context) error {
+	var errs []error
+	for _, member := range p.members {
+		errs = append(errs, member.ForceFlush(ctx))
+	}
+	return errors.Join(errs...)
+}
+
+func (p *metricExporterPool) Shutdown(ctx context.Context) error {
+	var errs []error
+	for _, member := range p.members {
+		errs = append(errs, member.Shutdown(ctx))
+	}
+	return errors.Join(errs...)
+}
+
+// shardedSpanProcessor spreads spans over one processor per connection by trace ID, so all spans of
+// a trace leave over the same connection, as they would from one instrumented process
+type shardedSpanProcessor struct {
+	shards []trace.SpanProcessor
+}
+
+func (p shardedSpanProcessor) shard(id oteltrace.TraceID) trace.SpanProcessor {
+	return p.shards[binary.BigEndian.Uint64(id[8:])%uint64(len(p.shards))]
+}
+
+func (p shardedSpanProcessor) OnStart(ctx context.Context, s trace.ReadWriteSpan) {
+	p.shard(s.SpanContext().TraceID()).OnStart(ctx, s)
+}
+
+func (p shardedSpanProcessor) OnEnd(s trace.ReadOnlySpan) {
+	p.shard(s.SpanContext().TraceID()).OnEnd(s)
+}
+
+func (p shardedSpanProcessor) Shutdown(ctx context.Context) error {
+	var errs []error
+	for _, shard := range p.shards {
+		errs = append(errs, shard.Shutdown(ctx))
+	}
+	return errors.Join(errs...)
+}
+
+func (p shardedSpanProcessor) ForceFlush(ctx context.Context) error {
+	var errs []error
+	for _, shard := range p.shards {
+		errs = append(errs, shard.ForceFlush(ctx))
+	}
+	return errors.Join(errs...)
+}
+
+// shardedLogProcessor spreads log records over one processor per connection, by trace ID when the
+// record has one and in turn otherwise
+type shardedLogProcessor struct {
+	shards []sdklog.Processor
+	next   *atomic.Uint64
+}
+
+func (p shardedLogProcessor) OnEmit(ctx context.Context, record *sdklog.Record) error {
+	n := uint64(len(p.shards))
+	i := (p.next.Add(1) - 1) % n
+	if id := record.TraceID(); id.IsValid() {
+		i = binary.BigEndian.Uint64(id[8:]) % n
+	}
+	return p.shards[i].OnEmit(ctx, record)
+}
+
+func (p shardedLogProcessor) Shutdown(ctx context.Context) error {
+	var errs []error
+	for _, shard := range p.shards {
+		errs = append(errs, shard.Shutdown(ctx))
+	}
+	return errors.Join(errs...)
+}
+
+func (p shardedLogProcessor) ForceFlush(ctx context.Context) error {
+	var errs []error
+	for _, shard := range p.shards {
+		errs = append(errs, shard.ForceFlush(ctx))
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,260 @@
+package exporters
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net/url"
+	"os"
+	"strings"
+
+	"github.com/spf13/viper"
+)
+
+// OTLPSecurityConfig holds the transport security and authentication settings of the OTLP exporters
+// Settings come from the otlp-* flags or the otlp section of the config file, which take precedence over
+// the standard OTEL_EXPORTER_OTLP_* environment variables; OTEL_EXPORTER_OTLP_<SIGNAL>_* variables
+// configure the exporters of one signal over the generic ones
+type OTLPSecurityConfig struct {
+	TLS                bool   // Use TLS with the system roots even without any other TLS setting
+	CAFile             string // PEM bundle verifying the collector's certificate
+	CertFile           string // PEM client certificate for mTLS
+	KeyFile            string // PEM client key for mTLS
+	ServerName         string // Overrides the name the collector's certificate is verified against
+	InsecureSkipVerify bool
+	Headers            map[string]string
+	BearerTokenFile    string // File holding a token sent as "Authorization: Bearer <token>"
+	EnvInsecure        string // OTEL_EXPORTER_OTLP_INSECURE, used when nothing else decides between TLS and plaintext
+}
+
+// ParseOTLPSecurityConfig reads the OTLP security settings from viper and the environment
+func ParseOTLPSecurityConfig() (*OTLPSecurityConfig, error) {
+	config := &OTLPSecurityConfig{
+		TLS:                viper.GetBool("otlp.tls"),
+		CAFile:             viper.GetString("otlp.ca_file"),
+		CertFile:           viper.GetString("otlp.cert_file"),
+		KeyFile:            viper.GetString("otlp.key_file"),
+		ServerName:         viper.GetString("otlp.server_name"),
+		InsecureSkipVerify: viper.GetBool("otlp.insecure_skip_verify"),
+		Headers:            make(map[string]string),
+		BearerTokenFile:    viper.GetString("otlp.bearer_token_file"),
+		EnvInsecure:        strings.ToLower(strings.TrimSpace(os.Getenv("OTEL_EXPORTER_OTLP_INSECURE"))),
+	}
+
+	headers, err := parseOTLPHeaders("OTEL_EXPORTER_OTLP_HEADERS")
+	if err != nil {
+		return nil, err
+	}
+	config.Headers = headers
+
+	if err := config.Validate(); err != nil {
+		return nil, err
+	}
+	return config, nil
+}
+
+// parseOTLPHeaders reads the headers of the named environment variable, with the otlp-header flags winning per key
+// The variable is a comma-separated list of key=value pairs with URL-encoded values
+func parseOTLPHeaders(envName string) (map[string]string, error) {
+	headers := make(map[string]string)
+	if env := os.Getenv(envName); env != "" {
+		for _, pair := range strings.Split(env, ",") {
+			key, value, ok := strings.Cut(pair, "=")
+			if !ok || strings.TrimSpace(key) == "" {
+				return nil, fmt.Errorf("invalid %s entry '%s': expected key=value", envName, pair)
+			}
+			if decoded, err := url.PathUnescape(strings.TrimSpace(value)); err == nil {
+				value = decoded
+			}
+			headers[strings.TrimSpace(key)] = value
+		}
+	}
+	for _, header := range viper.GetStringSlice("otlp.headers") {
+		key, value, ok := strings.Cut(header, "=")
+		if !ok || strings.TrimSpace(key) == "" {
+			return nil, fmt.Errorf("invalid header '%s': expected key=value", header)
+		}
+		headers[strings.TrimSpace(key)] = value
+	}
+	return headers, nil
+}
+
+// otlpSignals are the signals whose exporters OTEL_EXPORTER_OTLP_<SIGNAL>_* variables configure on their own
+var otlpSignals = []string{"traces", "metrics", "logs"}
+
+// parseSignalOTLPConfig returns the security settings of one signal, its own variables replacing the generic ones,
+// and its own endpoint ("" = the generic one); it reports false when the signal has no settings of its own
+// The otlp.<signal>.* keys are bound to the same flags as the generic keys, so flags still take precedence
+func parseSignalOTLPConfig(signal string, generic *OTLPSecurityConfig) (*OTLPSecurityConfig, string, bool, error) {
+	prefix := "otlp." + signal + "."
+	envPrefix := "OTEL_EXPORTER_OTLP_" + strings.ToUpper(signal) + "_"
+	config := *generic
+	found := false
+
+	for key, setting := range map[string]*string{"ca_file": &config.CAFile, "cert_file": &config.CertFile, "key_file": &config.KeyFile} {
+		if viper.IsSet(prefix + key) {
+			*setting = viper.GetString(prefix + key)
+			found = true
+		}
+	}
+	if os.Getenv(envPrefix+"HEADERS") != "" {
+		headers, err := parseOTLPHeaders(envPrefix + "HEADERS")
+		if err != nil {
+			return nil, "", false, err
+		}
+		config.Headers = headers
+		found = true
+	}
+	if env := os.Getenv(envPrefix + "INSECURE"); env != "" {
+		config.EnvInsecure = strings.ToLower(strings.TrimSpace(env))
+		found = true
+	}
+	endpoint := ""
+	if viper.IsSet(prefix + "endpoint") {
+		endpoint = viper.GetString(prefix + "endpoint")
+		found = true
+	}
+
+	if err := config.Validate(); err != nil {
+		return nil, "", false, fmt.Errorf("%s: %w", signal, err)
+	}
+	return &config, endpoint, found, nil
+}
+
+// Validate checks that the client certificate and key come together and that the files exist
+func (config *OTLPSecurityConfig) Validate() error {
+	if (config.CertFile == "") != (config.KeyFile == "") {
+		return fmt.Errorf("client certificate and key must be given together")
+	}
+	for _, file := range []string{config.CAFile, config.CertFile, config.KeyFile, config.BearerTokenFile} {
+		if file == "" {
+			continue
+		}
+		if _, err := os.Stat(file); err != nil {
+			return err
+		}
+	}
+	if config.EnvInsecure != "" && config.EnvInsecure != "true" && config.EnvInsecure != "false" {
+		return fmt.Errorf("invalid OTEL_EXPORTER_OTLP_INSECURE '%s': expected true or false", config.EnvInsecure)
+	}
+	return nil
+}
+
+// Enabled reports whether any TLS setting is given, which turns TLS on
+func (config *OTLPSecurityConfig) Enabled() bool {
+	return config.TLS || config.CAFile != "" || config.CertFile != "" || config.ServerName != "" || config.InsecureSkipVerify
+}
+
+// Apply sets the transport security and headers of an exporter configuration
+// An endpoint given as a URL is reduced to host:port, its scheme choosing TLS (https) or plaintext (http)
+// unless TLS settings are given; without either, OTEL_EXPORTER_OTLP_INSECURE=false turns TLS on, and
+// plaintext remains the default for local collectors
+func (config *OTLPSecurityConfig) Apply(exporterConfig *ExporterConfig) error {
+	secure := config.EnvInsecure == "false"
+	if scheme, _, ok := strings.Cut(exporterConfig.OTLPEndpoint, "://"); ok {
+		u, err := url.Parse(exporterConfig.OTLPEndpoint)
+		if err != nil || u.Host == "" {
+			return fmt.Errorf("invalid OTLP endpoint '%s'", exporterConfig.OTLPEndpoint)
+		}
+		switch scheme {
+		case "https":
+			secure = true
+		case "http":
+			secure = false
+		default:
+			return fmt.Errorf("invalid OTLP endpoint scheme '%s' (supported: http, https)", scheme)
+		}
+		exporterConfig.OTLPEndpoint = u.Host
+	}
+	if config.Enabled() {
+		secure = true
+	}
+	exporterConfig.Insecure = !secure
+
+	if secure {
+		tlsConfig := &tls.Config{
+			ServerName:         config.ServerName,
+			InsecureSkipVerify: config.InsecureSkipVerify,
+			MinVersion:         tls.VersionTLS12,
+		}
+		if config.CAFile != "" {
+			pem, err := os.ReadFile(config.CAFile)
+			if err != nil {
+				return err
+			}
+			tlsConfig.RootCAs = x509.NewCertPool()
+			if !tlsConfig.RootCAs.AppendCertsFromPEM(pem) {
+				return fmt.Errorf("no certificates found in CA file '%s'", config.CAFile)
+			}
+		}
+		if config.CertFile != "" {
+			certificate, err := tls.LoadX509KeyPair(config.CertFile, config.KeyFile)
+			if err != nil {
+				return fmt.Errorf("invalid client certificate: %v", err)
+			}
+			tlsConfig.Certificates = []tls.Certificate{certificate}
+		}
+		exporterConfig.TLSConfig = tlsConfig
+	}
+
+	if len(config.Headers) > 0 || config.BearerTokenFile != "" {
+		headers := make(map[string]string)
+		for key, value := range exporterConfig.Headers {
+			headers[key] = value
+		}
+		for key, value := range config.Headers {
+			headers[key] = value
+		}
+		if config.BearerTokenFile != "" {
+			token, err := os.ReadFile(config.BearerTokenFile)
+			if err != nil {
+				return err
+			}
+			if strings.TrimSpace(string(token)) == "" {
+				return fmt.Errorf("bearer token file '%s' is empty", config.BearerTokenFile)
+			}
+			headers["Authorization"] = "Bearer " + strings.TrimSpace(string(token))
+		}
+		exporterConfig.Headers = headers
+	}
+	return nil
+}
+
+// ConfigureOTLPSecurity parses the OTLP security settings and applies them to an exporter configuration
+// Signals with settings of their own get a configuration of their own in Signals
+func ConfigureOTLPSecurity(exporterConfig *ExporterConfig) error {
+	config, err := ParseOTLPSecurityConfig()
+	if err != nil {
+		return err
+	}
+	base := *exporterConfig
+	if err := config.Apply(exporterConfig); err != nil {
+		return err
+	}
+
+	for _, signal := range otlpSignals {
+		signalConfig, endpoint, ok, err := parseSignalOTLPConfig(signal, config)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			continue
+		}
+		signalExporterConfig := base
+		if endpoint != "" {
+			signalExporterConfig.OTLPEndpoint = endpoint
+			// A signal's endpoint URL is used as is, path included, by the HTTP exporters
+			if u, err := url.Parse(endpoint); err == nil && u.Host != "" && strings.Trim(u.Path, "/") != "" {
+				signalExporterConfig.URLPath = u.Path
+			}
+		}
+		if err := signalConfig.Apply(&signalExporterConfig); err != nil {
+			return fmt.Errorf("%s: %w", signal, err)
+		}
+		if exporterConfig.Signals == nil {
+			exporterConfig.Signals = make(map[string]*ExporterConfig)
+		}
+		exporterConfig.Signals[signal] = &signalExporterConfig
+	}
+	return nil
+}
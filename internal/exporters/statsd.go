@@ -0,0 +1,113 @@
+package exporters
+
+import (
+	"strconv"
+	"strings"
+)
+
+// statsdLines formats points as StatsD lines
+//
+//	name:value|type[|@rate][|#key:value,...]
+//
+// Counters are sent as increments (|c), converted from cumulative values with previous when needed,
+// gauges as absolute values (|g) and histograms as one sampled line per non-empty bucket (|h for
+// DogStatsD, |ms for plain StatsD), so the server rebuilds the bucket counts from the sample rates
+// Tags are only sent with the DogStatsD flavor
+func statsdLines(points []linePoint, dogstatsd bool, previous map[string]float64) []string {
+	var lines []string
+	for _, point := range points {
+		name := statsdName(point.name)
+		suffix := ""
+		if point.sampleRate != "" {
+			suffix = "|@" + point.sampleRate
+		}
+		if dogstatsd {
+			suffix += statsdTags(point.tags)
+		}
+		key := point.name + statsdTags(point.tags)
+
+		switch point.kind {
+		case "counter":
+			value := statsdIncrement(previous, key, point.value, point.delta)
+			lines = append(lines, name+":"+lineValue(value)+"|c"+suffix)
+		case "gauge":
+			if point.value < 0 && !dogstatsd {
+				// A signed value changes a plain StatsD gauge, so it is reset to zero first
+				lines = append(lines, name+":0|g")
+			}
+			lines = append(lines, name+":"+lineValue(point.value)+"|g"+suffix)
+		case "histogram":
+			kind := "|ms"
+			if dogstatsd {
+				kind = "|h"
+			}
+			var below uint64
+			for i := 0; i <= len(point.bounds); i++ {
+				cumulative, bound := point.count, 0.0
+				switch {
+				case i < len(point.bounds):
+					cumulative, bound = point.counts[i], point.bounds[i]
+				case len(point.bounds) > 0:
+					bound = point.bounds[len(point.bounds)-1] // Overflow samples are sent at the highest bound
+				case point.count > 0:
+					bound = point.sum / float64(point.count)
+				}
+				count := statsdIncrement(previous, key+"|le="+strconv.Itoa(i), float64(cumulative-below), point.delta)
+				below = cumulative
+				if count <= 0 {
+					continue
+				}
+				line := name + ":" + lineValue(bound) + kind
+				if point.sampleRate == "" && count > 1 {
+					line += "|@" + strconv.FormatFloat(1/count, 'g', -1, 64)
+				}
+				lines = append(lines, line+suffix)
+			}
+		}
+	}
+	return lines
+}
+
+// statsdIncrement returns the increment of a value since the previous export; previous holds the last
+// cumulative value of each series and a value below it is a reset, whose increment is the value itself
+func statsdIncrement(previous map[string]float64, key string, value float64, delta bool) float64 {
+	if delta || previous == nil {
+		return value
+	}
+	last, seen := previous[key]
+	previous[key] = value
+	if !seen || value < last {
+		return value
+	}
+	return value - last
+}
+
+// statsdName replaces the characters that separate the fields of a StatsD line
+func statsdName(name string) string {
+	return strings.NewReplacer(":", "_", "|", "_", "@", "_", "\n", "_").Replace(name)
+}
+
+// statsdTags formats tags in the DogStatsD form, |#key:value,...; separators in keys and values are replaced
+func statsdTags(tags [][2]string) string {
+	if len(tags) == 0 {
+		return ""
+	}
+	replacer := strings.NewReplacer(",", "_", "|", "_", "#", "_", "\n", "_")
+	var b strings.Builder
+	b.WriteString("|#")
+	for i, tag := range tags {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		b.WriteString(strings.ReplaceAll(replacer.Replace(tag[0]), ":", "_"))
+		if tag[1] != "" {
+			b.WriteString(":" + replacer.Replace(tag[1]))
+		}
+	}
+	return b.String()
+}
+
+// lineValue formats a value the way the line protocols parse it
+func lineValue(value float64) string {
+	return strconv.FormatFloat(value, 'g', -1, 64)
+}
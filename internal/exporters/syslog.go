@@ -0,0 +1,236 @@
+package exporters
+
+import (
+	"context"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	otellog "go.opentelemetry.io/otel/log"
+	sdklog "go.opentelemetry.io/otel/sdk/log"
+	semconv "go.opentelemetry.io/otel/semconv/v1.21.0"
+)
+
+// syslogFacility is the facility of every message: user-level messages
+const syslogFacility = 1
+
+// syslogStructuredDataID is the SD-ID carrying record attributes in RFC5424 messages
+const syslogStructuredDataID = "otel@32473"
+
+// SyslogExporter sends log records as RFC5424 or RFC3164 syslog messages over TCP or UDP
+// Over TCP, RFC5424 messages are framed by octet counting (RFC6587) and RFC3164 messages by newlines;
+// over UDP every message is one datagram
+type SyslogExporter struct {
+	address string
+	format  string
+
+	mu   sync.Mutex
+	conn net.Conn // Dialed on first export and again after a write error
+}
+
+// NewSyslogExporter creates an exporter for a tcp://host:port or udp://host:port address
+func NewSyslogExporter(address, format string) *SyslogExporter {
+	return &SyslogExporter{address: address, format: format}
+}
+
+// Export writes one message per record
+func (e *SyslogExporter) Export(ctx context.Context, records []sdklog.Record) error {
+	if len(records) == 0 {
+		return nil
+	}
+	network, hostPort, err := syslogNetwork(e.address)
+	if err != nil {
+		return err
+	}
+
+	var frames []byte
+	var datagrams [][]byte
+	for i := range records {
+		var message []byte
+		if e.format == "rfc3164" {
+			message = syslogRFC3164(&records[i])
+		} else {
+			message = syslogRFC5424(&records[i])
+		}
+		switch {
+		case network == "udp":
+			datagrams = append(datagrams, message)
+		case e.format == "rfc3164":
+			frames = append(append(frames, message...), '\n')
+		default:
+			frames = append(append(frames, strconv.Itoa(len(message))+" "...), message...)
+		}
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.conn == nil {
+		var dialer net.Dialer
+		e.conn, err = dialer.DialContext(ctx, network, hostPort)
+		if err != nil {
+			e.conn = nil
+			return err
+		}
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		e.conn.SetWriteDeadline(deadline)
+	} else {
+		e.conn.SetWriteDeadline(time.Now().Add(30 * time.Second))
+	}
+	if network == "tcp" {
+		datagrams = [][]byte{frames}
+	}
+	for _, data := range datagrams {
+		if _, err := e.conn.Write(data); err != nil {
+			e.conn.Close()
+			e.conn = nil
+			return err
+		}
+	}
+	return nil
+}
+
+// ForceFlush does nothing: every export is written immediately
+func (e *SyslogExporter) ForceFlush(context.Context) error {
+	return nil
+}
+
+// Shutdown closes the connection
+func (e *SyslogExporter) Shutdown(context.Context) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.conn == nil {
+		return nil
+	}
+	err := e.conn.Close()
+	e.conn = nil
+	return err
+}
+
+// syslogRFC5424 formats a record as an RFC5424 message
+//
+//	<PRI>1 TIMESTAMP HOSTNAME APP-NAME PROCID MSGID [otel@32473 key="value" ...] MSG
+//
+// The event name is the MSGID and the attributes and trace context are structured data
+func syslogRFC5424(r *sdklog.Record) []byte {
+	var b strings.Builder
+	b.WriteString("<" + strconv.Itoa(syslogPriority(r.Severity())) + ">1 ")
+	b.WriteString(recordTime(r).UTC().Format("2006-01-02T15:04:05.000000Z07:00") + " ")
+	b.WriteString(syslogHeaderField(syslogHostname(r), 255) + " ")
+	b.WriteString(syslogHeaderField(serviceName(r.Resource()), 48) + " ")
+	b.WriteString(syslogHeaderField(syslogProcID(r), 128) + " ")
+	b.WriteString(syslogHeaderField(r.EventName(), 32) + " ")
+
+	attrs := recordAttributes(r)
+	if len(attrs) == 0 {
+		b.WriteString("-")
+	} else {
+		b.WriteString("[" + syslogStructuredDataID)
+		for _, attr := range attrs {
+			b.WriteString(" " + syslogParamName(attr[0]) + `="` + syslogParamValue(attr[1]) + `"`)
+		}
+		b.WriteString("]")
+	}
+	if r.Body().Kind() != otellog.KindEmpty {
+		b.WriteString(" " + r.Body().String())
+	}
+	return []byte(b.String())
+}
+
+// syslogRFC3164 formats a record as an RFC3164 (BSD) message
+//
+//	<PRI>Mmm dd hh:mm:ss HOSTNAME TAG[PID]: MSG
+func syslogRFC3164(r *sdklog.Record) []byte {
+	tag := syslogHeaderField(serviceName(r.Resource()), 32)
+	if tag == "-" {
+		tag = "otel-datagen"
+	}
+	if pid := syslogProcID(r); pid != "" {
+		tag += "[" + pid + "]"
+	}
+	var b strings.Builder
+	b.WriteString("<" + strconv.Itoa(syslogPriority(r.Severity())) + ">")
+	b.WriteString(recordTime(r).Format(time.Stamp) + " ")
+	b.WriteString(syslogHeaderField(syslogHostname(r), 255) + " ")
+	b.WriteString(tag + ": " + r.Body().String())
+	return []byte(b.String())
+}
+
+// syslogPriority combines the facility with the syslog severity of an OTel severity number
+// Numbers outside the OTel range, as severity aggro produces, map to the nearest end
+func syslogPriority(severity otellog.Severity) int {
+	var level int
+	switch {
+	case severity >= otellog.SeverityFatal1:
+		level = 2 // Critical
+	case severity >= otellog.SeverityError1:
+		level = 3 // Error
+	case severity >= otellog.SeverityWarn1:
+		level = 4 // Warning
+	case severity >= otellog.SeverityInfo1:
+		level = 6 // Informational
+	default:
+		level = 7 // Debug, for trace, debug and unspecified severities
+	}
+	return syslogFacility*8 + level
+}
+
+// syslogHostname returns host.name from the resource, or the local hostname
+func syslogHostname(r *sdklog.Record) string {
+	if r.Resource() != nil {
+		if host, ok := r.Resource().Set().Value(semconv.HostNameKey); ok {
+			return host.Emit()
+		}
+	}
+	host, _ := os.Hostname()
+	return host
+}
+
+// syslogProcID returns process.pid from the resource, if any
+func syslogProcID(r *sdklog.Record) string {
+	if r.Resource() != nil {
+		if pid, ok := r.Resource().Set().Value(semconv.ProcessPIDKey); ok {
+			return pid.Emit()
+		}
+	}
+	return ""
+}
+
+// syslogHeaderField returns a header field as printable ASCII without spaces, cut to limit, or the nil value "-"
+func syslogHeaderField(value string, limit int) string {
+	var b strings.Builder
+	for i := 0; i < len(value) && b.Len() < limit; i++ {
+		if value[i] > ' ' && value[i] < 127 {
+			b.WriteByte(value[i])
+		}
+	}
+	if b.Len() == 0 {
+		return "-"
+	}
+	return b.String()
+}
+
+// syslogParamName returns a structured data parameter name without the characters RFC5424 forbids
+func syslogParamName(name string) string {
+	var b strings.Builder
+	for i := 0; i < len(name) && b.Len() < 32; i++ {
+		switch c := name[i]; {
+		case c <= ' ' || c >= 127 || c == '=' || c == ']' || c == '"':
+			b.WriteByte('_')
+		default:
+			b.WriteByte(c)
+		}
+	}
+	if b.Len() == 0 {
+		return "_"
+	}
+	return b.String()
+}
+
+// syslogParamValue escapes the characters RFC5424 requires escaping in parameter values
+func syslogParamValue(value string) string {
+	return strings.NewReplacer(`\`, `\\`, `"`, `\"`, `]`, `\]`).Replace(value)
+}
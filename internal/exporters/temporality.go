@@ -0,0 +1,46 @@
+package exporters
+
+import (
+	"fmt"
+
+	"go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+)
+
+// Temporalities lists the supported metric temporality preferences
+var Temporalities = []string{"cumulative", "delta", "lowmemory"}
+
+// TemporalitySelector returns the temporality selector for a preference, following the
+// OTEL_EXPORTER_OTLP_METRICS_TEMPORALITY_PREFERENCE semantics
+func TemporalitySelector(preference string) (metric.TemporalitySelector, error) {
+	switch preference {
+	case "", "cumulative":
+		return metric.DefaultTemporalitySelector, nil
+	case "delta":
+		return deltaTemporality, nil
+	case "lowmemory":
+		return lowMemoryTemporality, nil
+	default:
+		return nil, fmt.Errorf("invalid temporality '%s' (supported: cumulative, delta, lowmemory)", preference)
+	}
+}
+
+// deltaTemporality uses delta for counters and histograms; up/down counters stay cumulative
+func deltaTemporality(kind metric.InstrumentKind) metricdata.Temporality {
+	switch kind {
+	case metric.InstrumentKindCounter, metric.InstrumentKindObservableCounter, metric.InstrumentKindHistogram:
+		return metricdata.DeltaTemporality
+	default:
+		return metricdata.CumulativeTemporality
+	}
+}
+
+// lowMemoryTemporality uses delta only for synchronous counters and histograms
+func lowMemoryTemporality(kind metric.InstrumentKind) metricdata.Temporality {
+	switch kind {
+	case metric.InstrumentKindCounter, metric.InstrumentKindHistogram:
+		return metricdata.DeltaTemporality
+	default:
+		return metricdata.CumulativeTemporality
+	}
+}
@@ -0,0 +1,281 @@
+package exporters
+
+import (
+	"fmt"
+	"slices"
+	"sync/atomic"
+	"time"
+
+	"github.com/spf13/viper"
+	"go.opentelemetry.io/otel/exporters/otlp/otlplog/otlploggrpc"
+	"go.opentelemetry.io/otel/exporters/otlp/otlplog/otlploghttp"
+	"go.opentelemetry.io/otel/exporters/otlp/otlpmetric/otlpmetricgrpc"
+	"go.opentelemetry.io/otel/exporters/otlp/otlpmetric/otlpmetrichttp"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracegrpc"
+	sdklog "go.opentelemetry.io/otel/sdk/log"
+	"go.opentelemetry.io/otel/sdk/trace"
+)
+
+// TuningConfig holds the OTLP exporter transport settings and the span and log batching settings
+// Zero values keep the SDK defaults, including the OTEL_EXPORTER_OTLP_* and OTEL_BSP_*/OTEL_BLRP_*
+// environment variables the SDK reads for them
+type TuningConfig struct {
+	Compression   string        // gzip or none ("" = SDK default)
+	Timeout       time.Duration // Timeout of one OTLP export attempt
+	Retry         bool          // Retry failed OTLP exports with exponential backoff
+	RetryInitial  time.Duration // First backoff interval
+	RetryMax      time.Duration // Longest backoff interval
+	RetryElapsed  time.Duration // Time after which an export is given up
+	BatchSize     int           // Maximum spans or log records per export
+	QueueSize     int           // Maximum spans or log records waiting for export; more are dropped
+	ScheduleDelay time.Duration // Longest wait before a partial batch is exported
+	Sync          bool          // Export every span and log record on its own, synchronously, without batching
+	Connections   int           // OTLP exporter instances per signal, each with its own connection (0 = 1)
+}
+
+// SDK default backoff, used for the intervals that are not set when another one is
+const (
+	defaultRetryInitial = 5 * time.Second
+	defaultRetryMax     = 30 * time.Second
+	defaultRetryElapsed = time.Minute
+)
+
+// Supported OTLP compressions
+var compressions = []string{"gzip", "none"}
+
+// ParseTuningConfig reads the exporter and batching settings from viper
+func ParseTuningConfig() (*TuningConfig, error) {
+	config := &TuningConfig{
+		Compression:   viper.GetString("otlp.compression"),
+		Timeout:       viper.GetDuration("otlp.timeout"),
+		Retry:         true,
+		RetryInitial:  viper.GetDuration("otlp.retry_initial_interval"),
+		RetryMax:      viper.GetDuration("otlp.retry_max_interval"),
+		RetryElapsed:  viper.GetDuration("otlp.retry_max_elapsed_time"),
+		BatchSize:     viper.GetInt("batch.size"),
+		QueueSize:     viper.GetInt("batch.queue_size"),
+		ScheduleDelay: viper.GetDuration("batch.schedule_delay"),
+		Sync:          viper.GetBool("batch.sync"),
+		Connections:   viper.GetInt("concurrency.connections"),
+	}
+	if viper.IsSet("otlp.retry") {
+		config.Retry = viper.GetBool("otlp.retry")
+	}
+	if err := config.Validate(); err != nil {
+		return nil, err
+	}
+	return config, nil
+}
+
+// Validate checks the compression, durations and sizes
+func (config *TuningConfig) Validate() error {
+	if config.Compression != "" && !slices.Contains(compressions, config.Compression) {
+		return fmt.Errorf("unsupported compression '%s' (supported: gzip, none)", config.Compression)
+	}
+	for name, value := range map[string]time.Duration{"timeout": config.Timeout, "retry initial interval": config.RetryInitial,
+		"retry max interval": config.RetryMax, "retry max elapsed time": config.RetryElapsed, "schedule delay": config.ScheduleDelay} {
+		if value < 0 {
+			return fmt.Errorf("%s must not be negative, got %s", name, value)
+		}
+	}
+	if config.BatchSize < 0 || config.QueueSize < 0 {
+		return fmt.Errorf("batch and queue sizes must not be negative, got %d and %d", config.BatchSize, config.QueueSize)
+	}
+	if config.BatchSize > 0 && config.QueueSize > 0 && config.BatchSize > config.QueueSize {
+		return fmt.Errorf("batch size %d must not exceed queue size %d", config.BatchSize, config.QueueSize)
+	}
+	if config.Connections < 0 {
+		return fmt.Errorf("connections must not be negative, got %d", config.Connections)
+	}
+	if config.Sync && (config.BatchSize > 0 || config.QueueSize > 0 || config.ScheduleDelay > 0) {
+		return fmt.Errorf("synchronous export cannot be combined with batch settings")
+	}
+	return nil
+}
+
+// retryIntervals reports whether the retry settings differ from the SDK defaults, and the intervals to use
+func (config *TuningConfig) retryIntervals() (bool, time.Duration, time.Duration, time.Duration) {
+	if config == nil || (config.Retry && config.RetryInitial == 0 && config.RetryMax == 0 && config.RetryElapsed == 0) {
+		return false, 0, 0, 0
+	}
+	initial, max, elapsed := config.RetryInitial, config.RetryMax, config.RetryElapsed
+	if initial == 0 {
+		initial = defaultRetryInitial
+	}
+	if max == 0 {
+		max = defaultRetryMax
+	}
+	if elapsed == 0 {
+		elapsed = defaultRetryElapsed
+	}
+	return true, initial, max, elapsed
+}
+
+// connections returns the number of OTLP exporter instances to create per signal
+func (config *TuningConfig) connections() int {
+	if config == nil {
+		return 1
+	}
+	return max(1, config.Connections)
+}
+
+// SpanProcessor returns a synchronous processor in sync mode, or a batch processor with the batching settings
+// Spans handed to the processor and the outcomes of their exports are counted in the export outcomes
+// An exporter with several connections gets one processor per connection, sharded by trace ID
+func (config *TuningConfig) SpanProcessor(exporter trace.SpanExporter) trace.SpanProcessor {
+	pool, ok := exporter.(*spanExporterPool)
+	if !ok {
+		return countingSpanProcessor{config.spanProcessor(countingSpanExporter{exporter})}
+	}
+	sharded := shardedSpanProcessor{}
+	for _, member := range pool.members {
+		sharded.shards = append(sharded.shards, config.spanProcessor(countingSpanExporter{member}))
+	}
+	return countingSpanProcessor{sharded}
+}
+
+func (config *TuningConfig) spanProcessor(exporter trace.SpanExporter) trace.SpanProcessor {
+	if config == nil {
+		return trace.NewBatchSpanProcessor(exporter)
+	}
+	if config.Sync {
+		return trace.NewSimpleSpanProcessor(exporter)
+	}
+	var opts []trace.BatchSpanProcessorOption
+	if config.BatchSize > 0 {
+		opts = append(opts, trace.WithMaxExportBatchSize(config.BatchSize))
+	}
+	if config.QueueSize > 0 {
+		opts = append(opts, trace.WithMaxQueueSize(config.QueueSize))
+	}
+	if config.ScheduleDelay > 0 {
+		opts = append(opts, trace.WithBatchTimeout(config.ScheduleDelay))
+	}
+	return trace.NewBatchSpanProcessor(exporter, opts...)
+}
+
+// LogProcessor returns a synchronous processor in sync mode, or a batch processor with the batching settings
+// Log records handed to the processor and the outcomes of their exports are counted in the export outcomes
+// An exporter with several connections gets one processor per connection
+func (config *TuningConfig) LogProcessor(exporter sdklog.Exporter) sdklog.Processor {
+	pool, ok := exporter.(*logExporterPool)
+	if !ok {
+		return countingLogProcessor{config.logProcessor(countingLogExporter{exporter})}
+	}
+	sharded := shardedLogProcessor{next: &atomic.Uint64{}}
+	for _, member := range pool.members {
+		sharded.shards = append(sharded.shards, config.logProcessor(countingLogExporter{member}))
+	}
+	return countingLogProcessor{sharded}
+}
+
+func (config *TuningConfig) logProcessor(exporter sdklog.Exporter) sdklog.Processor {
+	if config == nil {
+		return sdklog.NewBatchProcessor(exporter)
+	}
+	if config.Sync {
+		return sdklog.NewSimpleProcessor(exporter)
+	}
+	var opts []sdklog.BatchProcessorOption
+	if config.BatchSize > 0 {
+		opts = append(opts, sdklog.WithExportMaxBatchSize(config.BatchSize))
+	}
+	if config.QueueSize > 0 {
+		opts = append(opts, sdklog.WithMaxQueueSize(config.QueueSize))
+	}
+	if config.ScheduleDelay > 0 {
+		opts = append(opts, sdklog.WithExportInterval(config.ScheduleDelay))
+	}
+	return sdklog.NewBatchProcessor(exporter, opts...)
+}
+
+func (config *TuningConfig) traceGRPCOptions() []otlptracegrpc.Option {
+	var opts []otlptracegrpc.Option
+	if config == nil {
+		return opts
+	}
+	if config.Compression != "" {
+		opts = append(opts, otlptracegrpc.WithCompressor(config.Compression))
+	}
+	if config.Timeout > 0 {
+		opts = append(opts, otlptracegrpc.WithTimeout(config.Timeout))
+	}
+	if set, initial, max, elapsed := config.retryIntervals(); set {
+		opts = append(opts, otlptracegrpc.WithRetry(otlptracegrpc.RetryConfig{Enabled: config.Retry, InitialInterval: initial, MaxInterval: max, MaxElapsedTime: elapsed}))
+	}
+	return opts
+}
+
+func (config *TuningConfig) logGRPCOptions() []otlploggrpc.Option {
+	var opts []otlploggrpc.Option
+	if config == nil {
+		return opts
+	}
+	if config.Compression != "" {
+		opts = append(opts, otlploggrpc.WithCompressor(config.Compression))
+	}
+	if config.Timeout > 0 {
+		opts = append(opts, otlploggrpc.WithTimeout(config.Timeout))
+	}
+	if set, initial, max, elapsed := config.retryIntervals(); set {
+		opts = append(opts, otlploggrpc.WithRetry(otlploggrpc.RetryConfig{Enabled: config.Retry, InitialInterval: initial, MaxInterval: max, MaxElapsedTime: elapsed}))
+	}
+	return opts
+}
+
+func (config *TuningConfig) logHTTPOptions() []otlploghttp.Option {
+	var opts []otlploghttp.Option
+	if config == nil {
+		return opts
+	}
+	switch config.Compression {
+	case "gzip":
+		opts = append(opts, otlploghttp.WithCompression(otlploghttp.GzipCompression))
+	case "none":
+		opts = append(opts, otlploghttp.WithCompression(otlploghttp.NoCompression))
+	}
+	if config.Timeout > 0 {
+		opts = append(opts, otlploghttp.WithTimeout(config.Timeout))
+	}
+	if set, initial, max, elapsed := config.retryIntervals(); set {
+		opts = append(opts, otlploghttp.WithRetry(otlploghttp.RetryConfig{Enabled: config.Retry, InitialInterval: initial, MaxInterval: max, MaxElapsedTime: elapsed}))
+	}
+	return opts
+}
+
+func (config *TuningConfig) metricGRPCOptions() []otlpmetricgrpc.Option {
+	var opts []otlpmetricgrpc.Option
+	if config == nil {
+		return opts
+	}
+	if config.Compression != "" {
+		opts = append(opts, otlpmetricgrpc.WithCompressor(config.Compression))
+	}
+	if config.Timeout > 0 {
+		opts = append(opts, otlpmetricgrpc.WithTimeout(config.Timeout))
+	}
+	if set, initial, max, elapsed := config.retryIntervals(); set {
+		opts = append(opts, otlpmetricgrpc.WithRetry(otlpmetricgrpc.RetryConfig{Enabled: config.Retry, InitialInterval: initial, MaxInterval: max, MaxElapsedTime: elapsed}))
+	}
+	return opts
+}
+
+func (config *TuningConfig) metricHTTPOptions() []otlpmetrichttp.Option {
+	var opts []otlpmetrichttp.Option
+	if config == nil {
+		return opts
+	}
+	switch config.Compression {
+	case "gzip":
+		opts = append(opts, otlpmetrichttp.WithCompression(otlpmetrichttp.GzipCompression))
+	case "none":
+		opts = append(opts, otlpmetrichttp.WithCompression(otlpmetrichttp.NoCompression))
+	}
+	if config.Timeout > 0 {
+		opts = append(opts, otlpmetrichttp.WithTimeout(config.Timeout))
+	}
+	if set, initial, max, elapsed := config.retryIntervals(); set {
+		opts = append(opts, otlpmetrichttp.WithRetry(otlpmetrichttp.RetryConfig{Enabled: config.Retry, InitialInterval: initial, MaxInterval: max, MaxElapsedTime: elapsed}))
+	}
+	return opts
+}
@@ -0,0 +1,187 @@
+package exporters
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/antithesishq/otel-datagen/internal/aggro"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/sdk/trace"
+	oteltrace "go.opentelemetry.io/otel/trace"
+)
+
+// ZipkinExporter sends spans as Zipkin v2 JSON, as services instrumented with Zipkin libraries do
+type ZipkinExporter struct {
+	url         string
+	aggroConfig *aggro.AggroConfig
+	client      *http.Client
+}
+
+// NewZipkinExporter creates an exporter posting to a Zipkin v2 spans endpoint
+func NewZipkinExporter(url string, aggroConfig *aggro.AggroConfig) *ZipkinExporter {
+	return &ZipkinExporter{url: url, aggroConfig: aggroConfig, client: &http.Client{Timeout: 30 * time.Second}}
+}
+
+// zipkinSpan is a span of the Zipkin v2 model
+type zipkinSpan struct {
+	TraceID        string             `json:"traceId"`
+	ID             string             `json:"id"`
+	ParentID       string             `json:"parentId,omitempty"`
+	Name           string             `json:"name,omitempty"`
+	Kind           string             `json:"kind,omitempty"`
+	Timestamp      int64              `json:"timestamp,omitempty"` // Microseconds
+	Duration       int64              `json:"duration,omitempty"`  // Microseconds
+	LocalEndpoint  *zipkinEndpoint    `json:"localEndpoint,omitempty"`
+	RemoteEndpoint *zipkinEndpoint    `json:"remoteEndpoint,omitempty"`
+	Annotations    []zipkinAnnotation `json:"annotations,omitempty"`
+	Tags           map[string]string  `json:"tags,omitempty"`
+}
+
+// zipkinEndpoint is the network context of a span
+type zipkinEndpoint struct {
+	ServiceName string `json:"serviceName,omitempty"`
+}
+
+// zipkinAnnotation is a timestamped event of a span
+type zipkinAnnotation struct {
+	Timestamp int64  `json:"timestamp"` // Microseconds
+	Value     string `json:"value"`
+}
+
+// zipkinKinds maps OTel span kinds to Zipkin kinds; internal spans have no kind
+var zipkinKinds = map[oteltrace.SpanKind]string{
+	oteltrace.SpanKindServer:   "SERVER",
+	oteltrace.SpanKindClient:   "CLIENT",
+	oteltrace.SpanKindProducer: "PRODUCER",
+	oteltrace.SpanKindConsumer: "CONSUMER",
+}
+
+// ExportSpans posts the spans in one request
+func (e *ZipkinExporter) ExportSpans(ctx context.Context, spans []trace.ReadOnlySpan) error {
+	if len(spans) == 0 {
+		return nil
+	}
+	target, faulty := formatAggroCase(e.aggroConfig, spans)
+
+	models := make([]zipkinSpan, len(spans))
+	for i, span := range spans {
+		models[i] = zipkinModel(span)
+		if i == faulty {
+			applyZipkinAggro(&models[i], span, target)
+		}
+	}
+	body, err := json.Marshal(models)
+	if err != nil {
+		return err
+	}
+	_, err = postBatch(ctx, e.client, e.url, "application/json", body)
+	return err
+}
+
+// Shutdown closes idle connections
+func (e *ZipkinExporter) Shutdown(context.Context) error {
+	e.client.CloseIdleConnections()
+	return nil
+}
+
+// zipkinModel converts a span the way the collector's Zipkin exporter does: attributes, status and
+// scope become tags, events become annotations and service.name becomes the local endpoint
+func zipkinModel(span trace.ReadOnlySpan) zipkinSpan {
+	sc := span.SpanContext()
+	model := zipkinSpan{
+		TraceID:       sc.TraceID().String(),
+		ID:            sc.SpanID().String(),
+		Name:          span.Name(),
+		Kind:          zipkinKinds[span.SpanKind()],
+		Timestamp:     span.StartTime().UnixMicro(),
+		Duration:      span.EndTime().Sub(span.StartTime()).Microseconds(),
+		LocalEndpoint: &zipkinEndpoint{ServiceName: serviceName(span.Resource())},
+		Tags:          make(map[string]string),
+	}
+	if span.Parent().IsValid() {
+		model.ParentID = span.Parent().SpanID().String()
+	}
+
+	for _, kv := range span.Attributes() {
+		model.Tags[string(kv.Key)] = kv.Value.Emit()
+		if kv.Key == "peer.service" {
+			model.RemoteEndpoint = &zipkinEndpoint{ServiceName: kv.Value.Emit()}
+		}
+	}
+	switch span.Status().Code {
+	case codes.Error:
+		model.Tags["otel.status_code"] = "ERROR"
+		model.Tags["error"] = span.Status().Description
+	case codes.Ok:
+		model.Tags["otel.status_code"] = "OK"
+	}
+	if scope := span.InstrumentationScope(); scope.Name != "" {
+		model.Tags["otel.scope.name"] = scope.Name
+		if scope.Version != "" {
+			model.Tags["otel.scope.version"] = scope.Version
+		}
+	}
+
+	for _, event := range span.Events() {
+		model.Annotations = append(model.Annotations, zipkinAnnotation{Timestamp: event.Time.UnixMicro(), Value: event.Name})
+	}
+	return model
+}
+
+// applyZipkinAggro puts one format fault in a span
+func applyZipkinAggro(model *zipkinSpan, span trace.ReadOnlySpan, target string) {
+	switch target {
+	case "64-bit-ids":
+		// Only the low 64 bits of the trace ID, as older Zipkin tracers send
+		model.TraceID = model.TraceID[16:]
+		model.Tags[string(aggroTraceFormatKey)] = target
+	case "missing-local-endpoint":
+		model.LocalEndpoint = nil
+		model.Tags[string(aggroTraceFormatKey)] = target
+	case "annotation-only":
+		// Nothing but IDs and annotations, as v1 tracers report spans finished by another host
+		*model = zipkinSpan{
+			TraceID:       model.TraceID,
+			ID:            model.ID,
+			ParentID:      model.ParentID,
+			LocalEndpoint: model.LocalEndpoint,
+			Annotations: append(model.Annotations, zipkinAnnotation{
+				Timestamp: span.EndTime().UnixMicro(),
+				Value:     string(aggroTraceFormatKey) + "=" + target,
+			}),
+		}
+	}
+}
+
+// postBatch posts one encoded batch, fails on any non-2xx answer and returns the response body
+func postBatch(ctx context.Context, client *http.Client, url, contentType string, body []byte) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", contentType)
+	req.Header.Set("User-Agent", "otel-datagen")
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	response, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode/100 != 2 {
+		if len(response) > 256 {
+			response = response[:256]
+		}
+		return nil, fmt.Errorf("export to %s failed: %s: %s", url, resp.Status, strings.TrimSpace(string(response)))
+	}
+	return response, nil
+}
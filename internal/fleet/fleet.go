@@ -0,0 +1,193 @@
+package fleet
+
+import (
+	"encoding/binary"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/antithesishq/otel-datagen/internal/randomness"
+	"github.com/spf13/viper"
+)
+
+// Config describes a simulated fleet of instances, each exporting under its own resource
+type Config struct {
+	Size      int        // Number of running instances (0 = no fleet)
+	Templates []Template // Resource attributes rendered for every instance
+	Churn     float64    // Fraction of instances replaced by new ones after every round
+}
+
+// Template is a resource attribute whose value is rendered per instance
+// Values may contain {instance}, the instance number, {slot}, the instance's position in the fleet,
+// both optionally taken modulo N as in {slot%10}, and {uuid}, a random UUID
+type Template struct {
+	Key   string
+	Value string
+}
+
+// Instance is one simulated process of the fleet
+type Instance struct {
+	ID         int      // Instance number, never reused
+	Slot       int      // Position in the fleet, taken over by the instance that replaces it
+	Attributes []string // Rendered resource attributes as key=value
+}
+
+// instanceIDKey is templated with a random UUID unless a template sets it
+const instanceIDKey = "service.instance.id"
+
+// placeholderPattern matches the placeholders of template values
+var placeholderPattern = regexp.MustCompile(`\{([^{}]*)\}`)
+
+// ParseConfig reads the fleet settings for the given component from viper
+func ParseConfig(component string) (*Config, error) {
+	prefix := "generate." + component + "."
+	config := &Config{
+		Size:  viper.GetInt(prefix + "fleet_size"),
+		Churn: viper.GetFloat64(prefix + "fleet_churn"),
+	}
+	for _, entry := range viper.GetStringSlice(prefix + "fleet_attr") {
+		key, value, ok := strings.Cut(entry, "=")
+		if !ok || strings.TrimSpace(key) == "" {
+			return nil, fmt.Errorf("invalid fleet attribute '%s' (expected key=template)", entry)
+		}
+		config.Templates = append(config.Templates, Template{Key: strings.TrimSpace(key), Value: value})
+	}
+	if err := config.Validate(); err != nil {
+		return nil, err
+	}
+	return config, nil
+}
+
+// Validate checks the size, churn and template placeholders
+func (config *Config) Validate() error {
+	if config.Size < 0 {
+		return fmt.Errorf("fleet size must not be negative, got %d", config.Size)
+	}
+	if config.Churn < 0 || config.Churn > 1 {
+		return fmt.Errorf("fleet churn must be between 0 and 1, got %g", config.Churn)
+	}
+	if config.Size == 0 && (len(config.Templates) > 0 || config.Churn > 0) {
+		return fmt.Errorf("fleet attributes and churn require a fleet size")
+	}
+	for _, template := range config.Templates {
+		for _, match := range placeholderPattern.FindAllStringSubmatch(template.Value, -1) {
+			if _, _, err := parsePlaceholder(match[1]); err != nil {
+				return fmt.Errorf("invalid fleet attribute '%s': %v", template.Key, err)
+			}
+		}
+	}
+	return nil
+}
+
+// Enabled reports whether a fleet is simulated
+func (config *Config) Enabled() bool {
+	return config != nil && config.Size > 0
+}
+
+// parsePlaceholder splits a placeholder into its name and modulus (0 = none)
+func parsePlaceholder(placeholder string) (string, int, error) {
+	name, modulus, hasModulus := strings.Cut(placeholder, "%")
+	switch name {
+	case "instance", "slot":
+	case "uuid":
+		if hasModulus {
+			return "", 0, fmt.Errorf("placeholder {%s} takes no modulus", placeholder)
+		}
+	default:
+		return "", 0, fmt.Errorf("unknown placeholder {%s} (supported: instance, slot, uuid)", placeholder)
+	}
+	if !hasModulus {
+		return name, 0, nil
+	}
+	n, err := strconv.Atoi(modulus)
+	if err != nil || n <= 0 {
+		return "", 0, fmt.Errorf("invalid modulus in placeholder {%s}", placeholder)
+	}
+	return name, n, nil
+}
+
+// render fills in the placeholders of a template value for an instance
+func render(value string, id int, slot int) string {
+	return placeholderPattern.ReplaceAllStringFunc(value, func(match string) string {
+		name, modulus, _ := parsePlaceholder(match[1 : len(match)-1])
+		number := id
+		switch name {
+		case "uuid":
+			return randomUUID()
+		case "slot":
+			number = slot
+		}
+		if modulus > 0 {
+			number %= modulus
+		}
+		return strconv.Itoa(number)
+	})
+}
+
+// randomUUID returns a random version 4 UUID
+func randomUUID() string {
+	var b [16]byte
+	binary.BigEndian.PutUint64(b[:8], randomness.Uint64())
+	binary.BigEndian.PutUint64(b[8:], randomness.Uint64())
+	b[6] = b[6]&0x0f | 0x40
+	b[8] = b[8]&0x3f | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:])
+}
+
+// Fleet tracks the running instances as they churn
+type Fleet struct {
+	config *Config
+	active []Instance // By slot
+	nextID int
+	carry  float64 // Fractional churn carried over to the next round
+}
+
+// NewFleet starts the initial instances
+func (config *Config) NewFleet() *Fleet {
+	f := &Fleet{config: config}
+	for slot := 0; slot < config.Size; slot++ {
+		f.active = append(f.active, f.start(slot))
+	}
+	return f
+}
+
+// start creates a new instance in the given slot
+func (f *Fleet) start(slot int) Instance {
+	instance := Instance{ID: f.nextID, Slot: slot}
+	f.nextID++
+	templated := false
+	for _, template := range f.config.Templates {
+		instance.Attributes = append(instance.Attributes, template.Key+"="+render(template.Value, instance.ID, slot))
+		templated = templated || template.Key == instanceIDKey
+	}
+	if !templated {
+		instance.Attributes = append(instance.Attributes, instanceIDKey+"="+randomUUID())
+	}
+	return instance
+}
+
+// Active returns the running instances by slot
+func (f *Fleet) Active() []Instance {
+	return f.active
+}
+
+// Advance applies churn after a round, stopping random instances and starting new ones in their slots
+// It returns the slots whose instance was replaced
+func (f *Fleet) Advance() []int {
+	f.carry += f.config.Churn * float64(len(f.active))
+	replace := int(f.carry)
+	f.carry -= float64(replace)
+
+	// Replace distinct random slots: a partial shuffle moves the chosen ones to the front
+	slots := make([]int, len(f.active))
+	for i := range slots {
+		slots[i] = i
+	}
+	for i := 0; i < replace && i < len(slots); i++ {
+		j := i + randomness.Intn(len(slots)-i)
+		slots[i], slots[j] = slots[j], slots[i]
+		f.active[slots[i]] = f.start(slots[i])
+	}
+	return slots[:min(replace, len(slots))]
+}
@@ -0,0 +1,272 @@
+package generators
+
+import (
+	"context"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/antithesishq/otel-datagen/internal/aggro"
+	"github.com/antithesishq/otel-datagen/internal/exporters"
+	"github.com/antithesishq/otel-datagen/internal/fleet"
+	"github.com/antithesishq/otel-datagen/internal/latency"
+	"github.com/antithesishq/otel-datagen/internal/timestamps"
+	"go.opentelemetry.io/otel/attribute"
+	otellog "go.opentelemetry.io/otel/log"
+	"go.opentelemetry.io/otel/metric"
+	sdklog "go.opentelemetry.io/otel/sdk/log"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/trace"
+	oteltrace "go.opentelemetry.io/otel/trace"
+)
+
+// GenerateAll generates correlated traces, logs and metrics from one shared run
+// Spans are the source of truth: logs are emitted inside them and RED metrics are derived from them
+func GenerateAll(numTraces int, numSpans int, numAttributes int, overrideAttrs []string, resourceAttrs []string, otlpEndpoint string, otlpProtocol string, stdoutEnabled bool, spanLogConfig *SpanLogConfig, errorRate float64, latencyDist latency.Distribution, timestampConfig *timestamps.TimestampConfig) {
+	// Parse aggro configuration for this component
+	aggroConfig := aggro.ParseAggroConfig("all")
+
+	ctx := context.Background()
+
+	// Create exporter configuration shared by all three signals
+	exporterConfig := exporters.ExporterConfig{
+		OTLPEndpoint:  otlpEndpoint,
+		Protocol:      otlpProtocol, // "grpc" or "http"
+		StdoutEnabled: stdoutEnabled,
+	}
+
+	// Resolve TLS, headers and authentication of the OTLP exporters
+	if err := exporters.ConfigureOTLPSecurity(&exporterConfig); err != nil {
+		log.Fatalf("Invalid OTLP security configuration: %v", err)
+	}
+	// Compression, timeout, retry and batching of the exporters
+	tuning, err := exporters.ParseTuningConfig()
+	if err != nil {
+		log.Fatalf("Invalid exporter tuning configuration: %v", err)
+	}
+	exporterConfig.Tuning = tuning
+	// Goroutines generating traces
+	workers, err := ParseWorkers()
+	if err != nil {
+		log.Fatalf("Invalid concurrency configuration: %v", err)
+	}
+
+	// A fleet spreads the traces over many instances, each exporting under its own resource
+	fleetConfig, err := fleet.ParseConfig("all")
+	if err != nil {
+		log.Fatalf("Invalid fleet configuration: %v", err)
+	}
+	if fleetConfig.Enabled() {
+		signals, err := newFleetSignals(ctx, exporterConfig, resourceAttrs, timestampConfig.Spacing > 0)
+		if err != nil {
+			log.Fatalf("Failed to create exporters: %v", err)
+		}
+		run, err := newFleetRun(ctx, fleetConfig, signals)
+		if err != nil {
+			log.Fatalf("Failed to start fleet: %v", err)
+		}
+		// Stopping the instances exports their last metrics; the shared processors flush logs before spans
+		defer run.close(ctx)
+
+		if err := generateAllWithProviders(ctx, nil, nil, nil, run, workers, numTraces, numSpans, numAttributes, overrideAttrs, aggroConfig, spanLogConfig, errorRate, latencyDist, timestampConfig, nil); err != nil {
+			log.Printf("Error generating signals: %v", err)
+		}
+		return
+	}
+
+	// Create one resource so every signal describes the same service
+	res, err := exporters.CreateResource(ctx, resourceAttrs)
+	if err != nil {
+		log.Fatalf("Failed to create resource: %v", err)
+	}
+
+	// With timestamp spacing, metrics are collected manually per trace so that
+	// data points line up with the (historical) span timestamps
+	providers, err := newSignalProviders(ctx, exporterConfig, res, timestampConfig.Spacing > 0)
+	if err != nil {
+		log.Fatalf("Failed to create exporters: %v", err)
+	}
+	defer providers.shutdown(ctx)
+
+	onTraceEnd := func(ctx context.Context, traceEnd time.Time) error {
+		return providers.exportMetrics(ctx, traceEnd, timestampConfig.StartTime)
+	}
+
+	if err := generateAllWithProviders(ctx, providers.tp, providers.lp, providers.mp, nil, workers, numTraces, numSpans, numAttributes, overrideAttrs, aggroConfig, spanLogConfig, errorRate, latencyDist, timestampConfig, onTraceEnd); err != nil {
+		log.Printf("Error generating signals: %v", err)
+	}
+
+	// Force flush to ensure output
+	providers.flush(ctx)
+}
+
+// GenerateAllWithProviders runs span generation, span log emission and RED metric recording concurrently
+// onTraceEnd, when set, is called by a metrics worker after the last span of each trace with the trace's end time
+func GenerateAllWithProviders(ctx context.Context, tp *trace.TracerProvider, lp *sdklog.LoggerProvider, mp *sdkmetric.MeterProvider, numTraces int, numSpans int, numAttributes int, overrideAttrs []string, aggroConfig *aggro.AggroConfig, spanLogConfig *SpanLogConfig, errorRate float64, latencyDist latency.Distribution, timestampConfig *timestamps.TimestampConfig, onTraceEnd func(context.Context, time.Time) error) error {
+	return generateAllWithProviders(ctx, tp, lp, mp, nil, 1, numTraces, numSpans, numAttributes, overrideAttrs, aggroConfig, spanLogConfig, errorRate, latencyDist, timestampConfig, onTraceEnd)
+}
+
+// generateAllWithProviders is GenerateAllWithProviders with traces spread over the given number of workers
+// Every worker has its own span log worker and RED metrics worker, which tracks the end of the worker's current trace
+// With a fleet run, every trace, its span logs and its RED metrics are generated by the providers of the
+// trace's instance instead of tp, lp and mp, which may then be nil, and each instance exports its own metrics
+func generateAllWithProviders(ctx context.Context, tp *trace.TracerProvider, lp *sdklog.LoggerProvider, mp *sdkmetric.MeterProvider, run *fleetRun, workers int, numTraces int, numSpans int, numAttributes int, overrideAttrs []string, aggroConfig *aggro.AggroConfig, spanLogConfig *SpanLogConfig, errorRate float64, latencyDist latency.Distribution, timestampConfig *timestamps.TimestampConfig, onTraceEnd func(context.Context, time.Time) error) error {
+	var tracer oteltrace.Tracer
+	var logger otellog.Logger
+	var red *redRecorder
+	if run == nil {
+		var err error
+		if red, err = newREDRecorder(mp); err != nil {
+			return err
+		}
+		tracer = tp.Tracer("otel-datagen")
+		if spanLogConfig.Enabled() {
+			logger = lp.Logger("otel-datagen")
+		}
+	}
+
+	var builder *logRecordBuilder
+	if spanLogConfig.Enabled() {
+		builder = newLogRecordBuilder(numAttributes, overrideAttrs, aggroConfig, spanLogConfig.Severity)
+	}
+
+	logEvents := make([]chan spanEvent, workers)
+	metricEvents := make([]chan spanEvent, workers)
+
+	var wg sync.WaitGroup
+
+	// Logs workers, one per trace worker as emitters track the current trace
+	for shard := range logEvents {
+		logEvents[shard] = make(chan spanEvent, 1024)
+		var spanLogs *spanLogEmitter
+		if builder != nil {
+			spanLogs = &spanLogEmitter{
+				builder: builder,
+				config:  spanLogConfig,
+			}
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for ev := range logEvents[shard] {
+				if spanLogs != nil {
+					spanLogger := logger
+					if ev.member != nil {
+						spanLogger = ev.member.logger
+					}
+					spanLogs.emit(spanLogger, ev.ctx, ev.name, ev.start, ev.end)
+				}
+			}
+		}()
+	}
+
+	// Metrics workers, one per trace worker so RED metrics are recorded concurrently
+	// Collections after each trace are serialized by exportMu and never go back in time
+	var exportMu sync.Mutex
+	var lastEnd time.Time
+	var metricsErr error
+	for shard := range metricEvents {
+		metricEvents[shard] = make(chan spanEvent, 1024)
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			var traceEnd time.Time
+			for ev := range metricEvents[shard] {
+				if ev.member != nil {
+					ev.member.red.record(ev)
+				} else {
+					red.record(ev)
+				}
+				if ev.end.After(traceEnd) {
+					traceEnd = ev.end
+				}
+				if !ev.lastInTrace {
+					continue
+				}
+				exportMu.Lock()
+				switch {
+				case metricsErr != nil:
+				case ev.member != nil:
+					// Every instance exports its own metrics, never going back in time either
+					end := traceEnd
+					if end.Before(ev.member.lastExport) {
+						end = ev.member.lastExport
+					}
+					metricsErr = ev.member.exportMetrics(ctx, end, timestampConfig.StartTime)
+				case onTraceEnd != nil:
+					// Traces of different workers finish out of order
+					if traceEnd.After(lastEnd) {
+						lastEnd = traceEnd
+					}
+					metricsErr = onTraceEnd(ctx, lastEnd)
+				}
+				exportMu.Unlock()
+				traceEnd = time.Time{}
+				run.finish(ev.trace)
+			}
+		}()
+	}
+
+	// Traces workers feed the others
+	err := generateSpans(ctx, tracer, run, workers, numTraces, numSpans, numAttributes, overrideAttrs, aggroConfig, errorRate, latencyDist, timestampConfig, func(ev spanEvent) {
+		logEvents[ev.shard] <- ev
+		metricEvents[ev.shard] <- ev
+	})
+
+	for shard := range logEvents {
+		close(logEvents[shard])
+		close(metricEvents[shard])
+	}
+	wg.Wait()
+
+	if err != nil {
+		return err
+	}
+	return metricsErr
+}
+
+// redRecorder records Rate/Errors/Duration metrics for generated spans
+type redRecorder struct {
+	requests metric.Int64Counter
+	errors   metric.Int64Counter
+	duration metric.Float64Histogram
+}
+
+// newREDRecorder creates the RED instruments on the given meter provider
+func newREDRecorder(mp *sdkmetric.MeterProvider) (*redRecorder, error) {
+	meter := mp.Meter("otel-datagen")
+
+	requests, err := meter.Int64Counter("requests", metric.WithDescription("Number of generated requests (spans)"), metric.WithUnit("{request}"))
+	if err != nil {
+		return nil, err
+	}
+	errors, err := meter.Int64Counter("errors", metric.WithDescription("Number of generated requests that failed"), metric.WithUnit("{request}"))
+	if err != nil {
+		return nil, err
+	}
+	duration, err := meter.Float64Histogram("duration", metric.WithDescription("Duration of generated requests"), metric.WithUnit("ms"))
+	if err != nil {
+		return nil, err
+	}
+
+	return &redRecorder{requests: requests, errors: errors, duration: duration}, nil
+}
+
+// record adds a span to the RED metrics
+// Recording with the span's context lets the SDK attach exemplars that link back to the span
+func (r *redRecorder) record(ev spanEvent) {
+	status := "ok"
+	if ev.isError {
+		status = "error"
+	}
+	attrs := metric.WithAttributes(
+		attribute.String("operation", ev.operation),
+		attribute.String("status.code", status),
+	)
+
+	r.requests.Add(ev.ctx, 1, attrs)
+	if ev.isError {
+		r.errors.Add(ev.ctx, 1, attrs)
+	}
+	r.duration.Record(ev.ctx, float64(ev.end.Sub(ev.start))/float64(time.Millisecond), attrs)
+}
@@ -0,0 +1,84 @@
+package generators
+
+import (
+	"context"
+	"encoding/binary"
+	"fmt"
+	"time"
+
+	"github.com/antithesishq/otel-datagen/internal/randomness"
+	"github.com/antithesishq/otel-datagen/internal/severity"
+	otellog "go.opentelemetry.io/otel/log"
+	oteltrace "go.opentelemetry.io/otel/trace"
+)
+
+// SpanLogConfig controls emission of log records inside the context of generated spans
+type SpanLogConfig struct {
+	LogsPerSpan   int                      // Number of log records emitted within each span's time window
+	MismatchRatio float64                  // Fraction of span logs that carry a trace/span ID not matching the enclosing span
+	Severity      *severity.SeverityConfig // Severity mix, text mode and event names of span logs (nil = all info)
+}
+
+// Enabled reports whether span logs should be emitted
+func (c *SpanLogConfig) Enabled() bool {
+	return c != nil && c.LogsPerSpan > 0
+}
+
+// spanLogEmitter emits log records correlated with generated spans
+type spanLogEmitter struct {
+	builder *logRecordBuilder
+	config  *SpanLogConfig
+
+	// current and previous track span contexts of the current and prior trace,
+	// so mismatches can point at real IDs from another trace
+	current  oteltrace.SpanContext
+	previous oteltrace.SpanContext
+}
+
+// emit writes config.LogsPerSpan records evenly spaced inside [start, end] to the logger
+// Records inherit TraceId/SpanId/TraceFlags from spanCtx unless a mismatch is injected
+func (e *spanLogEmitter) emit(logger otellog.Logger, spanCtx context.Context, spanName string, start, end time.Time) {
+	sc := oteltrace.SpanContextFromContext(spanCtx)
+	if sc.TraceID() != e.current.TraceID() {
+		e.previous = e.current
+	}
+	e.current = sc
+
+	window := end.Sub(start)
+
+	for k := 0; k < e.config.LogsPerSpan; k++ {
+		// Place log k strictly inside the span window
+		logTime := start.Add(window * time.Duration(k+1) / time.Duration(e.config.LogsPerSpan+1))
+		record := e.builder.build(fmt.Sprintf("%s-log-%d", spanName, k+1), logTime)
+
+		ctx := spanCtx
+		if e.config.MismatchRatio > 0 && randomness.Float64() < e.config.MismatchRatio {
+			mismatched, kind := e.mismatchedSpanContext(sc)
+			ctx = oteltrace.ContextWithSpanContext(spanCtx, mismatched)
+			record.AddAttributes(otellog.String("aggro.correlation", kind))
+		}
+
+		logger.Emit(ctx, record)
+	}
+}
+
+// mismatchedSpanContext returns a span context that doesn't match sc, and a label describing how
+func (e *spanLogEmitter) mismatchedSpanContext(sc oteltrace.SpanContext) (oteltrace.SpanContext, string) {
+	// Point at a real span from another trace when one is available
+	if e.previous.IsValid() && randomness.Float64() < 0.5 {
+		return e.previous, "other-trace"
+	}
+
+	// Otherwise fabricate IDs that belong to no generated span
+	var traceID oteltrace.TraceID
+	var spanID oteltrace.SpanID
+	binary.BigEndian.PutUint64(traceID[:8], randomness.Uint64())
+	binary.BigEndian.PutUint64(traceID[8:], randomness.Uint64())
+	binary.BigEndian.PutUint64(spanID[:], randomness.Uint64())
+
+	return oteltrace.NewSpanContext(oteltrace.SpanContextConfig{
+		TraceID:    traceID,
+		SpanID:     spanID,
+		TraceFlags: sc.TraceFlags(),
+	}), "random-trace"
+}
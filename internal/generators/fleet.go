@@ -0,0 +1,256 @@
+package generators
+
+import (
+	"context"
+	"log"
+	"sync"
+
+	"github.com/antithesishq/otel-datagen/internal/exporters"
+	"github.com/antithesishq/otel-datagen/internal/fleet"
+	otellog "go.opentelemetry.io/otel/log"
+	sdklog "go.opentelemetry.io/otel/sdk/log"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/trace"
+	oteltrace "go.opentelemetry.io/otel/trace"
+)
+
+// fleetSignals holds the processors and metric exporters shared by every instance of a simulated fleet,
+// so a fleet of any size exports over the connections and batch queues of one process
+// Batches mix the instances' resources, as they would in a collector's gateway tier
+type fleetSignals struct {
+	spanProcessors  []trace.SpanProcessor
+	logProcessors   []sdklog.Processor
+	metricExporters []sdkmetric.Exporter // Metrics are recorded per instance only when set
+	manualMetrics   bool                 // Metrics are exported per trace by exportMetrics instead of a periodic reader
+	resourceAttrs   []string             // Global resource attributes, below the instance's own
+}
+
+// newFleetSignals creates the processors and metric exporters of all three signals for a fleet
+// With manualMetrics, every instance's metrics are only exported by exportMetrics
+func newFleetSignals(ctx context.Context, exporterConfig exporters.ExporterConfig, resourceAttrs []string, manualMetrics bool) (*fleetSignals, error) {
+	s := &fleetSignals{resourceAttrs: resourceAttrs, manualMetrics: manualMetrics}
+
+	traceExporters, err := exporters.CreateDualTraceExporters(ctx, exporterConfig, nil)
+	if err != nil {
+		return nil, err
+	}
+	s.spanProcessors = []trace.SpanProcessor{exporters.GeneratedSpanCounter()}
+	for _, exporter := range traceExporters {
+		s.spanProcessors = append(s.spanProcessors, exporterConfig.Tuning.SpanProcessor(exporter))
+	}
+
+	logExporters, err := exporters.CreateDualLogExporters(ctx, exporterConfig, nil)
+	if err != nil {
+		return nil, err
+	}
+	s.logProcessors = []sdklog.Processor{exporters.GeneratedLogCounter()}
+	for _, exporter := range logExporters {
+		s.logProcessors = append(s.logProcessors, exporterConfig.Tuning.LogProcessor(exporter))
+	}
+
+	metricExporters, err := exporters.CreateDualMetricExporters(ctx, exporterConfig, nil)
+	if err != nil {
+		return nil, err
+	}
+	s.metricExporters = exporters.CountMetricExporters(metricExporters)
+	return s, nil
+}
+
+// sharedMetricExporter keeps a metric exporter shared by the instances open when one of them stops
+type sharedMetricExporter struct {
+	sdkmetric.Exporter
+}
+
+func (sharedMetricExporter) Shutdown(context.Context) error { return nil }
+
+// fleetMember is a running instance with providers under its own resource
+type fleetMember struct {
+	*signalProviders
+	instance fleet.Instance
+	tracer   oteltrace.Tracer
+	logger   otellog.Logger
+	red      *redRecorder // nil unless metrics are recorded
+}
+
+// start creates the providers of an instance around the shared processors and exporters
+// Its tracer and logger providers are never shut down, so spans and logs of an instance that stops while
+// they are generated are still exported; only the meter provider, which reports on its own, is stopped
+func (s *fleetSignals) start(ctx context.Context, instance fleet.Instance) (*fleetMember, error) {
+	attrs := append(append([]string{}, s.resourceAttrs...), instance.Attributes...)
+	res, err := exporters.CreateResource(ctx, attrs)
+	if err != nil {
+		return nil, err
+	}
+
+	tracerOptions := []trace.TracerProviderOption{trace.WithResource(res)}
+	for _, processor := range s.spanProcessors {
+		tracerOptions = append(tracerOptions, trace.WithSpanProcessor(processor))
+	}
+	loggerOptions := []sdklog.LoggerProviderOption{sdklog.WithResource(res)}
+	for _, processor := range s.logProcessors {
+		loggerOptions = append(loggerOptions, sdklog.WithProcessor(processor))
+	}
+	p := &signalProviders{tp: trace.NewTracerProvider(tracerOptions...), lp: sdklog.NewLoggerProvider(loggerOptions...)}
+	member := &fleetMember{
+		signalProviders: p,
+		instance:        instance,
+		tracer:          p.tp.Tracer("otel-datagen"),
+		logger:          p.lp.Logger("otel-datagen"),
+	}
+
+	if len(s.metricExporters) > 0 {
+		if s.manualMetrics {
+			p.metricReader = sdkmetric.NewManualReader(sdkmetric.WithTemporalitySelector(s.metricExporters[0].Temporality))
+			p.metricExporters = s.metricExporters
+			p.mp = sdkmetric.NewMeterProvider(sdkmetric.WithReader(p.metricReader), sdkmetric.WithResource(res))
+		} else {
+			options := []sdkmetric.Option{sdkmetric.WithResource(res)}
+			for _, exporter := range s.metricExporters {
+				options = append(options, sdkmetric.WithReader(sdkmetric.NewPeriodicReader(sharedMetricExporter{exporter})))
+			}
+			p.mp = sdkmetric.NewMeterProvider(options...)
+		}
+		if member.red, err = newREDRecorder(p.mp); err != nil {
+			return nil, err
+		}
+	}
+	return member, nil
+}
+
+// stop shuts down the instance's meter provider, which exports its last values
+func (m *fleetMember) stop(ctx context.Context) {
+	if m.mp == nil {
+		return
+	}
+	if err := m.mp.Shutdown(ctx); err != nil {
+		log.Printf("Error shutting down meter provider of instance %d: %v", m.instance.ID, err)
+	}
+}
+
+// shutdown flushes and shuts down the shared processors and exporters, logs before spans
+func (s *fleetSignals) shutdown(ctx context.Context) {
+	for _, processor := range s.logProcessors {
+		if err := processor.Shutdown(ctx); err != nil {
+			log.Printf("Error shutting down log processor: %v", err)
+		}
+	}
+	for _, processor := range s.spanProcessors {
+		if err := processor.Shutdown(ctx); err != nil {
+			log.Printf("Error shutting down span processor: %v", err)
+		}
+	}
+	for _, exporter := range s.metricExporters {
+		if err := exporter.Shutdown(ctx); err != nil {
+			log.Printf("Error shutting down metric exporter: %v", err)
+		}
+	}
+}
+
+// fleetRun assigns generated traces or log records to the instances of a fleet, round by round
+// Item i belongs to round i/size and goes to the instance in slot i%size, so every instance sends one
+// item per round; churn is applied between rounds. Instances replaced after a round are stopped once
+// every item of that round and the rounds before it is finished, whatever order workers finish them in
+type fleetRun struct {
+	ctx     context.Context
+	signals *fleetSignals
+	fleet   *fleet.Fleet
+	size    int
+
+	mu        sync.Mutex
+	current   []*fleetMember         // Members of the latest round started, by slot
+	latest    int                    // Latest round started
+	rounds    map[int][]*fleetMember // Members of the rounds not finished yet, by slot
+	finished  map[int]int            // Finished items per round
+	completed int                    // Rounds before this one are finished
+	replaced  map[int][]*fleetMember // Members replaced after each round
+}
+
+// newFleetRun starts the instances of the first round
+func newFleetRun(ctx context.Context, config *fleet.Config, signals *fleetSignals) (*fleetRun, error) {
+	r := &fleetRun{
+		ctx:      ctx,
+		signals:  signals,
+		fleet:    config.NewFleet(),
+		size:     config.Size,
+		rounds:   make(map[int][]*fleetMember),
+		finished: make(map[int]int),
+		replaced: make(map[int][]*fleetMember),
+	}
+	var members []*fleetMember
+	for _, instance := range r.fleet.Active() {
+		member, err := signals.start(ctx, instance)
+		if err != nil {
+			return nil, err
+		}
+		members = append(members, member)
+	}
+	r.current = members
+	r.rounds[0] = members
+	log.Printf("Simulating a fleet of %d instances", r.size)
+	return r, nil
+}
+
+// member returns the instance generating item i, starting the rounds up to its own
+// It returns nil without a fleet
+func (r *fleetRun) member(i int) (*fleetMember, error) {
+	if r == nil {
+		return nil, nil
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	round := i / r.size
+	for r.latest < round {
+		members := append([]*fleetMember{}, r.current...)
+		for _, slot := range r.fleet.Advance() {
+			member, err := r.signals.start(r.ctx, r.fleet.Active()[slot])
+			if err != nil {
+				return nil, err
+			}
+			// A replaced instance whose rounds are all finished already is stopped at once
+			if r.latest < r.completed {
+				members[slot].stop(r.ctx)
+			} else {
+				r.replaced[r.latest] = append(r.replaced[r.latest], members[slot])
+			}
+			members[slot] = member
+		}
+		r.latest++
+		r.current = members
+		r.rounds[r.latest] = members
+	}
+	return r.rounds[round][i%r.size], nil
+}
+
+// finish records that item i is done with its instance, and stops the instances no longer in use
+func (r *fleetRun) finish(i int) {
+	if r == nil {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.finished[i/r.size]++
+	for r.finished[r.completed] == r.size {
+		for _, member := range r.replaced[r.completed] {
+			member.stop(r.ctx)
+		}
+		delete(r.rounds, r.completed)
+		delete(r.finished, r.completed)
+		delete(r.replaced, r.completed)
+		r.completed++
+	}
+}
+
+// close stops every instance and shuts down the shared processors and exporters
+func (r *fleetRun) close(ctx context.Context) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, members := range r.replaced {
+		for _, member := range members {
+			member.stop(ctx)
+		}
+	}
+	for _, member := range r.current {
+		member.stop(ctx)
+	}
+	r.signals.shutdown(ctx)
+}
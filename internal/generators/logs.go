@@ -5,9 +5,12 @@ import (
 	"fmt"
 	"log"
 	"strings"
+	"time"
 
 	"github.com/antithesishq/otel-datagen/internal/aggro"
 	"github.com/antithesishq/otel-datagen/internal/exporters"
+	"github.com/antithesishq/otel-datagen/internal/fleet"
+	"github.com/antithesishq/otel-datagen/internal/severity"
 	"github.com/antithesishq/otel-datagen/internal/timestamps"
 	"github.com/go-faker/faker/v4"
 	otellog "go.opentelemetry.io/otel/log"
@@ -19,32 +22,88 @@ func GenerateLogs(numLogs int, numAttributes int, overrideAttrs []string, resour
 	// Parse aggro configuration for this component
 	aggroConfig := aggro.ParseAggroConfig("logs")
 
+	// Parse severity configuration for this component
+	severityConfig, err := severity.ParseSeverityConfig("logs")
+	if err != nil {
+		log.Fatalf("Invalid severity configuration: %v", err)
+	}
+
 	ctx := context.Background()
 
 	// Create exporter configuration
 	exporterConfig := exporters.ExporterConfig{
 		OTLPEndpoint:  otlpEndpoint,
 		Protocol:      otlpProtocol, // "grpc" or "http"
-		Insecure:      true,         // For local testing, can be made configurable
 		StdoutEnabled: stdoutEnabled,
 	}
 
+	// Resolve TLS, headers and authentication of the OTLP exporters
+	if err := exporters.ConfigureOTLPSecurity(&exporterConfig); err != nil {
+		log.Fatalf("Invalid OTLP security configuration: %v", err)
+	}
+	// Compression, timeout, retry and batching of the exporters
+	tuning, err := exporters.ParseTuningConfig()
+	if err != nil {
+		log.Fatalf("Invalid exporter tuning configuration: %v", err)
+	}
+	exporterConfig.Tuning = tuning
+	// Goroutines generating log records
+	workers, err := ParseWorkers()
+	if err != nil {
+		log.Fatalf("Invalid concurrency configuration: %v", err)
+	}
+
 	// Create dual log exporters (console + OTLP when endpoint specified)
 	logExporters, err := exporters.CreateDualLogExporters(ctx, exporterConfig, nil)
 	if err != nil {
 		log.Fatalf("Failed to create log exporters: %v", err)
 	}
 
+	// Loki, Elasticsearch, syslog and Fluent Forward exporters are added to OTLP;
+	// without an OTLP endpoint they replace the console output
+	outputConfig, err := exporters.ParseLogOutputConfig("logs")
+	if err != nil {
+		log.Fatalf("Invalid log output configuration: %v", err)
+	}
+	if outputConfig.Enabled() {
+		if otlpEndpoint == "" {
+			logExporters = nil
+		}
+		logExporters = append(logExporters, exporters.CreateLogOutputExporters(outputConfig)...)
+	}
+
+	// A fleet spreads the log records over many instances, each exporting under its own resource
+	fleetConfig, err := fleet.ParseConfig("logs")
+	if err != nil {
+		log.Fatalf("Invalid fleet configuration: %v", err)
+	}
+	if fleetConfig.Enabled() {
+		signals := &fleetSignals{resourceAttrs: resourceAttrs, logProcessors: []sdklog.Processor{exporters.GeneratedLogCounter()}}
+		for _, exporter := range logExporters {
+			signals.logProcessors = append(signals.logProcessors, exporterConfig.Tuning.LogProcessor(exporter))
+		}
+		run, err := newFleetRun(ctx, fleetConfig, signals)
+		if err != nil {
+			log.Fatalf("Failed to start fleet: %v", err)
+		}
+		defer run.close(ctx)
+
+		if err := generateLogs(ctx, nil, run, workers, numLogs, numAttributes, overrideAttrs, aggroConfig, severityConfig, timestampConfig); err != nil {
+			log.Printf("Error generating logs: %v", err)
+		}
+		return
+	}
+
 	// Create resource
 	res, err := exporters.CreateResource(ctx, resourceAttrs)
 	if err != nil {
 		log.Fatalf("Failed to create resource: %v", err)
 	}
 
-	// Create logger provider with multiple processors (one per exporter)
-	var logProcessors []sdklog.LoggerProviderOption
+	// Create logger provider with multiple processors (one per exporter), after the generated record counter
+	logProcessors := []sdklog.LoggerProviderOption{sdklog.WithProcessor(exporters.GeneratedLogCounter())}
 	for _, exporter := range logExporters {
-		logProcessors = append(logProcessors, sdklog.WithProcessor(sdklog.NewBatchProcessor(exporter)))
+		logProcessors = append(logProcessors, sdklog.WithProcessor(exporterConfig.Tuning.LogProcessor(exporter)))
 	}
 	logProcessors = append(logProcessors, sdklog.WithResource(res))
 
@@ -56,7 +115,7 @@ func GenerateLogs(numLogs int, numAttributes int, overrideAttrs []string, resour
 	}()
 
 	// Generate logs with the provider
-	if err := GenerateLogsWithProvider(ctx, lp, numLogs, numAttributes, overrideAttrs, aggroConfig, timestampConfig); err != nil {
+	if err := generateLogs(ctx, lp, nil, workers, numLogs, numAttributes, overrideAttrs, aggroConfig, severityConfig, timestampConfig); err != nil {
 		log.Printf("Error generating logs: %v", err)
 	}
 
@@ -67,9 +126,65 @@ func GenerateLogs(numLogs int, numAttributes int, overrideAttrs []string, resour
 }
 
 // GenerateLogsWithProvider generates logs using the provided logger provider
-func GenerateLogsWithProvider(ctx context.Context, lp *sdklog.LoggerProvider, numLogs int, numAttributes int, overrideAttrs []string, aggroConfig *aggro.AggroConfig, timestampConfig *timestamps.TimestampConfig) error {
+func GenerateLogsWithProvider(ctx context.Context, lp *sdklog.LoggerProvider, numLogs int, numAttributes int, overrideAttrs []string, aggroConfig *aggro.AggroConfig, severityConfig *severity.SeverityConfig, timestampConfig *timestamps.TimestampConfig) error {
+	return generateLogs(ctx, lp, nil, 1, numLogs, numAttributes, overrideAttrs, aggroConfig, severityConfig, timestampConfig)
+}
+
+// generateLogs is GenerateLogsWithProvider spread over the given number of workers
+// With a fleet run, every record is emitted by the logger of its instance instead of lp, which may then be nil
+func generateLogs(ctx context.Context, lp *sdklog.LoggerProvider, run *fleetRun, workers int, numLogs int, numAttributes int, overrideAttrs []string, aggroConfig *aggro.AggroConfig, severityConfig *severity.SeverityConfig, timestampConfig *timestamps.TimestampConfig) error {
 	// Get logger
-	logger := lp.Logger("otel-datagen")
+	var logger otellog.Logger
+	if lp != nil {
+		logger = lp.Logger("otel-datagen")
+	}
+
+	builder := newLogRecordBuilder(numAttributes, overrideAttrs, aggroConfig, severityConfig)
+
+	// Generate the specified number of log records, each worker taking every workers-th record
+	return runWorkers(workers, func(shard int) error {
+		for i := shard; i < numLogs; i += workers {
+			logMessage := fmt.Sprintf("example-log-%d", i+1)
+
+			// Set timestamp for this log record
+			logTime := timestampConfig.CalculateTimestamp(i)
+
+			record := builder.build(logMessage, logTime)
+
+			// The instance emitting this record when simulating a fleet
+			member, err := run.member(i)
+			if err != nil {
+				return err
+			}
+			if member != nil {
+				member.logger.Emit(ctx, record)
+			} else {
+				logger.Emit(ctx, record)
+			}
+			run.finish(i)
+		}
+		return nil
+	})
+}
+
+// logRecordBuilder builds log records with attributes, severity and aggro applied
+// It is shared by standalone log generation and logs emitted inside generated spans
+type logRecordBuilder struct {
+	numAttributes  int
+	overrides      map[string]string
+	aggroConfig    *aggro.AggroConfig
+	severityConfig *severity.SeverityConfig
+}
+
+// newLogRecordBuilder creates a record builder, parsing override attributes once
+func newLogRecordBuilder(numAttributes int, overrideAttrs []string, aggroConfig *aggro.AggroConfig, severityConfig *severity.SeverityConfig) *logRecordBuilder {
+	// Fall back to the historical all-info behavior when no severity config is given
+	if severityConfig == nil {
+		severityConfig = severity.DefaultSeverityConfig()
+	}
+	if aggroConfig == nil {
+		aggroConfig = &aggro.AggroConfig{}
+	}
 
 	// Parse override attributes
 	overrides := make(map[string]string)
@@ -80,57 +195,69 @@ func GenerateLogsWithProvider(ctx context.Context, lp *sdklog.LoggerProvider, nu
 		}
 	}
 
-	// Generate the specified number of log records
-	for i := 0; i < numLogs; i++ {
-		logMessage := fmt.Sprintf("example-log-%d", i+1)
+	return &logRecordBuilder{
+		numAttributes:  numAttributes,
+		overrides:      overrides,
+		aggroConfig:    aggroConfig,
+		severityConfig: severityConfig,
+	}
+}
 
-		// Create attributes list starting with base attribute
-		var attrs []otellog.KeyValue
-		attrs = append(attrs, otellog.String("log.level", "info"))
+// build creates a single log record with the given message and timestamp
+func (b *logRecordBuilder) build(logMessage string, logTime time.Time) otellog.Record {
+	// Pick severity, severity text and event name for this record
+	choice := b.severityConfig.Pick()
 
-		// Generate random attributes using faker
-		for j := 0; j < numAttributes; j++ {
-			key := fmt.Sprintf("fake.attr.%d", j+1)
-			value := faker.Word()
+	// Create attributes list starting with base attribute
+	var attrs []otellog.KeyValue
+	attrs = append(attrs, otellog.String("log.level", choice.Level))
 
-			// Check for override
-			if override, exists := overrides[key]; exists {
-				value = override
-			}
+	// Generate random attributes using faker
+	for j := 0; j < b.numAttributes; j++ {
+		key := fmt.Sprintf("fake.attr.%d", j+1)
+		value := faker.Word()
 
-			attrs = append(attrs, otellog.String(key, value))
+		// Check for override
+		if override, exists := b.overrides[key]; exists {
+			value = override
 		}
 
-		// Apply any remaining overrides that didn't match generated keys
-		for key, value := range overrides {
-			if !strings.HasPrefix(key, "fake.attr.") {
-				attrs = append(attrs, otellog.String(key, value))
-			}
-		}
+		attrs = append(attrs, otellog.String(key, value))
+	}
 
-		// Apply aggro modifications if configured
-		skipKeys := []string{"log.level"} // System attributes that shouldn't be replaced
-		// Use gRPC sanitization for now (will be made conditional in next iteration)
-		modifiedAttrs, modifiedMessage, metadataAttrs := aggroConfig.ApplyAggroToLogAttributes(attrs, logMessage, skipKeys, "grpc")
-		attrs = modifiedAttrs
-		logMessage = modifiedMessage
+	// Apply any remaining overrides that didn't match generated keys
+	for key, value := range b.overrides {
+		if !strings.HasPrefix(key, "fake.attr.") {
+			attrs = append(attrs, otellog.String(key, value))
+		}
+	}
 
-		// Add metadata attributes about aggro modifications
-		attrs = append(attrs, metadataAttrs...)
+	// Apply aggro modifications if configured
+	skipKeys := []string{"log.level"} // System attributes that shouldn't be replaced
+	// Use gRPC sanitization for now (will be made conditional in next iteration)
+	modifiedAttrs, modifiedMessage, metadataAttrs := b.aggroConfig.ApplyAggroToLogAttributes(attrs, logMessage, skipKeys, "grpc")
+	attrs = modifiedAttrs
+	logMessage = modifiedMessage
 
-		record := otellog.Record{}
-		record.SetBody(otellog.StringValue(logMessage))
-		record.SetSeverity(otellog.SeverityInfo)
+	// Add metadata attributes about aggro modifications
+	attrs = append(attrs, metadataAttrs...)
 
-		// Set timestamp for this log record
-		logTime := timestampConfig.CalculateTimestamp(i)
-		record.SetTimestamp(logTime)
-		// Also set observed timestamp to match for historical data generation
-		record.SetObservedTimestamp(logTime)
+	// Apply severity aggro (out-of-range numbers, hostile text) if configured
+	sev, sevText, severityMetadata := b.aggroConfig.ApplyAggroToLogSeverity(choice.Severity, choice.Text)
+	attrs = append(attrs, severityMetadata...)
 
-		record.AddAttributes(attrs...)
-		logger.Emit(ctx, record)
+	record := otellog.Record{}
+	record.SetBody(otellog.StringValue(logMessage))
+	record.SetSeverity(sev)
+	record.SetSeverityText(sevText)
+	if choice.EventName != "" {
+		record.SetEventName(choice.EventName)
 	}
 
-	return nil
+	record.SetTimestamp(logTime)
+	// Also set observed timestamp to match for historical data generation
+	record.SetObservedTimestamp(logTime)
+
+	record.AddAttributes(attrs...)
+	return record
 }
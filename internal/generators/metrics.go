@@ -8,34 +8,111 @@ import (
 	"github.com/antithesishq/otel-datagen/internal/aggro"
 	"github.com/antithesishq/otel-datagen/internal/exporters"
 	"github.com/antithesishq/otel-datagen/internal/metrics"
-	"github.com/antithesishq/otel-datagen/internal/randomness"
+	"github.com/antithesishq/otel-datagen/internal/prometheus"
+	"github.com/antithesishq/otel-datagen/internal/shapes"
 	"github.com/antithesishq/otel-datagen/internal/timestamps"
 	"go.opentelemetry.io/otel/attribute"
-	"go.opentelemetry.io/otel/metric"
 	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
 	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+	"go.opentelemetry.io/otel/sdk/resource"
 )
 
 // GenerateMetrics generates metric data with the given parameters
-func GenerateMetrics(numMetrics int, metricType string, metricName string, counterMin int, counterMax int, resourceAttrs []string, otlpEndpoint string, otlpProtocol string, stdoutEnabled bool, timestampConfig *timestamps.TimestampConfig) {
+func GenerateMetrics(numMetrics int, metricType string, metricName string, counterMin int, counterMax int, resourceAttrs []string, otlpEndpoint string, otlpProtocol string, stdoutEnabled bool, temporality string, timestampConfig *timestamps.TimestampConfig) {
 	// Parse aggro configuration for this component
 	aggroConfig := aggro.ParseAggroConfig("metrics")
 
+	// Parse histogram aggregation configuration for this component
+	histogramConfig, err := metrics.ParseHistogramConfig("metrics")
+	if err != nil {
+		log.Fatalf("Invalid histogram configuration: %v", err)
+	}
+
+	// Parse value shape configuration for this component
+	shapeConfig, err := metrics.ParseShapeConfig("metrics", counterMin, counterMax)
+	if err != nil {
+		log.Fatalf("Invalid value shape configuration: %v", err)
+	}
+
+	// Parse the metric catalog; when set it replaces the single --metric-name instrument
+	catalog, err := metrics.ParseCatalog("metrics")
+	if err != nil {
+		log.Fatalf("Invalid metric catalog: %v", err)
+	}
+
+	// Parse series cardinality configuration for this component
+	cardinality, err := metrics.ParseCardinalityConfig("metrics")
+	if err != nil {
+		log.Fatalf("Invalid cardinality configuration: %v", err)
+	}
+
+	// Parse exemplar configuration for this component
+	exemplarConfig, err := metrics.ParseExemplarConfig("metrics")
+	if err != nil {
+		log.Fatalf("Invalid exemplar configuration: %v", err)
+	}
+
+	// Parse series lifecycle configuration (restarts, gaps and staleness) for this component
+	lifecycleConfig, err := metrics.ParseLifecycleConfig("metrics")
+	if err != nil {
+		log.Fatalf("Invalid series lifecycle configuration: %v", err)
+	}
+
+	// Parse historical backfill configuration for this component
+	backfillConfig, err := metrics.ParseBackfillConfig("metrics")
+	if err != nil {
+		log.Fatalf("Invalid backfill configuration: %v", err)
+	}
+	if backfillConfig.Enabled && timestampConfig.Spacing <= 0 {
+		log.Fatalf("Invalid backfill configuration: --backfill needs --timestamp-spacing as its step")
+	}
+
+	// Parse Prometheus scrape endpoint configuration for this component
+	serveConfig, err := prometheus.ParseServeConfig("metrics")
+	if err != nil {
+		log.Fatalf("Invalid Prometheus configuration: %v", err)
+	}
+	// Parse Prometheus remote-write configuration for this component
+	remoteWriteConfig, err := prometheus.ParseRemoteWriteConfig("metrics")
+	if err != nil {
+		log.Fatalf("Invalid remote write configuration: %v", err)
+	}
+	// Parse StatsD, InfluxDB and Graphite output configuration for this component
+	metricOutputConfig, err := exporters.ParseMetricOutputConfig("metrics")
+	if err != nil {
+		log.Fatalf("Invalid metric output configuration: %v", err)
+	}
+
+	// Goroutines recording periodic metrics, each taking whole series
+	workers, err := ParseWorkers()
+	if err != nil {
+		log.Fatalf("Invalid concurrency configuration: %v", err)
+	}
+
+	if serveConfig.Enabled() && backfillConfig.Enabled {
+		log.Fatalf("Invalid Prometheus configuration: --serve-prometheus cannot be combined with --backfill")
+	}
+
 	ctx := context.Background()
 
 	// Create exporter configuration
 	exporterConfig := exporters.ExporterConfig{
 		OTLPEndpoint:  otlpEndpoint,
 		Protocol:      otlpProtocol, // "grpc" or "http"
-		Insecure:      true,         // For local testing, can be made configurable
 		StdoutEnabled: stdoutEnabled,
+		Temporality:   temporality,
 	}
 
-	// Create dual metric exporters (console + OTLP when endpoint specified)
-	metricExporters, err := exporters.CreateDualMetricExporters(ctx, exporterConfig, nil)
+	// Resolve TLS, headers and authentication of the OTLP exporters
+	if err := exporters.ConfigureOTLPSecurity(&exporterConfig); err != nil {
+		log.Fatalf("Invalid OTLP security configuration: %v", err)
+	}
+	// Compression, timeout, retry and batching of the exporters
+	tuning, err := exporters.ParseTuningConfig()
 	if err != nil {
-		log.Fatalf("Failed to create metric exporters: %v", err)
+		log.Fatalf("Invalid exporter tuning configuration: %v", err)
 	}
+	exporterConfig.Tuning = tuning
 
 	// Create resource
 	res, err := exporters.CreateResource(ctx, resourceAttrs)
@@ -43,32 +120,152 @@ func GenerateMetrics(numMetrics int, metricType string, metricName string, count
 		log.Fatalf("Failed to create resource: %v", err)
 	}
 
+	generateMetricsForResource(ctx, exporterConfig, res, numMetrics, metricType, metricName, counterMin, counterMax, aggroConfig, histogramConfig, shapeConfig, catalog, cardinality, exemplarConfig, lifecycleConfig, backfillConfig, serveConfig, remoteWriteConfig, metricOutputConfig, workers, timestampConfig)
+
+	// Temporality aggro: export the same metric from a second resource with a different temporality
+	// Scrapes are always cumulative, so it does not apply to a served endpoint
+	if aggroConfig.TemporalityActive && !serveConfig.Enabled() {
+		altConfig := exporterConfig
+		altConfig.Temporality = aggroConfig.AlternateTemporality(temporality)
+
+		altAttrs := append(append([]string{}, resourceAttrs...), "service.instance.id=aggro-temporality", "aggro.temporality="+altConfig.Temporality)
+		altRes, err := exporters.CreateResource(ctx, altAttrs)
+		if err != nil {
+			log.Fatalf("Failed to create resource: %v", err)
+		}
+
+		generateMetricsForResource(ctx, altConfig, altRes, numMetrics, metricType, metricName, counterMin, counterMax, aggroConfig, histogramConfig, shapeConfig, catalog, cardinality, exemplarConfig, lifecycleConfig, backfillConfig, serveConfig, remoteWriteConfig, metricOutputConfig, workers, timestampConfig)
+	}
+}
+
+// generateMetricsForResource creates exporters and a meter provider for one resource and generates metrics with it
+func generateMetricsForResource(ctx context.Context, exporterConfig exporters.ExporterConfig, res *resource.Resource, numMetrics int, metricType string, metricName string, counterMin int, counterMax int, aggroConfig *aggro.AggroConfig, histogramConfig *metrics.HistogramConfig, shapeConfig *metrics.ShapeConfig, catalog []metrics.CatalogEntry, cardinality *metrics.CardinalityConfig, exemplarConfig *metrics.ExemplarConfig, lifecycleConfig *metrics.LifecycleConfig, backfillConfig *metrics.BackfillConfig, serveConfig *prometheus.ServeConfig, remoteWriteConfig *prometheus.RemoteWriteConfig, metricOutputConfig *exporters.MetricOutputConfig, workers int, timestampConfig *timestamps.TimestampConfig) {
+	temporality, err := exporters.TemporalitySelector(exporterConfig.Temporality)
+	if err != nil {
+		log.Fatalf("Invalid temporality: %v", err)
+	}
+
+	// Every resource and series gets its own value shape
+	newShape := func() shapes.Shape {
+		shape, err := shapeConfig.ShapeFor(metricName, counterMin, counterMax)
+		if err != nil {
+			log.Fatalf("Invalid value shape: %v", err)
+		}
+		return shape
+	}
+
+	// Serve metrics for scraping instead of pushing them
+	if serveConfig.Enabled() {
+		var pool *metrics.SeriesPool
+		if cardinality.Enabled() {
+			pool = cardinality.NewSeriesPool()
+		}
+		var lifecycle *metrics.Lifecycle
+		if lifecycleConfig.Enabled() {
+			lifecycle = lifecycleConfig.NewLifecycle()
+		}
+		err := ServePrometheus(ctx, res, serveConfig, catalog, metricType, metricName, newShape, shapeConfig, pool, histogramConfig, aggroConfig, lifecycle)
+		if err != nil {
+			log.Printf("Error serving metrics: %v", err)
+		}
+		return
+	}
+
+	// Create dual metric exporters (console + OTLP when endpoint specified)
+	metricExporters, err := exporters.CreateDualMetricExporters(ctx, exporterConfig, nil)
+	if err != nil {
+		log.Fatalf("Failed to create metric exporters: %v", err)
+	}
+
+	// Remote write is one more exporter; without an OTLP endpoint it replaces the console output
+	if remoteWriteConfig.Enabled() {
+		if exporterConfig.ForSignal("metrics").OTLPEndpoint == "" {
+			metricExporters = nil
+		}
+		metricExporters = append(metricExporters, prometheus.NewRemoteWriteExporter(remoteWriteConfig, aggroConfig))
+	}
+	// So are StatsD, InfluxDB and Graphite outputs
+	if metricOutputConfig.Enabled() {
+		if exporterConfig.ForSignal("metrics").OTLPEndpoint == "" && !remoteWriteConfig.Enabled() {
+			metricExporters = nil
+		}
+		metricExporters = append(metricExporters, exporters.CreateMetricOutputExporters(metricOutputConfig, aggroConfig)...)
+	}
+	// Count the outcome of every export for the final summary
+	metricExporters = exporters.CountMetricExporters(metricExporters)
+
+	// Backfill builds the data directly, without meter providers or spans
+	if backfillConfig.Enabled {
+		var pool *metrics.SeriesPool
+		if cardinality.Enabled() {
+			pool = cardinality.NewSeriesPool()
+		}
+		var lifecycle *metrics.Lifecycle
+		if lifecycleConfig.Enabled() {
+			lifecycle = lifecycleConfig.NewLifecycle()
+		}
+		err := BackfillMetrics(ctx, res, metricExporters, temporality, catalog, metricType, metricName, newShape, shapeConfig, pool, histogramConfig, aggroConfig, lifecycle, backfillConfig, timestampConfig)
+		if err != nil {
+			log.Printf("Error backfilling metrics: %v", err)
+		}
+		return
+	}
+
+	// Spans for exemplars are exported like any generated trace, so exemplars link to traces that exist
+	var spans *metrics.ExemplarSpans
+	if exemplarConfig.SpanRatio > 0 {
+		tp, err := newTracerProvider(ctx, exporterConfig, res)
+		if err != nil {
+			log.Fatalf("Failed to create trace exporters: %v", err)
+		}
+		defer func() {
+			if err := tp.Shutdown(ctx); err != nil {
+				log.Printf("Error shutting down tracer provider: %v", err)
+			}
+		}()
+		spans = exemplarConfig.NewExemplarSpans(tp.Tracer("otel-datagen"))
+	}
+
 	// Check if we need timestamp control or regular periodic collection
 	if timestampConfig.Spacing > 0 {
 		// Use manual readers for timestamp control - need one manual reader for collection
 		// but separate exporters for console and OTLP
-		reader := sdkmetric.NewManualReader()
-		mp := sdkmetric.NewMeterProvider(
-			sdkmetric.WithReader(reader),
-			sdkmetric.WithResource(res),
-		)
+		reader := sdkmetric.NewManualReader(sdkmetric.WithTemporalitySelector(temporality))
+		options := []sdkmetric.Option{sdkmetric.WithReader(reader), sdkmetric.WithResource(res)}
+		mp := sdkmetric.NewMeterProvider(append(options, exemplarConfig.Options(histogramConfig.Views()...)...)...)
 		defer func() {
 			if err := mp.Shutdown(ctx); err != nil {
 				log.Printf("Error shutting down meter provider: %v", err)
 			}
 		}()
 
+		var pool *metrics.SeriesPool
+		if cardinality.Enabled() {
+			pool = cardinality.NewSeriesPool()
+		}
+		var lifecycle *metrics.Lifecycle
+		if lifecycleConfig.Enabled() {
+			lifecycle = lifecycleConfig.NewLifecycle()
+		}
+
 		// Generate metrics with timestamp control using all exporters
-		if err := GenerateMetricsWithTimestamps(ctx, mp, reader, metricExporters, numMetrics, metricType, metricName, counterMin, counterMax, timestampConfig); err != nil {
+		if len(catalog) > 0 {
+			err = GenerateCatalogWithTimestamps(ctx, mp, reader, metricExporters, catalog, numMetrics, shapeConfig, spans, aggroConfig, lifecycle, timestampConfig)
+		} else {
+			err = GenerateMetricsWithTimestamps(ctx, mp, reader, metricExporters, numMetrics, metricType, metricName, newShape, pool, spans, aggroConfig, lifecycle, timestampConfig)
+		}
+		if err != nil {
 			log.Printf("Error generating metrics: %v", err)
 		}
 	} else {
 		// Use periodic readers for regular operation - one per exporter
+		// (each reader takes its temporality from its exporter)
 		var options []sdkmetric.Option
 		for _, exporter := range metricExporters {
 			options = append(options, sdkmetric.WithReader(sdkmetric.NewPeriodicReader(exporter)))
 		}
 		options = append(options, sdkmetric.WithResource(res))
+		options = append(options, exemplarConfig.Options(histogramConfig.Views()...)...)
 
 		mp := sdkmetric.NewMeterProvider(options...)
 		defer func() {
@@ -78,7 +275,12 @@ func GenerateMetrics(numMetrics int, metricType string, metricName string, count
 		}()
 
 		// Generate metrics with the provider
-		if err := GenerateMetricsWithProvider(ctx, mp, numMetrics, metricType, metricName, counterMin, counterMax, aggroConfig); err != nil {
+		if len(catalog) > 0 {
+			err = metrics.GenerateCatalog(ctx, mp, catalog, numMetrics, shapeConfig, spans, workers, aggroConfig, "grpc")
+		} else {
+			err = GenerateMetricsWithProvider(ctx, mp, numMetrics, metricType, metricName, newShape(), cardinality, spans, workers, aggroConfig)
+		}
+		if err != nil {
 			log.Printf("Error generating metrics: %v", err)
 		}
 
@@ -89,129 +291,142 @@ func GenerateMetrics(numMetrics int, metricType string, metricName string, count
 	}
 }
 
-// GenerateMetricsWithProvider generates metrics using the provided meter provider
-func GenerateMetricsWithProvider(ctx context.Context, mp *sdkmetric.MeterProvider, numMetrics int, metricType string, metricName string, counterMin int, counterMax int, aggroConfig *aggro.AggroConfig) error {
+// GenerateMetricsWithProvider generates metrics using the provided meter provider, taking values from shape
+// spans, when set, records measurements inside generated spans so exemplars link to them; workers record whole series each
+func GenerateMetricsWithProvider(ctx context.Context, mp *sdkmetric.MeterProvider, numMetrics int, metricType string, metricName string, shape shapes.Shape, cardinality *metrics.CardinalityConfig, spans *metrics.ExemplarSpans, workers int, aggroConfig *aggro.AggroConfig) error {
 	// For now, always use gRPC sanitization in metrics (will be made conditional later)
-	return metrics.Generate(ctx, mp, numMetrics, metricType, metricName, counterMin, counterMax, aggroConfig, "grpc")
+	return metrics.GenerateWithShape(ctx, mp, numMetrics, metricType, metricName, shape, cardinality, spans, workers, aggroConfig, "grpc")
 }
 
 // GenerateMetricsWithTimestamps generates metrics with explicit timestamps using manual reader
-func GenerateMetricsWithTimestamps(ctx context.Context, mp *sdkmetric.MeterProvider, reader *sdkmetric.ManualReader, exporters []sdkmetric.Exporter, numMetrics int, metricType string, metricName string, counterMin int, counterMax int, timestampConfig *timestamps.TimestampConfig) error {
-	// Generate a time series by creating individual data points at different timestamps
-	// Each data point gets fresh random values to create natural variation
+// Every instrument type is recorded on the one meter provider and collected once per timestamp, so cumulative
+// series keep accumulating. newShape creates the value shape of each series; pool, when set, provides the series
+// of each data point; spans, when set, records measurements inside generated spans; lifecycle, when set,
+// simulates restarts, gaps and staleness on each collection
+func GenerateMetricsWithTimestamps(ctx context.Context, mp *sdkmetric.MeterProvider, reader *sdkmetric.ManualReader, exporters []sdkmetric.Exporter, numMetrics int, metricType string, metricName string, newShape func() shapes.Shape, pool *metrics.SeriesPool, spans *metrics.ExemplarSpans, aggroConfig *aggro.AggroConfig, lifecycle *metrics.Lifecycle, timestampConfig *timestamps.TimestampConfig) error {
+	// For now, always use gRPC sanitization in metrics (will be made conditional later)
+	recorder, err := metrics.NewSeriesRecorder(mp.Meter("otel-datagen"), metricType, metricName, newShape, spans, aggroConfig, "grpc")
+	if err != nil {
+		return err
+	}
 
+	previous := timestampConfig.StartTime
 	for i := 0; i < numMetrics; i++ {
-		// Create individual meter provider for this timestamp
-		individualReader := sdkmetric.NewManualReader()
-
-		// Get resource from original meter provider (reuse existing resource)
-		resourceMetrics := &metricdata.ResourceMetrics{}
-		if err := reader.Collect(ctx, resourceMetrics); err != nil {
-			return err
-		}
-
-		// Create new meter provider with same resource
-		individualMP := sdkmetric.NewMeterProvider(
-			sdkmetric.WithReader(individualReader),
-			sdkmetric.WithResource(resourceMetrics.Resource),
-		)
-		defer func(mp *sdkmetric.MeterProvider) {
-			mp.Shutdown(ctx)
-		}(individualMP)
-
-		// Generate metrics with timestamped values - this creates fresh random values each time
-		if err := generateSingleTimestampedMetric(ctx, individualMP, metricType, metricName, counterMin, counterMax, i); err != nil {
-			return err
+		// Use the pool's active series, or a single consistent series
+		series := [][]attribute.KeyValue{generateTimestampedMetricAttributes(i)}
+		if pool != nil {
+			series = pool.Active()
+			pool.Advance()
 		}
 
 		// Calculate the intended timestamp for this data point
 		intendedTimestamp := timestampConfig.CalculateTimestamp(i)
+		recorder.Record(ctx, series, i, intendedTimestamp)
 
-		// Collect metrics from the individual provider
-		individualResourceMetrics := &metricdata.ResourceMetrics{}
-		if err := individualReader.Collect(ctx, individualResourceMetrics); err != nil {
+		resourceMetrics := &metricdata.ResourceMetrics{}
+		if err := reader.Collect(ctx, resourceMetrics); err != nil {
 			return err
 		}
+		recorder.DropUnrecorded(resourceMetrics)
 
 		// Manually adjust timestamps in the collected data to match intended timestamp
-		adjustTimestamps(individualResourceMetrics, intendedTimestamp)
+		adjustTimestamps(resourceMetrics, intendedTimestamp)
+		alignStartTimestamps(resourceMetrics, timestampConfig.StartTime, previous)
+		previous = intendedTimestamp
+		if lifecycle != nil {
+			lifecycle.Apply(resourceMetrics, intendedTimestamp)
+		}
 
 		// Export the timestamped metrics to all exporters
 		for _, exporter := range exporters {
-			if err := exporter.Export(ctx, individualResourceMetrics); err != nil {
+			if err := exporter.Export(ctx, resourceMetrics); err != nil {
 				return err
 			}
 		}
-
-		// Small delay between iterations for cleaner output
-		if i < numMetrics-1 {
-			time.Sleep(10 * time.Millisecond)
-		}
 	}
 
 	return nil
 }
 
-// generateSingleTimestampedMetric generates a single metric data point with fresh random values
-func generateSingleTimestampedMetric(ctx context.Context, mp *sdkmetric.MeterProvider, metricType string, metricName string, counterMin int, counterMax int, iteration int) error {
-	// Get meter
-	meter := mp.Meter("otel-datagen")
-
-	// Generate fresh attributes for this iteration
-	attrs := generateTimestampedMetricAttributes(iteration)
+// GenerateCatalogWithTimestamps records every series of the catalog once per timestamp on one meter provider,
+// exporting each collection with its intended timestamp so cumulative series keep accumulating
+// spans, when set, records values inside generated spans; lifecycle, when set, simulates restarts, gaps and staleness on each collection
+func GenerateCatalogWithTimestamps(ctx context.Context, mp *sdkmetric.MeterProvider, reader *sdkmetric.ManualReader, exporters []sdkmetric.Exporter, catalog []metrics.CatalogEntry, numMetrics int, shapeConfig *metrics.ShapeConfig, spans *metrics.ExemplarSpans, aggroConfig *aggro.AggroConfig, lifecycle *metrics.Lifecycle, timestampConfig *timestamps.TimestampConfig) error {
+	recorder, err := metrics.NewCatalogRecorder(mp.Meter("otel-datagen"), catalog, shapeConfig, spans, aggroConfig, "grpc")
+	if err != nil {
+		return err
+	}
 
-	// Generate fresh random value for this timestamp
-	value := generateFreshRandomValue(counterMin, counterMax)
+	previous := timestampConfig.StartTime
+	for i := 0; i < numMetrics; i++ {
+		intendedTimestamp := timestampConfig.CalculateTimestamp(i)
+		recorder.Record(ctx, i, intendedTimestamp)
 
-	// Create the metric based on type and record the value
-	switch metricType {
-	case "gauge", "int64-gauge":
-		gauge, err := meter.Int64Gauge(metricName)
-		if err != nil {
+		resourceMetrics := &metricdata.ResourceMetrics{}
+		if err := reader.Collect(ctx, resourceMetrics); err != nil {
 			return err
 		}
-		gauge.Record(ctx, int64(value), metric.WithAttributes(attrs...))
-
-	case "float64-gauge":
-		gauge, err := meter.Float64Gauge(metricName)
-		if err != nil {
-			return err
+		adjustTimestamps(resourceMetrics, intendedTimestamp)
+		alignStartTimestamps(resourceMetrics, timestampConfig.StartTime, previous)
+		previous = intendedTimestamp
+		if lifecycle != nil {
+			lifecycle.Apply(resourceMetrics, intendedTimestamp)
 		}
-		gauge.Record(ctx, float64(value), metric.WithAttributes(attrs...))
 
-	case "counter", "int64-counter":
-		counter, err := meter.Int64Counter(metricName)
-		if err != nil {
-			return err
+		for _, exporter := range exporters {
+			if err := exporter.Export(ctx, resourceMetrics); err != nil {
+				return err
+			}
 		}
-		counter.Add(ctx, int64(value), metric.WithAttributes(attrs...))
+	}
 
-	case "float64-counter":
-		counter, err := meter.Float64Counter(metricName)
-		if err != nil {
-			return err
-		}
-		counter.Add(ctx, float64(value), metric.WithAttributes(attrs...))
+	return nil
+}
 
-	case "histogram", "float64-histogram":
-		histogram, err := meter.Float64Histogram(metricName)
+// BackfillMetrics exports every step from the timestamp start through the backfill end, spaced by the timestamp spacing
+// The catalog, when set, replaces the single metricType instrument; pool, when set, provides its series
+// Data is built directly rather than recorded, so only per-series state is kept and exports hold at most
+// the backfill batch size of data points each
+func BackfillMetrics(ctx context.Context, res *resource.Resource, exporters []sdkmetric.Exporter, temporality sdkmetric.TemporalitySelector, catalog []metrics.CatalogEntry, metricType string, metricName string, newShape func() shapes.Shape, shapeConfig *metrics.ShapeConfig, pool *metrics.SeriesPool, histogramConfig *metrics.HistogramConfig, aggroConfig *aggro.AggroConfig, lifecycle *metrics.Lifecycle, backfillConfig *metrics.BackfillConfig, timestampConfig *timestamps.TimestampConfig) error {
+	var backfillMetrics []metrics.BackfillMetric
+	if len(catalog) > 0 {
+		var err error
+		backfillMetrics, err = metrics.CatalogBackfillMetrics(catalog, shapeConfig)
 		if err != nil {
 			return err
 		}
-		histogram.Record(ctx, float64(value), metric.WithAttributes(attrs...))
-
-	case "int64-histogram":
-		histogram, err := meter.Int64Histogram(metricName)
-		if err != nil {
-			return err
+	} else {
+		series := func() [][]attribute.KeyValue {
+			return [][]attribute.KeyValue{generateTimestampedMetricAttributes(0)}
 		}
-		histogram.Record(ctx, int64(value), metric.WithAttributes(attrs...))
+		if pool != nil {
+			series = func() [][]attribute.KeyValue {
+				active := pool.Active()
+				pool.Advance()
+				return active
+			}
+		}
+		backfillMetrics = []metrics.BackfillMetric{{Name: metricName, Type: metricType, NewShape: newShape, Series: series}}
+	}
 
-	default:
-		// For other metric types, fall back to the original generation method
-		return metrics.Generate(ctx, mp, 1, metricType, metricName, counterMin, counterMax, nil, "grpc")
+	// For now, always use gRPC sanitization in metrics (will be made conditional later)
+	backfill, err := metrics.NewBackfill(backfillMetrics, temporality, histogramConfig, aggroConfig, lifecycle, "grpc")
+	if err != nil {
+		return err
 	}
 
+	points, batches, err := backfill.Run(ctx, res, timestampConfig.StartTime, backfillConfig.End, timestampConfig.Spacing, backfillConfig.BatchSize, func(ctx context.Context, rm *metricdata.ResourceMetrics) error {
+		for _, exporter := range exporters {
+			if err := exporter.Export(ctx, rm); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	log.Printf("Backfilled %d data points in %d batches", points, batches)
 	return nil
 }
 
@@ -229,12 +444,6 @@ func generateTimestampedMetricAttributes(iteration int) []attribute.KeyValue {
 	return attrs
 }
 
-// generateFreshRandomValue creates a new random value for each call, ensuring variation
-func generateFreshRandomValue(counterMin, counterMax int) int {
-	// Generate normal random value in range
-	return randomness.Intn(counterMax-counterMin+1) + counterMin
-}
-
 // adjustTimestamps manually adjusts timestamps in metric data to the intended timestamp
 // This is a workaround for OpenTelemetry Go SDK's limitation in timestamp control
 func adjustTimestamps(resourceMetrics *metricdata.ResourceMetrics, intendedTimestamp time.Time) {
@@ -248,11 +457,13 @@ func adjustTimestamps(resourceMetrics *metricdata.ResourceMetrics, intendedTimes
 					data.DataPoints[k].Time = intendedTimestamp
 					// For gauges, StartTime is typically the same as Time
 					data.DataPoints[k].StartTime = intendedTimestamp
+					adjustExemplarTimestamps(data.DataPoints[k].Exemplars, intendedTimestamp)
 				}
 			case metricdata.Gauge[float64]:
 				for k := range data.DataPoints {
 					data.DataPoints[k].Time = intendedTimestamp
 					data.DataPoints[k].StartTime = intendedTimestamp
+					adjustExemplarTimestamps(data.DataPoints[k].Exemplars, intendedTimestamp)
 				}
 			case metricdata.Sum[int64]:
 				for k := range data.DataPoints {
@@ -261,6 +472,7 @@ func adjustTimestamps(resourceMetrics *metricdata.ResourceMetrics, intendedTimes
 					if !data.IsMonotonic {
 						data.DataPoints[k].StartTime = intendedTimestamp
 					}
+					adjustExemplarTimestamps(data.DataPoints[k].Exemplars, intendedTimestamp)
 				}
 			case metricdata.Sum[float64]:
 				for k := range data.DataPoints {
@@ -268,14 +480,79 @@ func adjustTimestamps(resourceMetrics *metricdata.ResourceMetrics, intendedTimes
 					if !data.IsMonotonic {
 						data.DataPoints[k].StartTime = intendedTimestamp
 					}
+					adjustExemplarTimestamps(data.DataPoints[k].Exemplars, intendedTimestamp)
 				}
 			case metricdata.Histogram[int64]:
 				for k := range data.DataPoints {
 					data.DataPoints[k].Time = intendedTimestamp
+					adjustExemplarTimestamps(data.DataPoints[k].Exemplars, intendedTimestamp)
 				}
 			case metricdata.Histogram[float64]:
 				for k := range data.DataPoints {
 					data.DataPoints[k].Time = intendedTimestamp
+					adjustExemplarTimestamps(data.DataPoints[k].Exemplars, intendedTimestamp)
+				}
+			case metricdata.ExponentialHistogram[int64]:
+				for k := range data.DataPoints {
+					data.DataPoints[k].Time = intendedTimestamp
+					adjustExemplarTimestamps(data.DataPoints[k].Exemplars, intendedTimestamp)
+				}
+			case metricdata.ExponentialHistogram[float64]:
+				for k := range data.DataPoints {
+					data.DataPoints[k].Time = intendedTimestamp
+					adjustExemplarTimestamps(data.DataPoints[k].Exemplars, intendedTimestamp)
+				}
+			}
+		}
+	}
+}
+
+// adjustExemplarTimestamps moves exemplar timestamps to the intended timestamp so they stay within the data point window
+func adjustExemplarTimestamps[N int64 | float64](exemplars []metricdata.Exemplar[N], intendedTimestamp time.Time) {
+	for e := range exemplars {
+		exemplars[e].Time = intendedTimestamp
+	}
+}
+
+// alignStartTimestamps sets the StartTime of sums and histograms when a long-lived meter provider reports historical data,
+// so StartTime never lands after Time: cumulative data starts at start, delta data at the previous collection
+func alignStartTimestamps(resourceMetrics *metricdata.ResourceMetrics, start time.Time, previous time.Time) {
+	startFor := func(temporality metricdata.Temporality) time.Time {
+		if temporality == metricdata.DeltaTemporality {
+			return previous
+		}
+		return start
+	}
+	for i := range resourceMetrics.ScopeMetrics {
+		for j := range resourceMetrics.ScopeMetrics[i].Metrics {
+			switch data := resourceMetrics.ScopeMetrics[i].Metrics[j].Data.(type) {
+			case metricdata.Sum[int64]:
+				if data.IsMonotonic {
+					for k := range data.DataPoints {
+						data.DataPoints[k].StartTime = startFor(data.Temporality)
+					}
+				}
+			case metricdata.Sum[float64]:
+				if data.IsMonotonic {
+					for k := range data.DataPoints {
+						data.DataPoints[k].StartTime = startFor(data.Temporality)
+					}
+				}
+			case metricdata.Histogram[int64]:
+				for k := range data.DataPoints {
+					data.DataPoints[k].StartTime = startFor(data.Temporality)
+				}
+			case metricdata.Histogram[float64]:
+				for k := range data.DataPoints {
+					data.DataPoints[k].StartTime = startFor(data.Temporality)
+				}
+			case metricdata.ExponentialHistogram[int64]:
+				for k := range data.DataPoints {
+					data.DataPoints[k].StartTime = startFor(data.Temporality)
+				}
+			case metricdata.ExponentialHistogram[float64]:
+				for k := range data.DataPoints {
+					data.DataPoints[k].StartTime = startFor(data.Temporality)
 				}
 			}
 		}
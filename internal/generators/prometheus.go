@@ -0,0 +1,119 @@
+package generators
+
+import (
+	"context"
+	"log"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/antithesishq/otel-datagen/internal/aggro"
+	"github.com/antithesishq/otel-datagen/internal/metrics"
+	"github.com/antithesishq/otel-datagen/internal/prometheus"
+	"github.com/antithesishq/otel-datagen/internal/shapes"
+	"go.opentelemetry.io/otel/attribute"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+	"go.opentelemetry.io/otel/sdk/resource"
+)
+
+// ServePrometheus exposes the generated metrics for scraping instead of pushing them
+// Every series gets its next value each serve interval and scrapes see the latest cumulative values,
+// until the serve duration has passed or the process is interrupted
+// The catalog, when set, replaces the single metricType instrument; pool, when set, provides its series;
+// lifecycle, when set, simulates restarts, gaps and staleness on each update
+func ServePrometheus(ctx context.Context, res *resource.Resource, serveConfig *prometheus.ServeConfig, catalog []metrics.CatalogEntry, metricType string, metricName string, newShape func() shapes.Shape, shapeConfig *metrics.ShapeConfig, pool *metrics.SeriesPool, histogramConfig *metrics.HistogramConfig, aggroConfig *aggro.AggroConfig, lifecycle *metrics.Lifecycle) error {
+	ctx, stop := signal.NotifyContext(ctx, os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	if serveConfig.Duration > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, serveConfig.Duration)
+		defer cancel()
+	}
+
+	// Scrapers expect cumulative data, which is the manual reader's default
+	reader := sdkmetric.NewManualReader()
+	mp := sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader), sdkmetric.WithResource(res), sdkmetric.WithView(histogramConfig.Views()...))
+	defer func() {
+		if err := mp.Shutdown(context.Background()); err != nil {
+			log.Printf("Error shutting down meter provider: %v", err)
+		}
+	}()
+
+	update, err := newPrometheusUpdate(ctx, mp, reader, catalog, metricType, metricName, newShape, shapeConfig, pool, aggroConfig, lifecycle)
+	if err != nil {
+		return err
+	}
+
+	server := prometheus.NewServer(serveConfig, aggroConfig)
+	rm, err := update(0)
+	if err != nil {
+		return err
+	}
+	server.Update(rm)
+
+	errs := make(chan error, 1)
+	go func() { errs <- server.ListenAndServe(ctx) }()
+	log.Printf("Serving Prometheus metrics on %s/metrics", serveConfig.Address)
+
+	ticker := time.NewTicker(serveConfig.Interval)
+	defer ticker.Stop()
+	for i := 1; ; i++ {
+		select {
+		case err := <-errs:
+			return err
+		case <-ctx.Done():
+			return <-errs
+		case <-ticker.C:
+			rm, err := update(i)
+			if err != nil {
+				return err
+			}
+			server.Update(rm)
+		}
+	}
+}
+
+// newPrometheusUpdate returns a function recording the next value of every series and collecting the result
+func newPrometheusUpdate(ctx context.Context, mp *sdkmetric.MeterProvider, reader *sdkmetric.ManualReader, catalog []metrics.CatalogEntry, metricType string, metricName string, newShape func() shapes.Shape, shapeConfig *metrics.ShapeConfig, pool *metrics.SeriesPool, aggroConfig *aggro.AggroConfig, lifecycle *metrics.Lifecycle) (func(int) (*metricdata.ResourceMetrics, error), error) {
+	// For now, always use gRPC sanitization in metrics (will be made conditional later)
+	var record func(int)
+	var dropUnrecorded func(*metricdata.ResourceMetrics)
+	if len(catalog) > 0 {
+		recorder, err := metrics.NewCatalogRecorder(mp.Meter("otel-datagen"), catalog, shapeConfig, nil, aggroConfig, "grpc")
+		if err != nil {
+			return nil, err
+		}
+		record = func(i int) { recorder.Record(ctx, i, time.Time{}) }
+		dropUnrecorded = func(*metricdata.ResourceMetrics) {}
+	} else {
+		recorder, err := metrics.NewSeriesRecorder(mp.Meter("otel-datagen"), metricType, metricName, newShape, nil, aggroConfig, "grpc")
+		if err != nil {
+			return nil, err
+		}
+		record = func(i int) {
+			series := [][]attribute.KeyValue{generateTimestampedMetricAttributes(i)}
+			if pool != nil {
+				series = pool.Active()
+				pool.Advance()
+			}
+			recorder.Record(ctx, series, i, time.Time{})
+		}
+		dropUnrecorded = recorder.DropUnrecorded
+	}
+
+	return func(i int) (*metricdata.ResourceMetrics, error) {
+		record(i)
+		rm := &metricdata.ResourceMetrics{}
+		if err := reader.Collect(ctx, rm); err != nil {
+			return nil, err
+		}
+		// Series churned out or ended disappear from scrapes, as they would from a real exporter
+		dropUnrecorded(rm)
+		if lifecycle != nil {
+			lifecycle.Apply(rm, time.Now())
+		}
+		return rm, nil
+	}, nil
+}
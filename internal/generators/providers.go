@@ -0,0 +1,143 @@
+package generators
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/antithesishq/otel-datagen/internal/exporters"
+	sdklog "go.opentelemetry.io/otel/sdk/log"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+	"go.opentelemetry.io/otel/sdk/resource"
+	"go.opentelemetry.io/otel/sdk/trace"
+)
+
+// signalProviders holds tracer, logger and meter providers that share one resource and exporter configuration
+type signalProviders struct {
+	tp *trace.TracerProvider
+	lp *sdklog.LoggerProvider
+	mp *sdkmetric.MeterProvider
+
+	// Set when metrics are collected manually so data points can carry historical timestamps
+	metricReader    *sdkmetric.ManualReader
+	metricExporters []sdkmetric.Exporter
+	lastExport      time.Time // Timestamp of the previous manual export, the start of delta data points
+}
+
+// newSignalProviders creates providers for all three signals
+// With manualMetrics, metrics are only exported by exportMetrics; otherwise a periodic reader is used
+func newSignalProviders(ctx context.Context, exporterConfig exporters.ExporterConfig, res *resource.Resource, manualMetrics bool) (*signalProviders, error) {
+	p := &signalProviders{}
+
+	// Tracer provider
+	tp, err := newTracerProvider(ctx, exporterConfig, res)
+	if err != nil {
+		return nil, err
+	}
+	p.tp = tp
+
+	// Logger provider
+	logExporters, err := exporters.CreateDualLogExporters(ctx, exporterConfig, nil)
+	if err != nil {
+		return nil, err
+	}
+	logProcessors := []sdklog.LoggerProviderOption{sdklog.WithProcessor(exporters.GeneratedLogCounter())}
+	for _, exporter := range logExporters {
+		logProcessors = append(logProcessors, sdklog.WithProcessor(exporterConfig.Tuning.LogProcessor(exporter)))
+	}
+	logProcessors = append(logProcessors, sdklog.WithResource(res))
+	p.lp = sdklog.NewLoggerProvider(logProcessors...)
+
+	// Meter provider
+	metricExporters, err := exporters.CreateDualMetricExporters(ctx, exporterConfig, nil)
+	if err != nil {
+		return nil, err
+	}
+	metricExporters = exporters.CountMetricExporters(metricExporters)
+	if manualMetrics {
+		temporality, err := exporters.TemporalitySelector(exporterConfig.Temporality)
+		if err != nil {
+			return nil, err
+		}
+		p.metricReader = sdkmetric.NewManualReader(sdkmetric.WithTemporalitySelector(temporality))
+		p.metricExporters = metricExporters
+		p.mp = sdkmetric.NewMeterProvider(sdkmetric.WithReader(p.metricReader), sdkmetric.WithResource(res))
+	} else {
+		var options []sdkmetric.Option
+		for _, exporter := range metricExporters {
+			options = append(options, sdkmetric.WithReader(sdkmetric.NewPeriodicReader(exporter)))
+		}
+		options = append(options, sdkmetric.WithResource(res))
+		p.mp = sdkmetric.NewMeterProvider(options...)
+	}
+
+	return p, nil
+}
+
+// newTracerProvider creates a tracer provider batching spans to the configured trace exporters
+func newTracerProvider(ctx context.Context, exporterConfig exporters.ExporterConfig, res *resource.Resource) (*trace.TracerProvider, error) {
+	traceExporters, err := exporters.CreateDualTraceExporters(ctx, exporterConfig, nil)
+	if err != nil {
+		return nil, err
+	}
+	spanProcessors := []trace.TracerProviderOption{trace.WithSpanProcessor(exporters.GeneratedSpanCounter())}
+	for _, exporter := range traceExporters {
+		spanProcessors = append(spanProcessors, trace.WithSpanProcessor(exporterConfig.Tuning.SpanProcessor(exporter)))
+	}
+	spanProcessors = append(spanProcessors, trace.WithResource(res))
+	return trace.NewTracerProvider(spanProcessors...), nil
+}
+
+// exportMetrics collects manually read metrics, moves them to the given timestamps and exports them
+// It is a no-op when metrics are exported by a periodic reader
+func (p *signalProviders) exportMetrics(ctx context.Context, timestamp time.Time, start time.Time) error {
+	if p.metricReader == nil {
+		return nil
+	}
+
+	rm := &metricdata.ResourceMetrics{}
+	if err := p.metricReader.Collect(ctx, rm); err != nil {
+		return err
+	}
+	previous := p.lastExport
+	if previous.IsZero() {
+		previous = start
+	}
+	p.lastExport = timestamp
+
+	adjustTimestamps(rm, timestamp)
+	alignStartTimestamps(rm, start, previous)
+	for _, exporter := range p.metricExporters {
+		if err := exporter.Export(ctx, rm); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// flush forces export of buffered data, logs before spans so correlated logs are not lost on exit
+func (p *signalProviders) flush(ctx context.Context) {
+	if err := p.lp.ForceFlush(ctx); err != nil {
+		log.Printf("Error flushing logger: %v", err)
+	}
+	if err := p.tp.ForceFlush(ctx); err != nil {
+		log.Printf("Error flushing tracer: %v", err)
+	}
+	if err := p.mp.ForceFlush(ctx); err != nil {
+		log.Printf("Error flushing meter: %v", err)
+	}
+}
+
+// shutdown shuts down all three providers
+func (p *signalProviders) shutdown(ctx context.Context) {
+	if err := p.tp.Shutdown(ctx); err != nil {
+		log.Printf("Error shutting down tracer provider: %v", err)
+	}
+	if err := p.lp.Shutdown(ctx); err != nil {
+		log.Printf("Error shutting down logger provider: %v", err)
+	}
+	if err := p.mp.Shutdown(ctx); err != nil {
+		log.Printf("Error shutting down meter provider: %v", err)
+	}
+}
@@ -0,0 +1,346 @@
+package generators
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/antithesishq/otel-datagen/internal/aggro"
+	"github.com/antithesishq/otel-datagen/internal/exporters"
+	"github.com/antithesishq/otel-datagen/internal/fleet"
+	"github.com/antithesishq/otel-datagen/internal/randomness"
+	"github.com/antithesishq/otel-datagen/internal/scenario"
+	"github.com/antithesishq/otel-datagen/internal/severity"
+	"github.com/antithesishq/otel-datagen/internal/shapes"
+	"github.com/antithesishq/otel-datagen/internal/timestamps"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	otellog "go.opentelemetry.io/otel/log"
+	"go.opentelemetry.io/otel/metric"
+	sdklog "go.opentelemetry.io/otel/sdk/log"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/trace"
+	oteltrace "go.opentelemetry.io/otel/trace"
+)
+
+// ServiceProviders holds the providers that export one scenario service's signals under its own resource
+type ServiceProviders struct {
+	TracerProvider *trace.TracerProvider
+	LoggerProvider *sdklog.LoggerProvider
+	MeterProvider  *sdkmetric.MeterProvider
+}
+
+// GenerateScenario simulates numRequests requests through the services of a scenario
+// Every service gets its own resource and providers; global resource attributes apply to all of them
+func GenerateScenario(sc *scenario.Scenario, numRequests int, resourceAttrs []string, otlpEndpoint string, otlpProtocol string, stdoutEnabled bool, timestampConfig *timestamps.TimestampConfig) {
+	// Parse aggro configuration for this component
+	aggroConfig := aggro.ParseAggroConfig("all")
+
+	ctx := context.Background()
+
+	// Create exporter configuration shared by all services
+	exporterConfig := exporters.ExporterConfig{
+		OTLPEndpoint:  otlpEndpoint,
+		Protocol:      otlpProtocol, // "grpc" or "http"
+		StdoutEnabled: stdoutEnabled,
+	}
+
+	// Resolve TLS, headers and authentication of the OTLP exporters
+	if err := exporters.ConfigureOTLPSecurity(&exporterConfig); err != nil {
+		log.Fatalf("Invalid OTLP security configuration: %v", err)
+	}
+	// Compression, timeout, retry and batching of the exporters
+	tuning, err := exporters.ParseTuningConfig()
+	if err != nil {
+		log.Fatalf("Invalid exporter tuning configuration: %v", err)
+	}
+	exporterConfig.Tuning = tuning
+
+	// Scenario services have resources of their own, which a fleet would replace
+	fleetConfig, err := fleet.ParseConfig("all")
+	if err != nil {
+		log.Fatalf("Invalid fleet configuration: %v", err)
+	}
+	if fleetConfig.Enabled() {
+		log.Fatalf("Fleet simulation cannot be combined with a scenario")
+	}
+
+	allProviders := make(map[string]*signalProviders)
+	services := make(map[string]*ServiceProviders)
+	for _, svc := range sc.Services {
+		// Service attributes come last so they win over the global ones
+		attrs := append(append([]string{}, resourceAttrs...), svc.ResourceAttributes()...)
+		res, err := exporters.CreateResource(ctx, attrs)
+		if err != nil {
+			log.Fatalf("Failed to create resource for service %s: %v", svc.Name, err)
+		}
+
+		providers, err := newSignalProviders(ctx, exporterConfig, res, timestampConfig.Spacing > 0)
+		if err != nil {
+			log.Fatalf("Failed to create exporters for service %s: %v", svc.Name, err)
+		}
+		defer providers.shutdown(ctx)
+
+		allProviders[svc.Name] = providers
+		services[svc.Name] = &ServiceProviders{
+			TracerProvider: providers.tp,
+			LoggerProvider: providers.lp,
+			MeterProvider:  providers.mp,
+		}
+	}
+
+	onRequestEnd := func(ctx context.Context, requestEnd time.Time) error {
+		for _, providers := range allProviders {
+			if err := providers.exportMetrics(ctx, requestEnd, timestampConfig.StartTime); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	if err := GenerateScenarioWithProviders(ctx, sc, services, numRequests, aggroConfig, timestampConfig, onRequestEnd); err != nil {
+		log.Printf("Error generating scenario: %v", err)
+	}
+
+	// Force flush to ensure output
+	for _, providers := range allProviders {
+		providers.flush(ctx)
+	}
+}
+
+// GenerateScenarioWithProviders simulates numRequests requests using the given per-service providers
+// onRequestEnd, when set, is called after each request with the request's end time
+func GenerateScenarioWithProviders(ctx context.Context, sc *scenario.Scenario, providers map[string]*ServiceProviders, numRequests int, aggroConfig *aggro.AggroConfig, timestampConfig *timestamps.TimestampConfig, onRequestEnd func(context.Context, time.Time) error) error {
+	runner, err := newScenarioRunner(sc, providers, aggroConfig)
+	if err != nil {
+		return err
+	}
+
+	for i := 0; i < numRequests; i++ {
+		inv := sc.Request(timestampConfig.CalculateTimestamp(i))
+		runner.run(ctx, inv, nil)
+
+		if onRequestEnd != nil {
+			if err := onRequestEnd(ctx, inv.End); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// scenarioRunner turns simulated invocations into spans, logs and metrics
+type scenarioRunner struct {
+	services    map[string]*scenarioService
+	aggroConfig *aggro.AggroConfig
+}
+
+// scenarioService holds the instruments of one simulated service
+type scenarioService struct {
+	tracer      oteltrace.Tracer
+	logger      otellog.Logger
+	red         *redRecorder
+	metrics     []func(context.Context)
+	logBuilders map[*scenario.LogLine]*logRecordBuilder
+}
+
+// newScenarioRunner creates tracers, loggers and instruments for every service of the scenario
+func newScenarioRunner(sc *scenario.Scenario, providers map[string]*ServiceProviders, aggroConfig *aggro.AggroConfig) (*scenarioRunner, error) {
+	if aggroConfig == nil {
+		aggroConfig = &aggro.AggroConfig{}
+	}
+
+	runner := &scenarioRunner{
+		services:    make(map[string]*scenarioService),
+		aggroConfig: aggroConfig,
+	}
+
+	for i := range sc.Services {
+		svc := &sc.Services[i]
+		p, ok := providers[svc.Name]
+		if !ok {
+			return nil, fmt.Errorf("no providers for service '%s'", svc.Name)
+		}
+
+		red, err := newREDRecorder(p.MeterProvider)
+		if err != nil {
+			return nil, err
+		}
+
+		s := &scenarioService{
+			tracer:      p.TracerProvider.Tracer("otel-datagen"),
+			logger:      p.LoggerProvider.Logger("otel-datagen"),
+			red:         red,
+			logBuilders: make(map[*scenario.LogLine]*logRecordBuilder),
+		}
+
+		meter := p.MeterProvider.Meter("otel-datagen")
+		for _, def := range svc.Metrics {
+			record, err := newScenarioMetric(meter, def)
+			if err != nil {
+				return nil, err
+			}
+			s.metrics = append(s.metrics, record)
+		}
+
+		// Each log line gets a builder with its fixed severity and attributes, so aggro still applies
+		for j := range svc.Operations {
+			for k := range svc.Operations[j].Logs {
+				line := &svc.Operations[j].Logs[k]
+				weights, err := severity.ParseWeights(line.Severity)
+				if err != nil {
+					return nil, err
+				}
+				severityConfig := &severity.SeverityConfig{Weights: weights, TextMode: "standard"}
+				s.logBuilders[line] = newLogRecordBuilder(0, scenario.SortedPairs(line.Attributes), aggroConfig, severityConfig)
+			}
+		}
+
+		runner.services[svc.Name] = s
+	}
+
+	return runner, nil
+}
+
+// newScenarioMetric creates the instrument for a declared metric and returns a function recording one value
+func newScenarioMetric(meter metric.Meter, def scenario.Metric) (func(context.Context), error) {
+	value := func() float64 {
+		return def.Min + randomness.Float64()*(def.Max-def.Min)
+	}
+	if def.Shape != "" {
+		shape, err := shapes.Parse(def.Shape, def.Min, def.Max)
+		if err != nil {
+			return nil, err
+		}
+		value = shape.Next
+		switch def.Type {
+		case "counter":
+			value = shapes.CounterIncrements(shape)
+		case "updowncounter":
+			value = shapes.Increments(shape)
+		}
+	}
+
+	switch def.Type {
+	case "counter":
+		counter, err := meter.Float64Counter(def.Name, metric.WithDescription(def.Description), metric.WithUnit(def.Unit))
+		if err != nil {
+			return nil, err
+		}
+		return func(ctx context.Context) { counter.Add(ctx, value()) }, nil
+	case "updowncounter":
+		counter, err := meter.Float64UpDownCounter(def.Name, metric.WithDescription(def.Description), metric.WithUnit(def.Unit))
+		if err != nil {
+			return nil, err
+		}
+		return func(ctx context.Context) { counter.Add(ctx, value()) }, nil
+	case "histogram":
+		histogram, err := meter.Float64Histogram(def.Name, metric.WithDescription(def.Description), metric.WithUnit(def.Unit))
+		if err != nil {
+			return nil, err
+		}
+		return func(ctx context.Context) { histogram.Record(ctx, value()) }, nil
+	default:
+		gauge, err := meter.Float64Gauge(def.Name, metric.WithDescription(def.Description), metric.WithUnit(def.Unit))
+		if err != nil {
+			return nil, err
+		}
+		return func(ctx context.Context) { gauge.Record(ctx, value()) }, nil
+	}
+}
+
+// spanKinds maps scenario operation kinds to the server-side span kind
+var spanKinds = map[string]oteltrace.SpanKind{
+	"server":   oteltrace.SpanKindServer,
+	"consumer": oteltrace.SpanKindConsumer,
+	"internal": oteltrace.SpanKindInternal,
+}
+
+// run emits the signals for an invocation and its downstream calls
+// caller is the calling service, or nil for requests arriving at an entrypoint
+func (r *scenarioRunner) run(ctx context.Context, inv *scenario.Invocation, caller *scenarioService) {
+	svc := r.services[inv.Service.Name]
+	op := inv.Operation
+
+	// Downstream calls get a client (or producer) span on the caller's side
+	var clientSpan oteltrace.Span
+	if caller != nil {
+		kind := oteltrace.SpanKindClient
+		if op.Kind == "consumer" {
+			kind = oteltrace.SpanKindProducer
+		}
+		ctx, clientSpan = caller.tracer.Start(ctx, op.Name,
+			oteltrace.WithSpanKind(kind),
+			oteltrace.WithTimestamp(inv.CallStart),
+			oteltrace.WithAttributes(attribute.String("peer.service", inv.Service.Name)),
+		)
+	}
+
+	spanCtx, span := svc.tracer.Start(ctx, op.Name, oteltrace.WithSpanKind(spanKinds[op.Kind]), oteltrace.WithTimestamp(inv.Start))
+
+	var attrs []attribute.KeyValue
+	for _, key := range scenario.SortedKeys(op.Attributes) {
+		attrs = append(attrs, attribute.String(key, op.Attributes[key]))
+	}
+
+	// Apply aggro modifications if configured
+	modifiedAttrs, metadataAttrs := r.aggroConfig.ApplyAggroToTraceAttributes(attrs, nil, "grpc")
+	attrs = append(modifiedAttrs, metadataAttrs...)
+	span.SetAttributes(attrs...)
+
+	for _, child := range inv.Calls {
+		r.run(spanCtx, child, svc)
+	}
+
+	r.emitLogs(spanCtx, svc, inv)
+
+	// RED metrics and declared metrics, recorded in the span's context for exemplars
+	svc.red.record(spanEvent{
+		ctx:       spanCtx,
+		name:      op.Name,
+		operation: op.Name,
+		start:     inv.Start,
+		end:       inv.End,
+		isError:   inv.Failed,
+	})
+	for _, record := range svc.metrics {
+		record(spanCtx)
+	}
+
+	if inv.Failed {
+		if inv.Origin {
+			span.SetStatus(codes.Error, "simulated failure")
+			span.SetAttributes(attribute.String("error.type", "simulated"))
+		} else {
+			span.SetStatus(codes.Error, "downstream failure")
+			span.SetAttributes(attribute.String("error.type", "downstream"))
+		}
+	}
+	span.End(oteltrace.WithTimestamp(inv.End))
+
+	if clientSpan != nil {
+		if inv.Failed {
+			clientSpan.SetStatus(codes.Error, "call failed")
+		}
+		clientSpan.End(oteltrace.WithTimestamp(inv.CallEnd))
+	}
+}
+
+// emitLogs writes the operation's log lines that apply to this outcome, evenly spaced inside the span
+func (r *scenarioRunner) emitLogs(spanCtx context.Context, svc *scenarioService, inv *scenario.Invocation) {
+	var lines []*scenario.LogLine
+	for i := range inv.Operation.Logs {
+		line := &inv.Operation.Logs[i]
+		if line.When == "always" || (line.When == "error") == inv.Failed {
+			lines = append(lines, line)
+		}
+	}
+
+	window := inv.End.Sub(inv.Start)
+	for k, line := range lines {
+		logTime := inv.Start.Add(window * time.Duration(k+1) / time.Duration(len(lines)+1))
+		svc.logger.Emit(spanCtx, svc.logBuilders[line].build(line.Body, logTime))
+	}
+}
@@ -9,18 +9,23 @@ import (
 
 	"github.com/antithesishq/otel-datagen/internal/aggro"
 	"github.com/antithesishq/otel-datagen/internal/exporters"
+	"github.com/antithesishq/otel-datagen/internal/fleet"
+	"github.com/antithesishq/otel-datagen/internal/latency"
 	"github.com/antithesishq/otel-datagen/internal/randomness"
 	"github.com/antithesishq/otel-datagen/internal/timestamps"
 	"github.com/go-faker/faker/v4"
 	"go.opentelemetry.io/otel"
 	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	otellog "go.opentelemetry.io/otel/log"
+	sdklog "go.opentelemetry.io/otel/sdk/log"
 	"go.opentelemetry.io/otel/sdk/trace"
 	semconv "go.opentelemetry.io/otel/semconv/v1.21.0"
 	oteltrace "go.opentelemetry.io/otel/trace"
 )
 
 // GenerateTraces generates trace data with the given parameters
-func GenerateTraces(numTraces int, numSpans int, numAttributes int, overrideAttrs []string, resourceAttrs []string, otlpEndpoint string, stdoutEnabled bool, timestampConfig *timestamps.TimestampConfig) {
+func GenerateTraces(numTraces int, numSpans int, numAttributes int, overrideAttrs []string, resourceAttrs []string, otlpEndpoint string, stdoutEnabled bool, spanLogConfig *SpanLogConfig, latencyDist latency.Distribution, timestampConfig *timestamps.TimestampConfig) {
 	// Parse aggro configuration for this component
 	aggroConfig := aggro.ParseAggroConfig("traces")
 
@@ -30,26 +35,91 @@ func GenerateTraces(numTraces int, numSpans int, numAttributes int, overrideAttr
 	exporterConfig := exporters.ExporterConfig{
 		OTLPEndpoint:  otlpEndpoint,
 		Protocol:      "grpc", // Default to gRPC for traces
-		Insecure:      true,   // For local testing, can be made configurable
 		StdoutEnabled: stdoutEnabled,
 	}
 
+	// Resolve TLS, headers and authentication of the OTLP exporters
+	if err := exporters.ConfigureOTLPSecurity(&exporterConfig); err != nil {
+		log.Fatalf("Invalid OTLP security configuration: %v", err)
+	}
+	// Compression, timeout, retry and batching of the exporters
+	tuning, err := exporters.ParseTuningConfig()
+	if err != nil {
+		log.Fatalf("Invalid exporter tuning configuration: %v", err)
+	}
+	exporterConfig.Tuning = tuning
+	// Goroutines generating traces
+	workers, err := ParseWorkers()
+	if err != nil {
+		log.Fatalf("Invalid concurrency configuration: %v", err)
+	}
+
 	// Create dual trace exporters (console + OTLP when endpoint specified)
 	traceExporters, err := exporters.CreateDualTraceExporters(ctx, exporterConfig, nil)
 	if err != nil {
 		log.Fatalf("Failed to create trace exporters: %v", err)
 	}
 
+	// Zipkin and Jaeger exporters are added to OTLP; without an OTLP endpoint they replace the console output
+	legacyConfig, err := exporters.ParseLegacyTraceConfig("traces")
+	if err != nil {
+		log.Fatalf("Invalid legacy trace configuration: %v", err)
+	}
+	if legacyConfig.Enabled() {
+		legacyExporters, err := exporters.CreateLegacyTraceExporters(legacyConfig, aggroConfig)
+		if err != nil {
+			log.Fatalf("Failed to create legacy trace exporters: %v", err)
+		}
+		if otlpEndpoint == "" {
+			traceExporters = nil
+		}
+		traceExporters = append(traceExporters, legacyExporters...)
+	}
+
+	// A fleet spreads the traces over many instances, each exporting under its own resource
+	fleetConfig, err := fleet.ParseConfig("traces")
+	if err != nil {
+		log.Fatalf("Invalid fleet configuration: %v", err)
+	}
+	if fleetConfig.Enabled() {
+		signals := &fleetSignals{resourceAttrs: resourceAttrs, spanProcessors: []trace.SpanProcessor{exporters.GeneratedSpanCounter()}}
+		for _, exporter := range traceExporters {
+			signals.spanProcessors = append(signals.spanProcessors, exporterConfig.Tuning.SpanProcessor(exporter))
+		}
+		if spanLogConfig.Enabled() {
+			logExporters, err := exporters.CreateDualLogExporters(ctx, exporterConfig, nil)
+			if err != nil {
+				log.Fatalf("Failed to create log exporters: %v", err)
+			}
+			signals.logProcessors = []sdklog.Processor{exporters.GeneratedLogCounter()}
+			for _, exporter := range logExporters {
+				signals.logProcessors = append(signals.logProcessors, exporterConfig.Tuning.LogProcessor(exporter))
+			}
+		}
+
+		run, err := newFleetRun(ctx, fleetConfig, signals)
+		if err != nil {
+			log.Fatalf("Failed to start fleet: %v", err)
+		}
+		// Shutting down the shared processors flushes span logs before spans
+		defer run.close(ctx)
+
+		if err := generateTracesWithLogs(ctx, nil, nil, run, workers, numTraces, numSpans, numAttributes, overrideAttrs, aggroConfig, spanLogConfig, latencyDist, timestampConfig); err != nil {
+			log.Printf("Error generating traces: %v", err)
+		}
+		return
+	}
+
 	// Create resource
 	res, err := exporters.CreateResource(ctx, resourceAttrs)
 	if err != nil {
 		log.Fatalf("Failed to create resource: %v", err)
 	}
 
-	// Create tracer provider with multiple processors (one per exporter)
-	var spanProcessors []trace.TracerProviderOption
+	// Create tracer provider with multiple processors (one per exporter), after the generated span counter
+	spanProcessors := []trace.TracerProviderOption{trace.WithSpanProcessor(exporters.GeneratedSpanCounter())}
 	for _, exporter := range traceExporters {
-		spanProcessors = append(spanProcessors, trace.WithBatcher(exporter))
+		spanProcessors = append(spanProcessors, trace.WithSpanProcessor(exporterConfig.Tuning.SpanProcessor(exporter)))
 	}
 	spanProcessors = append(spanProcessors, trace.WithResource(res))
 
@@ -63,11 +133,40 @@ func GenerateTraces(numTraces int, numSpans int, numAttributes int, overrideAttr
 	// Set global tracer provider
 	otel.SetTracerProvider(tp)
 
+	// Logs emitted inside spans share this run's resource and exporter configuration
+	var lp *sdklog.LoggerProvider
+	if spanLogConfig.Enabled() {
+		logExporters, err := exporters.CreateDualLogExporters(ctx, exporterConfig, nil)
+		if err != nil {
+			log.Fatalf("Failed to create log exporters: %v", err)
+		}
+
+		logProcessors := []sdklog.LoggerProviderOption{sdklog.WithProcessor(exporters.GeneratedLogCounter())}
+		for _, exporter := range logExporters {
+			logProcessors = append(logProcessors, sdklog.WithProcessor(exporterConfig.Tuning.LogProcessor(exporter)))
+		}
+		logProcessors = append(logProcessors, sdklog.WithResource(res))
+
+		lp = sdklog.NewLoggerProvider(logProcessors...)
+		defer func() {
+			if err := lp.Shutdown(ctx); err != nil {
+				log.Printf("Error shutting down logger provider: %v", err)
+			}
+		}()
+	}
+
 	// Generate traces with the provider
-	if err := GenerateTracesWithProvider(ctx, tp, numTraces, numSpans, numAttributes, overrideAttrs, aggroConfig, timestampConfig); err != nil {
+	if err := generateTracesWithLogs(ctx, tp, lp, nil, workers, numTraces, numSpans, numAttributes, overrideAttrs, aggroConfig, spanLogConfig, latencyDist, timestampConfig); err != nil {
 		log.Printf("Error generating traces: %v", err)
 	}
 
+	// Flush span logs before spans so correlated logs are not lost on exit
+	if lp != nil {
+		if err := lp.ForceFlush(ctx); err != nil {
+			log.Printf("Error flushing logger: %v", err)
+		}
+	}
+
 	// Force flush to ensure output
 	if err := tp.ForceFlush(ctx); err != nil {
 		log.Printf("Error flushing tracer: %v", err)
@@ -76,8 +175,79 @@ func GenerateTraces(numTraces int, numSpans int, numAttributes int, overrideAttr
 
 // GenerateTracesWithProvider generates traces using the provided tracer provider
 func GenerateTracesWithProvider(ctx context.Context, tp *trace.TracerProvider, numTraces int, numSpans int, numAttributes int, overrideAttrs []string, aggroConfig *aggro.AggroConfig, timestampConfig *timestamps.TimestampConfig) error {
-	// Get tracer
-	tracer := otel.Tracer("otel-datagen")
+	return GenerateTracesWithLogs(ctx, tp, nil, numTraces, numSpans, numAttributes, overrideAttrs, aggroConfig, nil, nil, timestampConfig)
+}
+
+// GenerateTracesWithLogs generates traces and, when spanLogConfig is enabled, emits log records
+// inside each span using the provided logger provider so logs carry the span's TraceId/SpanId
+// Span durations are sampled from latencyDist (nil keeps the default 10-100ms)
+func GenerateTracesWithLogs(ctx context.Context, tp *trace.TracerProvider, lp *sdklog.LoggerProvider, numTraces int, numSpans int, numAttributes int, overrideAttrs []string, aggroConfig *aggro.AggroConfig, spanLogConfig *SpanLogConfig, latencyDist latency.Distribution, timestampConfig *timestamps.TimestampConfig) error {
+	return generateTracesWithLogs(ctx, tp, lp, nil, 1, numTraces, numSpans, numAttributes, overrideAttrs, aggroConfig, spanLogConfig, latencyDist, timestampConfig)
+}
+
+// generateTracesWithLogs is GenerateTracesWithLogs spread over the given number of workers
+// With a fleet run, every trace and its span logs are generated by the providers of the trace's instance
+// instead of tp and lp, which may then be nil
+func generateTracesWithLogs(ctx context.Context, tp *trace.TracerProvider, lp *sdklog.LoggerProvider, run *fleetRun, workers int, numTraces int, numSpans int, numAttributes int, overrideAttrs []string, aggroConfig *aggro.AggroConfig, spanLogConfig *SpanLogConfig, latencyDist latency.Distribution, timestampConfig *timestamps.TimestampConfig) error {
+	var tracer oteltrace.Tracer
+	if tp != nil {
+		tracer = tp.Tracer("otel-datagen")
+	}
+	var logger otellog.Logger
+	if lp != nil {
+		logger = lp.Logger("otel-datagen")
+	}
+
+	// Set up span log emission when requested, one emitter per worker as emitters track the current trace
+	var spanLogs []*spanLogEmitter
+	if spanLogConfig.Enabled() && (lp != nil || run != nil) {
+		builder := newLogRecordBuilder(numAttributes, overrideAttrs, aggroConfig, spanLogConfig.Severity)
+		for shard := 0; shard < workers; shard++ {
+			spanLogs = append(spanLogs, &spanLogEmitter{
+				builder: builder,
+				config:  spanLogConfig,
+			})
+		}
+	}
+
+	return generateSpans(ctx, tracer, run, workers, numTraces, numSpans, numAttributes, overrideAttrs, aggroConfig, 0, latencyDist, timestampConfig, func(ev spanEvent) {
+		// Emit correlated logs inside the span window
+		if spanLogs != nil {
+			spanLogger := logger
+			if ev.member != nil {
+				spanLogger = ev.member.logger
+			}
+			spanLogs[ev.shard].emit(spanLogger, ev.ctx, ev.name, ev.start, ev.end)
+		}
+		if ev.lastInTrace {
+			run.finish(ev.trace)
+		}
+	})
+}
+
+// spanEvent describes a finished generated span, for consumers such as span logs and RED metrics
+type spanEvent struct {
+	ctx         context.Context // Context carrying the span, for correlation and exemplars
+	name        string
+	operation   string // Span name without the trace index, used as a low-cardinality metric dimension
+	start       time.Time
+	end         time.Time
+	isError     bool
+	lastInTrace bool         // Set on the final span of each trace
+	shard       int          // Worker that generated the span
+	trace       int          // Index of the span's trace
+	member      *fleetMember // Fleet instance that generated the trace, nil without a fleet
+}
+
+// generateSpans generates traces with the given tracer and reports every finished span to onSpan
+// A fraction errorRate of spans is marked with an error status. Child durations are sampled from
+// latencyDist; the root span lasts at least until its last child ends and is reported last
+// Traces are spread over workers goroutines, which call onSpan concurrently. With a fleet run, every
+// trace is started by the tracer of its instance instead of tracer
+func generateSpans(ctx context.Context, tracer oteltrace.Tracer, run *fleetRun, workers int, numTraces int, numSpans int, numAttributes int, overrideAttrs []string, aggroConfig *aggro.AggroConfig, errorRate float64, latencyDist latency.Distribution, timestampConfig *timestamps.TimestampConfig, onSpan func(spanEvent)) error {
+	if latencyDist == nil {
+		latencyDist = latency.Default()
+	}
 
 	// Parse override attributes
 	overrides := make(map[string]string)
@@ -88,75 +258,131 @@ func GenerateTracesWithProvider(ctx context.Context, tp *trace.TracerProvider, n
 		}
 	}
 
-	// Generate the specified number of traces
-	totalSpans := 0
-	for traceIdx := 0; traceIdx < numTraces; traceIdx++ {
-		// Calculate timestamp for the first span of this trace (will be used for root span)
-		rootStartTime := timestampConfig.CalculateTimestamp(totalSpans)
+	// Generate the specified number of traces, each worker taking every workers-th trace
+	return runWorkers(workers, func(shard int) error {
+		for traceIdx := shard; traceIdx < numTraces; traceIdx += workers {
+			if numSpans <= 0 {
+				break
+			}
 
-		// Create a new trace context for this trace with the calculated timestamp
-		traceCtx, rootSpan := tracer.Start(ctx, fmt.Sprintf("trace-%d-root", traceIdx+1), oteltrace.WithTimestamp(rootStartTime))
+			// The instance generating this trace when simulating a fleet
+			member, err := run.member(traceIdx)
+			if err != nil {
+				return err
+			}
+			traceTracer := tracer
+			if member != nil {
+				traceTracer = member.tracer
+			}
+
+			// Calculate timestamp for the first span of this trace (will be used for root span)
+			totalSpans := traceIdx * numSpans
+			rootStartTime := timestampConfig.CalculateTimestamp(totalSpans)
+			rootName := fmt.Sprintf("trace-%d-root", traceIdx+1)
 
-		// Generate the specified number of spans for this trace
-		for spanIdx := 0; spanIdx < numSpans; spanIdx++ {
-			// Calculate timestamp for this span across all traces and spans
-			startTime := timestampConfig.CalculateTimestamp(totalSpans)
+			// Create a new trace context for this trace with the calculated timestamp
+			traceCtx, rootSpan := traceTracer.Start(ctx, rootName, oteltrace.WithTimestamp(rootStartTime))
+			rootIsError := setSpanAttributes(rootSpan, numAttributes, overrides, aggroConfig, errorRate)
 			totalSpans++
 
-			var span oteltrace.Span
-			var spanName string
-
-			// First span of each trace is the root span we already created
-			if spanIdx == 0 {
-				span = rootSpan
-				spanName = fmt.Sprintf("trace-%d-root", traceIdx+1)
-				// Root span already has the correct start time from tracer.Start()
-			} else {
-				// Child spans share the same trace context
-				spanName = fmt.Sprintf("trace-%d-span-%d", traceIdx+1, spanIdx+1)
-				_, span = tracer.Start(traceCtx, spanName, oteltrace.WithTimestamp(startTime))
-			}
+			// The root span covers its own latency and all of its children
+			rootEndTime := rootStartTime.Add(latencyDist.Sample())
 
-			// Create attributes list starting with base attribute
-			var attrs []attribute.KeyValue
-			attrs = append(attrs, semconv.HTTPMethodKey.String("GET"))
+			// Generate the remaining spans of this trace as children of the root
+			for spanIdx := 1; spanIdx < numSpans; spanIdx++ {
+				// Calculate timestamp for this span across all traces and spans
+				startTime := timestampConfig.CalculateTimestamp(totalSpans)
+				totalSpans++
 
-			// Generate random attributes using faker
-			for j := 0; j < numAttributes; j++ {
-				key := fmt.Sprintf("fake.attr.%d", j+1)
-				value := faker.Word()
+				spanName := fmt.Sprintf("trace-%d-span-%d", traceIdx+1, spanIdx+1)
+				spanCtx, span := traceTracer.Start(traceCtx, spanName, oteltrace.WithTimestamp(startTime))
+				isError := setSpanAttributes(span, numAttributes, overrides, aggroConfig, errorRate)
 
-				// Check for override
-				if override, exists := overrides[key]; exists {
-					value = override
+				endTime := startTime.Add(latencyDist.Sample())
+				span.End(oteltrace.WithTimestamp(endTime))
+				if endTime.After(rootEndTime) {
+					rootEndTime = endTime
 				}
 
-				attrs = append(attrs, attribute.String(key, value))
+				if onSpan != nil {
+					onSpan(spanEvent{
+						ctx:       spanCtx,
+						name:      spanName,
+						operation: fmt.Sprintf("span-%d", spanIdx+1),
+						start:     startTime,
+						end:       endTime,
+						isError:   isError,
+						shard:     shard,
+						trace:     traceIdx,
+						member:    member,
+					})
+				}
 			}
 
-			// Apply any remaining overrides that didn't match generated keys
-			for key, value := range overrides {
-				if !strings.HasPrefix(key, "fake.attr.") {
-					attrs = append(attrs, attribute.String(key, value))
-				}
+			rootSpan.End(oteltrace.WithTimestamp(rootEndTime))
+
+			if onSpan != nil {
+				onSpan(spanEvent{
+					ctx:         traceCtx,
+					name:        rootName,
+					operation:   "root",
+					start:       rootStartTime,
+					end:         rootEndTime,
+					isError:     rootIsError,
+					lastInTrace: true,
+					shard:       shard,
+					trace:       traceIdx,
+					member:      member,
+				})
 			}
+		}
 
-			// Apply aggro modifications if configured
-			skipKeys := []string{"http.method"} // System attributes that shouldn't be replaced
-			modifiedAttrs, metadataAttrs := aggroConfig.ApplyAggroToTraceAttributes(attrs, skipKeys, "grpc")
-			attrs = modifiedAttrs
+		return nil
+	})
+}
 
-			// Add metadata attributes about aggro modifications
-			attrs = append(attrs, metadataAttrs...)
+// setSpanAttributes adds the base, fake, override and aggro attributes to a span and marks a fraction
+// errorRate of spans as failed, reporting whether the span failed
+func setSpanAttributes(span oteltrace.Span, numAttributes int, overrides map[string]string, aggroConfig *aggro.AggroConfig, errorRate float64) bool {
+	// Create attributes list starting with base attribute
+	var attrs []attribute.KeyValue
+	attrs = append(attrs, semconv.HTTPMethodKey.String("GET"))
 
-			span.SetAttributes(attrs...)
+	// Generate random attributes using faker
+	for j := 0; j < numAttributes; j++ {
+		key := fmt.Sprintf("fake.attr.%d", j+1)
+		value := faker.Word()
 
-			// End span with a duration of 10-100ms after start time
-			spanDuration := time.Duration(randomness.Intn(90)+10) * time.Millisecond
-			endTime := startTime.Add(spanDuration)
-			span.End(oteltrace.WithTimestamp(endTime))
+		// Check for override
+		if override, exists := overrides[key]; exists {
+			value = override
+		}
+
+		attrs = append(attrs, attribute.String(key, value))
+	}
+
+	// Apply any remaining overrides that didn't match generated keys
+	for key, value := range overrides {
+		if !strings.HasPrefix(key, "fake.attr.") {
+			attrs = append(attrs, attribute.String(key, value))
 		}
 	}
 
-	return nil
+	// Apply aggro modifications if configured
+	skipKeys := []string{"http.method"} // System attributes that shouldn't be replaced
+	modifiedAttrs, metadataAttrs := aggroConfig.ApplyAggroToTraceAttributes(attrs, skipKeys, "grpc")
+	attrs = modifiedAttrs
+
+	// Add metadata attributes about aggro modifications
+	attrs = append(attrs, metadataAttrs...)
+
+	span.SetAttributes(attrs...)
+
+	// Mark a fraction of spans as failed
+	isError := errorRate > 0 && randomness.Float64() < errorRate
+	if isError {
+		span.SetStatus(codes.Error, "simulated failure")
+		span.SetAttributes(attribute.String("error.type", "simulated"))
+	}
+	return isError
 }
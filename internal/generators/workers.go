@@ -0,0 +1,37 @@
+package generators
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/spf13/viper"
+)
+
+// ParseWorkers reads the number of goroutines generating traces, logs and periodic metrics from viper (0 = 1)
+func ParseWorkers() (int, error) {
+	workers := viper.GetInt("concurrency.workers")
+	if workers < 0 {
+		return 0, fmt.Errorf("workers must not be negative, got %d", workers)
+	}
+	return max(1, workers), nil
+}
+
+// runWorkers calls fn once per shard on its own goroutine and waits for all of them
+// Shard s of n generates items s, s+n, s+2n, ..., so names and timestamps stay those of a single worker
+func runWorkers(workers int, fn func(shard int) error) error {
+	if workers <= 1 {
+		return fn(0)
+	}
+	errs := make([]error, workers)
+	var wg sync.WaitGroup
+	for shard := 0; shard < workers; shard++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			errs[shard] = fn(shard)
+		}()
+	}
+	wg.Wait()
+	return errors.Join(errs...)
+}
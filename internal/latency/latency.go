@@ -0,0 +1,334 @@
+package latency
+
+import (
+	"bufio"
+	"fmt"
+	"math"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/antithesishq/otel-datagen/internal/randomness"
+	"github.com/antithesishq/otel-datagen/internal/spec"
+)
+
+// Distribution samples span durations
+type Distribution interface {
+	Sample() time.Duration
+}
+
+// Supported distribution kinds
+var kinds = []string{"fixed", "uniform", "normal", "lognormal", "pareto", "bimodal", "empirical"}
+
+// Default returns the historical span duration distribution (uniform 10-100ms)
+func Default() Distribution {
+	return uniform{min: 10 * time.Millisecond, max: 100 * time.Millisecond}
+}
+
+// Parse parses a latency distribution spec such as "lognormal:median=50ms,sigma=0.5"
+// A plain duration such as "50ms" means a typical latency, varying uniformly between half and one and a half times it
+func Parse(s string) (Distribution, error) {
+	if d, err := time.ParseDuration(strings.TrimSpace(s)); err == nil {
+		if d < 0 {
+			return nil, fmt.Errorf("latency '%s' must not be negative", s)
+		}
+		return uniform{min: d / 2, max: d + d/2}, nil
+	}
+
+	sp, err := spec.Parse(s)
+	if err != nil {
+		return nil, err
+	}
+
+	var dist Distribution
+	switch sp.Kind {
+	case "fixed":
+		dist, err = parseFixed(sp)
+	case "uniform":
+		dist, err = parseUniform(sp)
+	case "normal":
+		dist, err = parseNormal(sp)
+	case "lognormal":
+		dist, err = parseLognormal(sp)
+	case "pareto":
+		dist, err = parsePareto(sp)
+	case "bimodal":
+		dist, err = parseBimodal(sp)
+	case "empirical":
+		dist, err = parseEmpirical(sp)
+	default:
+		return nil, fmt.Errorf("unknown latency distribution '%s' (supported: %s)", sp.Kind, strings.Join(kinds, ", "))
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := sp.CheckUnused(); err != nil {
+		return nil, err
+	}
+	return dist, nil
+}
+
+// fixed always returns the same duration
+type fixed struct {
+	value time.Duration
+}
+
+func (d fixed) Sample() time.Duration { return d.value }
+
+// parseFixed parses "fixed:value=50ms"
+func parseFixed(sp *spec.Spec) (Distribution, error) {
+	value, err := sp.Duration("value", 0)
+	if err != nil {
+		return nil, err
+	}
+	if value <= 0 {
+		return nil, fmt.Errorf("fixed latency requires a positive value (e.g., 'fixed:value=50ms')")
+	}
+	return fixed{value: value}, nil
+}
+
+// uniform returns durations uniformly distributed in [min, max]
+type uniform struct {
+	min time.Duration
+	max time.Duration
+}
+
+func (d uniform) Sample() time.Duration {
+	return d.min + time.Duration(randomness.Float64()*float64(d.max-d.min))
+}
+
+func parseUniform(sp *spec.Spec) (Distribution, error) {
+	lo, err := sp.Duration("min", 0)
+	if err != nil {
+		return nil, err
+	}
+	hi, err := sp.Duration("max", 0)
+	if err != nil {
+		return nil, err
+	}
+	if lo < 0 || hi < lo {
+		return nil, fmt.Errorf("uniform latency requires 0 <= min <= max")
+	}
+	return uniform{min: lo, max: hi}, nil
+}
+
+// normal returns normally distributed durations, clamped at zero
+type normal struct {
+	mean   time.Duration
+	stddev time.Duration
+}
+
+func (d normal) Sample() time.Duration {
+	return clamp(float64(d.mean) + randomness.NormFloat64()*float64(d.stddev))
+}
+
+func parseNormal(sp *spec.Spec) (Distribution, error) {
+	mean, err := sp.Duration("mean", 0)
+	if err != nil {
+		return nil, err
+	}
+	stddev, err := sp.Duration("stddev", mean/10)
+	if err != nil {
+		return nil, err
+	}
+	if mean <= 0 || stddev < 0 {
+		return nil, fmt.Errorf("normal latency requires a positive mean and non-negative stddev")
+	}
+	return normal{mean: mean, stddev: stddev}, nil
+}
+
+// lognormal returns log-normally distributed durations, the typical shape of service latencies
+type lognormal struct {
+	median time.Duration
+	sigma  float64
+}
+
+func (d lognormal) Sample() time.Duration {
+	return clamp(float64(d.median) * math.Exp(d.sigma*randomness.NormFloat64()))
+}
+
+func parseLognormal(sp *spec.Spec) (Distribution, error) {
+	median, err := sp.Duration("median", 0)
+	if err != nil {
+		return nil, err
+	}
+	sigma, err := sp.Float("sigma", 0.5)
+	if err != nil {
+		return nil, err
+	}
+	if median <= 0 || sigma < 0 {
+		return nil, fmt.Errorf("lognormal latency requires a positive median and non-negative sigma")
+	}
+	return lognormal{median: median, sigma: sigma}, nil
+}
+
+// pareto returns long-tailed durations starting at min, capped at max
+type pareto struct {
+	min   time.Duration
+	alpha float64
+	max   time.Duration
+}
+
+func (d pareto) Sample() time.Duration {
+	// Inverse transform; 1-U avoids division by zero
+	value := float64(d.min) / math.Pow(1-randomness.Float64(), 1/d.alpha)
+	if value > float64(d.max) {
+		return d.max
+	}
+	return clamp(value)
+}
+
+func parsePareto(sp *spec.Spec) (Distribution, error) {
+	lo, err := sp.Duration("min", 0)
+	if err != nil {
+		return nil, err
+	}
+	alpha, err := sp.Float("alpha", 1.5)
+	if err != nil {
+		return nil, err
+	}
+	hi, err := sp.Duration("max", lo*1000)
+	if err != nil {
+		return nil, err
+	}
+	if lo <= 0 || alpha <= 0 || hi < lo {
+		return nil, fmt.Errorf("pareto latency requires a positive min and alpha, and max >= min")
+	}
+	return pareto{min: lo, alpha: alpha, max: hi}, nil
+}
+
+// bimodal mixes a fast and a slow log-normal mode, such as cache hits and misses
+type bimodal struct {
+	fast      lognormal
+	slow      lognormal
+	slowRatio float64
+}
+
+func (d bimodal) Sample() time.Duration {
+	if randomness.Float64() < d.slowRatio {
+		return d.slow.Sample()
+	}
+	return d.fast.Sample()
+}
+
+func parseBimodal(sp *spec.Spec) (Distribution, error) {
+	fast, err := sp.Duration("fast", 0)
+	if err != nil {
+		return nil, err
+	}
+	slow, err := sp.Duration("slow", 0)
+	if err != nil {
+		return nil, err
+	}
+	slowRatio, err := sp.Float("slow_ratio", 0.1)
+	if err != nil {
+		return nil, err
+	}
+	sigma, err := sp.Float("sigma", 0.2)
+	if err != nil {
+		return nil, err
+	}
+	if fast <= 0 || slow <= 0 || slowRatio < 0 || slowRatio > 1 || sigma < 0 {
+		return nil, fmt.Errorf("bimodal latency requires positive fast and slow modes and slow_ratio in [0, 1]")
+	}
+	return bimodal{
+		fast:      lognormal{median: fast, sigma: sigma},
+		slow:      lognormal{median: slow, sigma: sigma},
+		slowRatio: slowRatio,
+	}, nil
+}
+
+// empirical samples from a histogram: a bucket is chosen by its count, then a duration uniformly within it
+type empirical struct {
+	bounds []time.Duration // Upper bound of each bucket, ascending
+	counts []int
+	total  int
+}
+
+func (d empirical) Sample() time.Duration {
+	n := randomness.Intn(d.total)
+	for i, count := range d.counts {
+		if n < count {
+			lower := time.Duration(0)
+			if i > 0 {
+				lower = d.bounds[i-1]
+			}
+			return lower + time.Duration(randomness.Float64()*float64(d.bounds[i]-lower))
+		}
+		n -= count
+	}
+	return d.bounds[len(d.bounds)-1]
+}
+
+// parseEmpirical reads a histogram file given as "empirical:file=latencies.txt"
+// Each line holds a bucket upper bound and a count, e.g. "50ms 1200"; blank lines and '#' comments are ignored
+func parseEmpirical(sp *spec.Spec) (Distribution, error) {
+	path := sp.String("file", "")
+	if path == "" {
+		return nil, fmt.Errorf("empirical latency requires a histogram file (e.g., 'empirical:file=latencies.txt')")
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open latency histogram: %w", err)
+	}
+	defer f.Close()
+
+	type bucket struct {
+		bound time.Duration
+		count int
+	}
+	var buckets []bucket
+
+	scanner := bufio.NewScanner(f)
+	for lineNum := 1; scanner.Scan(); lineNum++ {
+		line, _, _ := strings.Cut(scanner.Text(), "#")
+		fields := strings.FieldsFunc(line, func(r rune) bool {
+			return r == ',' || r == ' ' || r == '\t'
+		})
+		if len(fields) == 0 {
+			continue
+		}
+		if len(fields) != 2 {
+			return nil, fmt.Errorf("%s:%d: expected '<upper bound> <count>'", path, lineNum)
+		}
+		bound, err := time.ParseDuration(fields[0])
+		if err != nil || bound <= 0 {
+			return nil, fmt.Errorf("%s:%d: invalid bucket bound '%s'", path, lineNum, fields[0])
+		}
+		count, err := strconv.Atoi(fields[1])
+		if err != nil || count < 0 {
+			return nil, fmt.Errorf("%s:%d: invalid count '%s'", path, lineNum, fields[1])
+		}
+		buckets = append(buckets, bucket{bound: bound, count: count})
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read latency histogram: %w", err)
+	}
+
+	sort.Slice(buckets, func(i, j int) bool { return buckets[i].bound < buckets[j].bound })
+
+	dist := empirical{}
+	for _, b := range buckets {
+		dist.bounds = append(dist.bounds, b.bound)
+		dist.counts = append(dist.counts, b.count)
+		dist.total += b.count
+	}
+	if dist.total == 0 {
+		return nil, fmt.Errorf("latency histogram %s has no samples", path)
+	}
+	return dist, nil
+}
+
+// clamp converts a float duration to time.Duration, keeping it within [0, MaxInt64]
+func clamp(value float64) time.Duration {
+	if value < 0 {
+		return 0
+	}
+	if value >= math.MaxInt64 {
+		return time.Duration(math.MaxInt64)
+	}
+	return time.Duration(value)
+}
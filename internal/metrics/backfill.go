@@ -0,0 +1,563 @@
+package metrics
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/antithesishq/otel-datagen/internal/aggro"
+	"github.com/antithesishq/otel-datagen/internal/randomness"
+	"github.com/antithesishq/otel-datagen/internal/shapes"
+	"github.com/antithesishq/otel-datagen/internal/timestamps"
+	"github.com/spf13/viper"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/sdk/instrumentation"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+	"go.opentelemetry.io/otel/sdk/resource"
+)
+
+// BackfillConfig controls historical backfill: data points for every step from the timestamp start to End,
+// built directly as metricdata instead of being recorded through a meter provider
+type BackfillConfig struct {
+	Enabled   bool
+	End       time.Time
+	BatchSize int // Maximum data points per export
+}
+
+// ParseBackfillConfig reads the backfill settings for the given component from viper
+func ParseBackfillConfig(component string) (*BackfillConfig, error) {
+	prefix := "generate." + component + "."
+	config := &BackfillConfig{
+		Enabled:   viper.GetBool(prefix + "backfill"),
+		BatchSize: viper.GetInt(prefix + "backfill_batch_size"),
+	}
+	if config.BatchSize == 0 {
+		config.BatchSize = 10000
+	}
+	end, err := timestamps.ParseTime(viper.GetString(prefix + "backfill_end"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid backfill-end: %w", err)
+	}
+	config.End = end
+
+	if config.BatchSize < 1 {
+		return nil, fmt.Errorf("backfill batch size must be at least 1, got %d", config.BatchSize)
+	}
+	return config, nil
+}
+
+// BackfillMetric describes one metric to backfill
+type BackfillMetric struct {
+	Name        string
+	Type        string // Any type supported by GenerateWithShape
+	Unit        string
+	Description string
+	NewShape    func() shapes.Shape           // Value shape of each new series
+	Series      func() [][]attribute.KeyValue // Series of the next step
+}
+
+// CatalogBackfillMetrics describes every metric of a catalog for backfill
+// shapeConfig, when set, overrides entry shapes for the metrics named by --metric-value-shape
+func CatalogBackfillMetrics(catalog []CatalogEntry, shapeConfig *ShapeConfig) ([]BackfillMetric, error) {
+	var backfill []BackfillMetric
+	for i := range catalog {
+		entry := catalog[i]
+		spec := entry.shapeSpec()
+		if shapeConfig != nil {
+			if override, ok := shapeConfig.MetricShapes[entry.Name]; ok {
+				spec = override
+			}
+		}
+		if _, err := shapes.Parse(spec, entry.Min, entry.Max); err != nil {
+			return nil, fmt.Errorf("metric '%s': %w", entry.Name, err)
+		}
+		series := entry.Series()
+		backfill = append(backfill, BackfillMetric{
+			Name:        entry.Name,
+			Type:        entry.Type,
+			Unit:        entry.Unit,
+			Description: entry.Description,
+			NewShape: func() shapes.Shape {
+				shape, _ := shapes.Parse(spec, entry.Min, entry.Max)
+				return shape
+			},
+			Series: func() [][]attribute.KeyValue { return series },
+		})
+	}
+	return backfill, nil
+}
+
+// Backfill builds metric data points step by step without a meter provider, keeping only per-series state,
+// so long ranges with many series can be generated and exported in bounded batches
+type Backfill struct {
+	metrics   []*backfillMetric
+	lifecycle *Lifecycle
+
+	aggroConfig *aggro.AggroConfig
+	aggroValues []string
+	aggroProb   float64
+}
+
+// backfillMetric is a metric with its instrument semantics and the state of its series
+type backfillMetric struct {
+	BackfillMetric
+	kind        sdkmetric.InstrumentKind
+	integer     bool
+	temporality metricdata.Temporality
+	aggregation sdkmetric.Aggregation // Histograms only: explicit or exponential
+
+	values map[attribute.Distinct]func() float64 // By series, before aggro attributes
+	states map[attribute.Distinct]*backfillState // By data point attributes
+}
+
+// backfillState is the running state of one data point series
+type backfillState struct {
+	total     float64 // Cumulative sum of synchronous sums
+	last      float64 // Last observed value of observable sums
+	observed  bool
+	histogram *histogramState
+}
+
+// backfillKinds maps metric types to the instrument they stand for and whether values are integers
+var backfillKinds = map[string]struct {
+	kind    sdkmetric.InstrumentKind
+	integer bool
+}{
+	"counter":                          {sdkmetric.InstrumentKindCounter, true},
+	"int64-counter":                    {sdkmetric.InstrumentKindCounter, true},
+	"float64-counter":                  {sdkmetric.InstrumentKindCounter, false},
+	"updowncounter":                    {sdkmetric.InstrumentKindUpDownCounter, true},
+	"int64-updowncounter":              {sdkmetric.InstrumentKindUpDownCounter, true},
+	"float64-updowncounter":            {sdkmetric.InstrumentKindUpDownCounter, false},
+	"histogram":                        {sdkmetric.InstrumentKindHistogram, false},
+	"float64-histogram":                {sdkmetric.InstrumentKindHistogram, false},
+	"int64-histogram":                  {sdkmetric.InstrumentKindHistogram, true},
+	"gauge":                            {sdkmetric.InstrumentKindGauge, true},
+	"int64-gauge":                      {sdkmetric.InstrumentKindGauge, true},
+	"float64-gauge":                    {sdkmetric.InstrumentKindGauge, false},
+	"observable-counter":               {sdkmetric.InstrumentKindObservableCounter, true},
+	"int64-observable-counter":         {sdkmetric.InstrumentKindObservableCounter, true},
+	"float64-observable-counter":       {sdkmetric.InstrumentKindObservableCounter, false},
+	"observable-updowncounter":         {sdkmetric.InstrumentKindObservableUpDownCounter, true},
+	"int64-observable-updowncounter":   {sdkmetric.InstrumentKindObservableUpDownCounter, true},
+	"float64-observable-updowncounter": {sdkmetric.InstrumentKindObservableUpDownCounter, false},
+	"observable-gauge":                 {sdkmetric.InstrumentKindObservableGauge, true},
+	"int64-observable-gauge":           {sdkmetric.InstrumentKindObservableGauge, true},
+	"float64-observable-gauge":         {sdkmetric.InstrumentKindObservableGauge, false},
+}
+
+// NewBackfill prepares the metrics for backfill
+// temporality selects cumulative or delta data per instrument kind; histogramConfig selects histogram buckets;
+// lifecycle, when set, simulates restarts, gaps and staleness on every step
+func NewBackfill(backfillMetrics []BackfillMetric, temporality sdkmetric.TemporalitySelector, histogramConfig *HistogramConfig, aggroConfig *aggro.AggroConfig, lifecycle *Lifecycle, protocol string) (*Backfill, error) {
+	b := &Backfill{lifecycle: lifecycle, aggroConfig: aggroConfig}
+	b.aggroValues, b.aggroProb = newAggroValues(aggroConfig, protocol)
+	if histogramConfig == nil {
+		histogramConfig = DefaultHistogramConfig()
+	}
+
+	for _, metric := range backfillMetrics {
+		k, ok := backfillKinds[metric.Type]
+		if !ok {
+			return nil, unsupportedMetricType(metric.Type)
+		}
+		m := &backfillMetric{
+			BackfillMetric: metric,
+			kind:           k.kind,
+			integer:        k.integer,
+			temporality:    temporality(k.kind),
+			values:         make(map[attribute.Distinct]func() float64),
+			states:         make(map[attribute.Distinct]*backfillState),
+		}
+		if k.kind == sdkmetric.InstrumentKindHistogram {
+			m.aggregation = histogramConfig.aggregationFor(metric.Name)
+			if m.aggregation == nil {
+				m.aggregation = sdkmetric.DefaultAggregationSelector(sdkmetric.InstrumentKindHistogram)
+			}
+		}
+		b.metrics = append(b.metrics, m)
+	}
+	return b, nil
+}
+
+// Run builds every step from start through end and passes the data to export in batches of at most batchSize
+// data points. It returns the number of data points and batches exported
+func (b *Backfill) Run(ctx context.Context, res *resource.Resource, start time.Time, end time.Time, step time.Duration, batchSize int, export func(context.Context, *metricdata.ResourceMetrics) error) (int, int, error) {
+	if step <= 0 {
+		return 0, 0, fmt.Errorf("backfill needs a positive step, got %s", step)
+	}
+	if end.Before(start) {
+		return 0, 0, fmt.Errorf("backfill end %s is before start %s", end.Format(time.RFC3339), start.Format(time.RFC3339))
+	}
+
+	batch := &backfillBatch{
+		resource: res,
+		scope:    instrumentation.Scope{Name: "otel-datagen"},
+		limit:    batchSize,
+		export:   export,
+		index:    make(map[string]int),
+	}
+
+	previous := start
+	for i := 0; ; i++ {
+		timestamp := start.Add(time.Duration(i) * step)
+		if timestamp.After(end) {
+			break
+		}
+
+		rm := &metricdata.ResourceMetrics{Resource: res, ScopeMetrics: []metricdata.ScopeMetrics{{Scope: batch.scope}}}
+		for _, m := range b.metrics {
+			rm.ScopeMetrics[0].Metrics = append(rm.ScopeMetrics[0].Metrics, b.step(m, i, start, previous, timestamp))
+		}
+		if b.lifecycle != nil {
+			b.lifecycle.Apply(rm, timestamp)
+		}
+		for _, m := range rm.ScopeMetrics[0].Metrics {
+			if err := batch.add(ctx, m); err != nil {
+				return batch.points, batch.batches, err
+			}
+		}
+		previous = timestamp
+	}
+
+	err := batch.flush(ctx)
+	return batch.points, batch.batches, err
+}
+
+// step builds the data points of one metric at timestamp
+// Values follow the same rules as SeriesRecorder: sums of synchronous instruments add increments of the shape,
+// observable instruments observe its values
+func (b *Backfill) step(m *backfillMetric, iteration int, start, previous, timestamp time.Time) metricdata.Metrics {
+	var numbers []numberPoint
+	var histograms []histogramPoint
+
+	// Cumulative data starts with the backfill, delta data at the previous step
+	pointStart := start
+	if m.temporality == metricdata.DeltaTemporality {
+		pointStart = previous
+	}
+
+	for _, attrs := range m.Series() {
+		value := m.next(attrs)
+
+		attrs = appendAggroValue(attrs, b.aggroValues, b.aggroProb)
+		if m.kind == sdkmetric.InstrumentKindHistogram {
+			value, attrs = applyHistogramAggro(b.aggroConfig, value, attrs, iteration)
+		}
+		if m.integer {
+			value = float64(roundToInt64(value))
+		}
+		set := attribute.NewSet(attrs...)
+		state := m.state(set)
+
+		switch m.kind {
+		case sdkmetric.InstrumentKindCounter, sdkmetric.InstrumentKindUpDownCounter:
+			state.total += value
+			if m.temporality == metricdata.CumulativeTemporality {
+				value = state.total
+			}
+			numbers = append(numbers, numberPoint{attrs: set, start: pointStart, time: timestamp, value: value})
+		case sdkmetric.InstrumentKindObservableCounter, sdkmetric.InstrumentKindObservableUpDownCounter:
+			observed := value
+			if m.temporality == metricdata.DeltaTemporality && state.observed {
+				value -= state.last
+			}
+			state.last, state.observed = observed, true
+			numbers = append(numbers, numberPoint{attrs: set, start: pointStart, time: timestamp, value: value})
+		case sdkmetric.InstrumentKindHistogram:
+			if state.histogram == nil || m.temporality == metricdata.DeltaTemporality {
+				state.histogram = newHistogramState(m.aggregation)
+			}
+			state.histogram.record(value)
+			histograms = append(histograms, histogramPoint{attrs: set, start: pointStart, time: timestamp, state: state.histogram})
+		default:
+			// Gauges: StartTime matches Time, as for timestamped gauges
+			numbers = append(numbers, numberPoint{attrs: set, start: timestamp, time: timestamp, value: value})
+		}
+	}
+
+	metric := metricdata.Metrics{Name: m.Name, Description: m.Description, Unit: m.Unit}
+	switch {
+	case m.kind == sdkmetric.InstrumentKindHistogram:
+		metric.Data = histogramData(m, histograms)
+	case m.integer:
+		metric.Data = numberData[int64](m, numbers)
+	default:
+		metric.Data = numberData[float64](m, numbers)
+	}
+	return metric
+}
+
+// next returns the next value of a series, creating its shape on first sight
+func (m *backfillMetric) next(attrs []attribute.KeyValue) float64 {
+	set := attribute.NewSet(attrs...)
+	key := set.Equivalent()
+	next, ok := m.values[key]
+	if !ok {
+		shape := m.NewShape()
+		next = shape.Next
+		switch m.kind {
+		case sdkmetric.InstrumentKindCounter:
+			next = shapes.CounterIncrements(shape)
+		case sdkmetric.InstrumentKindUpDownCounter:
+			next = shapes.Increments(shape)
+		}
+		updown := m.kind == sdkmetric.InstrumentKindUpDownCounter || m.kind == sdkmetric.InstrumentKindObservableUpDownCounter
+		if updown && shapes.Independent(shape) {
+			values := next
+			next = func() float64 {
+				value := values()
+				if randomness.Float64() < 0.3 { // 30% chance of negative value
+					value = -value
+				}
+				return value
+			}
+		}
+		m.values[key] = next
+	}
+	return next()
+}
+
+// state returns the running state of a data point series
+func (m *backfillMetric) state(set attribute.Set) *backfillState {
+	key := set.Equivalent()
+	state, ok := m.states[key]
+	if !ok {
+		state = &backfillState{}
+		m.states[key] = state
+	}
+	return state
+}
+
+// numberPoint is a sum or gauge data point before conversion to its value type
+type numberPoint struct {
+	attrs       attribute.Set
+	start, time time.Time
+	value       float64
+}
+
+// histogramPoint is a histogram data point before conversion to its value type
+type histogramPoint struct {
+	attrs       attribute.Set
+	start, time time.Time
+	state       *histogramState
+}
+
+// numberData builds the sum or gauge data of a metric
+func numberData[N int64 | float64](m *backfillMetric, points []numberPoint) metricdata.Aggregation {
+	dataPoints := make([]metricdata.DataPoint[N], len(points))
+	for i, p := range points {
+		dataPoints[i] = metricdata.DataPoint[N]{Attributes: p.attrs, StartTime: p.start, Time: p.time, Value: N(p.value)}
+	}
+	switch m.kind {
+	case sdkmetric.InstrumentKindGauge, sdkmetric.InstrumentKindObservableGauge:
+		return metricdata.Gauge[N]{DataPoints: dataPoints}
+	}
+	monotonic := m.kind == sdkmetric.InstrumentKindCounter || m.kind == sdkmetric.InstrumentKindObservableCounter
+	return metricdata.Sum[N]{DataPoints: dataPoints, Temporality: m.temporality, IsMonotonic: monotonic}
+}
+
+// histogramData builds the explicit or exponential histogram data of a metric
+func histogramData(m *backfillMetric, points []histogramPoint) metricdata.Aggregation {
+	_, exponential := m.aggregation.(sdkmetric.AggregationBase2ExponentialHistogram)
+	switch {
+	case exponential && m.integer:
+		return exponentialHistogramData[int64](m, points)
+	case exponential:
+		return exponentialHistogramData[float64](m, points)
+	case m.integer:
+		return explicitHistogramData[int64](m, points)
+	default:
+		return explicitHistogramData[float64](m, points)
+	}
+}
+
+// explicitHistogramData builds explicit bucket histogram data
+func explicitHistogramData[N int64 | float64](m *backfillMetric, points []histogramPoint) metricdata.Histogram[N] {
+	dataPoints := make([]metricdata.HistogramDataPoint[N], len(points))
+	for i, p := range points {
+		dataPoints[i] = histogramDataPoint[N](p.state, p.attrs, p.start, p.time)
+	}
+	return metricdata.Histogram[N]{DataPoints: dataPoints, Temporality: m.temporality}
+}
+
+// exponentialHistogramData builds exponential histogram data
+func exponentialHistogramData[N int64 | float64](m *backfillMetric, points []histogramPoint) metricdata.ExponentialHistogram[N] {
+	dataPoints := make([]metricdata.ExponentialHistogramDataPoint[N], len(points))
+	for i, p := range points {
+		dataPoints[i] = exponentialHistogramDataPoint[N](p.state, p.attrs, p.start, p.time)
+	}
+	return metricdata.ExponentialHistogram[N]{DataPoints: dataPoints, Temporality: m.temporality}
+}
+
+// backfillBatch collects data points of many steps and exports them once the limit is reached
+// Data points of the same metric are merged into one metric, so a batch holds each metric once
+type backfillBatch struct {
+	resource *resource.Resource
+	scope    instrumentation.Scope
+	limit    int
+	export   func(context.Context, *metricdata.ResourceMetrics) error
+
+	metrics []metricdata.Metrics
+	index   map[string]int // Position of each metric in metrics
+	size    int
+
+	points  int
+	batches int
+}
+
+// add appends the data points of a metric, exporting full batches as it goes
+func (b *backfillBatch) add(ctx context.Context, m metricdata.Metrics) error {
+	for pointCount(m.Data) > 0 {
+		head, tail := splitData(m.Data, b.limit-b.size)
+		n := pointCount(head)
+
+		i, ok := b.index[m.Name]
+		if !ok {
+			i = len(b.metrics)
+			b.index[m.Name] = i
+			b.metrics = append(b.metrics, metricdata.Metrics{Name: m.Name, Description: m.Description, Unit: m.Unit})
+		}
+		b.metrics[i].Data = mergeData(b.metrics[i].Data, head)
+		b.size += n
+
+		if b.size >= b.limit {
+			if err := b.flush(ctx); err != nil {
+				return err
+			}
+		}
+		m.Data = tail
+	}
+	return nil
+}
+
+// flush exports the collected data points, if any
+func (b *backfillBatch) flush(ctx context.Context) error {
+	if b.size == 0 {
+		return nil
+	}
+	rm := &metricdata.ResourceMetrics{
+		Resource:     b.resource,
+		ScopeMetrics: []metricdata.ScopeMetrics{{Scope: b.scope, Metrics: b.metrics}},
+	}
+	if err := b.export(ctx, rm); err != nil {
+		return err
+	}
+	b.points += b.size
+	b.batches++
+	b.metrics = nil
+	b.index = make(map[string]int)
+	b.size = 0
+	return nil
+}
+
+// pointCount returns the number of data points of metric data
+func pointCount(data metricdata.Aggregation) int {
+	switch d := data.(type) {
+	case metricdata.Sum[int64]:
+		return len(d.DataPoints)
+	case metricdata.Sum[float64]:
+		return len(d.DataPoints)
+	case metricdata.Gauge[int64]:
+		return len(d.DataPoints)
+	case metricdata.Gauge[float64]:
+		return len(d.DataPoints)
+	case metricdata.Histogram[int64]:
+		return len(d.DataPoints)
+	case metricdata.Histogram[float64]:
+		return len(d.DataPoints)
+	case metricdata.ExponentialHistogram[int64]:
+		return len(d.DataPoints)
+	case metricdata.ExponentialHistogram[float64]:
+		return len(d.DataPoints)
+	}
+	return 0
+}
+
+// splitData splits metric data after its first n data points
+func splitData(data metricdata.Aggregation, n int) (metricdata.Aggregation, metricdata.Aggregation) {
+	switch d := data.(type) {
+	case metricdata.Sum[int64]:
+		head, tail := d, d
+		head.DataPoints, tail.DataPoints = splitPoints(d.DataPoints, n)
+		return head, tail
+	case metricdata.Sum[float64]:
+		head, tail := d, d
+		head.DataPoints, tail.DataPoints = splitPoints(d.DataPoints, n)
+		return head, tail
+	case metricdata.Gauge[int64]:
+		head, tail := d, d
+		head.DataPoints, tail.DataPoints = splitPoints(d.DataPoints, n)
+		return head, tail
+	case metricdata.Gauge[float64]:
+		head, tail := d, d
+		head.DataPoints, tail.DataPoints = splitPoints(d.DataPoints, n)
+		return head, tail
+	case metricdata.Histogram[int64]:
+		head, tail := d, d
+		head.DataPoints, tail.DataPoints = splitPoints(d.DataPoints, n)
+		return head, tail
+	case metricdata.Histogram[float64]:
+		head, tail := d, d
+		head.DataPoints, tail.DataPoints = splitPoints(d.DataPoints, n)
+		return head, tail
+	case metricdata.ExponentialHistogram[int64]:
+		head, tail := d, d
+		head.DataPoints, tail.DataPoints = splitPoints(d.DataPoints, n)
+		return head, tail
+	case metricdata.ExponentialHistogram[float64]:
+		head, tail := d, d
+		head.DataPoints, tail.DataPoints = splitPoints(d.DataPoints, n)
+		return head, tail
+	}
+	return data, nil
+}
+
+// splitPoints splits data points after the first n
+func splitPoints[T any](points []T, n int) ([]T, []T) {
+	if n >= len(points) {
+		return points, nil
+	}
+	return points[:n], points[n:]
+}
+
+// mergeData appends the data points of src to dst, which is nil for a metric not yet in the batch
+// Points are copied, so the batch never shares memory with the step they came from
+func mergeData(dst metricdata.Aggregation, src metricdata.Aggregation) metricdata.Aggregation {
+	switch s := src.(type) {
+	case metricdata.Sum[int64]:
+		d, _ := dst.(metricdata.Sum[int64])
+		s.DataPoints = append(d.DataPoints, s.DataPoints...)
+		return s
+	case metricdata.Sum[float64]:
+		d, _ := dst.(metricdata.Sum[float64])
+		s.DataPoints = append(d.DataPoints, s.DataPoints...)
+		return s
+	case metricdata.Gauge[int64]:
+		d, _ := dst.(metricdata.Gauge[int64])
+		s.DataPoints = append(d.DataPoints, s.DataPoints...)
+		return s
+	case metricdata.Gauge[float64]:
+		d, _ := dst.(metricdata.Gauge[float64])
+		s.DataPoints = append(d.DataPoints, s.DataPoints...)
+		return s
+	case metricdata.Histogram[int64]:
+		d, _ := dst.(metricdata.Histogram[int64])
+		s.DataPoints = append(d.DataPoints, s.DataPoints...)
+		return s
+	case metricdata.Histogram[float64]:
+		d, _ := dst.(metricdata.Histogram[float64])
+		s.DataPoints = append(d.DataPoints, s.DataPoints...)
+		return s
+	case metricdata.ExponentialHistogram[int64]:
+		d, _ := dst.(metricdata.ExponentialHistogram[int64])
+		s.DataPoints = append(d.DataPoints, s.DataPoints...)
+		return s
+	case metricdata.ExponentialHistogram[float64]:
+		d, _ := dst.(metricdata.ExponentialHistogram[float64])
+		s.DataPoints = append(d.DataPoints, s.DataPoints...)
+		return s
+	}
+	return dst
+}
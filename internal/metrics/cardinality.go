@@ -0,0 +1,162 @@
+package metrics
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/antithesishq/otel-datagen/internal/randomness"
+	"github.com/spf13/viper"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// CardinalityConfig controls how many series a metric has and how they change over time
+type CardinalityConfig struct {
+	Dimensions []Dimension // Declared dimensions; every combination of values is one series
+	Series     int         // Target number of active series (0 = one per dimension combination)
+	Churn      float64     // Fraction of active series replaced by new ones after each data point
+	Explosion  int         // New series added after each data point, never retired
+}
+
+// Dimension is an attribute key with its fixed set of values
+type Dimension struct {
+	Key    string
+	Values []string
+}
+
+// seriesIDKey distinguishes series once the dimension combinations are used up
+const seriesIDKey = "series.id"
+
+// ParseCardinalityConfig reads the cardinality settings for the given component from viper
+func ParseCardinalityConfig(component string) (*CardinalityConfig, error) {
+	config := &CardinalityConfig{}
+	prefix := "generate." + component + "."
+
+	for _, entry := range viper.GetStringSlice(prefix + "dimension") {
+		key, values, ok := strings.Cut(entry, "=")
+		if !ok || key == "" || values == "" {
+			return nil, fmt.Errorf("invalid dimension '%s' (expected key=v1,v2,...)", entry)
+		}
+		dim := Dimension{Key: key}
+		for _, v := range strings.Split(values, ",") {
+			if v = strings.TrimSpace(v); v != "" {
+				dim.Values = append(dim.Values, v)
+			}
+		}
+		config.Dimensions = append(config.Dimensions, dim)
+	}
+
+	config.Series = viper.GetInt(prefix + "series")
+	config.Churn = viper.GetFloat64(prefix + "series_churn")
+	config.Explosion = viper.GetInt(prefix + "cardinality_explosion")
+
+	if err := config.Validate(); err != nil {
+		return nil, err
+	}
+	return config, nil
+}
+
+// Validate checks dimensions and ranges
+func (config *CardinalityConfig) Validate() error {
+	seen := make(map[string]bool)
+	for _, dim := range config.Dimensions {
+		if len(dim.Values) == 0 {
+			return fmt.Errorf("dimension '%s' has no values", dim.Key)
+		}
+		if seen[dim.Key] || dim.Key == seriesIDKey {
+			return fmt.Errorf("dimension '%s' is declared more than once or reserved", dim.Key)
+		}
+		seen[dim.Key] = true
+	}
+	if config.Series < 0 {
+		return fmt.Errorf("series must not be negative, got %d", config.Series)
+	}
+	if config.Churn < 0 || config.Churn > 1 {
+		return fmt.Errorf("series churn must be between 0 and 1, got %g", config.Churn)
+	}
+	if config.Explosion < 0 {
+		return fmt.Errorf("cardinality explosion must not be negative, got %d", config.Explosion)
+	}
+	return nil
+}
+
+// Enabled reports whether series are controlled by this configuration rather than the historical per-point attributes
+func (config *CardinalityConfig) Enabled() bool {
+	return config != nil && (len(config.Dimensions) > 0 || config.Series > 0 || config.Churn > 0 || config.Explosion > 0)
+}
+
+// combinations returns the number of distinct dimension value combinations
+func (config *CardinalityConfig) combinations() int {
+	n := 1
+	for _, dim := range config.Dimensions {
+		n *= len(dim.Values)
+	}
+	return n
+}
+
+// SeriesPool tracks the active series of a metric as they churn and grow
+type SeriesPool struct {
+	config *CardinalityConfig
+	active []int // Series IDs
+	nextID int
+	carry  float64 // Fractional churn carried over to the next data point
+}
+
+// NewSeriesPool creates the initial active series
+func (config *CardinalityConfig) NewSeriesPool() *SeriesPool {
+	pool := &SeriesPool{config: config}
+	target := config.Series
+	if target == 0 {
+		target = config.combinations()
+	}
+	for i := 0; i < target; i++ {
+		pool.active = append(pool.active, pool.nextID)
+		pool.nextID++
+	}
+	return pool
+}
+
+// Active returns the attributes of the currently active series
+func (p *SeriesPool) Active() [][]attribute.KeyValue {
+	series := make([][]attribute.KeyValue, len(p.active))
+	for i, id := range p.active {
+		series[i] = p.attributes(id)
+	}
+	return series
+}
+
+// Advance applies churn and explosion after a data point
+func (p *SeriesPool) Advance() {
+	p.carry += p.config.Churn * float64(len(p.active))
+	replace := int(p.carry)
+	p.carry -= float64(replace)
+
+	// Replace distinct random series: a partial shuffle moves the chosen ones to the front
+	for i := 0; i < replace && i < len(p.active); i++ {
+		j := i + randomness.Intn(len(p.active)-i)
+		p.active[i], p.active[j] = p.active[j], p.active[i]
+		p.active[i] = p.nextID
+		p.nextID++
+	}
+	for i := 0; i < p.config.Explosion; i++ {
+		p.active = append(p.active, p.nextID)
+		p.nextID++
+	}
+}
+
+// attributes maps a series ID onto dimension values, counting through the combinations like an odometer
+// IDs beyond the last combination start over with a series.id attribute so every ID stays unique
+func (p *SeriesPool) attributes(id int) []attribute.KeyValue {
+	var attrs []attribute.KeyValue
+	rest := id
+	for _, dim := range p.config.Dimensions {
+		attrs = append(attrs, attribute.String(dim.Key, dim.Values[rest%len(dim.Values)]))
+		rest /= len(dim.Values)
+	}
+	if len(p.config.Dimensions) == 0 {
+		attrs = append(attrs, attribute.String(seriesIDKey, strconv.Itoa(id)))
+	} else if rest > 0 {
+		attrs = append(attrs, attribute.String(seriesIDKey, strconv.Itoa(rest)))
+	}
+	return attrs
+}
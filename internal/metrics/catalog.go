@@ -0,0 +1,290 @@
+package metrics
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/antithesishq/otel-datagen/internal/aggro"
+	"github.com/antithesishq/otel-datagen/internal/shapes"
+	"github.com/spf13/viper"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+)
+
+// CatalogEntry describes one metric of a catalog
+type CatalogEntry struct {
+	Name        string              `mapstructure:"name"`
+	Type        string              `mapstructure:"type"` // One of catalogTypes
+	Unit        string              `mapstructure:"unit"`
+	Description string              `mapstructure:"description"`
+	Shape       string              `mapstructure:"shape"` // Value shape; independent values between min and max when empty
+	Min         float64             `mapstructure:"min"`
+	Max         float64             `mapstructure:"max"`
+	Attributes  map[string][]string `mapstructure:"attributes"` // Dimensions: every combination of values is one series
+}
+
+// Metric types supported in catalogs
+var catalogTypes = []string{"counter", "float64-counter", "updowncounter", "float64-updowncounter", "gauge", "float64-gauge", "histogram", "int64-histogram"}
+
+// ParseCatalog reads the metric catalog for the given component from viper:
+// the built-in packs named by "packs" followed by the entries under "catalog"
+// It returns nil when neither is configured
+func ParseCatalog(component string) ([]CatalogEntry, error) {
+	prefix := "generate." + component + "."
+
+	var catalog []CatalogEntry
+	for _, name := range viper.GetStringSlice(prefix + "packs") {
+		pack, ok := packs[name]
+		if !ok {
+			return nil, fmt.Errorf("unknown metric pack '%s' (supported: %s)", name, strings.Join(PackNames(), ", "))
+		}
+		catalog = append(catalog, pack...)
+	}
+
+	var entries []CatalogEntry
+	if err := viper.UnmarshalKey(prefix+"catalog", &entries); err != nil {
+		return nil, fmt.Errorf("invalid metric catalog: %w", err)
+	}
+	catalog = append(catalog, entries...)
+
+	if err := ValidateCatalog(catalog); err != nil {
+		return nil, err
+	}
+	return catalog, nil
+}
+
+// ValidateCatalog checks names, types, ranges and shapes, filling in the default type
+func ValidateCatalog(catalog []CatalogEntry) error {
+	seen := make(map[string]bool)
+	for i := range catalog {
+		entry := &catalog[i]
+		if entry.Name == "" {
+			return fmt.Errorf("metric catalog entry %d has no name", i+1)
+		}
+		if seen[entry.Name] {
+			return fmt.Errorf("metric '%s' is declared more than once in the catalog", entry.Name)
+		}
+		seen[entry.Name] = true
+
+		if entry.Type == "" {
+			entry.Type = "gauge"
+		}
+		valid := false
+		for _, t := range catalogTypes {
+			if entry.Type == t {
+				valid = true
+			}
+		}
+		if !valid {
+			return fmt.Errorf("metric '%s' has unsupported type '%s' (supported: %s)", entry.Name, entry.Type, strings.Join(catalogTypes, ", "))
+		}
+		if entry.Max < entry.Min {
+			return fmt.Errorf("metric '%s' has max below min", entry.Name)
+		}
+		for key, values := range entry.Attributes {
+			if len(values) == 0 {
+				return fmt.Errorf("dimension '%s' of metric '%s' has no values", key, entry.Name)
+			}
+		}
+		if _, err := shapes.Parse(entry.shapeSpec(), entry.Min, entry.Max); err != nil {
+			return fmt.Errorf("metric '%s': %w", entry.Name, err)
+		}
+	}
+	return nil
+}
+
+// shapeSpec returns the entry's value shape, defaulting to independent values
+func (entry *CatalogEntry) shapeSpec() string {
+	if entry.Shape == "" {
+		return "uniform"
+	}
+	return entry.Shape
+}
+
+// Series returns one attribute set per combination of the entry's dimension values, in a stable order
+func (entry *CatalogEntry) Series() [][]attribute.KeyValue {
+	keys := make([]string, 0, len(entry.Attributes))
+	for key := range entry.Attributes {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	series := [][]attribute.KeyValue{nil}
+	for _, key := range keys {
+		var next [][]attribute.KeyValue
+		for _, attrs := range series {
+			for _, value := range entry.Attributes[key] {
+				combined := append(append([]attribute.KeyValue{}, attrs...), attribute.String(key, value))
+				next = append(next, combined)
+			}
+		}
+		series = next
+	}
+	return series
+}
+
+// CatalogRecorder records one data point for every series of every catalog metric at a time
+type CatalogRecorder struct {
+	series      []*catalogSeries
+	spans       *ExemplarSpans
+	aggroValues []string
+	aggroProb   float64
+}
+
+// catalogSeries is one attribute set of a catalog metric with its own value series
+type catalogSeries struct {
+	name   string
+	record func(context.Context, float64, []attribute.KeyValue, int)
+	next   func() float64
+	attrs  []attribute.KeyValue
+}
+
+// NewCatalogRecorder creates the instruments of a catalog on the meter
+// shapeConfig, when set, overrides entry shapes for the metrics named by --metric-value-shape;
+// spans, when set, records measurements inside generated spans
+func NewCatalogRecorder(meter metric.Meter, catalog []CatalogEntry, shapeConfig *ShapeConfig, spans *ExemplarSpans, aggroConfig *aggro.AggroConfig, protocol string) (*CatalogRecorder, error) {
+	recorder := &CatalogRecorder{spans: spans}
+	recorder.aggroValues, recorder.aggroProb = newAggroValues(aggroConfig, protocol)
+	for i := range catalog {
+		entry := &catalog[i]
+		record, sum, err := newCatalogInstrument(meter, entry, aggroConfig)
+		if err != nil {
+			return nil, err
+		}
+
+		spec := entry.shapeSpec()
+		if shapeConfig != nil {
+			if override, ok := shapeConfig.MetricShapes[entry.Name]; ok {
+				spec = override
+			}
+		}
+
+		for _, attrs := range entry.Series() {
+			shape, err := shapes.Parse(spec, entry.Min, entry.Max)
+			if err != nil {
+				return nil, fmt.Errorf("metric '%s': %w", entry.Name, err)
+			}
+			next := shape.Next
+			switch {
+			case entry.Type == "counter" || entry.Type == "float64-counter":
+				next = shapes.CounterIncrements(shape)
+			case sum:
+				next = shapes.Increments(shape)
+			}
+			recorder.series = append(recorder.series, &catalogSeries{name: entry.Name, record: record, next: next, attrs: attrs})
+		}
+	}
+	return recorder, nil
+}
+
+// Record records the next value of every series; iteration feeds histogram aggro
+// timestamp is the intended time of the values, for the spans they are recorded in (zero = now)
+func (r *CatalogRecorder) Record(ctx context.Context, iteration int, timestamp time.Time) {
+	r.record(ctx, r.series, iteration, timestamp)
+}
+
+// record records the next value of the given series
+func (r *CatalogRecorder) record(ctx context.Context, series []*catalogSeries, iteration int, timestamp time.Time) {
+	for _, s := range series {
+		attrs := appendAggroValue(s.attrs, r.aggroValues, r.aggroProb)
+		pointCtx, end := r.spans.Start(ctx, s.name, timestamp)
+		s.record(pointCtx, s.next(), attrs, iteration)
+		end()
+	}
+}
+
+// GenerateCatalog records numMetrics data points for every series of the catalog using the provided meter provider
+// Series are spread over the given number of workers, worker k of n taking series k, k+n, k+2n, ... with all their data points
+func GenerateCatalog(ctx context.Context, mp *sdkmetric.MeterProvider, catalog []CatalogEntry, numMetrics int, shapeConfig *ShapeConfig, spans *ExemplarSpans, workers int, aggroConfig *aggro.AggroConfig, protocol string) error {
+	recorder, err := NewCatalogRecorder(mp.Meter("otel-datagen"), catalog, shapeConfig, spans, aggroConfig, protocol)
+	if err != nil {
+		return err
+	}
+	workers = max(1, min(workers, len(recorder.series)))
+	shards := make([][]*catalogSeries, workers)
+	for i, s := range recorder.series {
+		shards[i%workers] = append(shards[i%workers], s)
+	}
+
+	var wg sync.WaitGroup
+	for _, shard := range shards {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < numMetrics; i++ {
+				recorder.record(ctx, shard, i, time.Time{})
+			}
+		}()
+	}
+	wg.Wait()
+	return nil
+}
+
+// newCatalogInstrument creates the instrument for a catalog entry and returns a function recording one value
+// sum reports whether the instrument adds values, so shapes should provide increments
+func newCatalogInstrument(meter metric.Meter, entry *CatalogEntry, aggroConfig *aggro.AggroConfig) (func(context.Context, float64, []attribute.KeyValue, int), bool, error) {
+	description := metric.WithDescription(entry.Description)
+	unit := metric.WithUnit(entry.Unit)
+
+	switch entry.Type {
+	case "counter":
+		counter, err := meter.Int64Counter(entry.Name, description, unit)
+		return func(ctx context.Context, value float64, attrs []attribute.KeyValue, _ int) {
+			counter.Add(ctx, roundToInt64(value), metric.WithAttributes(attrs...))
+		}, true, err
+	case "float64-counter":
+		counter, err := meter.Float64Counter(entry.Name, description, unit)
+		return func(ctx context.Context, value float64, attrs []attribute.KeyValue, _ int) {
+			counter.Add(ctx, value, metric.WithAttributes(attrs...))
+		}, true, err
+	case "updowncounter":
+		counter, err := meter.Int64UpDownCounter(entry.Name, description, unit)
+		return func(ctx context.Context, value float64, attrs []attribute.KeyValue, _ int) {
+			counter.Add(ctx, roundToInt64(value), metric.WithAttributes(attrs...))
+		}, true, err
+	case "float64-updowncounter":
+		counter, err := meter.Float64UpDownCounter(entry.Name, description, unit)
+		return func(ctx context.Context, value float64, attrs []attribute.KeyValue, _ int) {
+			counter.Add(ctx, value, metric.WithAttributes(attrs...))
+		}, true, err
+	case "gauge":
+		gauge, err := meter.Int64Gauge(entry.Name, description, unit)
+		return func(ctx context.Context, value float64, attrs []attribute.KeyValue, _ int) {
+			gauge.Record(ctx, roundToInt64(value), metric.WithAttributes(attrs...))
+		}, false, err
+	case "float64-gauge":
+		gauge, err := meter.Float64Gauge(entry.Name, description, unit)
+		return func(ctx context.Context, value float64, attrs []attribute.KeyValue, _ int) {
+			gauge.Record(ctx, value, metric.WithAttributes(attrs...))
+		}, false, err
+	case "int64-histogram":
+		histogram, err := meter.Int64Histogram(entry.Name, description, unit)
+		return func(ctx context.Context, value float64, attrs []attribute.KeyValue, i int) {
+			value, attrs = applyHistogramAggro(aggroConfig, value, attrs, i)
+			histogram.Record(ctx, clampToInt64(value), metric.WithAttributes(attrs...))
+		}, false, err
+	default:
+		histogram, err := meter.Float64Histogram(entry.Name, description, unit)
+		return func(ctx context.Context, value float64, attrs []attribute.KeyValue, i int) {
+			value, attrs = applyHistogramAggro(aggroConfig, value, attrs, i)
+			histogram.Record(ctx, value, metric.WithAttributes(attrs...))
+		}, false, err
+	}
+}
+
+// applyHistogramAggro replaces a histogram value when histogram aggro is configured
+func applyHistogramAggro(aggroConfig *aggro.AggroConfig, value float64, attrs []attribute.KeyValue, i int) (float64, []attribute.KeyValue) {
+	if aggroConfig == nil {
+		return value, attrs
+	}
+	value, metadata := aggroConfig.ApplyAggroToHistogramValue(value, i)
+	if len(metadata) == 0 {
+		return value, attrs
+	}
+	return value, append(append([]attribute.KeyValue{}, attrs...), metadata...)
+}
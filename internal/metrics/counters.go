@@ -2,52 +2,86 @@ package metrics
 
 import (
 	"context"
+	"time"
 
+	"github.com/antithesishq/otel-datagen/internal/aggro"
 	"github.com/antithesishq/otel-datagen/internal/randomness"
+	"github.com/antithesishq/otel-datagen/internal/shapes"
 	"github.com/go-faker/faker/v4"
 	"go.opentelemetry.io/otel/attribute"
 	"go.opentelemetry.io/otel/metric"
 )
 
+// AttributeSource returns the attributes of the i-th recorded value
+type AttributeSource func(i int) []attribute.KeyValue
+
 // generateMetricAttributes creates attribute slice for metrics with aggro condition support
+// Every value gets its own series
 func generateMetricAttributes(i int, aggroValues []string, aggroProb float64) []attribute.KeyValue {
 	var attrs []attribute.KeyValue
 	attrs = append(attrs, attribute.String("fake.attr", faker.Word()))
 	attrs = append(attrs, attribute.Int("iteration", i+1))
 
-	// Add aggro value attribute if probability check passes
+	return appendAggroValue(attrs, aggroValues, aggroProb)
+}
+
+// seriesMetricAttributes are the attributes of the single series of shaped values, so the shape stays one time series
+var seriesMetricAttributes = []attribute.KeyValue{attribute.String("instance", "primary")}
+
+// newAggroValues returns the values of the aggro value attribute and the probability of adding it to a data point
+// Aggro values are added to every data point when string, numeric or timestamp aggro is configured; other aggro
+// kinds leave the series alone, so they keep evolving over time
+func newAggroValues(aggroConfig *aggro.AggroConfig, protocol string) ([]string, float64) {
+	if aggroConfig == nil || !aggroConfig.HasValueActive() {
+		return nil, 0
+	}
+	return aggro.GetAggroValuesForProtocol(protocol), 1.0
+}
+
+// appendAggroValue adds an aggro value attribute if the probability check passes
+func appendAggroValue(attrs []attribute.KeyValue, aggroValues []string, aggroProb float64) []attribute.KeyValue {
 	if aggroProb > 0 && len(aggroValues) > 0 && randomness.Float64() < aggroProb {
 		aggroValue := randomness.Choice(aggroValues)
-		attrs = append(attrs, attribute.String("aggro.value", aggroValue))
+		attrs = append(append([]attribute.KeyValue{}, attrs...), attribute.String("aggro.value", aggroValue))
+		aggro.RecordInjection("value")
 	}
-
 	return attrs
 }
 
 // GenerateInt64Counter generates int64 counter metrics
-func GenerateInt64Counter(ctx context.Context, meter metric.Meter, metricName string, numMetrics int, counterMin int, counterMax int, aggroValues []string, aggroProb float64) error {
+func GenerateInt64Counter(ctx context.Context, meter metric.Meter, metricName string, numMetrics int, shape shapes.Shape, attributes AttributeSource, spans *ExemplarSpans, workers int) error {
 	counter, err := meter.Int64Counter(metricName)
 	if err != nil {
 		return err
 	}
-	for i := 0; i < numMetrics; i++ {
-		value := int64(randomness.Intn(counterMax-counterMin+1) + counterMin)
-		attrs := generateMetricAttributes(i, aggroValues, aggroProb)
-		counter.Add(ctx, value, metric.WithAttributes(attrs...))
+	next := shapes.CounterIncrements(shape)
+	points := make([]point, numMetrics)
+	for i := range points {
+		points[i] = point{value: next(), attrs: attributes(i)}
 	}
+	recordPoints(ctx, workers, points, func(ctx context.Context, value float64, attrs []attribute.KeyValue) {
+		pointCtx, end := spans.Start(ctx, metricName, time.Time{})
+		counter.Add(pointCtx, roundToInt64(value), metric.WithAttributes(attrs...))
+		end()
+	})
 	return nil
 }
 
 // GenerateFloat64Counter generates float64 counter metrics
-func GenerateFloat64Counter(ctx context.Context, meter metric.Meter, metricName string, numMetrics int, counterMin int, counterMax int, aggroValues []string, aggroProb float64) error {
+func GenerateFloat64Counter(ctx context.Context, meter metric.Meter, metricName string, numMetrics int, shape shapes.Shape, attributes AttributeSource, spans *ExemplarSpans, workers int) error {
 	counter, err := meter.Float64Counter(metricName)
 	if err != nil {
 		return err
 	}
-	for i := 0; i < numMetrics; i++ {
-		value := float64(randomness.Intn(counterMax-counterMin+1) + counterMin)
-		attrs := generateMetricAttributes(i, aggroValues, aggroProb)
-		counter.Add(ctx, value, metric.WithAttributes(attrs...))
+	next := shapes.CounterIncrements(shape)
+	points := make([]point, numMetrics)
+	for i := range points {
+		points[i] = point{value: next(), attrs: attributes(i)}
 	}
+	recordPoints(ctx, workers, points, func(ctx context.Context, value float64, attrs []attribute.KeyValue) {
+		pointCtx, end := spans.Start(ctx, metricName, time.Time{})
+		counter.Add(pointCtx, value, metric.WithAttributes(attrs...))
+		end()
+	})
 	return nil
 }
@@ -0,0 +1,182 @@
+package metrics
+
+import (
+	"context"
+	"encoding/binary"
+	"fmt"
+	"time"
+
+	"github.com/antithesishq/otel-datagen/internal/randomness"
+	"github.com/spf13/viper"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/exemplar"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// ExemplarConfig controls which measurements become exemplars, how many are kept,
+// and which measurements are recorded inside generated spans so exemplars link to real traces
+type ExemplarConfig struct {
+	Filter         string  // always-on, trace-based or off ("" = SDK default, honouring OTEL_METRICS_EXEMPLAR_FILTER)
+	Reservoir      string  // default, fixed or histogram
+	ReservoirSize  int     // Exemplars kept per data point by the fixed reservoir
+	SpanRatio      float64 // Fraction of measurements recorded inside a generated span
+	UnsampledRatio float64 // Fraction of those spans that are not sampled, and so not exported
+}
+
+// Supported exemplar filters and reservoirs
+var (
+	exemplarFilters    = []string{"always-on", "trace-based", "off"}
+	exemplarReservoirs = []string{"default", "fixed", "histogram"}
+)
+
+// ParseExemplarConfig reads the exemplar settings for the given component from viper
+func ParseExemplarConfig(component string) (*ExemplarConfig, error) {
+	prefix := "generate." + component + "."
+	config := &ExemplarConfig{
+		Filter:         viper.GetString(prefix + "exemplar_filter"),
+		Reservoir:      viper.GetString(prefix + "exemplar_reservoir"),
+		ReservoirSize:  viper.GetInt(prefix + "exemplar_reservoir_size"),
+		SpanRatio:      viper.GetFloat64(prefix + "exemplar_spans"),
+		UnsampledRatio: viper.GetFloat64(prefix + "exemplar_unsampled"),
+	}
+	if config.Reservoir == "" {
+		config.Reservoir = "default"
+	}
+	if config.ReservoirSize == 0 {
+		config.ReservoirSize = 1
+	}
+	if err := config.Validate(); err != nil {
+		return nil, err
+	}
+	return config, nil
+}
+
+// Validate checks the filter, reservoir and ratios
+func (config *ExemplarConfig) Validate() error {
+	if config.Filter != "" && !contains(exemplarFilters, config.Filter) {
+		return fmt.Errorf("unsupported exemplar filter '%s' (supported: always-on, trace-based, off)", config.Filter)
+	}
+	if config.Reservoir != "" && !contains(exemplarReservoirs, config.Reservoir) {
+		return fmt.Errorf("unsupported exemplar reservoir '%s' (supported: default, fixed, histogram)", config.Reservoir)
+	}
+	if config.Reservoir == "fixed" && config.ReservoirSize < 1 {
+		return fmt.Errorf("exemplar reservoir size must be at least 1, got %d", config.ReservoirSize)
+	}
+	if config.SpanRatio < 0 || config.SpanRatio > 1 {
+		return fmt.Errorf("exemplar spans must be between 0 and 1, got %g", config.SpanRatio)
+	}
+	if config.UnsampledRatio < 0 || config.UnsampledRatio > 1 {
+		return fmt.Errorf("exemplar unsampled must be between 0 and 1, got %g", config.UnsampledRatio)
+	}
+	return nil
+}
+
+// contains reports whether value is one of values
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// Options returns meter provider options applying the exemplar filter and reservoir on top of the given views
+// A nil config only applies the views
+func (config *ExemplarConfig) Options(views ...sdkmetric.View) []sdkmetric.Option {
+	if config == nil {
+		return []sdkmetric.Option{sdkmetric.WithView(views...)}
+	}
+
+	var options []sdkmetric.Option
+	switch config.Filter {
+	case "always-on":
+		options = append(options, sdkmetric.WithExemplarFilter(exemplar.AlwaysOnFilter))
+	case "trace-based":
+		options = append(options, sdkmetric.WithExemplarFilter(exemplar.TraceBasedFilter))
+	case "off":
+		options = append(options, sdkmetric.WithExemplarFilter(exemplar.AlwaysOffFilter))
+	}
+
+	selector := config.reservoirSelector()
+	if selector == nil {
+		return append(options, sdkmetric.WithView(views...))
+	}
+
+	// One view for all instruments: the SDK creates a stream per matching view,
+	// so the reservoir is set on the stream of the first matching view instead of adding another
+	return append(options, sdkmetric.WithView(func(inst sdkmetric.Instrument) (sdkmetric.Stream, bool) {
+		for _, view := range views {
+			if stream, ok := view(inst); ok {
+				stream.ExemplarReservoirProviderSelector = selector
+				return stream, true
+			}
+		}
+		return sdkmetric.Stream{
+			Name:                              inst.Name,
+			Description:                       inst.Description,
+			Unit:                              inst.Unit,
+			ExemplarReservoirProviderSelector: selector,
+		}, true
+	}))
+}
+
+// reservoirSelector returns the configured reservoir selector, or nil to keep the SDK default
+// The histogram reservoir keeps one exemplar per bucket; aggregations without buckets fall back to the fixed reservoir
+func (config *ExemplarConfig) reservoirSelector() sdkmetric.ExemplarReservoirProviderSelector {
+	switch config.Reservoir {
+	case "fixed":
+		return func(sdkmetric.Aggregation) exemplar.ReservoirProvider {
+			return exemplar.FixedSizeReservoirProvider(config.ReservoirSize)
+		}
+	case "histogram":
+		return func(agg sdkmetric.Aggregation) exemplar.ReservoirProvider {
+			if explicit, ok := agg.(sdkmetric.AggregationExplicitBucketHistogram); ok && len(explicit.Boundaries) > 0 {
+				return exemplar.HistogramReservoirProvider(explicit.Boundaries)
+			}
+			return exemplar.FixedSizeReservoirProvider(config.ReservoirSize)
+		}
+	}
+	return nil
+}
+
+// ExemplarSpans records measurements inside generated spans so their exemplars carry real trace and span IDs
+type ExemplarSpans struct {
+	tracer trace.Tracer
+	config *ExemplarConfig
+}
+
+// NewExemplarSpans creates spans with the given tracer, or returns nil when no measurements are recorded in spans
+func (config *ExemplarConfig) NewExemplarSpans(tracer trace.Tracer) *ExemplarSpans {
+	if config == nil || config.SpanRatio == 0 {
+		return nil
+	}
+	return &ExemplarSpans{tracer: tracer, config: config}
+}
+
+// Start returns the context to record a measurement of the named metric in, and a function ending its span
+// A zero timestamp records at the current time; otherwise the span ends at timestamp
+// Unsampled spans are never exported, so they only carry a span context with fresh IDs
+func (s *ExemplarSpans) Start(ctx context.Context, name string, timestamp time.Time) (context.Context, func()) {
+	if s == nil || randomness.Float64() >= s.config.SpanRatio {
+		return ctx, func() {}
+	}
+
+	if randomness.Float64() < s.config.UnsampledRatio {
+		var traceID trace.TraceID
+		var spanID trace.SpanID
+		binary.BigEndian.PutUint64(traceID[:8], randomness.Uint64())
+		binary.BigEndian.PutUint64(traceID[8:], randomness.Uint64())
+		binary.BigEndian.PutUint64(spanID[:], randomness.Uint64())
+		sc := trace.NewSpanContext(trace.SpanContextConfig{TraceID: traceID, SpanID: spanID})
+		return trace.ContextWithSpanContext(ctx, sc), func() {}
+	}
+
+	if timestamp.IsZero() {
+		ctx, span := s.tracer.Start(ctx, name)
+		return ctx, func() { span.End() }
+	}
+	duration := time.Duration(1+randomness.Intn(100)) * time.Millisecond
+	ctx, span := s.tracer.Start(ctx, name, trace.WithTimestamp(timestamp.Add(-duration)))
+	return ctx, func() { span.End(trace.WithTimestamp(timestamp)) }
+}
@@ -2,35 +2,45 @@ package metrics
 
 import (
 	"context"
+	"time"
 
-	"github.com/antithesishq/otel-datagen/internal/randomness"
+	"github.com/antithesishq/otel-datagen/internal/shapes"
+	"go.opentelemetry.io/otel/attribute"
 	"go.opentelemetry.io/otel/metric"
 )
 
 // GenerateInt64Gauge generates int64 gauge metrics
-func GenerateInt64Gauge(ctx context.Context, meter metric.Meter, metricName string, numMetrics int, counterMin int, counterMax int, aggroValues []string, aggroProb float64) error {
+func GenerateInt64Gauge(ctx context.Context, meter metric.Meter, metricName string, numMetrics int, shape shapes.Shape, attributes AttributeSource, spans *ExemplarSpans, workers int) error {
 	gauge, err := meter.Int64Gauge(metricName)
 	if err != nil {
 		return err
 	}
-	for i := 0; i < numMetrics; i++ {
-		value := int64(randomness.Intn(counterMax-counterMin+1) + counterMin)
-		attrs := generateMetricAttributes(i, aggroValues, aggroProb)
-		gauge.Record(ctx, value, metric.WithAttributes(attrs...))
+	points := make([]point, numMetrics)
+	for i := range points {
+		points[i] = point{value: shape.Next(), attrs: attributes(i)}
 	}
+	recordPoints(ctx, workers, points, func(ctx context.Context, value float64, attrs []attribute.KeyValue) {
+		pointCtx, end := spans.Start(ctx, metricName, time.Time{})
+		gauge.Record(pointCtx, roundToInt64(value), metric.WithAttributes(attrs...))
+		end()
+	})
 	return nil
 }
 
 // GenerateFloat64Gauge generates float64 gauge metrics
-func GenerateFloat64Gauge(ctx context.Context, meter metric.Meter, metricName string, numMetrics int, counterMin int, counterMax int, aggroValues []string, aggroProb float64) error {
+func GenerateFloat64Gauge(ctx context.Context, meter metric.Meter, metricName string, numMetrics int, shape shapes.Shape, attributes AttributeSource, spans *ExemplarSpans, workers int) error {
 	gauge, err := meter.Float64Gauge(metricName)
 	if err != nil {
 		return err
 	}
-	for i := 0; i < numMetrics; i++ {
-		value := float64(randomness.Intn(counterMax-counterMin+1) + counterMin)
-		attrs := generateMetricAttributes(i, aggroValues, aggroProb)
-		gauge.Record(ctx, value, metric.WithAttributes(attrs...))
+	points := make([]point, numMetrics)
+	for i := range points {
+		points[i] = point{value: shape.Next(), attrs: attributes(i)}
 	}
+	recordPoints(ctx, workers, points, func(ctx context.Context, value float64, attrs []attribute.KeyValue) {
+		pointCtx, end := spans.Start(ctx, metricName, time.Time{})
+		gauge.Record(pointCtx, value, metric.WithAttributes(attrs...))
+		end()
+	})
 	return nil
 }
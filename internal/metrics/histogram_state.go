@@ -0,0 +1,174 @@
+package metrics
+
+import (
+	"math"
+	"sort"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+)
+
+// histogramState aggregates histogram values without a meter provider, for either explicit buckets or
+// a base-2 exponential histogram, following the same bucketing rules as the SDK aggregations
+type histogramState struct {
+	count    uint64
+	sum      float64
+	min, max float64
+	noMinMax bool
+
+	// Explicit buckets
+	bounds  []float64
+	buckets []uint64
+
+	// Exponential buckets
+	exponential        bool
+	maxSize            int
+	scale              int32
+	zeroCount          uint64
+	positive, negative exponentialBuckets
+}
+
+// exponentialBuckets is one range of exponential histogram buckets starting at index offset
+type exponentialBuckets struct {
+	offset int32
+	counts []uint64
+}
+
+// newHistogramState creates an empty state for the aggregation, which must be explicit or exponential
+func newHistogramState(aggregation sdkmetric.Aggregation) *histogramState {
+	switch a := aggregation.(type) {
+	case sdkmetric.AggregationBase2ExponentialHistogram:
+		return &histogramState{exponential: true, maxSize: int(a.MaxSize), scale: a.MaxScale, noMinMax: a.NoMinMax}
+	case sdkmetric.AggregationExplicitBucketHistogram:
+		return &histogramState{bounds: a.Boundaries, buckets: make([]uint64, len(a.Boundaries)+1), noMinMax: a.NoMinMax}
+	}
+	return nil
+}
+
+// record adds one value
+func (h *histogramState) record(value float64) {
+	if h.count == 0 || value < h.min {
+		h.min = value
+	}
+	if h.count == 0 || value > h.max {
+		h.max = value
+	}
+	h.count++
+	h.sum += value
+
+	if !h.exponential {
+		// Buckets are upper-inclusive: (bounds[i-1], bounds[i]]
+		h.buckets[sort.SearchFloat64s(h.bounds, value)]++
+		return
+	}
+
+	switch {
+	case value == 0:
+		h.zeroCount++
+	case value > 0:
+		h.recordExponential(&h.positive, value)
+	default:
+		h.recordExponential(&h.negative, -value)
+	}
+}
+
+// recordExponential adds a positive value to buckets, lowering the scale of both ranges first if it would not fit in maxSize
+func (h *histogramState) recordExponential(b *exponentialBuckets, value float64) {
+	index := exponentialIndex(value, h.scale)
+	if len(b.counts) > 0 {
+		low, high := min(b.offset, index), max(b.offset+int32(len(b.counts))-1, index)
+		var change int32
+		for int(high-low) >= h.maxSize {
+			low >>= 1
+			high >>= 1
+			change++
+		}
+		if change > 0 {
+			h.positive.downscale(change)
+			h.negative.downscale(change)
+			h.scale -= change
+			index = exponentialIndex(value, h.scale)
+		}
+	}
+	b.add(index)
+}
+
+// exponentialIndex returns the bucket of a positive value at scale: bucket i covers (base^i, base^(i+1)] with base = 2^(2^-scale)
+func exponentialIndex(value float64, scale int32) int32 {
+	return int32(math.Ceil(math.Log2(value)*math.Ldexp(1, int(scale)))) - 1
+}
+
+// add counts one value in bucket index, growing the range as needed
+func (b *exponentialBuckets) add(index int32) {
+	switch {
+	case len(b.counts) == 0:
+		b.offset = index
+		b.counts = []uint64{1}
+	case index < b.offset:
+		counts := make([]uint64, int(b.offset-index)+len(b.counts))
+		copy(counts[b.offset-index:], b.counts)
+		counts[0] = 1
+		b.offset = index
+		b.counts = counts
+	case int(index-b.offset) >= len(b.counts):
+		b.counts = append(b.counts, make([]uint64, int(index-b.offset)-len(b.counts)+1)...)
+		b.counts[index-b.offset]++
+	default:
+		b.counts[index-b.offset]++
+	}
+}
+
+// downscale merges buckets for a scale lower by change
+func (b *exponentialBuckets) downscale(change int32) {
+	if len(b.counts) == 0 {
+		return
+	}
+	offset := b.offset >> change
+	last := (b.offset + int32(len(b.counts)) - 1) >> change
+	counts := make([]uint64, last-offset+1)
+	for i, c := range b.counts {
+		counts[((b.offset+int32(i))>>change)-offset] += c
+	}
+	b.offset = offset
+	b.counts = counts
+}
+
+// histogramDataPoint converts the state to an explicit bucket data point
+func histogramDataPoint[N int64 | float64](h *histogramState, attrs attribute.Set, start, timestamp time.Time) metricdata.HistogramDataPoint[N] {
+	dp := metricdata.HistogramDataPoint[N]{
+		Attributes:   attrs,
+		StartTime:    start,
+		Time:         timestamp,
+		Count:        h.count,
+		Bounds:       h.bounds,
+		BucketCounts: append([]uint64(nil), h.buckets...),
+		Sum:          N(h.sum),
+	}
+	if !h.noMinMax {
+		dp.Min = metricdata.NewExtrema(N(h.min))
+		dp.Max = metricdata.NewExtrema(N(h.max))
+	}
+	return dp
+}
+
+// exponentialHistogramDataPoint converts the state to an exponential histogram data point
+func exponentialHistogramDataPoint[N int64 | float64](h *histogramState, attrs attribute.Set, start, timestamp time.Time) metricdata.ExponentialHistogramDataPoint[N] {
+	dp := metricdata.ExponentialHistogramDataPoint[N]{
+		Attributes:     attrs,
+		StartTime:      start,
+		Time:           timestamp,
+		Count:          h.count,
+		Sum:            N(h.sum),
+		Scale:          h.scale,
+		ZeroCount:      h.zeroCount,
+		PositiveBucket: metricdata.ExponentialBucket{Offset: h.positive.offset, Counts: append([]uint64(nil), h.positive.counts...)},
+		NegativeBucket: metricdata.ExponentialBucket{Offset: h.negative.offset, Counts: append([]uint64(nil), h.negative.counts...)},
+	}
+	if !h.noMinMax {
+		dp.Min = metricdata.NewExtrema(N(h.min))
+		dp.Max = metricdata.NewExtrema(N(h.max))
+	}
+	return dp
+}
@@ -2,35 +2,73 @@ package metrics
 
 import (
 	"context"
+	"math"
+	"time"
 
-	"github.com/antithesishq/otel-datagen/internal/randomness"
+	"github.com/antithesishq/otel-datagen/internal/aggro"
+	"github.com/antithesishq/otel-datagen/internal/shapes"
+	"go.opentelemetry.io/otel/attribute"
 	"go.opentelemetry.io/otel/metric"
 )
 
 // GenerateInt64Histogram generates int64 histogram metrics
-func GenerateInt64Histogram(ctx context.Context, meter metric.Meter, metricName string, numMetrics int, counterMin int, counterMax int, aggroValues []string, aggroProb float64) error {
+func GenerateInt64Histogram(ctx context.Context, meter metric.Meter, metricName string, numMetrics int, shape shapes.Shape, attributes AttributeSource, spans *ExemplarSpans, workers int, aggroConfig *aggro.AggroConfig) error {
 	histogram, err := meter.Int64Histogram(metricName)
 	if err != nil {
 		return err
 	}
-	for i := 0; i < numMetrics; i++ {
-		value := int64(randomness.Intn(counterMax-counterMin+1) + counterMin)
-		attrs := generateMetricAttributes(i, aggroValues, aggroProb)
-		histogram.Record(ctx, value, metric.WithAttributes(attrs...))
+	points := make([]point, numMetrics)
+	for i := range points {
+		value := shape.Next()
+		attrs := attributes(i)
+		if aggroConfig != nil {
+			var metadata []attribute.KeyValue
+			value, metadata = aggroConfig.ApplyAggroToHistogramValue(value, i)
+			attrs = append(attrs, metadata...)
+		}
+		points[i] = point{value: value, attrs: attrs}
 	}
+	recordPoints(ctx, workers, points, func(ctx context.Context, value float64, attrs []attribute.KeyValue) {
+		pointCtx, end := spans.Start(ctx, metricName, time.Time{})
+		histogram.Record(pointCtx, clampToInt64(value), metric.WithAttributes(attrs...))
+		end()
+	})
 	return nil
 }
 
 // GenerateFloat64Histogram generates float64 histogram metrics
-func GenerateFloat64Histogram(ctx context.Context, meter metric.Meter, metricName string, numMetrics int, counterMin int, counterMax int, aggroValues []string, aggroProb float64) error {
+func GenerateFloat64Histogram(ctx context.Context, meter metric.Meter, metricName string, numMetrics int, shape shapes.Shape, attributes AttributeSource, spans *ExemplarSpans, workers int, aggroConfig *aggro.AggroConfig) error {
 	histogram, err := meter.Float64Histogram(metricName)
 	if err != nil {
 		return err
 	}
-	for i := 0; i < numMetrics; i++ {
-		value := float64(randomness.Intn(counterMax-counterMin+1) + counterMin)
-		attrs := generateMetricAttributes(i, aggroValues, aggroProb)
-		histogram.Record(ctx, value, metric.WithAttributes(attrs...))
+	points := make([]point, numMetrics)
+	for i := range points {
+		value := shape.Next()
+		attrs := attributes(i)
+		if aggroConfig != nil {
+			var metadata []attribute.KeyValue
+			value, metadata = aggroConfig.ApplyAggroToHistogramValue(value, i)
+			attrs = append(attrs, metadata...)
+		}
+		points[i] = point{value: value, attrs: attrs}
 	}
+	recordPoints(ctx, workers, points, func(ctx context.Context, value float64, attrs []attribute.KeyValue) {
+		pointCtx, end := spans.Start(ctx, metricName, time.Time{})
+		histogram.Record(pointCtx, value, metric.WithAttributes(attrs...))
+		end()
+	})
 	return nil
 }
+
+// clampToInt64 converts a histogram value to int64, saturating at the int64 range
+func clampToInt64(value float64) int64 {
+	switch {
+	case value >= math.MaxInt64:
+		return math.MaxInt64
+	case value <= math.MinInt64:
+		return math.MinInt64
+	default:
+		return int64(value)
+	}
+}
@@ -0,0 +1,357 @@
+package metrics
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/antithesishq/otel-datagen/internal/randomness"
+	"github.com/spf13/viper"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+)
+
+// LifecycleConfig controls process restarts, gaps and staleness of exported series
+// Rates are probabilities per collection interval
+type LifecycleConfig struct {
+	RestartRate   float64 // Process restarts, resetting every cumulative series
+	KeepStartRate float64 // Fraction of restarts that wrongly keep the old StartTime
+	GapRate       float64 // A series goes missing for GapLength intervals, then returns
+	GapLength     int
+	EndRate       float64 // A series ends permanently and goes stale
+}
+
+// ParseLifecycleConfig reads the series lifecycle settings for the given component from viper
+func ParseLifecycleConfig(component string) (*LifecycleConfig, error) {
+	prefix := "generate." + component + "."
+	config := &LifecycleConfig{
+		RestartRate:   viper.GetFloat64(prefix + "restart_rate"),
+		KeepStartRate: viper.GetFloat64(prefix + "restart_keep_start"),
+		GapRate:       viper.GetFloat64(prefix + "gap_rate"),
+		GapLength:     viper.GetInt(prefix + "gap_length"),
+		EndRate:       viper.GetFloat64(prefix + "end_rate"),
+	}
+	if config.GapLength == 0 {
+		config.GapLength = 3
+	}
+	if err := config.Validate(); err != nil {
+		return nil, err
+	}
+	return config, nil
+}
+
+// Validate checks that rates are probabilities and gaps last at least one interval
+func (config *LifecycleConfig) Validate() error {
+	rates := map[string]float64{
+		"restart rate":       config.RestartRate,
+		"restart keep start": config.KeepStartRate,
+		"gap rate":           config.GapRate,
+		"end rate":           config.EndRate,
+	}
+	for name, rate := range rates {
+		if rate < 0 || rate > 1 {
+			return fmt.Errorf("%s must be between 0 and 1, got %g", name, rate)
+		}
+	}
+	if config.GapLength < 1 {
+		return fmt.Errorf("gap length must be at least 1 interval, got %d", config.GapLength)
+	}
+	return nil
+}
+
+// Enabled reports whether any lifecycle event can happen
+func (config *LifecycleConfig) Enabled() bool {
+	return config != nil && (config.RestartRate > 0 || config.GapRate > 0 || config.EndRate > 0)
+}
+
+// Lifecycle rewrites collected metrics interval by interval to simulate restarts, gaps and staleness
+type Lifecycle struct {
+	config  *LifecycleConfig
+	series  map[seriesKey]*seriesState
+	restart *restart // Most recent restart, nil before the first
+	last    time.Time
+}
+
+// restart records when the simulated process restarted and whether it kept its old StartTime
+type restart struct {
+	at        time.Time
+	keepStart bool
+}
+
+// seriesKey identifies a series across collections
+type seriesKey struct {
+	metric string
+	attrs  attribute.Distinct
+}
+
+// seriesState tracks one series: its cumulative data at the previous collection, the offsets
+// subtracted since the last restart, and whether it is missing
+type seriesState struct {
+	seen      bool
+	restartAt *restart // Restart the offsets belong to
+
+	previous float64 // Sums
+	offset   float64
+
+	prevCount   uint64 // Histograms
+	prevBuckets []uint64
+	prevSum     float64
+	countOff    uint64
+	bucketsOff  []uint64
+	sumOff      float64
+	rebased     bool // Min and max still cover values recorded before the last restart
+
+	prevZero     uint64 // Exponential histograms
+	prevPositive expoBuckets
+	prevNegative expoBuckets
+	zeroOff      uint64
+	positiveOff  expoBuckets
+	negativeOff  expoBuckets
+
+	gapLeft int
+	ended   bool
+}
+
+// expoBuckets is a copy of the positive or negative buckets of an exponential histogram at its scale
+type expoBuckets struct {
+	scale  int32
+	offset int32
+	counts []uint64
+}
+
+// NewLifecycle creates a lifecycle simulator with no events yet
+func (config *LifecycleConfig) NewLifecycle() *Lifecycle {
+	return &Lifecycle{config: config, series: make(map[seriesKey]*seriesState)}
+}
+
+// Apply rewrites one collection taken at timestamp: it rolls a process restart for this interval,
+// drops data points of series that are in a gap or have ended, and rebases cumulative data after restarts
+func (l *Lifecycle) Apply(rm *metricdata.ResourceMetrics, timestamp time.Time) {
+	if !l.last.IsZero() && randomness.Float64() < l.config.RestartRate {
+		// The process restarted some time between the previous collection and this one
+		l.restart = &restart{
+			at:        l.last.Add(timestamp.Sub(l.last) / 2),
+			keepStart: randomness.Float64() < l.config.KeepStartRate,
+		}
+	}
+	l.last = timestamp
+
+	for i := range rm.ScopeMetrics {
+		for j := range rm.ScopeMetrics[i].Metrics {
+			m := &rm.ScopeMetrics[i].Metrics[j]
+			switch data := m.Data.(type) {
+			case metricdata.Sum[int64]:
+				data.DataPoints = applySum(l, m.Name, data.DataPoints, data.Temporality)
+				m.Data = data
+			case metricdata.Sum[float64]:
+				data.DataPoints = applySum(l, m.Name, data.DataPoints, data.Temporality)
+				m.Data = data
+			case metricdata.Gauge[int64]:
+				data.DataPoints = applyGauge(l, m.Name, data.DataPoints)
+				m.Data = data
+			case metricdata.Gauge[float64]:
+				data.DataPoints = applyGauge(l, m.Name, data.DataPoints)
+				m.Data = data
+			case metricdata.Histogram[int64]:
+				data.DataPoints = applyHistogram(l, m.Name, data.DataPoints, data.Temporality)
+				m.Data = data
+			case metricdata.Histogram[float64]:
+				data.DataPoints = applyHistogram(l, m.Name, data.DataPoints, data.Temporality)
+				m.Data = data
+			case metricdata.ExponentialHistogram[int64]:
+				data.DataPoints = applyExponentialHistogram(l, m.Name, data.DataPoints, data.Temporality)
+				m.Data = data
+			case metricdata.ExponentialHistogram[float64]:
+				data.DataPoints = applyExponentialHistogram(l, m.Name, data.DataPoints, data.Temporality)
+				m.Data = data
+			}
+		}
+	}
+}
+
+// state returns the tracked state of a series, creating it on first sight
+func (l *Lifecycle) state(metric string, attrs attribute.Set) *seriesState {
+	key := seriesKey{metric: metric, attrs: attrs.Equivalent()}
+	state, ok := l.series[key]
+	if !ok {
+		state = &seriesState{}
+		l.series[key] = state
+	}
+	return state
+}
+
+// visible rolls gap and end events for a series and reports whether it is exported in this interval
+func (l *Lifecycle) visible(state *seriesState) bool {
+	switch {
+	case state.ended:
+		return false
+	case state.gapLeft > 0:
+		state.gapLeft--
+		return false
+	case randomness.Float64() < l.config.EndRate:
+		state.ended = true
+		return false
+	case randomness.Float64() < l.config.GapRate:
+		state.gapLeft = l.config.GapLength - 1
+		return false
+	}
+	return true
+}
+
+// restarted reports whether a restart happened since the series' offsets were taken, and marks them current
+// Series first seen after a restart have nothing to subtract
+func (l *Lifecycle) restarted(state *seriesState) bool {
+	if l.restart == nil || state.restartAt == l.restart {
+		return false
+	}
+	state.restartAt = l.restart
+	return state.seen
+}
+
+// rebaseStart moves the StartTime of cumulative data to the last restart, unless the restart kept the old one
+func (l *Lifecycle) rebaseStart(start time.Time) time.Time {
+	if l.restart == nil || l.restart.keepStart {
+		return start
+	}
+	return l.restart.at
+}
+
+// applyGauge drops gauge points of missing series; restarts don't affect gauges
+func applyGauge[N int64 | float64](l *Lifecycle, metric string, points []metricdata.DataPoint[N]) []metricdata.DataPoint[N] {
+	kept := points[:0]
+	for _, dp := range points {
+		if l.visible(l.state(metric, dp.Attributes)) {
+			kept = append(kept, dp)
+		}
+	}
+	return kept
+}
+
+// applySum drops points of missing series and rebases cumulative sums on the last restart
+// Missing series are still tracked, so a series that returns after a restart is reset too
+func applySum[N int64 | float64](l *Lifecycle, metric string, points []metricdata.DataPoint[N], temporality metricdata.Temporality) []metricdata.DataPoint[N] {
+	kept := points[:0]
+	for _, dp := range points {
+		state := l.state(metric, dp.Attributes)
+		visible := l.visible(state)
+
+		if temporality == metricdata.CumulativeTemporality {
+			if l.restarted(state) {
+				// Everything counted before the restart is lost
+				state.offset = state.previous
+			}
+			state.seen = true
+			state.previous = float64(dp.Value)
+			dp.Value -= N(state.offset)
+			dp.StartTime = l.rebaseStart(dp.StartTime)
+		}
+
+		if visible {
+			kept = append(kept, dp)
+		}
+	}
+	return kept
+}
+
+// applyHistogram drops points of missing series and rebases cumulative histograms on the last restart
+func applyHistogram[N int64 | float64](l *Lifecycle, metric string, points []metricdata.HistogramDataPoint[N], temporality metricdata.Temporality) []metricdata.HistogramDataPoint[N] {
+	kept := points[:0]
+	for _, dp := range points {
+		state := l.state(metric, dp.Attributes)
+		visible := l.visible(state)
+
+		if temporality == metricdata.CumulativeTemporality {
+			if l.restarted(state) {
+				state.countOff = state.prevCount
+				state.bucketsOff = state.prevBuckets
+				state.sumOff = state.prevSum
+				state.rebased = true
+			}
+			if state.rebased {
+				// Min and max can't be recovered for the period since the restart
+				dp.Min = metricdata.Extrema[N]{}
+				dp.Max = metricdata.Extrema[N]{}
+			}
+			state.seen = true
+			state.prevCount = dp.Count
+			state.prevBuckets = append([]uint64(nil), dp.BucketCounts...)
+			state.prevSum = float64(dp.Sum)
+
+			dp.Count -= state.countOff
+			dp.BucketCounts = append([]uint64(nil), dp.BucketCounts...)
+			for k := range dp.BucketCounts {
+				if k < len(state.bucketsOff) {
+					dp.BucketCounts[k] -= state.bucketsOff[k]
+				}
+			}
+			dp.Sum -= N(state.sumOff)
+			dp.StartTime = l.rebaseStart(dp.StartTime)
+		}
+
+		if visible {
+			kept = append(kept, dp)
+		}
+	}
+	return kept
+}
+
+// applyExponentialHistogram drops points of missing series and rebases cumulative exponential histograms on the last restart
+func applyExponentialHistogram[N int64 | float64](l *Lifecycle, metric string, points []metricdata.ExponentialHistogramDataPoint[N], temporality metricdata.Temporality) []metricdata.ExponentialHistogramDataPoint[N] {
+	kept := points[:0]
+	for _, dp := range points {
+		state := l.state(metric, dp.Attributes)
+		visible := l.visible(state)
+
+		if temporality == metricdata.CumulativeTemporality {
+			if l.restarted(state) {
+				state.countOff = state.prevCount
+				state.zeroOff = state.prevZero
+				state.positiveOff = state.prevPositive
+				state.negativeOff = state.prevNegative
+				state.sumOff = state.prevSum
+				state.rebased = true
+			}
+			if state.rebased {
+				// Min and max can't be recovered for the period since the restart
+				dp.Min = metricdata.Extrema[N]{}
+				dp.Max = metricdata.Extrema[N]{}
+			}
+			state.seen = true
+			state.prevCount = dp.Count
+			state.prevZero = dp.ZeroCount
+			state.prevPositive = expoBuckets{dp.Scale, dp.PositiveBucket.Offset, append([]uint64(nil), dp.PositiveBucket.Counts...)}
+			state.prevNegative = expoBuckets{dp.Scale, dp.NegativeBucket.Offset, append([]uint64(nil), dp.NegativeBucket.Counts...)}
+			state.prevSum = float64(dp.Sum)
+
+			dp.Count -= state.countOff
+			dp.ZeroCount -= state.zeroOff
+			dp.PositiveBucket = state.positiveOff.subtractFrom(dp.PositiveBucket, dp.Scale)
+			dp.NegativeBucket = state.negativeOff.subtractFrom(dp.NegativeBucket, dp.Scale)
+			dp.Sum -= N(state.sumOff)
+			dp.StartTime = l.rebaseStart(dp.StartTime)
+		}
+
+		if visible {
+			kept = append(kept, dp)
+		}
+	}
+	return kept
+}
+
+// subtractFrom returns bucket without the counts of b, downscaling b to the given scale first
+// Cumulative exponential histograms only ever lower their scale, so every bucket of b falls into one of the given scale
+func (b expoBuckets) subtractFrom(bucket metricdata.ExponentialBucket, scale int32) metricdata.ExponentialBucket {
+	counts := append([]uint64(nil), bucket.Counts...)
+	shift := b.scale - scale
+	if shift < 0 {
+		return bucket
+	}
+	for k, c := range b.counts {
+		// Arithmetic shifts floor negative indexes too, as downscaling does
+		i := int((b.offset+int32(k))>>shift - bucket.Offset)
+		if i >= 0 && i < len(counts) {
+			counts[i] -= c
+		}
+	}
+	bucket.Counts = counts
+	return bucket
+}
@@ -3,57 +3,135 @@ package metrics
 import (
 	"context"
 	"fmt"
+	"sync"
 
 	"github.com/antithesishq/otel-datagen/internal/aggro"
+	"github.com/antithesishq/otel-datagen/internal/shapes"
+	"go.opentelemetry.io/otel/attribute"
 	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
 )
 
-// Generate generates metrics using the provided meter provider
+// Generate generates metrics using the provided meter provider, with independent uniform values in [counterMin, counterMax]
 func Generate(ctx context.Context, mp *sdkmetric.MeterProvider, numMetrics int, metricType string, metricName string, counterMin int, counterMax int, aggroConfig *aggro.AggroConfig, protocol string) error {
+	return GenerateWithShape(ctx, mp, numMetrics, metricType, metricName, shapes.Uniform(counterMin, counterMax), nil, nil, 1, aggroConfig, protocol)
+}
+
+// GenerateWithShape generates metrics using the provided meter provider, taking values from shape
+// When cardinality is enabled, numMetrics data points are recorded for every active series; otherwise shaped values
+// form one series and independent uniform values each get their own. spans, when set, records synchronous measurements inside generated spans
+// Synchronous instruments are recorded by the given number of workers, each taking whole series
+func GenerateWithShape(ctx context.Context, mp *sdkmetric.MeterProvider, numMetrics int, metricType string, metricName string, shape shapes.Shape, cardinality *CardinalityConfig, spans *ExemplarSpans, workers int, aggroConfig *aggro.AggroConfig, protocol string) error {
 	// Get meter
 	meter := mp.Meter("otel-datagen")
 
 	// Generate aggro values for aggro system
-	var aggroValues []string
-	var effectiveAggroProb float64
+	aggroValues, effectiveAggroProb := newAggroValues(aggroConfig, protocol)
 
-	if aggroConfig != nil && aggroConfig.HasAnyActive() {
-		// Use aggro values when aggro is configured - set probability to 1.0 to always trigger
-		aggroValues = aggro.GetAggroValuesForProtocol(protocol)
-		effectiveAggroProb = 1.0
+	attributes := func(i int) []attribute.KeyValue {
+		return generateMetricAttributes(i, aggroValues, effectiveAggroProb)
+	}
+	if !shapes.Independent(shape) {
+		attributes = func(int) []attribute.KeyValue {
+			return appendAggroValue(seriesMetricAttributes, aggroValues, effectiveAggroProb)
+		}
+	}
+	if cardinality.Enabled() {
+		series := SeriesAttributes(cardinality.NewSeriesPool(), numMetrics)
+		numMetrics = len(series)
+		attributes = func(i int) []attribute.KeyValue {
+			return appendAggroValue(series[i], aggroValues, effectiveAggroProb)
+		}
 	}
 
 	// Generate metrics based on type
 	switch metricType {
 	case "counter", "int64-counter":
-		return GenerateInt64Counter(ctx, meter, metricName, numMetrics, counterMin, counterMax, aggroValues, effectiveAggroProb)
+		return GenerateInt64Counter(ctx, meter, metricName, numMetrics, shape, attributes, spans, workers)
 	case "float64-counter":
-		return GenerateFloat64Counter(ctx, meter, metricName, numMetrics, counterMin, counterMax, aggroValues, effectiveAggroProb)
+		return GenerateFloat64Counter(ctx, meter, metricName, numMetrics, shape, attributes, spans, workers)
 	case "histogram", "float64-histogram":
-		return GenerateFloat64Histogram(ctx, meter, metricName, numMetrics, counterMin, counterMax, aggroValues, effectiveAggroProb)
+		return GenerateFloat64Histogram(ctx, meter, metricName, numMetrics, shape, attributes, spans, workers, aggroConfig)
 	case "int64-histogram":
-		return GenerateInt64Histogram(ctx, meter, metricName, numMetrics, counterMin, counterMax, aggroValues, effectiveAggroProb)
+		return GenerateInt64Histogram(ctx, meter, metricName, numMetrics, shape, attributes, spans, workers, aggroConfig)
 	case "updowncounter", "int64-updowncounter":
-		return GenerateInt64UpDownCounter(ctx, meter, metricName, numMetrics, counterMin, counterMax, aggroValues, effectiveAggroProb)
+		return GenerateInt64UpDownCounter(ctx, meter, metricName, numMetrics, shape, attributes, spans, workers)
 	case "float64-updowncounter":
-		return GenerateFloat64UpDownCounter(ctx, meter, metricName, numMetrics, counterMin, counterMax, aggroValues, effectiveAggroProb)
+		return GenerateFloat64UpDownCounter(ctx, meter, metricName, numMetrics, shape, attributes, spans, workers)
 	case "gauge", "int64-gauge":
-		return GenerateInt64Gauge(ctx, meter, metricName, numMetrics, counterMin, counterMax, aggroValues, effectiveAggroProb)
+		return GenerateInt64Gauge(ctx, meter, metricName, numMetrics, shape, attributes, spans, workers)
 	case "float64-gauge":
-		return GenerateFloat64Gauge(ctx, meter, metricName, numMetrics, counterMin, counterMax, aggroValues, effectiveAggroProb)
+		return GenerateFloat64Gauge(ctx, meter, metricName, numMetrics, shape, attributes, spans, workers)
 	case "observable-counter", "int64-observable-counter":
-		return GenerateInt64ObservableCounter(ctx, meter, metricName, numMetrics, counterMin, counterMax, aggroValues, effectiveAggroProb)
+		return GenerateInt64ObservableCounter(ctx, meter, metricName, numMetrics, shape, attributes)
 	case "float64-observable-counter":
-		return GenerateFloat64ObservableCounter(ctx, meter, metricName, numMetrics, counterMin, counterMax, aggroValues, effectiveAggroProb)
+		return GenerateFloat64ObservableCounter(ctx, meter, metricName, numMetrics, shape, attributes)
 	case "observable-updowncounter", "int64-observable-updowncounter":
-		return GenerateInt64ObservableUpDownCounter(ctx, meter, metricName, numMetrics, counterMin, counterMax, aggroValues, effectiveAggroProb)
+		return GenerateInt64ObservableUpDownCounter(ctx, meter, metricName, numMetrics, shape, attributes)
 	case "float64-observable-updowncounter":
-		return GenerateFloat64ObservableUpDownCounter(ctx, meter, metricName, numMetrics, counterMin, counterMax, aggroValues, effectiveAggroProb)
+		return GenerateFloat64ObservableUpDownCounter(ctx, meter, metricName, numMetrics, shape, attributes)
 	case "observable-gauge", "int64-observable-gauge":
-		return GenerateInt64ObservableGauge(ctx, meter, metricName, numMetrics, counterMin, counterMax, aggroValues, effectiveAggroProb)
+		return GenerateInt64ObservableGauge(ctx, meter, metricName, numMetrics, shape, attributes)
 	case "float64-observable-gauge":
-		return GenerateFloat64ObservableGauge(ctx, meter, metricName, numMetrics, counterMin, counterMax, aggroValues, effectiveAggroProb)
+		return GenerateFloat64ObservableGauge(ctx, meter, metricName, numMetrics, shape, attributes)
 	default:
-		return fmt.Errorf("unsupported metric type: %s (supported: counter, float64-counter, histogram, int64-histogram, updowncounter, float64-updowncounter, gauge, float64-gauge, observable-counter, float64-observable-counter, observable-updowncounter, float64-observable-updowncounter, observable-gauge, float64-observable-gauge)", metricType)
+		return unsupportedMetricType(metricType)
+	}
+}
+
+// point is a value to record with its attributes
+type point struct {
+	value float64
+	attrs []attribute.KeyValue
+}
+
+// recordPoints calls record for every point, spread over the given number of workers
+// Every series is recorded by one worker in order, so sums, histograms and last values are the same for any number of workers
+func recordPoints(ctx context.Context, workers int, points []point, record func(ctx context.Context, value float64, attrs []attribute.KeyValue)) {
+	if workers <= 1 {
+		for _, p := range points {
+			record(ctx, p.value, p.attrs)
+		}
+		return
+	}
+
+	// Series are dealt to the workers in order of first appearance
+	shards := make([][]point, workers)
+	assigned := make(map[attribute.Distinct]int)
+	for _, p := range points {
+		set := attribute.NewSet(p.attrs...)
+		shard, ok := assigned[set.Equivalent()]
+		if !ok {
+			shard = len(assigned) % workers
+			assigned[set.Equivalent()] = shard
+		}
+		shards[shard] = append(shards[shard], p)
+	}
+
+	var wg sync.WaitGroup
+	for _, shard := range shards {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for _, p := range shard {
+				record(ctx, p.value, p.attrs)
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+// unsupportedMetricType reports a metric type that no generator handles
+func unsupportedMetricType(metricType string) error {
+	return fmt.Errorf("unsupported metric type: %s (supported: counter, float64-counter, histogram, int64-histogram, updowncounter, float64-updowncounter, gauge, float64-gauge, observable-counter, float64-observable-counter, observable-updowncounter, float64-observable-updowncounter, observable-gauge, float64-observable-gauge)", metricType)
+}
+
+// SeriesAttributes lists the attributes of every value to record for numPoints data points,
+// the active series of each data point in turn, advancing the pool in between
+func SeriesAttributes(pool *SeriesPool, numPoints int) [][]attribute.KeyValue {
+	var series [][]attribute.KeyValue
+	for i := 0; i < numPoints; i++ {
+		series = append(series, pool.Active()...)
+		pool.Advance()
 	}
+	return series
 }
@@ -0,0 +1,169 @@
+package metrics
+
+import "sort"
+
+// packs are built-in catalogs modelled on the OpenTelemetry semantic conventions
+var packs = map[string][]CatalogEntry{
+	"http-server": {
+		{
+			Name: "http.server.request.duration", Type: "histogram", Unit: "s",
+			Description: "Duration of HTTP server requests.",
+			Shape:       "random-walk:step=0.02,spike=0.02,spike_factor=10", Min: 0.005, Max: 0.5,
+			Attributes: map[string][]string{
+				"http.request.method":       {"GET", "POST"},
+				"http.response.status_code": {"200", "404", "500"},
+				"http.route":                {"/api/users", "/api/orders", "/health"},
+			},
+		},
+		{
+			Name: "http.server.active_requests", Type: "updowncounter", Unit: "{request}",
+			Description: "Number of active HTTP server requests.",
+			Shape:       "sine:period=30,offset=0,noise=1", Min: -5, Max: 5,
+			Attributes: map[string][]string{
+				"http.request.method": {"GET", "POST"},
+			},
+		},
+		{
+			Name: "http.server.request.body.size", Type: "int64-histogram", Unit: "By",
+			Description: "Size of HTTP server request bodies.",
+			Min:         0, Max: 65536,
+			Attributes: map[string][]string{
+				"http.request.method": {"GET", "POST"},
+			},
+		},
+		{
+			Name: "http.server.response.body.size", Type: "int64-histogram", Unit: "By",
+			Description: "Size of HTTP server response bodies.",
+			Min:         128, Max: 1048576,
+			Attributes: map[string][]string{
+				"http.request.method": {"GET", "POST"},
+			},
+		},
+	},
+	"runtime": {
+		{
+			Name: "go.memory.used", Type: "gauge", Unit: "By",
+			Description: "Memory used by the Go runtime.",
+			Shape:       "random-walk:step=2000000", Min: 50000000, Max: 200000000,
+		},
+		{
+			Name: "go.memory.allocated", Type: "counter", Unit: "By",
+			Description: "Memory allocated to the heap by the application.",
+			Shape:       "monotonic:rate=4000000,jitter=0.5",
+		},
+		{
+			Name: "go.memory.allocations", Type: "counter", Unit: "{allocation}",
+			Description: "Count of allocations to the heap by the application.",
+			Shape:       "monotonic:rate=20000,jitter=0.5",
+		},
+		{
+			Name: "go.memory.gc.goal", Type: "gauge", Unit: "By",
+			Description: "Heap size target for the end of the GC cycle.",
+			Shape:       "step:every=10", Min: 100000000, Max: 400000000,
+		},
+		{
+			Name: "go.goroutine.count", Type: "gauge", Unit: "{goroutine}",
+			Description: "Count of live goroutines.",
+			Shape:       "random-walk:step=5", Min: 10, Max: 500,
+		},
+		{
+			Name: "go.schedule.duration", Type: "histogram", Unit: "s",
+			Description: "The time goroutines have spent in the scheduler in a runnable state before actually running.",
+			Min:         0, Max: 0.001,
+		},
+	},
+	"host": {
+		{
+			Name: "system.cpu.utilization", Type: "float64-gauge", Unit: "1",
+			Description: "Difference in system.cpu.time since the last measurement, divided by the elapsed time and number of CPUs.",
+			Shape:       "sine:period=60,noise=0.05", Min: 0, Max: 1,
+			Attributes: map[string][]string{
+				"cpu.mode": {"user", "system", "idle", "iowait"},
+			},
+		},
+		{
+			Name: "system.memory.usage", Type: "gauge", Unit: "By",
+			Description: "Reports memory in use by state.",
+			Shape:       "random-walk:step=50000000", Min: 1000000000, Max: 8000000000,
+			Attributes: map[string][]string{
+				"system.memory.state": {"used", "free", "cached"},
+			},
+		},
+		{
+			Name: "system.disk.io", Type: "counter", Unit: "By",
+			Description: "Disk bytes transferred.",
+			Shape:       "monotonic:rate=5000000,jitter=0.8",
+			Attributes: map[string][]string{
+				"disk.io.direction": {"read", "write"},
+				"system.device":     {"sda", "sdb"},
+			},
+		},
+		{
+			Name: "system.network.io", Type: "counter", Unit: "By",
+			Description: "Network bytes transferred.",
+			Shape:       "monotonic:rate=10000000,jitter=0.8",
+			Attributes: map[string][]string{
+				"network.io.direction":   {"receive", "transmit"},
+				"network.interface.name": {"eth0"},
+			},
+		},
+		{
+			Name: "system.filesystem.utilization", Type: "float64-gauge", Unit: "1",
+			Description: "Fraction of filesystem bytes used.",
+			Shape:       "sawtooth:period=120", Min: 0.2, Max: 0.95,
+			Attributes: map[string][]string{
+				"system.filesystem.mountpoint": {"/", "/var"},
+			},
+		},
+	},
+	"kafka-consumer": {
+		{
+			Name: "messaging.client.consumed.messages", Type: "counter", Unit: "{message}",
+			Description: "Number of messages that were delivered to the application.",
+			Shape:       "monotonic:rate=500,jitter=0.3",
+			Attributes: map[string][]string{
+				"messaging.system":              {"kafka"},
+				"messaging.destination.name":    {"orders", "payments"},
+				"messaging.consumer.group.name": {"billing"},
+			},
+		},
+		{
+			Name: "messaging.process.duration", Type: "histogram", Unit: "s",
+			Description: "Duration of processing operation.",
+			Shape:       "random-walk:step=0.005,spike=0.01", Min: 0.001, Max: 0.2,
+			Attributes: map[string][]string{
+				"messaging.system":           {"kafka"},
+				"messaging.destination.name": {"orders", "payments"},
+			},
+		},
+		{
+			Name: "kafka.consumer.records_lag", Type: "gauge", Unit: "{message}",
+			Description: "Number of messages the consumer is behind the end of the partition.",
+			Shape:       "sawtooth:period=20,spike=0.02", Min: 0, Max: 5000,
+			Attributes: map[string][]string{
+				"messaging.destination.name":         {"orders", "payments"},
+				"messaging.destination.partition.id": {"0", "1", "2"},
+			},
+		},
+		{
+			Name: "kafka.consumer.fetch_rate", Type: "float64-gauge", Unit: "{fetch}/s",
+			Description: "Number of fetch requests per second.",
+			Shape:       "random-walk:step=2", Min: 0, Max: 100,
+		},
+		{
+			Name: "kafka.consumer.commit_latency", Type: "histogram", Unit: "s",
+			Description: "Time taken to commit offsets.",
+			Min:         0.001, Max: 0.05,
+		},
+	},
+}
+
+// PackNames returns the names of the built-in metric packs, sorted
+func PackNames() []string {
+	names := make([]string, 0, len(packs))
+	for name := range packs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
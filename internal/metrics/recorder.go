@@ -0,0 +1,252 @@
+package metrics
+
+import (
+	"context"
+	"time"
+
+	"github.com/antithesishq/otel-datagen/internal/aggro"
+	"github.com/antithesishq/otel-datagen/internal/randomness"
+	"github.com/antithesishq/otel-datagen/internal/shapes"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+)
+
+// SeriesRecorder records one metric of any supported type on a long-lived meter provider,
+// one data point per series at a time, so each collection holds exactly one interval
+// Values follow the same rules as GenerateWithShape, including aggro
+type SeriesRecorder struct {
+	metricName string
+	newShape   func() shapes.Shape
+	spans      *ExemplarSpans
+
+	record    func(context.Context, float64, []attribute.KeyValue) // Synchronous instruments
+	observed  []observation                                        // Observable instruments, reported by their callback
+	increment bool                                                 // Sums add increments of the shape rather than its values, never negative ones unless negate is set
+	negate    bool                                                 // Up-down counters flip independent values now and then
+	histogram bool                                                 // Histogram aggro applies
+
+	values   map[attribute.Distinct]func() float64
+	recorded map[attribute.Distinct]bool
+
+	aggroConfig *aggro.AggroConfig
+	aggroValues []string
+	aggroProb   float64
+}
+
+// observation is one value reported by an observable instrument's callback
+type observation struct {
+	value float64
+	attrs []attribute.KeyValue
+}
+
+// NewSeriesRecorder creates the instrument for metricType on the meter
+// newShape creates the value shape of each new series; spans, when set, records synchronous measurements inside generated spans
+func NewSeriesRecorder(meter metric.Meter, metricType string, metricName string, newShape func() shapes.Shape, spans *ExemplarSpans, aggroConfig *aggro.AggroConfig, protocol string) (*SeriesRecorder, error) {
+	r := &SeriesRecorder{
+		metricName:  metricName,
+		newShape:    newShape,
+		spans:       spans,
+		values:      make(map[attribute.Distinct]func() float64),
+		aggroConfig: aggroConfig,
+	}
+	r.aggroValues, r.aggroProb = newAggroValues(aggroConfig, protocol)
+
+	observeInt64 := metric.WithInt64Callback(func(_ context.Context, observer metric.Int64Observer) error {
+		for _, o := range r.observed {
+			observer.Observe(roundToInt64(o.value), metric.WithAttributes(o.attrs...))
+		}
+		return nil
+	})
+	observeFloat64 := metric.WithFloat64Callback(func(_ context.Context, observer metric.Float64Observer) error {
+		for _, o := range r.observed {
+			observer.Observe(o.value, metric.WithAttributes(o.attrs...))
+		}
+		return nil
+	})
+
+	var err error
+	switch metricType {
+	case "counter", "int64-counter":
+		var counter metric.Int64Counter
+		counter, err = meter.Int64Counter(metricName)
+		r.record = func(ctx context.Context, value float64, attrs []attribute.KeyValue) {
+			counter.Add(ctx, roundToInt64(value), metric.WithAttributes(attrs...))
+		}
+		r.increment = true
+	case "float64-counter":
+		var counter metric.Float64Counter
+		counter, err = meter.Float64Counter(metricName)
+		r.record = func(ctx context.Context, value float64, attrs []attribute.KeyValue) {
+			counter.Add(ctx, value, metric.WithAttributes(attrs...))
+		}
+		r.increment = true
+	case "histogram", "float64-histogram":
+		var histogram metric.Float64Histogram
+		histogram, err = meter.Float64Histogram(metricName)
+		r.record = func(ctx context.Context, value float64, attrs []attribute.KeyValue) {
+			histogram.Record(ctx, value, metric.WithAttributes(attrs...))
+		}
+		r.histogram = true
+	case "int64-histogram":
+		var histogram metric.Int64Histogram
+		histogram, err = meter.Int64Histogram(metricName)
+		r.record = func(ctx context.Context, value float64, attrs []attribute.KeyValue) {
+			histogram.Record(ctx, roundToInt64(value), metric.WithAttributes(attrs...))
+		}
+		r.histogram = true
+	case "updowncounter", "int64-updowncounter":
+		var counter metric.Int64UpDownCounter
+		counter, err = meter.Int64UpDownCounter(metricName)
+		r.record = func(ctx context.Context, value float64, attrs []attribute.KeyValue) {
+			counter.Add(ctx, roundToInt64(value), metric.WithAttributes(attrs...))
+		}
+		r.increment, r.negate = true, true
+	case "float64-updowncounter":
+		var counter metric.Float64UpDownCounter
+		counter, err = meter.Float64UpDownCounter(metricName)
+		r.record = func(ctx context.Context, value float64, attrs []attribute.KeyValue) {
+			counter.Add(ctx, value, metric.WithAttributes(attrs...))
+		}
+		r.increment, r.negate = true, true
+	case "gauge", "int64-gauge":
+		var gauge metric.Int64Gauge
+		gauge, err = meter.Int64Gauge(metricName)
+		r.record = func(ctx context.Context, value float64, attrs []attribute.KeyValue) {
+			gauge.Record(ctx, roundToInt64(value), metric.WithAttributes(attrs...))
+		}
+	case "float64-gauge":
+		var gauge metric.Float64Gauge
+		gauge, err = meter.Float64Gauge(metricName)
+		r.record = func(ctx context.Context, value float64, attrs []attribute.KeyValue) {
+			gauge.Record(ctx, value, metric.WithAttributes(attrs...))
+		}
+	case "observable-counter", "int64-observable-counter":
+		_, err = meter.Int64ObservableCounter(metricName, observeInt64)
+	case "float64-observable-counter":
+		_, err = meter.Float64ObservableCounter(metricName, observeFloat64)
+	case "observable-updowncounter", "int64-observable-updowncounter":
+		_, err = meter.Int64ObservableUpDownCounter(metricName, observeInt64)
+		r.negate = true
+	case "float64-observable-updowncounter":
+		_, err = meter.Float64ObservableUpDownCounter(metricName, observeFloat64)
+		r.negate = true
+	case "observable-gauge", "int64-observable-gauge":
+		_, err = meter.Int64ObservableGauge(metricName, observeInt64)
+	case "float64-observable-gauge":
+		_, err = meter.Float64ObservableGauge(metricName, observeFloat64)
+	default:
+		return nil, unsupportedMetricType(metricType)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// Record records the next value of every series; iteration feeds histogram aggro
+// timestamp is the intended time of the values, for the spans they are recorded in (zero = now)
+// Observable instruments report the values at the next collection
+func (r *SeriesRecorder) Record(ctx context.Context, series [][]attribute.KeyValue, iteration int, timestamp time.Time) {
+	r.observed = r.observed[:0]
+	r.recorded = make(map[attribute.Distinct]bool)
+
+	for _, attrs := range series {
+		value := r.next(attrs)
+
+		attrs = appendAggroValue(attrs, r.aggroValues, r.aggroProb)
+		if r.histogram {
+			value, attrs = applyHistogramAggro(r.aggroConfig, value, attrs, iteration)
+		}
+		set := attribute.NewSet(attrs...)
+		r.recorded[set.Equivalent()] = true
+
+		if r.record == nil {
+			r.observed = append(r.observed, observation{value: value, attrs: attrs})
+			continue
+		}
+		pointCtx, end := r.spans.Start(ctx, r.metricName, timestamp)
+		r.record(pointCtx, value, attrs)
+		end()
+	}
+}
+
+// next returns the next value of a series, creating its shape on first sight
+func (r *SeriesRecorder) next(attrs []attribute.KeyValue) float64 {
+	set := attribute.NewSet(attrs...)
+	key := set.Equivalent()
+	next, ok := r.values[key]
+	if !ok {
+		shape := r.newShape()
+		next = shape.Next
+		switch {
+		case r.increment && r.negate:
+			next = shapes.Increments(shape)
+		case r.increment:
+			next = shapes.CounterIncrements(shape)
+		}
+		if r.negate && shapes.Independent(shape) {
+			values := next
+			next = func() float64 {
+				value := values()
+				if randomness.Float64() < 0.3 { // 30% chance of negative value
+					value = -value
+				}
+				return value
+			}
+		}
+		r.values[key] = next
+	}
+	return next()
+}
+
+// DropUnrecorded removes data points of series that were not recorded by the last Record call
+// Cumulative aggregations keep reporting every series they have seen, including those churned out since
+func (r *SeriesRecorder) DropUnrecorded(rm *metricdata.ResourceMetrics) {
+	for i := range rm.ScopeMetrics {
+		for j := range rm.ScopeMetrics[i].Metrics {
+			m := &rm.ScopeMetrics[i].Metrics[j]
+			if m.Name != r.metricName {
+				continue
+			}
+			switch data := m.Data.(type) {
+			case metricdata.Sum[int64]:
+				data.DataPoints = keepRecorded(r, data.DataPoints, func(dp metricdata.DataPoint[int64]) attribute.Set { return dp.Attributes })
+				m.Data = data
+			case metricdata.Sum[float64]:
+				data.DataPoints = keepRecorded(r, data.DataPoints, func(dp metricdata.DataPoint[float64]) attribute.Set { return dp.Attributes })
+				m.Data = data
+			case metricdata.Gauge[int64]:
+				data.DataPoints = keepRecorded(r, data.DataPoints, func(dp metricdata.DataPoint[int64]) attribute.Set { return dp.Attributes })
+				m.Data = data
+			case metricdata.Gauge[float64]:
+				data.DataPoints = keepRecorded(r, data.DataPoints, func(dp metricdata.DataPoint[float64]) attribute.Set { return dp.Attributes })
+				m.Data = data
+			case metricdata.Histogram[int64]:
+				data.DataPoints = keepRecorded(r, data.DataPoints, func(dp metricdata.HistogramDataPoint[int64]) attribute.Set { return dp.Attributes })
+				m.Data = data
+			case metricdata.Histogram[float64]:
+				data.DataPoints = keepRecorded(r, data.DataPoints, func(dp metricdata.HistogramDataPoint[float64]) attribute.Set { return dp.Attributes })
+				m.Data = data
+			case metricdata.ExponentialHistogram[int64]:
+				data.DataPoints = keepRecorded(r, data.DataPoints, func(dp metricdata.ExponentialHistogramDataPoint[int64]) attribute.Set { return dp.Attributes })
+				m.Data = data
+			case metricdata.ExponentialHistogram[float64]:
+				data.DataPoints = keepRecorded(r, data.DataPoints, func(dp metricdata.ExponentialHistogramDataPoint[float64]) attribute.Set { return dp.Attributes })
+				m.Data = data
+			}
+		}
+	}
+}
+
+// keepRecorded filters data points down to the series recorded last
+func keepRecorded[T any](r *SeriesRecorder, points []T, attributes func(T) attribute.Set) []T {
+	kept := points[:0]
+	for _, dp := range points {
+		set := attributes(dp)
+		if r.recorded[set.Equivalent()] {
+			kept = append(kept, dp)
+		}
+	}
+	return kept
+}
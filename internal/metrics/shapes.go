@@ -0,0 +1,69 @@
+package metrics
+
+import (
+	"fmt"
+	"math"
+	"strings"
+
+	"github.com/antithesishq/otel-datagen/internal/shapes"
+	"github.com/spf13/viper"
+)
+
+// ShapeConfig selects the value shape of generated metrics
+type ShapeConfig struct {
+	Default      string            // Shape spec for all metrics ("" = independent uniform values)
+	MetricShapes map[string]string // Shape specs for individual metrics, by name
+}
+
+// ParseShapeConfig reads the value shape settings for the given component from viper
+// Specs are parsed up front so mistakes are reported before any data is generated
+func ParseShapeConfig(component string, counterMin int, counterMax int) (*ShapeConfig, error) {
+	config := &ShapeConfig{}
+	prefix := "generate." + component + "."
+
+	config.Default = viper.GetString(prefix + "value_shape")
+
+	for _, entry := range viper.GetStringSlice(prefix + "metric_value_shape") {
+		name, spec, ok := strings.Cut(entry, "=")
+		if !ok || name == "" || spec == "" {
+			return nil, fmt.Errorf("invalid metric-value-shape '%s' (expected name=shape)", entry)
+		}
+		if config.MetricShapes == nil {
+			config.MetricShapes = make(map[string]string)
+		}
+		config.MetricShapes[name] = spec
+	}
+
+	if config.Default != "" {
+		if _, err := shapes.Parse(config.Default, float64(counterMin), float64(counterMax)); err != nil {
+			return nil, err
+		}
+	}
+	for name, spec := range config.MetricShapes {
+		if _, err := shapes.Parse(spec, float64(counterMin), float64(counterMax)); err != nil {
+			return nil, fmt.Errorf("value shape for %s: %w", name, err)
+		}
+	}
+	return config, nil
+}
+
+// ShapeFor returns a new value shape for the named metric, ranging over [counterMin, counterMax] by default
+// A nil config, or one without a matching shape, gives independent uniform values
+func (config *ShapeConfig) ShapeFor(metricName string, counterMin int, counterMax int) (shapes.Shape, error) {
+	if config == nil {
+		return shapes.Uniform(counterMin, counterMax), nil
+	}
+	spec, ok := config.MetricShapes[metricName]
+	if !ok {
+		spec = config.Default
+	}
+	if spec == "" {
+		return shapes.Uniform(counterMin, counterMax), nil
+	}
+	return shapes.Parse(spec, float64(counterMin), float64(counterMax))
+}
+
+// roundToInt64 converts a shape value for an int64 instrument
+func roundToInt64(value float64) int64 {
+	return clampToInt64(math.Round(value))
+}
@@ -2,43 +2,58 @@ package metrics
 
 import (
 	"context"
+	"time"
 
 	"github.com/antithesishq/otel-datagen/internal/randomness"
+	"github.com/antithesishq/otel-datagen/internal/shapes"
+	"go.opentelemetry.io/otel/attribute"
 	"go.opentelemetry.io/otel/metric"
 )
 
 // GenerateInt64UpDownCounter generates int64 updowncounter metrics
-func GenerateInt64UpDownCounter(ctx context.Context, meter metric.Meter, metricName string, numMetrics int, counterMin int, counterMax int, aggroValues []string, aggroProb float64) error {
+func GenerateInt64UpDownCounter(ctx context.Context, meter metric.Meter, metricName string, numMetrics int, shape shapes.Shape, attributes AttributeSource, spans *ExemplarSpans, workers int) error {
 	upDownCounter, err := meter.Int64UpDownCounter(metricName)
 	if err != nil {
 		return err
 	}
-	for i := 0; i < numMetrics; i++ {
-		// UpDownCounters can go negative, so we'll allow negative values
-		value := int64(randomness.Intn(counterMax-counterMin+1) + counterMin)
-		if randomness.Float64() < 0.3 { // 30% chance of negative value
+	next := shapes.Increments(shape)
+	points := make([]point, numMetrics)
+	for i := range points {
+		// UpDownCounters can go negative, so we'll allow negative values unless the shape defines the series
+		value := next()
+		if shapes.Independent(shape) && randomness.Float64() < 0.3 { // 30% chance of negative value
 			value = -value
 		}
-		attrs := generateMetricAttributes(i, aggroValues, aggroProb)
-		upDownCounter.Add(ctx, value, metric.WithAttributes(attrs...))
+		points[i] = point{value: value, attrs: attributes(i)}
 	}
+	recordPoints(ctx, workers, points, func(ctx context.Context, value float64, attrs []attribute.KeyValue) {
+		pointCtx, end := spans.Start(ctx, metricName, time.Time{})
+		upDownCounter.Add(pointCtx, roundToInt64(value), metric.WithAttributes(attrs...))
+		end()
+	})
 	return nil
 }
 
 // GenerateFloat64UpDownCounter generates float64 updowncounter metrics
-func GenerateFloat64UpDownCounter(ctx context.Context, meter metric.Meter, metricName string, numMetrics int, counterMin int, counterMax int, aggroValues []string, aggroProb float64) error {
+func GenerateFloat64UpDownCounter(ctx context.Context, meter metric.Meter, metricName string, numMetrics int, shape shapes.Shape, attributes AttributeSource, spans *ExemplarSpans, workers int) error {
 	upDownCounter, err := meter.Float64UpDownCounter(metricName)
 	if err != nil {
 		return err
 	}
-	for i := 0; i < numMetrics; i++ {
-		// UpDownCounters can go negative, so we'll allow negative values
-		value := float64(randomness.Intn(counterMax-counterMin+1) + counterMin)
-		if randomness.Float64() < 0.3 { // 30% chance of negative value
+	next := shapes.Increments(shape)
+	points := make([]point, numMetrics)
+	for i := range points {
+		// UpDownCounters can go negative, so we'll allow negative values unless the shape defines the series
+		value := next()
+		if shapes.Independent(shape) && randomness.Float64() < 0.3 { // 30% chance of negative value
 			value = -value
 		}
-		attrs := generateMetricAttributes(i, aggroValues, aggroProb)
-		upDownCounter.Add(ctx, value, metric.WithAttributes(attrs...))
+		points[i] = point{value: value, attrs: attributes(i)}
 	}
+	recordPoints(ctx, workers, points, func(ctx context.Context, value float64, attrs []attribute.KeyValue) {
+		pointCtx, end := spans.Start(ctx, metricName, time.Time{})
+		upDownCounter.Add(pointCtx, value, metric.WithAttributes(attrs...))
+		end()
+	})
 	return nil
 }
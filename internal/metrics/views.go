@@ -0,0 +1,169 @@
+package metrics
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/spf13/viper"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+)
+
+// HistogramConfig controls how histogram instruments are aggregated
+type HistogramConfig struct {
+	Aggregation      string               // "explicit" (default) or "exponential"
+	Boundaries       []float64            // Explicit bucket boundaries for all histograms (nil = SDK default)
+	MetricBoundaries map[string][]float64 // Explicit bucket boundaries for individual metrics, by name
+	MaxSize          int32                // Maximum number of buckets of exponential histograms
+	MaxScale         int32                // Maximum scale of exponential histograms (-10 to 20)
+	NoMinMax         bool                 // Don't record min and max
+}
+
+// Supported histogram aggregations
+var histogramAggregations = []string{"explicit", "exponential"}
+
+// DefaultHistogramConfig returns the configuration matching the SDK defaults
+func DefaultHistogramConfig() *HistogramConfig {
+	return &HistogramConfig{
+		Aggregation: "explicit",
+		MaxSize:     160,
+		MaxScale:    20,
+	}
+}
+
+// ParseHistogramConfig reads the histogram settings for the given component from viper
+func ParseHistogramConfig(component string) (*HistogramConfig, error) {
+	config := DefaultHistogramConfig()
+	prefix := "generate." + component + "."
+
+	if aggregation := viper.GetString(prefix + "histogram_aggregation"); aggregation != "" {
+		config.Aggregation = aggregation
+	}
+
+	if spec := viper.GetString(prefix + "histogram_buckets"); spec != "" {
+		boundaries, err := ParseBoundaries(spec)
+		if err != nil {
+			return nil, err
+		}
+		config.Boundaries = boundaries
+	}
+
+	for _, entry := range viper.GetStringSlice(prefix + "histogram_metric_buckets") {
+		name, spec, ok := strings.Cut(entry, "=")
+		if !ok || name == "" {
+			return nil, fmt.Errorf("invalid histogram-metric-buckets '%s' (expected name=b1,b2,...)", entry)
+		}
+		boundaries, err := ParseBoundaries(spec)
+		if err != nil {
+			return nil, err
+		}
+		if config.MetricBoundaries == nil {
+			config.MetricBoundaries = make(map[string][]float64)
+		}
+		config.MetricBoundaries[name] = boundaries
+	}
+
+	if viper.IsSet(prefix + "histogram_max_size") {
+		config.MaxSize = viper.GetInt32(prefix + "histogram_max_size")
+	}
+	if viper.IsSet(prefix + "histogram_max_scale") {
+		config.MaxScale = viper.GetInt32(prefix + "histogram_max_scale")
+	}
+	config.NoMinMax = viper.GetBool(prefix + "histogram_no_minmax")
+
+	if err := config.Validate(); err != nil {
+		return nil, err
+	}
+	return config, nil
+}
+
+// Validate checks the aggregation name and exponential histogram limits
+func (config *HistogramConfig) Validate() error {
+	valid := false
+	for _, a := range histogramAggregations {
+		if config.Aggregation == a {
+			valid = true
+		}
+	}
+	if !valid {
+		return fmt.Errorf("invalid histogram aggregation '%s' (supported: %s)", config.Aggregation, strings.Join(histogramAggregations, ", "))
+	}
+	if config.MaxSize < 2 {
+		return fmt.Errorf("histogram max size must be at least 2, got %d", config.MaxSize)
+	}
+	if config.MaxScale < -10 || config.MaxScale > 20 {
+		return fmt.Errorf("histogram max scale must be between -10 and 20, got %d", config.MaxScale)
+	}
+	return nil
+}
+
+// ParseBoundaries parses comma-separated, strictly increasing bucket boundaries such as "5,10,25,50"
+func ParseBoundaries(spec string) ([]float64, error) {
+	var boundaries []float64
+	for _, part := range strings.Split(spec, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		b, err := strconv.ParseFloat(part, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid bucket boundary '%s'", part)
+		}
+		if len(boundaries) > 0 && b <= boundaries[len(boundaries)-1] {
+			return nil, fmt.Errorf("bucket boundaries must be strictly increasing ('%s')", spec)
+		}
+		boundaries = append(boundaries, b)
+	}
+	return boundaries, nil
+}
+
+// Views returns the views that apply the configuration to histogram instruments
+// A single view function is used so an instrument never matches more than one view (which would duplicate the stream)
+func (config *HistogramConfig) Views() []sdkmetric.View {
+	if config == nil {
+		return nil
+	}
+
+	return []sdkmetric.View{func(inst sdkmetric.Instrument) (sdkmetric.Stream, bool) {
+		if inst.Kind != sdkmetric.InstrumentKindHistogram {
+			return sdkmetric.Stream{}, false
+		}
+		aggregation := config.aggregationFor(inst.Name)
+		if aggregation == nil {
+			return sdkmetric.Stream{}, false
+		}
+		return sdkmetric.Stream{
+			Name:        inst.Name,
+			Description: inst.Description,
+			Unit:        inst.Unit,
+			Aggregation: aggregation,
+		}, true
+	}}
+}
+
+// aggregationFor returns the aggregation for the named histogram, or nil to keep the SDK default
+func (config *HistogramConfig) aggregationFor(name string) sdkmetric.Aggregation {
+	if boundaries, ok := config.MetricBoundaries[name]; ok {
+		return sdkmetric.AggregationExplicitBucketHistogram{Boundaries: boundaries, NoMinMax: config.NoMinMax}
+	}
+
+	if config.Aggregation == "exponential" {
+		return sdkmetric.AggregationBase2ExponentialHistogram{
+			MaxSize:  config.MaxSize,
+			MaxScale: config.MaxScale,
+			NoMinMax: config.NoMinMax,
+		}
+	}
+
+	if config.Boundaries == nil && !config.NoMinMax {
+		return nil
+	}
+
+	// Start from the SDK default so only the configured parts change
+	explicit := sdkmetric.DefaultAggregationSelector(sdkmetric.InstrumentKindHistogram).(sdkmetric.AggregationExplicitBucketHistogram)
+	if config.Boundaries != nil {
+		explicit.Boundaries = config.Boundaries
+	}
+	explicit.NoMinMax = config.NoMinMax
+	return explicit
+}
@@ -0,0 +1,118 @@
+package prometheus
+
+import (
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/spf13/viper"
+)
+
+// ServeConfig controls the Prometheus scrape endpoint and the faults it injects into scrapes
+type ServeConfig struct {
+	Address       string        // Listen address, e.g. ":9464" ("" = push instead of serving)
+	Format        string        // prometheus or openmetrics ("" = negotiated from the Accept header)
+	Interval      time.Duration // How often every series gets its next value
+	Duration      time.Duration // How long to serve (0 = until interrupted)
+	SlowRate      float64       // Fraction of scrapes answered after SlowDelay
+	SlowDelay     time.Duration
+	TruncateRate  float64 // Fraction of scrapes whose body is cut off mid-way
+	DuplicateRate float64 // Fraction of scrapes repeating one series
+}
+
+// Supported exposition formats
+var formats = []string{"prometheus", "openmetrics"}
+
+// ParseServeConfig reads the scrape endpoint settings for the given component from viper
+func ParseServeConfig(component string) (*ServeConfig, error) {
+	prefix := "generate." + component + "."
+	config := &ServeConfig{
+		Address:       viper.GetString(prefix + "serve_prometheus"),
+		Format:        viper.GetString(prefix + "prometheus_format"),
+		Interval:      viper.GetDuration(prefix + "serve_interval"),
+		Duration:      viper.GetDuration(prefix + "serve_duration"),
+		SlowRate:      viper.GetFloat64(prefix + "scrape_slow_rate"),
+		SlowDelay:     viper.GetDuration(prefix + "scrape_slow_delay"),
+		TruncateRate:  viper.GetFloat64(prefix + "scrape_truncate_rate"),
+		DuplicateRate: viper.GetFloat64(prefix + "scrape_duplicate_rate"),
+	}
+	if config.Interval == 0 {
+		config.Interval = 10 * time.Second
+	}
+	if config.SlowDelay == 0 {
+		config.SlowDelay = 5 * time.Second
+	}
+	if err := config.Validate(); err != nil {
+		return nil, err
+	}
+	return config, nil
+}
+
+// Validate checks the format, durations and fault rates
+func (config *ServeConfig) Validate() error {
+	if config.Format != "" && !slices.Contains(formats, config.Format) {
+		return fmt.Errorf("unsupported prometheus format '%s' (supported: prometheus, openmetrics)", config.Format)
+	}
+	if config.Interval <= 0 {
+		return fmt.Errorf("serve interval must be positive, got %s", config.Interval)
+	}
+	if config.Duration < 0 {
+		return fmt.Errorf("serve duration must not be negative, got %s", config.Duration)
+	}
+	if config.SlowDelay < 0 {
+		return fmt.Errorf("scrape slow delay must not be negative, got %s", config.SlowDelay)
+	}
+	if config.SlowRate < 0 || config.SlowRate > 1 {
+		return fmt.Errorf("scrape slow rate must be between 0 and 1, got %g", config.SlowRate)
+	}
+	if config.TruncateRate < 0 || config.TruncateRate > 1 {
+		return fmt.Errorf("scrape truncate rate must be between 0 and 1, got %g", config.TruncateRate)
+	}
+	if config.DuplicateRate < 0 || config.DuplicateRate > 1 {
+		return fmt.Errorf("scrape duplicate rate must be between 0 and 1, got %g", config.DuplicateRate)
+	}
+	return nil
+}
+
+// Enabled reports whether metrics are served for scraping
+func (config *ServeConfig) Enabled() bool {
+	return config != nil && config.Address != ""
+}
+
+// RemoteWriteConfig controls sending metrics over Prometheus remote write
+type RemoteWriteConfig struct {
+	URL     string // Receiver URL, e.g. "http://localhost:9009/api/v1/push" ("" = no remote write)
+	Version string // Protocol version: 1 (snappy protobuf prometheus.WriteRequest) or 2 (io.prometheus.write.v2.Request)
+}
+
+// Supported remote-write protocol versions
+var remoteWriteVersions = []string{"1", "2"}
+
+// ParseRemoteWriteConfig reads the remote-write settings for the given component from viper
+func ParseRemoteWriteConfig(component string) (*RemoteWriteConfig, error) {
+	prefix := "generate." + component + "."
+	config := &RemoteWriteConfig{
+		URL:     viper.GetString(prefix + "remote_write_url"),
+		Version: viper.GetString(prefix + "remote_write_version"),
+	}
+	if config.Version == "" {
+		config.Version = "1"
+	}
+	if err := config.Validate(); err != nil {
+		return nil, err
+	}
+	return config, nil
+}
+
+// Validate checks the protocol version
+func (config *RemoteWriteConfig) Validate() error {
+	if !slices.Contains(remoteWriteVersions, config.Version) {
+		return fmt.Errorf("unsupported remote write version '%s' (supported: 1, 2)", config.Version)
+	}
+	return nil
+}
+
+// Enabled reports whether metrics are sent over remote write
+func (config *RemoteWriteConfig) Enabled() bool {
+	return config != nil && config.URL != ""
+}
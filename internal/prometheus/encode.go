@@ -0,0 +1,332 @@
+package prometheus
+
+import (
+	"bytes"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+)
+
+// Content types of the exposition formats
+const (
+	ContentTypePrometheus  = "text/plain; version=0.0.4; charset=utf-8"
+	ContentTypeOpenMetrics = "application/openmetrics-text; version=1.0.0; charset=utf-8"
+)
+
+// unitSuffixes maps UCUM units to the suffix Prometheus metric names carry, as the OTel Prometheus exporter does
+var unitSuffixes = map[string]string{
+	"s":   "seconds",
+	"ms":  "milliseconds",
+	"us":  "microseconds",
+	"ns":  "nanoseconds",
+	"min": "minutes",
+	"h":   "hours",
+	"By":  "bytes",
+	"KBy": "kilobytes",
+	"MBy": "megabytes",
+	"%":   "percent",
+	"1":   "ratio",
+	"Hz":  "hertz",
+	"Cel": "celsius",
+}
+
+// encoder writes collected metrics in the classic text format or in OpenMetrics
+// extra, when set, returns an additional label for a series, identified by its metric name and labels
+type encoder struct {
+	buf         bytes.Buffer
+	openMetrics bool
+	extra       func(series string) (string, string, bool)
+}
+
+// Encode renders the resource metrics as a scrape body
+// Cumulative sums become counters, other sums and gauges become gauges, and histograms become classic
+// histograms; exponential histograms are converted to classic buckets at their bucket boundaries.
+// Resource attributes are exposed as target_info and the instrumentation scope as otel_scope_* labels
+func Encode(rm *metricdata.ResourceMetrics, openMetrics bool, extra func(series string) (string, string, bool)) []byte {
+	e := &encoder{openMetrics: openMetrics, extra: extra}
+
+	if rm.Resource != nil && rm.Resource.Len() > 0 {
+		if openMetrics {
+			e.family("target", "info", "Target metadata", "")
+		} else {
+			e.family("target_info", "gauge", "Target metadata", "")
+		}
+		e.sample("target_info", labelsOf(*rm.Resource.Set()), "1")
+	}
+
+	for _, sm := range rm.ScopeMetrics {
+		var scope []label
+		if sm.Scope.Name != "" {
+			scope = append(scope, label{"otel_scope_name", sm.Scope.Name})
+		}
+		if sm.Scope.Version != "" {
+			scope = append(scope, label{"otel_scope_version", sm.Scope.Version})
+		}
+		for _, m := range sm.Metrics {
+			e.metric(m, scope)
+		}
+	}
+
+	if openMetrics {
+		e.buf.WriteString("# EOF\n")
+	}
+	return e.buf.Bytes()
+}
+
+// label is one name and value pair of a series
+type label struct {
+	name, value string
+}
+
+// metric writes one metric family
+func (e *encoder) metric(m metricdata.Metrics, scope []label) {
+	name := metricName(m.Name, m.Unit)
+	unit := unitSuffixes[m.Unit]
+
+	switch data := m.Data.(type) {
+	case metricdata.Sum[int64]:
+		writeSum(e, name, unit, m.Description, data, scope)
+	case metricdata.Sum[float64]:
+		writeSum(e, name, unit, m.Description, data, scope)
+	case metricdata.Gauge[int64]:
+		e.family(name, "gauge", m.Description, unit)
+		for _, dp := range data.DataPoints {
+			e.sample(name, e.seriesLabels(name, dp.Attributes, scope), formatValue(float64(dp.Value)))
+		}
+	case metricdata.Gauge[float64]:
+		e.family(name, "gauge", m.Description, unit)
+		for _, dp := range data.DataPoints {
+			e.sample(name, e.seriesLabels(name, dp.Attributes, scope), formatValue(dp.Value))
+		}
+	case metricdata.Histogram[int64]:
+		writeHistogram(e, name, unit, m.Description, data, scope)
+	case metricdata.Histogram[float64]:
+		writeHistogram(e, name, unit, m.Description, data, scope)
+	case metricdata.ExponentialHistogram[int64]:
+		writeExponentialHistogram(e, name, unit, m.Description, data, scope)
+	case metricdata.ExponentialHistogram[float64]:
+		writeExponentialHistogram(e, name, unit, m.Description, data, scope)
+	}
+}
+
+// writeSum writes a sum: monotonic cumulative sums as counters with the _total suffix, others as gauges
+func writeSum[N int64 | float64](e *encoder, name, unit, description string, data metricdata.Sum[N], scope []label) {
+	if !data.IsMonotonic || data.Temporality != metricdata.CumulativeTemporality {
+		e.family(name, "gauge", description, unit)
+		for _, dp := range data.DataPoints {
+			e.sample(name, e.seriesLabels(name, dp.Attributes, scope), formatValue(float64(dp.Value)))
+		}
+		return
+	}
+
+	// The classic format types the sample name, OpenMetrics the family name without _total
+	family := name + "_total"
+	if e.openMetrics {
+		family = name
+	}
+	e.family(family, "counter", description, unit)
+	for _, dp := range data.DataPoints {
+		e.sample(name+"_total", e.seriesLabels(name, dp.Attributes, scope), formatValue(float64(dp.Value)))
+	}
+}
+
+// writeHistogram writes explicit bucket histograms with cumulative buckets
+func writeHistogram[N int64 | float64](e *encoder, name, unit, description string, data metricdata.Histogram[N], scope []label) {
+	e.family(name, "histogram", description, unit)
+	for _, dp := range data.DataPoints {
+		e.histogram(name, e.seriesLabels(name, dp.Attributes, scope), dp.Bounds, CumulativeCounts(dp.BucketCounts), dp.Count, float64(dp.Sum))
+	}
+}
+
+// writeExponentialHistogram writes exponential histograms as classic histograms
+func writeExponentialHistogram[N int64 | float64](e *encoder, name, unit, description string, data metricdata.ExponentialHistogram[N], scope []label) {
+	e.family(name, "histogram", description, unit)
+	for _, dp := range data.DataPoints {
+		bounds, counts := ExponentialBuckets(dp)
+		e.histogram(name, e.seriesLabels(name, dp.Attributes, scope), bounds, counts, dp.Count, float64(dp.Sum))
+	}
+}
+
+// histogram writes the bucket, sum and count samples of one histogram series
+// counts are cumulative, one per bound; the +Inf bucket holds count
+func (e *encoder) histogram(name string, labels []label, bounds []float64, counts []uint64, count uint64, sum float64) {
+	for i, bound := range bounds {
+		e.sample(name+"_bucket", append(labels, label{"le", formatValue(bound)}), strconv.FormatUint(counts[i], 10))
+	}
+	e.sample(name+"_bucket", append(labels, label{"le", "+Inf"}), strconv.FormatUint(count, 10))
+	e.sample(name+"_sum", labels, formatValue(sum))
+	e.sample(name+"_count", labels, strconv.FormatUint(count, 10))
+}
+
+// CumulativeCounts returns the cumulative counts of explicit buckets, without the overflow bucket
+func CumulativeCounts(bucketCounts []uint64) []uint64 {
+	if len(bucketCounts) == 0 {
+		return nil
+	}
+	counts := make([]uint64, len(bucketCounts)-1)
+	var cumulative uint64
+	for i := range counts {
+		cumulative += bucketCounts[i]
+		counts[i] = cumulative
+	}
+	return counts
+}
+
+// ExponentialBuckets converts an exponential histogram point to classic bucket bounds and cumulative counts
+// Negative buckets come first, from the most negative, then the zero bucket, then positive buckets
+func ExponentialBuckets[N int64 | float64](dp metricdata.ExponentialHistogramDataPoint[N]) ([]float64, []uint64) {
+	base := math.Exp2(math.Exp2(-float64(dp.Scale)))
+	var bounds []float64
+	var counts []uint64
+
+	var cumulative uint64
+	negative := dp.NegativeBucket
+	for i := len(negative.Counts) - 1; i >= 0; i-- {
+		// Bucket i covers [-base^(index+1), -base^index)
+		cumulative += negative.Counts[i]
+		bounds = append(bounds, -math.Pow(base, float64(negative.Offset+int32(i))))
+		counts = append(counts, cumulative)
+	}
+	cumulative += dp.ZeroCount
+	bounds = append(bounds, 0)
+	counts = append(counts, cumulative)
+	positive := dp.PositiveBucket
+	for i, count := range positive.Counts {
+		// Bucket i covers (base^index, base^(index+1)]
+		cumulative += count
+		bounds = append(bounds, math.Pow(base, float64(positive.Offset+int32(i)+1)))
+		counts = append(counts, cumulative)
+	}
+	return bounds, counts
+}
+
+// family writes the metadata lines of a metric family
+func (e *encoder) family(name, kind, description, unit string) {
+	if description != "" {
+		e.buf.WriteString("# HELP " + name + " " + escapeHelp(description) + "\n")
+	}
+	e.buf.WriteString("# TYPE " + name + " " + kind + "\n")
+	if e.openMetrics && unit != "" {
+		e.buf.WriteString("# UNIT " + name + " " + unit + "\n")
+	}
+}
+
+// sample writes one sample line
+func (e *encoder) sample(name string, labels []label, value string) {
+	e.buf.WriteString(name)
+	if len(labels) > 0 {
+		e.buf.WriteByte('{')
+		for i, l := range labels {
+			if i > 0 {
+				e.buf.WriteByte(',')
+			}
+			e.buf.WriteString(l.name + `="` + escapeLabelValue(l.value) + `"`)
+		}
+		e.buf.WriteByte('}')
+	}
+	e.buf.WriteString(" " + value + "\n")
+}
+
+// seriesLabels returns the labels of a series: its attributes, the scope and any extra label
+func (e *encoder) seriesLabels(name string, attrs attribute.Set, scope []label) []label {
+	labels := append(labelsOf(attrs), scope...)
+	if e.extra != nil {
+		var series strings.Builder
+		series.WriteString(name)
+		for _, l := range labels {
+			series.WriteString("," + l.name + "=" + l.value)
+		}
+		if name, value, ok := e.extra(series.String()); ok {
+			labels = append(labels, label{name, value})
+		}
+	}
+	// Cap the capacity, so the le label appended to each bucket never writes into a shared array
+	return labels[:len(labels):len(labels)]
+}
+
+// labelsOf converts attributes to labels with sanitized names, merging attributes whose names collide
+func labelsOf(attrs attribute.Set) []label {
+	var labels []label
+	index := make(map[string]int)
+	for _, kv := range attrs.ToSlice() {
+		name := sanitizeLabelName(string(kv.Key))
+		value := kv.Value.Emit()
+		if i, ok := index[name]; ok {
+			labels[i].value += ";" + value
+			continue
+		}
+		index[name] = len(labels)
+		labels = append(labels, label{name, value})
+	}
+	sort.Slice(labels, func(i, j int) bool { return labels[i].name < labels[j].name })
+	return labels
+}
+
+// metricName converts an OTel metric name to a Prometheus metric name with its unit suffix
+func metricName(name, unit string) string {
+	var b strings.Builder
+	for i, r := range name {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r == '_', r == ':':
+			b.WriteRune(r)
+		case r >= '0' && r <= '9':
+			if i == 0 {
+				b.WriteByte('_')
+			}
+			b.WriteRune(r)
+		default:
+			b.WriteByte('_')
+		}
+	}
+	result := b.String()
+	if suffix, ok := unitSuffixes[unit]; ok && !strings.HasSuffix(result, "_"+suffix) {
+		result += "_" + suffix
+	}
+	return result
+}
+
+// sanitizeLabelName replaces characters not allowed in label names with underscores
+func sanitizeLabelName(name string) string {
+	var b strings.Builder
+	for i, r := range name {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r == '_':
+			b.WriteRune(r)
+		case r >= '0' && r <= '9':
+			if i == 0 {
+				b.WriteString("key_")
+			}
+			b.WriteRune(r)
+		default:
+			b.WriteByte('_')
+		}
+	}
+	return b.String()
+}
+
+// escapeLabelValue escapes backslashes, double quotes and newlines
+func escapeLabelValue(value string) string {
+	return strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(value)
+}
+
+// escapeHelp escapes backslashes and newlines
+func escapeHelp(help string) string {
+	return strings.NewReplacer(`\`, `\\`, "\n", `\n`).Replace(help)
+}
+
+// formatValue formats a sample value, spelling out infinities and NaN as the formats require
+func formatValue(value float64) string {
+	switch {
+	case math.IsInf(value, 1):
+		return "+Inf"
+	case math.IsInf(value, -1):
+		return "-Inf"
+	case math.IsNaN(value):
+		return "NaN"
+	}
+	return strconv.FormatFloat(value, 'g', -1, 64)
+}
@@ -0,0 +1,294 @@
+package prometheus
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/antithesishq/otel-datagen/internal/aggro"
+	"github.com/antithesishq/otel-datagen/internal/randomness"
+	"github.com/golang/snappy"
+	"go.opentelemetry.io/otel/attribute"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+)
+
+// staleNaN is the NaN Prometheus uses as a stale marker, ending a series
+var staleNaN = math.Float64frombits(0x7ff0000000000002)
+
+// Metric types of remote-write metadata, the same in both protocol versions
+const (
+	metadataCounter   = 1
+	metadataGauge     = 2
+	metadataHistogram = 3
+	metadataInfo      = 6
+)
+
+// RemoteWriteExporter sends collected metrics to a Prometheus remote-write receiver
+// It is a metric exporter, so every generation mode can send over remote write as it does over OTLP
+type RemoteWriteExporter struct {
+	config      *RemoteWriteConfig
+	aggroConfig *aggro.AggroConfig
+	client      *http.Client
+}
+
+// NewRemoteWriteExporter creates an exporter for the configuration; aggroConfig, when its remote-write aggro
+// is active, puts one protocol fault in every request
+func NewRemoteWriteExporter(config *RemoteWriteConfig, aggroConfig *aggro.AggroConfig) *RemoteWriteExporter {
+	return &RemoteWriteExporter{config: config, aggroConfig: aggroConfig, client: &http.Client{Timeout: 30 * time.Second}}
+}
+
+// Temporality returns cumulative temporality, the only one Prometheus understands
+func (e *RemoteWriteExporter) Temporality(sdkmetric.InstrumentKind) metricdata.Temporality {
+	return metricdata.CumulativeTemporality
+}
+
+// Aggregation returns the SDK default aggregation
+func (e *RemoteWriteExporter) Aggregation(kind sdkmetric.InstrumentKind) sdkmetric.Aggregation {
+	return sdkmetric.DefaultAggregationSelector(kind)
+}
+
+// Export sends the metrics in one remote-write request
+func (e *RemoteWriteExporter) Export(ctx context.Context, rm *metricdata.ResourceMetrics) error {
+	series := remoteWriteSeries(rm)
+	if len(series) == 0 {
+		return nil
+	}
+	if e.aggroConfig != nil {
+		if target, ok := e.aggroConfig.AggroRemoteWriteCase(); ok {
+			applyRemoteWriteAggro(series, target)
+		}
+	}
+
+	var body []byte
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, e.config.URL, nil)
+	if err != nil {
+		return err
+	}
+	if e.config.Version == "2" {
+		body = encodeWriteRequestV2(series)
+		req.Header.Set("Content-Type", "application/x-protobuf;proto=io.prometheus.write.v2.Request")
+		req.Header.Set("X-Prometheus-Remote-Write-Version", "2.0.0")
+	} else {
+		body = encodeWriteRequestV1(series)
+		req.Header.Set("Content-Type", "application/x-protobuf")
+		req.Header.Set("X-Prometheus-Remote-Write-Version", "0.1.0")
+	}
+	req.Header.Set("Content-Encoding", "snappy")
+	req.Header.Set("User-Agent", "otel-datagen")
+	compressed := snappy.Encode(nil, body)
+	req.Body = io.NopCloser(bytes.NewReader(compressed))
+	req.ContentLength = int64(len(compressed))
+
+	resp, err := e.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		message, _ := io.ReadAll(io.LimitReader(resp.Body, 256))
+		return fmt.Errorf("remote write to %s failed: %s: %s", e.config.URL, resp.Status, bytes.TrimSpace(message))
+	}
+	io.Copy(io.Discard, resp.Body)
+	return nil
+}
+
+// ForceFlush does nothing: every export is sent immediately
+func (e *RemoteWriteExporter) ForceFlush(context.Context) error {
+	return nil
+}
+
+// Shutdown closes idle connections
+func (e *RemoteWriteExporter) Shutdown(context.Context) error {
+	e.client.CloseIdleConnections()
+	return nil
+}
+
+// writeSeries is one remote-write time series with the metadata of its metric family
+type writeSeries struct {
+	labels  []label
+	samples []writeSample
+	created int64 // Milliseconds, 0 = unknown
+
+	family string
+	kind   int
+	help   string
+	unit   string
+}
+
+// writeSample is one sample of a time series
+type writeSample struct {
+	value     float64
+	timestamp int64 // Milliseconds
+}
+
+// remoteWriteSeries converts the resource metrics to time series, named as in the exposition formats
+// job and instance come from service.name and service.instance.id, and target_info carries the resource attributes
+func remoteWriteSeries(rm *metricdata.ResourceMetrics) []writeSeries {
+	c := &seriesConverter{}
+	if rm.Resource != nil {
+		c.resource = labelsOf(*rm.Resource.Set())
+		if name, ok := rm.Resource.Set().Value("service.name"); ok {
+			job := name.Emit()
+			if namespace, ok := rm.Resource.Set().Value("service.namespace"); ok {
+				job = namespace.Emit() + "/" + job
+			}
+			c.target = append(c.target, label{"job", job})
+		}
+		if instance, ok := rm.Resource.Set().Value("service.instance.id"); ok {
+			c.target = append(c.target, label{"instance", instance.Emit()})
+		}
+	}
+
+	for _, sm := range rm.ScopeMetrics {
+		c.scope = c.scope[:0]
+		if sm.Scope.Name != "" {
+			c.scope = append(c.scope, label{"otel_scope_name", sm.Scope.Name})
+		}
+		if sm.Scope.Version != "" {
+			c.scope = append(c.scope, label{"otel_scope_version", sm.Scope.Version})
+		}
+		for _, m := range sm.Metrics {
+			c.metric(m)
+		}
+	}
+
+	if len(c.resource) > 0 && c.latest > 0 {
+		labels := append(append([]label{}, c.resource...), c.target...)
+		c.add("target_info", metadataInfo, "Target metadata", "", "target_info", labels, 1, c.latest, 0)
+	}
+	return c.series
+}
+
+// seriesConverter collects the time series of one collection
+type seriesConverter struct {
+	resource []label // Resource attributes, for target_info
+	target   []label // job and instance, on every series
+	scope    []label
+	series   []writeSeries
+	latest   int64 // Latest sample timestamp, for target_info
+}
+
+// metric converts one metric
+func (c *seriesConverter) metric(m metricdata.Metrics) {
+	name := metricName(m.Name, m.Unit)
+	unit := unitSuffixes[m.Unit]
+
+	switch data := m.Data.(type) {
+	case metricdata.Sum[int64]:
+		convertSum(c, name, unit, m.Description, data)
+	case metricdata.Sum[float64]:
+		convertSum(c, name, unit, m.Description, data)
+	case metricdata.Gauge[int64]:
+		for _, dp := range data.DataPoints {
+			c.point(name, metadataGauge, m.Description, unit, name, dp.Attributes, float64(dp.Value), dp.Time, time.Time{})
+		}
+	case metricdata.Gauge[float64]:
+		for _, dp := range data.DataPoints {
+			c.point(name, metadataGauge, m.Description, unit, name, dp.Attributes, dp.Value, dp.Time, time.Time{})
+		}
+	case metricdata.Histogram[int64]:
+		for _, dp := range data.DataPoints {
+			c.histogram(name, m.Description, unit, dp.Attributes, dp.Bounds, CumulativeCounts(dp.BucketCounts), dp.Count, float64(dp.Sum), dp.Time, dp.StartTime)
+		}
+	case metricdata.Histogram[float64]:
+		for _, dp := range data.DataPoints {
+			c.histogram(name, m.Description, unit, dp.Attributes, dp.Bounds, CumulativeCounts(dp.BucketCounts), dp.Count, dp.Sum, dp.Time, dp.StartTime)
+		}
+	case metricdata.ExponentialHistogram[int64]:
+		for _, dp := range data.DataPoints {
+			bounds, counts := ExponentialBuckets(dp)
+			c.histogram(name, m.Description, unit, dp.Attributes, bounds, counts, dp.Count, float64(dp.Sum), dp.Time, dp.StartTime)
+		}
+	case metricdata.ExponentialHistogram[float64]:
+		for _, dp := range data.DataPoints {
+			bounds, counts := ExponentialBuckets(dp)
+			c.histogram(name, m.Description, unit, dp.Attributes, bounds, counts, dp.Count, dp.Sum, dp.Time, dp.StartTime)
+		}
+	}
+}
+
+// convertSum converts a sum: monotonic cumulative sums to counters with the _total suffix, others to gauges
+func convertSum[N int64 | float64](c *seriesConverter, name, unit, description string, data metricdata.Sum[N]) {
+	counter := data.IsMonotonic && data.Temporality == metricdata.CumulativeTemporality
+	for _, dp := range data.DataPoints {
+		if counter {
+			c.point(name, metadataCounter, description, unit, name+"_total", dp.Attributes, float64(dp.Value), dp.Time, dp.StartTime)
+		} else {
+			c.point(name, metadataGauge, description, unit, name, dp.Attributes, float64(dp.Value), dp.Time, time.Time{})
+		}
+	}
+}
+
+// histogram converts one histogram point to bucket, sum and count series
+func (c *seriesConverter) histogram(name, description, unit string, attrs attribute.Set, bounds []float64, counts []uint64, count uint64, sum float64, timestamp, start time.Time) {
+	for i, bound := range bounds {
+		c.point(name, metadataHistogram, description, unit, name+"_bucket", attrs, float64(counts[i]), timestamp, start, label{"le", formatValue(bound)})
+	}
+	c.point(name, metadataHistogram, description, unit, name+"_bucket", attrs, float64(count), timestamp, start, label{"le", "+Inf"})
+	c.point(name, metadataHistogram, description, unit, name+"_sum", attrs, sum, timestamp, start)
+	c.point(name, metadataHistogram, description, unit, name+"_count", attrs, float64(count), timestamp, start)
+}
+
+// point adds the series of one data point
+func (c *seriesConverter) point(family string, kind int, help, unit, name string, attrs attribute.Set, value float64, timestamp, start time.Time, extra ...label) {
+	labels := labelsOf(attrs)
+	labels = append(labels, c.scope...)
+	labels = append(labels, c.target...)
+	labels = append(labels, extra...)
+	var created int64
+	if !start.IsZero() {
+		created = start.UnixMilli()
+	}
+	c.add(family, kind, help, unit, name, labels, value, timestamp.UnixMilli(), created)
+}
+
+// add adds one series with a single sample, with __name__ and its labels sorted by name
+func (c *seriesConverter) add(family string, kind int, help, unit, name string, labels []label, value float64, timestamp int64, created int64) {
+	labels = append([]label{{"__name__", name}}, labels...)
+	sort.SliceStable(labels, func(i, j int) bool { return labels[i].name < labels[j].name })
+	c.series = append(c.series, writeSeries{
+		labels:  labels,
+		samples: []writeSample{{value: value, timestamp: timestamp}},
+		created: created,
+		family:  family,
+		kind:    kind,
+		help:    help,
+		unit:    unit,
+	})
+	if timestamp > c.latest {
+		c.latest = timestamp
+	}
+}
+
+// applyRemoteWriteAggro puts one protocol fault in a randomly chosen series, tagged with aggro_remote_write
+func applyRemoteWriteAggro(series []writeSeries, target string) {
+	s := &series[randomness.Intn(len(series))]
+	s.labels = append(s.labels, label{"aggro_remote_write", target})
+	sort.SliceStable(s.labels, func(i, j int) bool { return s.labels[i].name < s.labels[j].name })
+	last := s.samples[len(s.samples)-1]
+
+	switch target {
+	case "out-of-order":
+		// A sample older than the one before it
+		s.samples = append(s.samples, writeSample{value: last.value, timestamp: last.timestamp - 60000})
+	case "duplicate-timestamp":
+		// A second sample at the same time with another value
+		s.samples = append(s.samples, writeSample{value: last.value + 1, timestamp: last.timestamp})
+	case "unsorted-labels":
+		for i, j := 0, len(s.labels)-1; i < j; i, j = i+1, j-1 {
+			s.labels[i], s.labels[j] = s.labels[j], s.labels[i]
+		}
+	case "duplicate-labels":
+		// The same label name twice with different values
+		s.labels = append(s.labels, label{s.labels[len(s.labels)-1].name, "aggro"})
+	case "stale":
+		// A stale marker right after the sample, although the series goes on
+		s.samples = append(s.samples, writeSample{value: staleNaN, timestamp: last.timestamp + 1})
+	}
+}
@@ -0,0 +1,123 @@
+package prometheus
+
+import (
+	"math"
+
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+// encodeWriteRequestV1 encodes a prometheus.WriteRequest (remote write 1.0) with the metadata of every family
+//
+//	WriteRequest   { repeated TimeSeries timeseries = 1; repeated MetricMetadata metadata = 3; }
+//	TimeSeries     { repeated Label labels = 1; repeated Sample samples = 2; }
+//	Label          { string name = 1; string value = 2; }
+//	Sample         { double value = 1; int64 timestamp = 2; }
+//	MetricMetadata { MetricType type = 1; string metric_family_name = 2; string help = 4; string unit = 5; }
+func encodeWriteRequestV1(series []writeSeries) []byte {
+	var b []byte
+	for _, s := range series {
+		var ts []byte
+		for _, l := range s.labels {
+			var lb []byte
+			lb = appendString(lb, 1, l.name)
+			lb = appendString(lb, 2, l.value)
+			ts = appendMessage(ts, 1, lb)
+		}
+		for _, sample := range s.samples {
+			ts = appendMessage(ts, 2, encodeSample(sample))
+		}
+		b = appendMessage(b, 1, ts)
+	}
+
+	seen := make(map[string]bool)
+	for _, s := range series {
+		if seen[s.family] {
+			continue
+		}
+		seen[s.family] = true
+		var md []byte
+		md = protowire.AppendTag(md, 1, protowire.VarintType)
+		md = protowire.AppendVarint(md, uint64(s.kind))
+		md = appendString(md, 2, s.family)
+		md = appendString(md, 4, s.help)
+		md = appendString(md, 5, s.unit)
+		b = appendMessage(b, 3, md)
+	}
+	return b
+}
+
+// encodeWriteRequestV2 encodes an io.prometheus.write.v2.Request (remote write 2.0), with strings interned in symbols
+//
+//	Request    { repeated string symbols = 4; repeated TimeSeries timeseries = 5; }
+//	TimeSeries { repeated uint32 labels_refs = 1; repeated Sample samples = 2; Metadata metadata = 5; int64 created_timestamp = 6; }
+//	Sample     { double value = 1; int64 timestamp = 2; }
+//	Metadata   { MetricType type = 1; uint32 help_ref = 3; uint32 unit_ref = 4; }
+func encodeWriteRequestV2(series []writeSeries) []byte {
+	symbols := []string{""} // The empty string is always symbol 0
+	refs := map[string]uint64{"": 0}
+	ref := func(s string) uint64 {
+		r, ok := refs[s]
+		if !ok {
+			r = uint64(len(symbols))
+			refs[s] = r
+			symbols = append(symbols, s)
+		}
+		return r
+	}
+
+	var timeseries []byte
+	for _, s := range series {
+		var labelRefs []byte
+		for _, l := range s.labels {
+			labelRefs = protowire.AppendVarint(labelRefs, ref(l.name))
+			labelRefs = protowire.AppendVarint(labelRefs, ref(l.value))
+		}
+		var ts []byte
+		ts = appendMessage(ts, 1, labelRefs)
+		for _, sample := range s.samples {
+			ts = appendMessage(ts, 2, encodeSample(sample))
+		}
+
+		var md []byte
+		md = protowire.AppendTag(md, 1, protowire.VarintType)
+		md = protowire.AppendVarint(md, uint64(s.kind))
+		md = protowire.AppendTag(md, 3, protowire.VarintType)
+		md = protowire.AppendVarint(md, ref(s.help))
+		md = protowire.AppendTag(md, 4, protowire.VarintType)
+		md = protowire.AppendVarint(md, ref(s.unit))
+		ts = appendMessage(ts, 5, md)
+
+		if s.created != 0 {
+			ts = protowire.AppendTag(ts, 6, protowire.VarintType)
+			ts = protowire.AppendVarint(ts, uint64(s.created))
+		}
+		timeseries = appendMessage(timeseries, 5, ts)
+	}
+
+	var b []byte
+	for _, symbol := range symbols {
+		b = appendString(b, 4, symbol)
+	}
+	return append(b, timeseries...)
+}
+
+// encodeSample encodes a Sample message
+func encodeSample(sample writeSample) []byte {
+	var b []byte
+	b = protowire.AppendTag(b, 1, protowire.Fixed64Type)
+	b = protowire.AppendFixed64(b, math.Float64bits(sample.value))
+	b = protowire.AppendTag(b, 2, protowire.VarintType)
+	return protowire.AppendVarint(b, uint64(sample.timestamp))
+}
+
+// appendString appends a string field
+func appendString(b []byte, field protowire.Number, value string) []byte {
+	b = protowire.AppendTag(b, field, protowire.BytesType)
+	return protowire.AppendString(b, value)
+}
+
+// appendMessage appends an embedded message or packed field
+func appendMessage(b []byte, field protowire.Number, message []byte) []byte {
+	b = protowire.AppendTag(b, field, protowire.BytesType)
+	return protowire.AppendBytes(b, message)
+}
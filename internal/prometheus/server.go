@@ -0,0 +1,153 @@
+package prometheus
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/antithesishq/otel-datagen/internal/aggro"
+	"github.com/antithesishq/otel-datagen/internal/randomness"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+)
+
+// Server exposes the latest collection of generated metrics on /metrics
+// Each scrape renders the collection passed to the last Update call, with the configured faults
+type Server struct {
+	config      *ServeConfig
+	aggroConfig *aggro.AggroConfig
+
+	mu          sync.Mutex
+	latest      *metricdata.ResourceMetrics
+	aggroLabels map[string]label // Extra label of each series, kept stable across scrapes
+}
+
+// NewServer creates a server for the configuration; aggroConfig, when its label aggro is active,
+// adds labels with invalid names or unusual values to the series
+func NewServer(config *ServeConfig, aggroConfig *aggro.AggroConfig) *Server {
+	return &Server{config: config, aggroConfig: aggroConfig, aggroLabels: make(map[string]label)}
+}
+
+// Update replaces the collection served to scrapes
+func (s *Server) Update(rm *metricdata.ResourceMetrics) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.latest = rm
+}
+
+// ListenAndServe serves scrapes on the configured address until ctx is done
+func (s *Server) ListenAndServe(ctx context.Context) error {
+	listener, err := net.Listen("tcp", s.config.Address)
+	if err != nil {
+		return err
+	}
+	return s.Serve(ctx, listener)
+}
+
+// Serve serves scrapes on listener until ctx is done
+func (s *Server) Serve(ctx context.Context, listener net.Listener) error {
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", s)
+	server := &http.Server{Handler: mux}
+
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		server.Shutdown(shutdownCtx)
+	}()
+
+	if err := server.Serve(listener); !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
+}
+
+// ServeHTTP answers one scrape
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	openMetrics := s.config.Format == "openmetrics"
+	if s.config.Format == "" {
+		openMetrics = strings.Contains(r.Header.Get("Accept"), "application/openmetrics-text")
+	}
+
+	s.mu.Lock()
+	var body []byte
+	if s.latest != nil {
+		body = Encode(s.latest, openMetrics, s.aggroLabel)
+	} else if openMetrics {
+		body = []byte("# EOF\n")
+	}
+	s.mu.Unlock()
+
+	if randomness.Float64() < s.config.DuplicateRate {
+		body = duplicateSample(body)
+	}
+
+	if randomness.Float64() < s.config.SlowRate {
+		select {
+		case <-time.After(s.config.SlowDelay):
+		case <-r.Context().Done():
+			return
+		}
+	}
+
+	if openMetrics {
+		w.Header().Set("Content-Type", ContentTypeOpenMetrics)
+	} else {
+		w.Header().Set("Content-Type", ContentTypePrometheus)
+	}
+
+	if len(body) > 1 && randomness.Float64() < s.config.TruncateRate {
+		// Announce the full body, send part of it and drop the connection, as a crashing exporter would
+		w.Header().Set("Content-Length", strconv.Itoa(len(body)))
+		w.Write(body[:1+randomness.Intn(len(body)-1)])
+		if flusher, ok := w.(http.Flusher); ok {
+			flusher.Flush()
+		}
+		panic(http.ErrAbortHandler)
+	}
+	w.Write(body)
+}
+
+// aggroLabel returns the extra label of a series, choosing it on first sight
+func (s *Server) aggroLabel(series string) (string, string, bool) {
+	if s.aggroConfig == nil || !s.aggroConfig.LabelsActive {
+		return "", "", false
+	}
+	l, ok := s.aggroLabels[series]
+	if !ok {
+		name, value, _ := s.aggroConfig.AggroLabel()
+		l = label{name, value}
+		s.aggroLabels[series] = l
+	}
+	return l.name, l.value, l.name != ""
+}
+
+// duplicateSample repeats one randomly chosen sample line right after itself
+func duplicateSample(body []byte) []byte {
+	lines := bytes.SplitAfter(body, []byte("\n"))
+	var samples []int
+	for i, line := range lines {
+		if len(line) > 0 && line[0] != '#' {
+			samples = append(samples, i)
+		}
+	}
+	if len(samples) == 0 {
+		return body
+	}
+	i := samples[randomness.Intn(len(samples))]
+
+	var result bytes.Buffer
+	for j, line := range lines {
+		result.Write(line)
+		if j == i {
+			result.Write(line)
+		}
+	}
+	return result.Bytes()
+}
@@ -1,6 +1,10 @@
 package randomness
 
-import "github.com/antithesishq/antithesis-sdk-go/random"
+import (
+	"math"
+
+	"github.com/antithesishq/antithesis-sdk-go/random"
+)
 
 // Float64 converts Antithesis uint64 random to float64 in range [0, 1)
 func Float64() float64 {
@@ -18,4 +22,17 @@ func Intn(n int) int {
 // Choice returns a randomly chosen item from a list of options using Antithesis randomness
 func Choice[T any](items []T) T {
 	return random.RandomChoice(items)
-}
\ No newline at end of file
+}
+
+// Uint64 returns a raw 64-bit Antithesis random value
+func Uint64() uint64 {
+	return random.GetRandom()
+}
+
+// NormFloat64 returns a standard normal sample using the Box-Muller transform on Antithesis random
+func NormFloat64() float64 {
+	// Take 53 bits so u1 is exactly representable and in (0, 1], avoiding log(0)
+	u1 := float64(random.GetRandom()>>11+1) / float64(1<<53)
+	u2 := Float64()
+	return math.Sqrt(-2*math.Log(u1)) * math.Cos(2*math.Pi*u2)
+}
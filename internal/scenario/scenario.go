@@ -0,0 +1,358 @@
+package scenario
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/antithesishq/otel-datagen/internal/latency"
+	"github.com/antithesishq/otel-datagen/internal/severity"
+	"github.com/antithesishq/otel-datagen/internal/shapes"
+	"gopkg.in/yaml.v3"
+)
+
+// Scenario describes a synthetic application: its services, their operations and how they call each other
+type Scenario struct {
+	Name        string       `yaml:"name"`
+	Services    []Service    `yaml:"services"`
+	Entrypoints []Entrypoint `yaml:"entrypoints"` // Operations that receive external traffic (defaults to operations nobody calls)
+}
+
+// Service is one simulated service, exported with its own resource
+type Service struct {
+	Name       string            `yaml:"name"`
+	Resource   map[string]string `yaml:"resource"` // Extra resource attributes for this service
+	Operations []Operation       `yaml:"operations"`
+	Metrics    []Metric          `yaml:"metrics"` // Metrics recorded each time the service handles a request
+}
+
+// Operation is an endpoint or handler of a service
+type Operation struct {
+	Name       string            `yaml:"name"`
+	Kind       string            `yaml:"kind"`       // "server" (default), "consumer" or "internal"
+	Latency    string            `yaml:"latency"`    // Distribution of time spent in the operation itself, excluding downstream calls
+	ErrorRate  float64           `yaml:"error_rate"` // Fraction of invocations that fail on their own
+	Attributes map[string]string `yaml:"attributes"`
+	Logs       []LogLine         `yaml:"logs"`
+	Calls      []Call            `yaml:"calls"` // Downstream calls, made sequentially
+
+	latencyDist latency.Distribution
+}
+
+// Call is a downstream call from an operation to another service's operation
+type Call struct {
+	Service   string `yaml:"service"`
+	Operation string `yaml:"operation"`
+}
+
+// LogLine is a log record emitted by an operation
+type LogLine struct {
+	Body       string            `yaml:"body"`
+	Severity   string            `yaml:"severity"` // Level name or severity number (default info)
+	When       string            `yaml:"when"`     // "always" (default), "error" or "ok"
+	Attributes map[string]string `yaml:"attributes"`
+}
+
+// Metric is a metric exported by a service
+type Metric struct {
+	Name        string  `yaml:"name"`
+	Type        string  `yaml:"type"` // "counter", "updowncounter", "gauge" or "histogram"
+	Unit        string  `yaml:"unit"`
+	Description string  `yaml:"description"`
+	Min         float64 `yaml:"min"`
+	Max         float64 `yaml:"max"`
+	Shape       string  `yaml:"shape"` // Value shape such as "sine:period=60"; values are independent between min and max when empty
+}
+
+// Entrypoint weights external traffic to an operation
+type Entrypoint struct {
+	Service   string `yaml:"service"`
+	Operation string `yaml:"operation"`
+	Weight    int    `yaml:"weight"`
+}
+
+// Supported operation kinds and metric types
+var operationKinds = []string{"server", "consumer", "internal"}
+var metricTypes = []string{"counter", "updowncounter", "gauge", "histogram"}
+var logConditions = []string{"always", "error", "ok"}
+
+// defaultLatency is used for operations that don't declare one
+const defaultLatency = "10ms"
+
+// Load reads and validates a scenario file
+func Load(path string) (*Scenario, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read scenario file: %w", err)
+	}
+	return Parse(data)
+}
+
+// Parse parses and validates a YAML scenario
+func Parse(data []byte) (*Scenario, error) {
+	var s Scenario
+	if err := yaml.Unmarshal(data, &s); err != nil {
+		return nil, fmt.Errorf("invalid scenario: %w", err)
+	}
+	if err := s.Validate(); err != nil {
+		return nil, err
+	}
+	return &s, nil
+}
+
+// Validate checks references between services and operations, fills in defaults and rejects call cycles
+func (s *Scenario) Validate() error {
+	if len(s.Services) == 0 {
+		return fmt.Errorf("scenario must declare at least one service")
+	}
+
+	seen := make(map[string]bool)
+	for i := range s.Services {
+		svc := &s.Services[i]
+		if svc.Name == "" {
+			return fmt.Errorf("service %d has no name", i+1)
+		}
+		if seen[svc.Name] {
+			return fmt.Errorf("duplicate service '%s'", svc.Name)
+		}
+		seen[svc.Name] = true
+
+		if len(svc.Operations) == 0 {
+			return fmt.Errorf("service '%s' must declare at least one operation", svc.Name)
+		}
+		opSeen := make(map[string]bool)
+		for j := range svc.Operations {
+			if err := svc.Operations[j].validate(svc.Name, opSeen); err != nil {
+				return err
+			}
+		}
+
+		for j := range svc.Metrics {
+			m := &svc.Metrics[j]
+			if m.Name == "" {
+				return fmt.Errorf("service '%s' has a metric without a name", svc.Name)
+			}
+			if m.Type == "" {
+				m.Type = "gauge"
+			}
+			if !contains(metricTypes, m.Type) {
+				return fmt.Errorf("metric '%s' of service '%s' has unknown type '%s' (supported: %s)", m.Name, svc.Name, m.Type, strings.Join(metricTypes, ", "))
+			}
+			if m.Max < m.Min {
+				return fmt.Errorf("metric '%s' of service '%s' has max below min", m.Name, svc.Name)
+			}
+			if m.Type == "counter" && m.Min < 0 {
+				return fmt.Errorf("counter '%s' of service '%s' must have a non-negative min", m.Name, svc.Name)
+			}
+			if m.Shape != "" {
+				if _, err := shapes.Parse(m.Shape, m.Min, m.Max); err != nil {
+					return fmt.Errorf("metric '%s' of service '%s': %w", m.Name, svc.Name, err)
+				}
+			}
+		}
+	}
+
+	// Every downstream call must point at a declared operation
+	for _, svc := range s.Services {
+		for _, op := range svc.Operations {
+			for _, call := range op.Calls {
+				if s.Operation(call.Service, call.Operation) == nil {
+					return fmt.Errorf("operation '%s' of service '%s' calls unknown operation '%s' of service '%s'", op.Name, svc.Name, call.Operation, call.Service)
+				}
+			}
+		}
+	}
+
+	if err := s.checkCycles(); err != nil {
+		return err
+	}
+
+	if len(s.Entrypoints) == 0 {
+		s.Entrypoints = s.defaultEntrypoints()
+		if len(s.Entrypoints) == 0 {
+			return fmt.Errorf("scenario has no entrypoints and every operation is called by another")
+		}
+	}
+	for i := range s.Entrypoints {
+		ep := &s.Entrypoints[i]
+		if s.Operation(ep.Service, ep.Operation) == nil {
+			return fmt.Errorf("entrypoint refers to unknown operation '%s' of service '%s'", ep.Operation, ep.Service)
+		}
+		if ep.Weight < 0 {
+			return fmt.Errorf("entrypoint '%s/%s' has a negative weight", ep.Service, ep.Operation)
+		}
+		if ep.Weight == 0 {
+			ep.Weight = 1
+		}
+	}
+
+	return nil
+}
+
+// validate checks a single operation and fills in defaults
+func (op *Operation) validate(service string, seen map[string]bool) error {
+	if op.Name == "" {
+		return fmt.Errorf("service '%s' has an operation without a name", service)
+	}
+	if seen[op.Name] {
+		return fmt.Errorf("duplicate operation '%s' in service '%s'", op.Name, service)
+	}
+	seen[op.Name] = true
+
+	if op.Kind == "" {
+		op.Kind = "server"
+	}
+	if !contains(operationKinds, op.Kind) {
+		return fmt.Errorf("operation '%s' of service '%s' has unknown kind '%s' (supported: %s)", op.Name, service, op.Kind, strings.Join(operationKinds, ", "))
+	}
+
+	if op.Latency == "" {
+		op.Latency = defaultLatency
+	}
+	dist, err := latency.Parse(op.Latency)
+	if err != nil {
+		return fmt.Errorf("operation '%s' of service '%s' has invalid latency: %w", op.Name, service, err)
+	}
+	op.latencyDist = dist
+
+	if op.ErrorRate < 0 || op.ErrorRate > 1 {
+		return fmt.Errorf("operation '%s' of service '%s' has error_rate outside [0, 1]", op.Name, service)
+	}
+
+	for i := range op.Logs {
+		line := &op.Logs[i]
+		if line.Severity == "" {
+			line.Severity = "info"
+		}
+		if _, err := severity.ParseWeights(line.Severity); err != nil {
+			return fmt.Errorf("log line '%s' of operation '%s': %w", line.Body, op.Name, err)
+		}
+		if line.When == "" {
+			line.When = "always"
+		}
+		if !contains(logConditions, line.When) {
+			return fmt.Errorf("log line '%s' of operation '%s' has unknown condition '%s' (supported: %s)", line.Body, op.Name, line.When, strings.Join(logConditions, ", "))
+		}
+	}
+
+	return nil
+}
+
+// checkCycles rejects scenarios where an operation (indirectly) calls itself
+func (s *Scenario) checkCycles() error {
+	const (
+		visiting = 1
+		done     = 2
+	)
+	state := make(map[string]int)
+
+	var visit func(service, operation string) error
+	visit = func(service, operation string) error {
+		key := service + "/" + operation
+		switch state[key] {
+		case visiting:
+			return fmt.Errorf("call cycle detected at operation '%s' of service '%s'", operation, service)
+		case done:
+			return nil
+		}
+		state[key] = visiting
+		for _, call := range s.Operation(service, operation).Calls {
+			if err := visit(call.Service, call.Operation); err != nil {
+				return err
+			}
+		}
+		state[key] = done
+		return nil
+	}
+
+	for _, svc := range s.Services {
+		for _, op := range svc.Operations {
+			if err := visit(svc.Name, op.Name); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// defaultEntrypoints returns every operation that no other operation calls
+func (s *Scenario) defaultEntrypoints() []Entrypoint {
+	called := make(map[string]bool)
+	for _, svc := range s.Services {
+		for _, op := range svc.Operations {
+			for _, call := range op.Calls {
+				called[call.Service+"/"+call.Operation] = true
+			}
+		}
+	}
+
+	var entrypoints []Entrypoint
+	for _, svc := range s.Services {
+		for _, op := range svc.Operations {
+			if !called[svc.Name+"/"+op.Name] {
+				entrypoints = append(entrypoints, Entrypoint{Service: svc.Name, Operation: op.Name, Weight: 1})
+			}
+		}
+	}
+	return entrypoints
+}
+
+// Service returns the service with the given name, or nil
+func (s *Scenario) Service(name string) *Service {
+	for i := range s.Services {
+		if s.Services[i].Name == name {
+			return &s.Services[i]
+		}
+	}
+	return nil
+}
+
+// Operation returns the named operation of the named service, or nil
+func (s *Scenario) Operation(service, operation string) *Operation {
+	svc := s.Service(service)
+	if svc == nil {
+		return nil
+	}
+	for i := range svc.Operations {
+		if svc.Operations[i].Name == operation {
+			return &svc.Operations[i]
+		}
+	}
+	return nil
+}
+
+// ResourceAttributes returns the service's resource attributes as sorted key=value pairs,
+// with service.name set to the service's name
+func (svc *Service) ResourceAttributes() []string {
+	attrs := []string{"service.name=" + svc.Name}
+	return append(attrs, SortedPairs(svc.Resource)...)
+}
+
+// SortedKeys returns the keys of a map in order, for deterministic output
+func SortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// SortedPairs renders a map as key=value pairs in key order
+func SortedPairs(m map[string]string) []string {
+	var pairs []string
+	for _, key := range SortedKeys(m) {
+		pairs = append(pairs, key+"="+m[key])
+	}
+	return pairs
+}
+
+func contains(items []string, item string) bool {
+	for _, i := range items {
+		if i == item {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,96 @@
+package scenario
+
+import (
+	"time"
+
+	"github.com/antithesishq/otel-datagen/internal/randomness"
+)
+
+// Invocation is one simulated execution of an operation, with its downstream calls
+type Invocation struct {
+	Service   *Service
+	Operation *Operation
+	Start     time.Time // When the operation started handling the request
+	End       time.Time
+	Failed    bool // Set when the operation failed on its own or a downstream call failed
+	Origin    bool // Set when the failure originated in this operation rather than downstream
+
+	// CallStart/CallEnd are the caller's view of the call (zero for entrypoints)
+	// and include network time on both sides
+	CallStart time.Time
+	CallEnd   time.Time
+
+	Calls []*Invocation
+}
+
+// Request simulates one request arriving at a weighted-random entrypoint at the given time
+func (s *Scenario) Request(start time.Time) *Invocation {
+	ep := s.pickEntrypoint()
+	return s.invoke(s.Service(ep.Service), s.Operation(ep.Service, ep.Operation), start)
+}
+
+// pickEntrypoint makes a weighted selection from the scenario's entrypoints
+func (s *Scenario) pickEntrypoint() Entrypoint {
+	total := 0
+	for _, ep := range s.Entrypoints {
+		total += ep.Weight
+	}
+
+	n := randomness.Intn(total)
+	for _, ep := range s.Entrypoints {
+		if n < ep.Weight {
+			return ep
+		}
+		n -= ep.Weight
+	}
+	return s.Entrypoints[len(s.Entrypoints)-1]
+}
+
+// invoke simulates an operation starting at start
+// Half of the operation's own latency is spent before its downstream calls and half after,
+// so its duration is derived from its children's
+func (s *Scenario) invoke(svc *Service, op *Operation, start time.Time) *Invocation {
+	inv := &Invocation{
+		Service:   svc,
+		Operation: op,
+		Start:     start,
+	}
+
+	own := op.sampleLatency()
+	cursor := start.Add(own / 2)
+
+	for _, call := range op.Calls {
+		network := networkLatency()
+		child := s.invoke(s.Service(call.Service), s.Operation(call.Service, call.Operation), cursor.Add(network))
+		child.CallStart = cursor
+		child.CallEnd = child.End.Add(network)
+		cursor = child.CallEnd
+
+		inv.Calls = append(inv.Calls, child)
+		if child.Failed {
+			inv.Failed = true
+		}
+	}
+
+	inv.End = cursor.Add(own - own/2)
+
+	if op.ErrorRate > 0 && randomness.Float64() < op.ErrorRate {
+		inv.Failed = true
+		inv.Origin = true
+	}
+
+	return inv
+}
+
+// sampleLatency returns the operation's own latency
+func (op *Operation) sampleLatency() time.Duration {
+	if op.latencyDist == nil {
+		return 0
+	}
+	return op.latencyDist.Sample()
+}
+
+// networkLatency returns a one-way network delay of 0.2-2ms
+func networkLatency() time.Duration {
+	return time.Duration(200+randomness.Intn(1800)) * time.Microsecond
+}
@@ -0,0 +1,263 @@
+package selftelemetry
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/antithesishq/otel-datagen/internal/aggro"
+	"github.com/antithesishq/otel-datagen/internal/exporters"
+	"github.com/antithesishq/otel-datagen/internal/prometheus"
+	"github.com/spf13/viper"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+	"go.opentelemetry.io/otel/sdk/resource"
+	semconv "go.opentelemetry.io/otel/semconv/v1.21.0"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// Config controls where otel-datagen reports metrics about itself
+type Config struct {
+	Address  string        // Prometheus scrape address, e.g. ":9465" ("" = not served)
+	Endpoint string        // OTLP gRPC endpoint the metrics are pushed to ("" = not pushed)
+	Interval time.Duration // How often the metrics are pushed
+}
+
+// ParseConfig reads the self-telemetry settings from viper
+func ParseConfig() (*Config, error) {
+	config := &Config{
+		Address:  viper.GetString("self_telemetry.address"),
+		Endpoint: viper.GetString("self_telemetry.endpoint"),
+		Interval: viper.GetDuration("self_telemetry.interval"),
+	}
+	if config.Interval == 0 {
+		config.Interval = 10 * time.Second
+	}
+	if err := config.Validate(); err != nil {
+		return nil, err
+	}
+	return config, nil
+}
+
+// Validate checks the push interval
+func (config *Config) Validate() error {
+	if config.Interval <= 0 {
+		return fmt.Errorf("self-telemetry interval must be positive, got %s", config.Interval)
+	}
+	return nil
+}
+
+// Enabled reports whether the metrics are served or pushed anywhere
+func (config *Config) Enabled() bool {
+	return config != nil && (config.Address != "" || config.Endpoint != "")
+}
+
+// Telemetry reports generation and export metrics of the running process on its own meter provider,
+// apart from the generated data
+//
+// The SDK version in use has no metrics about its own processors and exporters, so they are built on
+// the export outcome accounting of the exporters package and the injection counts of the aggro package:
+//
+//	otel_datagen.items.generated    counter   generated spans, log records and data points, by signal
+//	otel_datagen.export.items       counter   exported, failed, retried and rejected items, by signal and outcome
+//	otel_datagen.export.duration    histogram duration of every export, by signal and error.type on failure
+//	otel_datagen.export.errors      counter   failed exports, by signal and error.type
+//	otel_datagen.queue.depth        gauge     spans and log records waiting in batch processors, by signal
+//	otel_datagen.aggro.injections   counter   injected aggro values, by category
+type Telemetry struct {
+	provider  *sdkmetric.MeterProvider
+	server    *http.Server
+	unobserve func()
+}
+
+// Start creates the meter provider, starts serving or pushing its metrics and begins observing exports
+// It returns nil when self-telemetry is not enabled; a nil Telemetry is safe to shut down
+func Start(ctx context.Context, config *Config) (*Telemetry, error) {
+	if !config.Enabled() {
+		return nil, nil
+	}
+
+	res, err := resource.New(ctx, resource.WithAttributes(semconv.ServiceName("otel-datagen")))
+	if err != nil {
+		return nil, err
+	}
+	options := []sdkmetric.Option{sdkmetric.WithResource(res)}
+
+	var reader *sdkmetric.ManualReader
+	var listener net.Listener
+	if config.Address != "" {
+		listener, err = net.Listen("tcp", config.Address)
+		if err != nil {
+			return nil, err
+		}
+		reader = sdkmetric.NewManualReader()
+		options = append(options, sdkmetric.WithReader(reader))
+	}
+	if config.Endpoint != "" {
+		exporterConfig := exporters.ExporterConfig{OTLPEndpoint: config.Endpoint, Protocol: "grpc"}
+		if err := exporters.ConfigureOTLPSecurity(&exporterConfig); err != nil {
+			closeListener(listener)
+			return nil, err
+		}
+		// The push endpoint is explicit, so OTEL_EXPORTER_OTLP_METRICS_* settings don't redirect it
+		exporterConfig.Signals = nil
+		metricExporters, err := exporters.CreateDualMetricExporters(ctx, exporterConfig, nil)
+		if err != nil {
+			closeListener(listener)
+			return nil, err
+		}
+		for _, exporter := range metricExporters {
+			options = append(options, sdkmetric.WithReader(sdkmetric.NewPeriodicReader(exporter, sdkmetric.WithInterval(config.Interval))))
+		}
+	}
+
+	t := &Telemetry{provider: sdkmetric.NewMeterProvider(options...)}
+	if err := t.register(t.provider.Meter("otel-datagen/selftelemetry")); err != nil {
+		closeListener(listener)
+		t.provider.Shutdown(ctx)
+		return nil, err
+	}
+
+	if listener != nil {
+		mux := http.NewServeMux()
+		mux.Handle("/metrics", scrapeHandler(reader))
+		t.server = &http.Server{Handler: mux}
+		go func() {
+			if err := t.server.Serve(listener); !errors.Is(err, http.ErrServerClosed) {
+				log.Printf("Error serving self-telemetry: %v", err)
+			}
+		}()
+		log.Printf("Serving self-telemetry on %s/metrics", config.Address)
+	}
+	return t, nil
+}
+
+// register creates the instruments and starts observing exports
+func (t *Telemetry) register(meter metric.Meter) error {
+	generated, err := meter.Int64ObservableCounter("otel_datagen.items.generated",
+		metric.WithDescription("Spans, log records and metric data points generated"), metric.WithUnit("{item}"))
+	if err != nil {
+		return err
+	}
+	exported, err := meter.Int64ObservableCounter("otel_datagen.export.items",
+		metric.WithDescription("Items by export outcome: exported, failed, retried or rejected"), metric.WithUnit("{item}"))
+	if err != nil {
+		return err
+	}
+	depth, err := meter.Int64ObservableGauge("otel_datagen.queue.depth",
+		metric.WithDescription("Spans and log records handed to batch processors and not yet exported; dropped items stay in it"), metric.WithUnit("{item}"))
+	if err != nil {
+		return err
+	}
+	injections, err := meter.Int64ObservableCounter("otel_datagen.aggro.injections",
+		metric.WithDescription("Aggro values injected into the generated data"), metric.WithUnit("{injection}"))
+	if err != nil {
+		return err
+	}
+	duration, err := meter.Float64Histogram("otel_datagen.export.duration",
+		metric.WithDescription("Duration of exports, including retries"), metric.WithUnit("s"))
+	if err != nil {
+		return err
+	}
+	failures, err := meter.Int64Counter("otel_datagen.export.errors",
+		metric.WithDescription("Exports that returned an error"), metric.WithUnit("{error}"))
+	if err != nil {
+		return err
+	}
+
+	_, err = meter.RegisterCallback(func(_ context.Context, o metric.Observer) error {
+		for signal, count := range exporters.Generated() {
+			o.ObserveInt64(generated, count, metric.WithAttributes(attribute.String("signal", signal)))
+		}
+		for signal, counts := range exporters.Outcomes().Signals {
+			for outcome, count := range map[string]int64{"exported": counts.Exported, "failed": counts.Failed, "retried": counts.Retried, "rejected": counts.Rejected} {
+				o.ObserveInt64(exported, count, metric.WithAttributes(attribute.String("signal", signal), attribute.String("outcome", outcome)))
+			}
+		}
+		for signal, count := range exporters.Pending() {
+			o.ObserveInt64(depth, count, metric.WithAttributes(attribute.String("signal", signal)))
+		}
+		for category, count := range aggro.Injections() {
+			o.ObserveInt64(injections, count, metric.WithAttributes(attribute.String("category", category)))
+		}
+		return nil
+	}, generated, exported, depth, injections)
+	if err != nil {
+		return err
+	}
+
+	t.unobserve = exporters.ObserveExports(func(signal string, _ int64, elapsed time.Duration, err error) {
+		attrs := []attribute.KeyValue{attribute.String("signal", signal)}
+		if err != nil {
+			attrs = append(attrs, attribute.String("error.type", ErrorType(err)))
+			failures.Add(context.Background(), 1, metric.WithAttributes(attrs...))
+		}
+		duration.Record(context.Background(), elapsed.Seconds(), metric.WithAttributes(attrs...))
+	})
+	return nil
+}
+
+// Shutdown stops observing exports, pushes the final values and stops serving
+func (t *Telemetry) Shutdown(ctx context.Context) error {
+	if t == nil {
+		return nil
+	}
+	t.unobserve()
+	err := t.provider.Shutdown(ctx)
+	if t.server != nil {
+		if shutdownErr := t.server.Shutdown(ctx); err == nil {
+			err = shutdownErr
+		}
+	}
+	return err
+}
+
+// ErrorType classifies an export error: timeout, the gRPC status code of OTLP errors, network for
+// connection failures, or other
+func ErrorType(err error) string {
+	var netErr net.Error
+	switch {
+	case errors.Is(err, context.DeadlineExceeded):
+		return "timeout"
+	case status.Code(err) == codes.DeadlineExceeded:
+		return "timeout"
+	case status.Code(err) != codes.Unknown:
+		return status.Code(err).String()
+	case errors.As(err, &netErr):
+		return "network"
+	}
+	return "other"
+}
+
+// scrapeHandler collects the reader on every scrape and renders the result
+func scrapeHandler(reader *sdkmetric.ManualReader) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		rm := &metricdata.ResourceMetrics{}
+		if err := reader.Collect(r.Context(), rm); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		openMetrics := strings.Contains(r.Header.Get("Accept"), "application/openmetrics-text")
+		if openMetrics {
+			w.Header().Set("Content-Type", prometheus.ContentTypeOpenMetrics)
+		} else {
+			w.Header().Set("Content-Type", prometheus.ContentTypePrometheus)
+		}
+		w.Write(prometheus.Encode(rm, openMetrics, nil))
+	})
+}
+
+// closeListener closes the scrape listener of a failed start
+func closeListener(listener net.Listener) {
+	if listener != nil {
+		listener.Close()
+	}
+}
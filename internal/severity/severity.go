@@ -0,0 +1,239 @@
+package severity
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/antithesishq/otel-datagen/internal/randomness"
+	"github.com/spf13/viper"
+	otellog "go.opentelemetry.io/otel/log"
+)
+
+// Weight pairs a severity number with its relative weight in the mix
+type Weight struct {
+	Severity otellog.Severity
+	Weight   int
+}
+
+// SeverityConfig holds configuration for log severity, severity text and event names
+type SeverityConfig struct {
+	Weights    []Weight
+	TextMode   string // "standard", "lower", "mismatch", "freeform" or "none"
+	EventNames []string
+	EventRatio float64 // Fraction of records that carry an event name
+}
+
+// Choice is the severity information selected for a single log record
+type Choice struct {
+	Severity  otellog.Severity
+	Text      string
+	Level     string // Normalized level name used for the log.level attribute
+	EventName string
+}
+
+// Supported severity text modes
+var textModes = []string{"standard", "lower", "mismatch", "freeform", "none"}
+
+// levelNames lists the base level names in ascending severity order
+var levelNames = []string{"trace", "debug", "info", "warn", "error", "fatal"}
+
+// severityNames maps level names accepted in weight specs to their base severity
+var severityNames = map[string]otellog.Severity{
+	"trace": otellog.SeverityTrace,
+	"debug": otellog.SeverityDebug,
+	"info":  otellog.SeverityInfo,
+	"warn":  otellog.SeverityWarn,
+	"error": otellog.SeverityError,
+	"fatal": otellog.SeverityFatal,
+}
+
+// freeformTexts are severity texts seen in the wild that don't follow the OTel naming
+var freeformTexts = map[string][]string{
+	"trace": {"TRACE", "Trace", "finest", "FINER", "verbose", "T"},
+	"debug": {"DEBUG", "Debug", "dbg", "FINE", "D", "debug1"},
+	"info":  {"INFO", "Information", "informational", "notice", "I", "ok"},
+	"warn":  {"WARN", "Warning", "WARNING", "wrn", "W", "alert"},
+	"error": {"ERROR", "Error", "ERR", "SEVERE", "E", "failure"},
+	"fatal": {"FATAL", "Fatal", "CRITICAL", "crit", "emerg", "PANIC"},
+}
+
+// DefaultSeverityConfig returns the configuration matching the historical behavior (all info)
+func DefaultSeverityConfig() *SeverityConfig {
+	return &SeverityConfig{
+		Weights:  []Weight{{Severity: otellog.SeverityInfo, Weight: 1}},
+		TextMode: "standard",
+	}
+}
+
+// ParseSeverityConfig reads the severity settings for the given component from viper
+func ParseSeverityConfig(component string) (*SeverityConfig, error) {
+	config := DefaultSeverityConfig()
+
+	if spec := viper.GetString("generate." + component + ".severity"); spec != "" {
+		weights, err := ParseWeights(spec)
+		if err != nil {
+			return nil, err
+		}
+		config.Weights = weights
+	}
+
+	if mode := viper.GetString("generate." + component + ".severity_text"); mode != "" {
+		if !isTextMode(mode) {
+			return nil, fmt.Errorf("invalid severity-text mode '%s' (supported: %s)", mode, strings.Join(textModes, ", "))
+		}
+		config.TextMode = mode
+	}
+
+	config.EventNames = viper.GetStringSlice("generate." + component + ".event_name")
+	config.EventRatio = 1.0
+	if viper.IsSet("generate." + component + ".event_ratio") {
+		config.EventRatio = viper.GetFloat64("generate." + component + ".event_ratio")
+	}
+
+	return config, nil
+}
+
+// ParseWeights parses a weighted severity mix such as "info=70,warn=20,error=10"
+// Levels may be given by name (trace, debug, info, warn, error, fatal) or by severity number
+func ParseWeights(spec string) ([]Weight, error) {
+	var weights []Weight
+	total := 0
+
+	for _, part := range strings.Split(spec, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+
+		name, weightStr, hasWeight := strings.Cut(part, "=")
+		weight := 1
+		if hasWeight {
+			w, err := strconv.Atoi(strings.TrimSpace(weightStr))
+			if err != nil || w < 0 {
+				return nil, fmt.Errorf("invalid weight in severity '%s'", part)
+			}
+			weight = w
+		}
+
+		sev, err := parseSeverity(strings.TrimSpace(name))
+		if err != nil {
+			return nil, err
+		}
+
+		weights = append(weights, Weight{Severity: sev, Weight: weight})
+		total += weight
+	}
+
+	if total == 0 {
+		return nil, fmt.Errorf("severity '%s' must contain at least one level with a positive weight", spec)
+	}
+
+	return weights, nil
+}
+
+// parseSeverity resolves a level name or severity number
+func parseSeverity(name string) (otellog.Severity, error) {
+	if sev, ok := severityNames[strings.ToLower(name)]; ok {
+		return sev, nil
+	}
+	if n, err := strconv.Atoi(name); err == nil {
+		return otellog.Severity(n), nil
+	}
+	return 0, fmt.Errorf("unknown severity level '%s' (supported: trace, debug, info, warn, error, fatal or a severity number)", name)
+}
+
+// Pick selects a severity, severity text and optional event name for one log record
+func (config *SeverityConfig) Pick() Choice {
+	sev := config.pickSeverity()
+	level := LevelName(sev)
+
+	choice := Choice{
+		Severity: sev,
+		Level:    level,
+	}
+
+	switch config.TextMode {
+	case "lower":
+		choice.Text = level
+	case "mismatch":
+		choice.Text = strings.ToUpper(mismatchedLevel(level))
+	case "freeform":
+		if texts, ok := freeformTexts[level]; ok {
+			choice.Text = randomness.Choice(texts)
+		} else {
+			choice.Text = strings.ToUpper(level)
+		}
+	case "none":
+		// Leave SeverityText unset
+	default:
+		choice.Text = sev.String()
+	}
+
+	if len(config.EventNames) > 0 && randomness.Float64() < config.EventRatio {
+		choice.EventName = randomness.Choice(config.EventNames)
+	}
+
+	return choice
+}
+
+// pickSeverity makes a weighted selection from the configured severities
+func (config *SeverityConfig) pickSeverity() otellog.Severity {
+	total := 0
+	for _, w := range config.Weights {
+		total += w.Weight
+	}
+	if total == 0 {
+		return otellog.SeverityInfo
+	}
+
+	n := randomness.Intn(total)
+	for _, w := range config.Weights {
+		if n < w.Weight {
+			return w.Severity
+		}
+		n -= w.Weight
+	}
+	return config.Weights[len(config.Weights)-1].Severity
+}
+
+// LevelName returns the lowercase base level name for a severity number
+// Numbers outside the OTel range are reported as "undefined"
+func LevelName(sev otellog.Severity) string {
+	switch {
+	case sev >= otellog.SeverityTrace1 && sev <= otellog.SeverityTrace4:
+		return "trace"
+	case sev >= otellog.SeverityDebug1 && sev <= otellog.SeverityDebug4:
+		return "debug"
+	case sev >= otellog.SeverityInfo1 && sev <= otellog.SeverityInfo4:
+		return "info"
+	case sev >= otellog.SeverityWarn1 && sev <= otellog.SeverityWarn4:
+		return "warn"
+	case sev >= otellog.SeverityError1 && sev <= otellog.SeverityError4:
+		return "error"
+	case sev >= otellog.SeverityFatal1 && sev <= otellog.SeverityFatal4:
+		return "fatal"
+	default:
+		return "undefined"
+	}
+}
+
+// mismatchedLevel returns a level name different from the given one
+func mismatchedLevel(level string) string {
+	var others []string
+	for _, name := range levelNames {
+		if name != level {
+			others = append(others, name)
+		}
+	}
+	return randomness.Choice(others)
+}
+
+func isTextMode(mode string) bool {
+	for _, m := range textModes {
+		if m == mode {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,346 @@
+package shapes
+
+import (
+	"fmt"
+	"math"
+	"strings"
+
+	"github.com/antithesishq/otel-datagen/internal/randomness"
+	"github.com/antithesishq/otel-datagen/internal/spec"
+)
+
+// Shape produces the successive values of a metric series, one per data point
+// For counters and up/down counters the value is the amount added per data point,
+// except for cumulative shapes (monotonic), whose value is the running total
+type Shape interface {
+	Next() float64
+}
+
+// Supported shape kinds
+var kinds = []string{"uniform", "random-walk", "sine", "sawtooth", "step", "monotonic"}
+
+// Uniform returns the historical value generator: independent whole numbers in [min, max]
+func Uniform(min, max int) Shape {
+	return &uniform{min: float64(min), max: float64(max), whole: true}
+}
+
+// Parse parses a value shape spec such as "sine:period=60,amplitude=20"
+// min and max are the defaults for the shape's range, normally --counter-min and --counter-max
+// Every shape also accepts spike=<probability>,spike_factor=<multiplier> and dropout=<probability>
+func Parse(s string, min, max float64) (Shape, error) {
+	sp, err := spec.Parse(s)
+	if err != nil {
+		return nil, err
+	}
+
+	lo, err := sp.Float("min", min)
+	if err != nil {
+		return nil, err
+	}
+	hi, err := sp.Float("max", max)
+	if err != nil {
+		return nil, err
+	}
+	if hi < lo {
+		return nil, fmt.Errorf("%s shape requires min <= max", sp.Kind)
+	}
+
+	var shape Shape
+	switch sp.Kind {
+	case "uniform":
+		shape = &uniform{min: lo, max: hi}
+	case "random-walk":
+		shape, err = parseRandomWalk(sp, lo, hi)
+	case "sine":
+		shape, err = parseSine(sp, lo, hi)
+	case "sawtooth":
+		shape, err = parseSawtooth(sp, lo, hi)
+	case "step":
+		shape, err = parseStep(sp, lo, hi)
+	case "monotonic":
+		shape, err = parseMonotonic(sp)
+	default:
+		return nil, fmt.Errorf("unknown value shape '%s' (supported: %s)", sp.Kind, strings.Join(kinds, ", "))
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	shape, err = parseAnomalies(sp, shape)
+	if err != nil {
+		return nil, err
+	}
+	if err := sp.CheckUnused(); err != nil {
+		return nil, err
+	}
+	return shape, nil
+}
+
+// Increments returns a function producing the amount to add to a sum for each data point
+// Cumulative shapes are converted to the difference from the previous value
+func Increments(shape Shape) func() float64 {
+	c, ok := shape.(*monotonic)
+	if !ok {
+		return shape.Next
+	}
+	previous := 0.0
+	return func() float64 {
+		value := c.Next()
+		delta := value - previous
+		previous = value
+		return delta
+	}
+}
+
+// CounterIncrements returns the increments of a monotonic counter, which can't decrease
+// Shapes that fall, such as sine or random-walk, add nothing for those data points rather than a negative amount
+func CounterIncrements(shape Shape) func() float64 {
+	next := Increments(shape)
+	return func() float64 {
+		return math.Max(0, next())
+	}
+}
+
+// Independent reports whether the shape's values are independent samples with no series to preserve
+func Independent(shape Shape) bool {
+	_, ok := shape.(*uniform)
+	return ok
+}
+
+// uniform returns independent samples in [min, max], whole numbers when whole is set
+type uniform struct {
+	min   float64
+	max   float64
+	whole bool
+}
+
+func (s *uniform) Next() float64 {
+	if s.whole {
+		return float64(randomness.Intn(int(s.max-s.min)+1)) + s.min
+	}
+	return s.min + randomness.Float64()*(s.max-s.min)
+}
+
+// randomWalk moves by a normally distributed step plus drift each data point, reflecting at the range bounds
+type randomWalk struct {
+	value float64
+	step  float64
+	drift float64
+	min   float64
+	max   float64
+}
+
+func (s *randomWalk) Next() float64 {
+	current := s.value
+	s.value += s.drift + s.step*randomness.NormFloat64()
+	// Reflect at the bounds so the walk does not stick to them
+	if s.value > s.max {
+		s.value = math.Max(s.min, 2*s.max-s.value)
+	}
+	if s.value < s.min {
+		s.value = math.Min(s.max, 2*s.min-s.value)
+	}
+	return current
+}
+
+func parseRandomWalk(sp *spec.Spec, lo, hi float64) (Shape, error) {
+	start, err := sp.Float("start", (lo+hi)/2)
+	if err != nil {
+		return nil, err
+	}
+	step, err := sp.Float("step", (hi-lo)/20)
+	if err != nil {
+		return nil, err
+	}
+	drift, err := sp.Float("drift", 0)
+	if err != nil {
+		return nil, err
+	}
+	if step < 0 || start < lo || start > hi {
+		return nil, fmt.Errorf("random-walk shape requires a non-negative step and a start within [min, max]")
+	}
+	return &randomWalk{value: start, step: step, drift: drift, min: lo, max: hi}, nil
+}
+
+// sine oscillates around an offset with the given amplitude and a period counted in data points
+type sine struct {
+	i         int
+	period    float64
+	amplitude float64
+	offset    float64
+	phase     float64
+	noise     float64
+}
+
+func (s *sine) Next() float64 {
+	angle := 2*math.Pi*float64(s.i)/s.period + s.phase
+	s.i++
+	return s.offset + s.amplitude*math.Sin(angle) + s.noise*randomness.NormFloat64()
+}
+
+func parseSine(sp *spec.Spec, lo, hi float64) (Shape, error) {
+	period, err := sp.Float("period", 60)
+	if err != nil {
+		return nil, err
+	}
+	amplitude, err := sp.Float("amplitude", (hi-lo)/2)
+	if err != nil {
+		return nil, err
+	}
+	offset, err := sp.Float("offset", (lo+hi)/2)
+	if err != nil {
+		return nil, err
+	}
+	phase, err := sp.Float("phase", 0)
+	if err != nil {
+		return nil, err
+	}
+	noise, err := sp.Float("noise", 0)
+	if err != nil {
+		return nil, err
+	}
+	if period <= 0 || amplitude < 0 || noise < 0 {
+		return nil, fmt.Errorf("sine shape requires a positive period and non-negative amplitude and noise")
+	}
+	return &sine{period: period, amplitude: amplitude, offset: offset, phase: phase, noise: noise}, nil
+}
+
+// sawtooth ramps linearly from min to max over a period counted in data points, then drops back
+type sawtooth struct {
+	i      int
+	period int
+	min    float64
+	max    float64
+}
+
+func (s *sawtooth) Next() float64 {
+	position := s.i % s.period
+	s.i++
+	if s.period == 1 {
+		return s.min
+	}
+	return s.min + (s.max-s.min)*float64(position)/float64(s.period-1)
+}
+
+func parseSawtooth(sp *spec.Spec, lo, hi float64) (Shape, error) {
+	period, err := sp.Float("period", 60)
+	if err != nil {
+		return nil, err
+	}
+	if period < 1 {
+		return nil, fmt.Errorf("sawtooth shape requires a period of at least 1 data point")
+	}
+	return &sawtooth{period: int(period), min: lo, max: hi}, nil
+}
+
+// step holds a level for a number of data points, then jumps to a new random level in [min, max]
+type step struct {
+	i     int
+	every int
+	level float64
+	min   float64
+	max   float64
+	noise float64
+}
+
+func (s *step) Next() float64 {
+	if s.i%s.every == 0 {
+		s.level = s.min + randomness.Float64()*(s.max-s.min)
+	}
+	s.i++
+	return s.level + s.noise*randomness.NormFloat64()
+}
+
+func parseStep(sp *spec.Spec, lo, hi float64) (Shape, error) {
+	every, err := sp.Float("every", 20)
+	if err != nil {
+		return nil, err
+	}
+	noise, err := sp.Float("noise", 0)
+	if err != nil {
+		return nil, err
+	}
+	if every < 1 || noise < 0 {
+		return nil, fmt.Errorf("step shape requires every >= 1 and non-negative noise")
+	}
+	return &step{every: int(every), min: lo, max: hi, noise: noise}, nil
+}
+
+// monotonic is a running total that grows by rate per data point, varied by jitter
+// It is cumulative: sums record the difference between successive values
+type monotonic struct {
+	total  float64
+	rate   float64
+	jitter float64
+}
+
+func (s *monotonic) Next() float64 {
+	s.total += s.rate * (1 + s.jitter*(2*randomness.Float64()-1))
+	return s.total
+}
+
+func parseMonotonic(sp *spec.Spec) (Shape, error) {
+	start, err := sp.Float("start", 0)
+	if err != nil {
+		return nil, err
+	}
+	rate, err := sp.Float("rate", 1)
+	if err != nil {
+		return nil, err
+	}
+	jitter, err := sp.Float("jitter", 0)
+	if err != nil {
+		return nil, err
+	}
+	if rate < 0 || jitter < 0 || jitter > 1 {
+		return nil, fmt.Errorf("monotonic shape requires a non-negative rate and jitter in [0, 1]")
+	}
+	return &monotonic{total: start, rate: rate, jitter: jitter}, nil
+}
+
+// anomalies occasionally multiplies a value (spike) or drops it to zero (dropout)
+type anomalies struct {
+	Shape
+	spike       float64
+	spikeFactor float64
+	dropout     float64
+}
+
+func (s *anomalies) Next() float64 {
+	value := s.Shape.Next()
+	switch r := randomness.Float64(); {
+	case r < s.spike:
+		return value * s.spikeFactor
+	case r < s.spike+s.dropout:
+		return 0
+	default:
+		return value
+	}
+}
+
+// parseAnomalies wraps the shape when spikes or dropouts are configured
+// Cumulative shapes are left unwrapped: a spike or dropout there would make a counter go backwards
+func parseAnomalies(sp *spec.Spec, shape Shape) (Shape, error) {
+	spike, err := sp.Float("spike", 0)
+	if err != nil {
+		return nil, err
+	}
+	spikeFactor, err := sp.Float("spike_factor", 5)
+	if err != nil {
+		return nil, err
+	}
+	dropout, err := sp.Float("dropout", 0)
+	if err != nil {
+		return nil, err
+	}
+	if spike < 0 || dropout < 0 || spike+dropout > 1 {
+		return nil, fmt.Errorf("spike and dropout probabilities must be non-negative and sum to at most 1")
+	}
+	if spike == 0 && dropout == 0 {
+		return shape, nil
+	}
+	if _, ok := shape.(*monotonic); ok {
+		return nil, fmt.Errorf("monotonic shape does not support spikes or dropouts")
+	}
+	return &anomalies{Shape: shape, spike: spike, spikeFactor: spikeFactor, dropout: dropout}, nil
+}
@@ -0,0 +1,103 @@
+package spec
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Spec is a parsed "kind:key=value,key=value" string, as used by latency distributions and value shapes
+type Spec struct {
+	Kind   string
+	params map[string]string
+	used   map[string]bool
+}
+
+// Parse splits a spec such as "lognormal:median=50ms,sigma=0.5" into its kind and parameters
+func Parse(s string) (*Spec, error) {
+	kind, rest, _ := strings.Cut(strings.TrimSpace(s), ":")
+	spec := &Spec{
+		Kind:   strings.ToLower(strings.TrimSpace(kind)),
+		params: make(map[string]string),
+		used:   make(map[string]bool),
+	}
+	if spec.Kind == "" {
+		return nil, fmt.Errorf("empty spec '%s'", s)
+	}
+
+	for _, part := range strings.Split(rest, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		key, value, ok := strings.Cut(part, "=")
+		if !ok {
+			return nil, fmt.Errorf("invalid parameter '%s' in '%s' (expected key=value)", part, s)
+		}
+		spec.params[strings.TrimSpace(key)] = strings.TrimSpace(value)
+	}
+
+	return spec, nil
+}
+
+// Has reports whether the parameter was given
+func (s *Spec) Has(key string) bool {
+	_, ok := s.params[key]
+	return ok
+}
+
+// String returns a string parameter, or def when it is not given
+func (s *Spec) String(key string, def string) string {
+	s.used[key] = true
+	if value, ok := s.params[key]; ok {
+		return value
+	}
+	return def
+}
+
+// Float returns a float parameter, or def when it is not given
+// NaN and infinities are rejected, as range checks such as sigma < 0 let them through
+func (s *Spec) Float(key string, def float64) (float64, error) {
+	s.used[key] = true
+	value, ok := s.params[key]
+	if !ok {
+		return def, nil
+	}
+	f, err := strconv.ParseFloat(value, 64)
+	if err != nil || math.IsNaN(f) || math.IsInf(f, 0) {
+		return 0, fmt.Errorf("invalid %s '%s' for %s: expected a finite number", key, value, s.Kind)
+	}
+	return f, nil
+}
+
+// Duration returns a duration parameter, or def when it is not given
+func (s *Spec) Duration(key string, def time.Duration) (time.Duration, error) {
+	s.used[key] = true
+	value, ok := s.params[key]
+	if !ok {
+		return def, nil
+	}
+	d, err := time.ParseDuration(value)
+	if err != nil {
+		return 0, fmt.Errorf("invalid %s '%s' for %s: expected a duration", key, value, s.Kind)
+	}
+	return d, nil
+}
+
+// CheckUnused returns an error naming parameters that were given but never read, to catch typos
+func (s *Spec) CheckUnused() error {
+	var unknown []string
+	for key := range s.params {
+		if !s.used[key] {
+			unknown = append(unknown, key)
+		}
+	}
+	if len(unknown) > 0 {
+		sort.Strings(unknown)
+		return fmt.Errorf("unknown parameter(s) for %s: %s", s.Kind, strings.Join(unknown, ", "))
+	}
+	return nil
+}
@@ -22,23 +22,9 @@ func ParseTimestampConfig(timestampStart, timestampSpacing string) (*TimestampCo
 	}
 
 	// Parse start time
-	var startTime time.Time
-	if timestampStart == "" {
-		// Default to current time
-		startTime = time.Now()
-	} else if strings.HasPrefix(timestampStart, "-") {
-		// Relative time (e.g., "-5m", "-1h")
-		relativeDuration, err := time.ParseDuration(timestampStart[1:]) // Remove the '-' prefix
-		if err != nil {
-			return nil, fmt.Errorf("invalid relative timestamp-start '%s': %w", timestampStart, err)
-		}
-		startTime = time.Now().Add(-relativeDuration)
-	} else {
-		// Try to parse as ISO 8601 timestamp
-		startTime, err = parseTimestamp(timestampStart)
-		if err != nil {
-			return nil, fmt.Errorf("invalid timestamp-start '%s': %w", timestampStart, err)
-		}
+	startTime, err := ParseTime(timestampStart)
+	if err != nil {
+		return nil, fmt.Errorf("invalid timestamp-start '%s': %w", timestampStart, err)
 	}
 
 	return &TimestampConfig{
@@ -47,6 +33,24 @@ func ParseTimestampConfig(timestampStart, timestampSpacing string) (*TimestampCo
 	}, nil
 }
 
+// ParseTime parses a point in time: empty for now, relative like "-5m" or "-1h", or an absolute timestamp
+func ParseTime(value string) (time.Time, error) {
+	if value == "" {
+		// Default to current time
+		return time.Now(), nil
+	}
+	if strings.HasPrefix(value, "-") {
+		// Relative time (e.g., "-5m", "-1h")
+		relativeDuration, err := time.ParseDuration(value[1:]) // Remove the '-' prefix
+		if err != nil {
+			return time.Time{}, fmt.Errorf("invalid relative time: %w", err)
+		}
+		return time.Now().Add(-relativeDuration), nil
+	}
+	// Try to parse as ISO 8601 timestamp
+	return parseTimestamp(value)
+}
+
 // CalculateTimestamp calculates the timestamp for the i-th data point
 func (tc *TimestampConfig) CalculateTimestamp(index int) time.Time {
 	return tc.StartTime.Add(time.Duration(index) * tc.Spacing)